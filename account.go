@@ -18,6 +18,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
@@ -27,18 +28,28 @@ import (
 	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
 )
 
+// ErrAccountDisabled is returned when a disabled account is asked to sign data.
+var ErrAccountDisabled = errors.New("account is disabled")
+
 // account contains the details of the account.
 type account struct {
-	id        uuid.UUID
-	name      string
-	publicKey e2types.PublicKey
-	crypto    map[string]interface{}
-	secretKey e2types.PrivateKey
-	version   uint
-	path      string
-	wallet    wtypes.Wallet
-	encryptor wtypes.Encryptor
-	mutex     *sync.RWMutex
+	id          uuid.UUID
+	name        string
+	publicKey   e2types.PublicKey
+	crypto      map[string]interface{}
+	secretKey   e2types.PrivateKey
+	version     uint
+	path        string
+	watchOnly   bool
+	disabled    bool
+	imported    bool
+	deleted     bool
+	description string
+	wallet      wtypes.Wallet
+	encryptor   wtypes.Encryptor
+	mutex       *sync.RWMutex
+	// heldKey is the private key of a programmatic account, which is held in memory rather than encrypted.
+	heldKey e2types.PrivateKey
 }
 
 // newAccount creates a new account
@@ -54,9 +65,25 @@ func (a *account) MarshalJSON() ([]byte, error) {
 	data["uuid"] = a.id.String()
 	data["name"] = a.name
 	data["pubkey"] = fmt.Sprintf("%x", a.publicKey.Marshal())
-	data["crypto"] = a.crypto
+	if a.watchOnly {
+		data["watchonly"] = true
+	} else {
+		data["crypto"] = a.crypto
+	}
 	data["path"] = a.path
 	data["version"] = a.version
+	if a.disabled {
+		data["disabled"] = true
+	}
+	if a.imported {
+		data["imported"] = true
+	}
+	if a.deleted {
+		data["deleted"] = true
+	}
+	if a.description != "" {
+		data["description"] = a.description
+	}
 	return json.Marshal(data)
 }
 
@@ -110,22 +137,57 @@ func (a *account) UnmarshalJSON(data []byte) error {
 		if err != nil {
 			return err
 		}
-		a.publicKey, err = e2types.BLSPublicKeyFromBytes(bytes)
+		a.publicKey, err = a.backend().PublicKeyFromBytes(bytes)
 		if err != nil {
 			return err
 		}
 	} else {
 		return errors.New("account pubkey missing")
 	}
+	if val, exists := v["watchonly"]; exists {
+		watchOnly, ok := val.(bool)
+		if !ok {
+			return errors.New("account watch-only flag invalid")
+		}
+		a.watchOnly = watchOnly
+	}
 	if val, exists := v["crypto"]; exists {
 		crypto, ok := val.(map[string]interface{})
 		if !ok {
 			return errors.New("account crypto invalid")
 		}
 		a.crypto = crypto
-	} else {
+	} else if !a.watchOnly {
 		return errors.New("account crypto missing")
 	}
+	if val, exists := v["disabled"]; exists {
+		disabled, ok := val.(bool)
+		if !ok {
+			return errors.New("account disabled flag invalid")
+		}
+		a.disabled = disabled
+	}
+	if val, exists := v["imported"]; exists {
+		imported, ok := val.(bool)
+		if !ok {
+			return errors.New("account imported flag invalid")
+		}
+		a.imported = imported
+	}
+	if val, exists := v["deleted"]; exists {
+		deleted, ok := val.(bool)
+		if !ok {
+			return errors.New("account deleted flag invalid")
+		}
+		a.deleted = deleted
+	}
+	if val, exists := v["description"]; exists {
+		description, ok := val.(string)
+		if !ok {
+			return errors.New("account description invalid")
+		}
+		a.description = description
+	}
 	if val, exists := v["path"]; exists {
 		path, ok := val.(string)
 		if !ok {
@@ -144,12 +206,19 @@ func (a *account) UnmarshalJSON(data []byte) error {
 	} else {
 		return errors.New("account version missing")
 	}
-	// Only support keystorev4 at current...
-	if a.version == 4 {
-		a.encryptor = keystorev4.New()
-	} else {
+	// Select the encryptor for the account's keystore version from those supplied to the wallet, falling back
+	// to keystorev4.
+	var encryptor wtypes.Encryptor
+	if w, ok := a.wallet.(*wallet); ok {
+		encryptor = w.encryptorFor("", a.version)
+	}
+	if encryptor == nil && a.version == 4 {
+		encryptor = keystorev4.New()
+	}
+	if encryptor == nil {
 		return errors.New("unsupported keystore version")
 	}
+	a.encryptor = encryptor
 
 	return nil
 }
@@ -164,51 +233,293 @@ func (a *account) Name() string {
 	return a.name
 }
 
+// Disabled returns true if the account is disabled.
+func (a *account) Disabled() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.disabled
+}
+
+// SetDisabled sets whether the account is disabled.  A disabled account retains its keys but cannot sign data,
+// for example when its validator has exited but the keystore must be retained for audit.
+// Watch-only accounts, which cannot sign, and programmatic accounts, which are not stored, cannot be disabled.
+func (a *account) SetDisabled(disabled bool) error {
+	if a.watchOnly {
+		return ErrWatchOnly
+	}
+	if !a.isStored() {
+		return fmt.Errorf("account %q cannot be disabled", a.Name())
+	}
+
+	a.mutex.Lock()
+	oldDisabled := a.disabled
+	a.disabled = disabled
+	a.mutex.Unlock()
+	if disabled == oldDisabled {
+		return nil
+	}
+
+	if err := a.storeAccount(); err != nil {
+		a.mutex.Lock()
+		a.disabled = oldDisabled
+		a.mutex.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Imported returns true if the account's key was imported rather than derived from the wallet's seed.
+func (a *account) Imported() bool {
+	return a.imported
+}
+
+// Description provides the description of the account.
+func (a *account) Description() string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.description
+}
+
+// SetDescription sets the description of the account, which is stored in the account's keystore as per EIP-2335.
+// Watch-only accounts, which have no keystore, and programmatic accounts, which are not stored, cannot be given a
+// description.
+func (a *account) SetDescription(description string) error {
+	if a.watchOnly {
+		return ErrWatchOnly
+	}
+	if !a.isStored() {
+		return fmt.Errorf("account %q cannot be given a description", a.Name())
+	}
+
+	a.mutex.Lock()
+	oldDescription := a.description
+	a.description = description
+	a.mutex.Unlock()
+	if description == oldDescription {
+		return nil
+	}
+
+	if err := a.storeAccount(); err != nil {
+		a.mutex.Lock()
+		a.description = oldDescription
+		a.mutex.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// Rename renames the account.
+// This will error if an account with the new name already exists in the wallet.
+func (a *account) Rename(newName string) error {
+	if newName == "" {
+		return errors.New("account name missing")
+	}
+	if strings.HasPrefix(newName, "_") {
+		return fmt.Errorf("invalid account name %q", newName)
+	}
+	if newName == a.Name() {
+		return nil
+	}
+	w, ok := a.wallet.(*wallet)
+	if !ok {
+		return fmt.Errorf("account %q cannot be renamed", a.Name())
+	}
+	if strings.HasPrefix(newName, "m/") {
+		// Programmatic names are not in the index.
+		if _, err := w.AccountByName(newName); err == nil {
+			return fmt.Errorf("account with name %q already exists", newName)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// The index is checked under the wallet's lock, so that another account cannot take the name before the index is
+	// updated.
+	if !w.index.IDKnown(a.id) {
+		return fmt.Errorf("account %q cannot be renamed", a.Name())
+	}
+	if _, exists := w.index.ID(newName); exists {
+		return fmt.Errorf("account with name %q already exists", newName)
+	}
+	oldName := a.name
+	a.setName(newName)
+	w.index.Remove(a.id, oldName)
+	w.index.Add(a.id, newName)
+	if err := a.storeAccount(); err != nil {
+		a.setName(oldName)
+		w.index.Remove(a.id, newName)
+		w.index.Add(a.id, oldName)
+		// Restore the stored index in case it was written before the failure.
+		if indexErr := w.storeAccountsIndex(); indexErr != nil {
+			return errors.Wrapf(err, "failed to rename account, and failed to restore accounts index: %v", indexErr)
+		}
+		return errors.Wrap(err, "failed to rename account")
+	}
+
+	return nil
+}
+
+// isStored returns true if the account is held in its wallet's accounts index, so its changes can be stored.
+// Programmatic accounts are calculated on demand, so are not.
+func (a *account) isStored() bool {
+	w, ok := a.wallet.(*wallet)
+	if !ok {
+		return false
+	}
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.index.IDKnown(a.id)
+}
+
+// setName sets the name of the account.
+func (a *account) setName(name string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.name = name
+}
+
+// setDeleted sets whether the account is soft-deleted.
+func (a *account) setDeleted(deleted bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.deleted = deleted
+}
+
 // PublicKey provides the public key for the account.
 func (a *account) PublicKey() e2types.PublicKey {
 	// Safe to ignore the error as this is already a public key
-	keyCopy, _ := e2types.BLSPublicKeyFromBytes(a.publicKey.Marshal())
+	keyCopy, _ := a.backend().PublicKeyFromBytes(a.publicKey.Marshal())
 	return keyCopy
 }
 
 // PrivateKey provides the private key for the account.
 func (a *account) PrivateKey() (e2types.PrivateKey, error) {
-	if !a.IsUnlocked() {
+	if a.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if !a.isUnlocked() {
 		return nil, errors.New("cannot provide private key when account is locked")
 	}
-	return e2types.BLSPrivateKeyFromBytes(a.secretKey.Marshal())
+	return a.backend().PrivateKeyFromBytes(a.secretKey.Marshal())
 }
 
 // Lock locks the account.  A locked account cannot sign data.
+// The decrypted private key is overwritten with zeros; see zeroPrivateKey for the keys that support this.  The key of a
+// programmatic account is held so that the account can be unlocked again, so is kept until the account is discarded.
 func (a *account) Lock() {
 	a.mutex.Lock()
-	defer a.mutex.Unlock()
+	wasUnlocked := a.isUnlocked()
+	if a.heldKey == nil && a.secretKey != nil {
+		zeroPrivateKey(a.secretKey)
+	}
 	a.secretKey = nil
+	a.mutex.Unlock()
+
+	if h := a.walletHooks(); h != nil && wasUnlocked {
+		h.accountLocked(a)
+	}
 }
 
 // Unlock unlocks the account.  An unlocked account can sign data.
 func (a *account) Unlock(passphrase []byte) error {
+	if err := a.unlock(passphrase); err != nil {
+		return err
+	}
+	if h := a.walletHooks(); h != nil {
+		h.accountUnlocked(a)
+	}
+
+	return nil
+}
+
+// unlock unlocks the account without calling hooks.
+func (a *account) unlock(passphrase []byte) error {
+	if a.watchOnly {
+		return ErrWatchOnly
+	}
+
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	secretBytes, err := a.encryptor.Decrypt(a.crypto, passphrase)
-	if err != nil {
-		return errors.New("incorrect passphrase")
+	if a.heldKey != nil {
+		// Programmatic accounts are unlocked with an empty passphrase.
+		if len(passphrase) != 0 {
+			return errors.New("incorrect passphrase")
+		}
+		a.secretKey = a.heldKey
+		return nil
 	}
-	secretKey, err := e2types.BLSPrivateKeyFromBytes(secretBytes)
+
+	secretKey, err := a.decryptKey(passphrase)
 	if err != nil {
 		return err
 	}
+	a.secretKey = secretKey
+	return nil
+}
+
+// secretKeyCopy provides a copy of the account's secret key, decrypted with the passphrase, without unlocking the
+// account.
+func (a *account) secretKeyCopy(passphrase []byte) (e2types.PrivateKey, error) {
+	if a.watchOnly {
+		return nil, ErrWatchOnly
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.heldKey != nil {
+		// Programmatic accounts are unlocked with an empty passphrase.
+		if len(passphrase) != 0 {
+			return nil, errors.New("incorrect passphrase")
+		}
+		return copyPrivateKey(a.backend(), a.heldKey)
+	}
+	return a.decryptKey(passphrase)
+}
+
+// decryptKey decrypts the account's secret key with the passphrase.
+// The caller must hold the account's lock.
+func (a *account) decryptKey(passphrase []byte) (e2types.PrivateKey, error) {
+	backoff := a.unlockBackoff()
+	if err := backoff.check(a.id); err != nil {
+		return nil, err
+	}
+	secretBytes, err := decryptSecret(a.encryptor, a.crypto, passphrase)
+	if err != nil {
+		backoff.failed(a.id)
+		return nil, errors.New("incorrect passphrase")
+	}
+	backoff.succeeded(a.id)
+	secretKey, err := a.backend().PrivateKeyFromBytes(secretBytes)
+	zeroBytes(secretBytes)
+	if err != nil {
+		return nil, err
+	}
 	publicKey := secretKey.PublicKey()
 	if !bytes.Equal(publicKey.Marshal(), a.publicKey.Marshal()) {
-		return errors.New("secret key does not correspond to public key")
+		return nil, errors.New("secret key does not correspond to public key")
 	}
-	a.secretKey = secretKey
-	return nil
+	return secretKey, nil
 }
 
 // IsUnlocked returns true if the account is unlocked.
 func (a *account) IsUnlocked() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.isUnlocked()
+}
+
+// isUnlocked returns true if the account is unlocked.
+// The caller must hold the account's lock.
+func (a *account) isUnlocked() bool {
 	return a.secretKey != nil
 }
 
@@ -221,12 +532,40 @@ func (a *account) Path() string {
 func (a *account) Sign(data []byte) (e2types.Signature, error) {
 	a.mutex.RLock()
 	defer a.mutex.RUnlock()
-	if !a.IsUnlocked() {
+	if a.disabled {
+		return nil, ErrAccountDisabled
+	}
+	if !a.isUnlocked() {
 		return nil, errors.New("cannot sign when account is locked")
 	}
 	return a.secretKey.Sign(data), nil
 }
 
+// backend provides the BLS backend of the account's wallet.
+func (a *account) backend() BLSBackend {
+	if w, ok := a.wallet.(*wallet); ok && w.backend != nil {
+		return w.backend
+	}
+	return defaultBackend
+}
+
+// unlockBackoff provides the backoff for failed unlock attempts, which is held by the wallet so that it persists
+// across retrievals of the account.
+func (a *account) unlockBackoff() *unlockBackoff {
+	if w, ok := a.wallet.(*wallet); ok && w.backoff != nil {
+		return w.backoff
+	}
+	return newUnlockBackoff(0, 0)
+}
+
+// walletHooks provides the hooks of the account's wallet, or nil if the account does not belong to a wallet.
+func (a *account) walletHooks() *hooks {
+	if w, ok := a.wallet.(*wallet); ok && w.hooks != nil {
+		return w.hooks
+	}
+	return nil
+}
+
 // storeAccount stores the accout.
 func (a *account) storeAccount() error {
 	a.mutex.RLock()
@@ -14,8 +14,14 @@
 package hd_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -56,7 +62,7 @@ func TestCreateAccount(t *testing.T) {
 
 	store := scratch.New()
 	encryptor := keystorev4.New()
-	wallet, err := hd.CreateWallet("test wallet", []byte("wallet passphrase"), store, encryptor)
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")))
 	require.Nil(t, err)
 
 	// Try to create without unlocking the wallet; should fail
@@ -90,3 +96,626 @@ func TestCreateAccount(t *testing.T) {
 		})
 	}
 }
+
+// countingStore is a store that counts the writes made to it.
+type countingStore struct {
+	wtypes.Store
+	writes int
+}
+
+func (s *countingStore) StoreWallet(walletID uuid.UUID, walletName string, data []byte) error {
+	s.writes++
+	return s.Store.StoreWallet(walletID, walletName, data)
+}
+
+func (s *countingStore) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	s.writes++
+	return s.Store.StoreAccount(walletID, accountID, data)
+}
+
+func (s *countingStore) StoreAccountsIndex(walletID uuid.UUID, data []byte) error {
+	s.writes++
+	return s.Store.StoreAccountsIndex(walletID, data)
+}
+
+func TestCreateAccounts(t *testing.T) {
+	store := &countingStore{Store: scratch.New()}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	creator, isCreator := wallet.(hd.WalletAccountsCreator)
+	require.True(t, isCreator)
+
+	_, err = creator.CreateAccounts([]string{"Account 1"}, nil)
+	assert.EqualError(t, err, "wallet must be unlocked to create accounts")
+
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Existing", nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		names []string
+		err   string
+	}{
+		{
+			name:  "Empty",
+			names: []string{"Account 1", ""},
+			err:   "account name missing",
+		},
+		{
+			name:  "Invalid",
+			names: []string{"_Account 1"},
+			err:   `invalid account name "_Account 1"`,
+		},
+		{
+			name:  "Duplicate",
+			names: []string{"Account 1", "Account 1"},
+			err:   `duplicate account name "Account 1"`,
+		},
+		{
+			name:  "Exists",
+			names: []string{"Account 1", "Existing"},
+			err:   `account with name "Existing" already exists`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := creator.CreateAccounts(test.names, nil)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+
+	names := make([]string, 10)
+	for i := range names {
+		names[i] = fmt.Sprintf("Account %d", i)
+	}
+	store.writes = 0
+	accounts, err := creator.CreateAccounts(names, []byte("account passphrase"))
+	require.NoError(t, err)
+	// One write per account, plus the wallet and the index before and after.
+	assert.Equal(t, len(names)+3, store.writes)
+	require.Len(t, accounts, len(names))
+	for i, account := range accounts {
+		assert.Equal(t, names[i], account.Name())
+		assert.Equal(t, fmt.Sprintf("m/12381/3600/%d/0", i+1), account.Path())
+	}
+
+	// Ensure the accounts were persisted, and match those that would have been created individually.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(names)+1), wallet.(hd.WalletNextAccountProvider).NextAccount())
+	for _, name := range names {
+		account, err := wallet.AccountByName(name)
+		require.NoError(t, err)
+		require.NoError(t, account.Unlock([]byte("account passphrase")))
+	}
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Next", nil)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("m/12381/3600/%d/0", len(names)+1), account.Path())
+}
+
+func TestCreateAccountsExhausted(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	creator := wallet.(hd.WalletAccountsCreator)
+
+	require.NoError(t, wallet.(hd.WalletNextAccountSetter).SetNextAccount(math.MaxInt32-1))
+	_, err = creator.CreateAccounts([]string{"Account 1", "Account 2"}, nil)
+	assert.EqualError(t, err, "too many accounts")
+
+	// Using the highest account number takes the next account number beyond it.
+	_, err = wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Account max", math.MaxInt32, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(math.MaxInt32+1), wallet.(hd.WalletNextAccountProvider).NextAccount())
+	_, err = creator.CreateAccounts([]string{"Account 1"}, nil)
+	assert.EqualError(t, err, "too many accounts")
+}
+
+func TestCreateAccountAtIndex(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	creator, isCreator := wallet.(hd.WalletAccountAtIndexCreator)
+	require.True(t, isCreator)
+
+	_, err = creator.CreateAccountAtIndex("Account 3", 3, nil)
+	assert.EqualError(t, err, "wallet must be unlocked to create accounts")
+
+	require.NoError(t, wallet.Unlock(nil))
+	account0, err := wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		accountName string
+		index       uint64
+		err         string
+		path        string
+		nextAccount uint64
+	}{
+		{
+			name:  "NameMissing",
+			index: 1,
+			err:   "account name missing",
+		},
+		{
+			name:        "NameExists",
+			accountName: "Account 0",
+			index:       1,
+			err:         `account with name "Account 0" already exists`,
+		},
+		{
+			name:        "IndexExists",
+			accountName: "Account 0 again",
+			index:       0,
+			err:         "account at index 0 already exists",
+		},
+		{
+			name:        "IndexTooLarge",
+			accountName: "Account big",
+			index:       math.MaxInt32 + 1,
+			err:         "account index too large",
+		},
+		{
+			name:        "Ahead",
+			accountName: "Account 3",
+			index:       3,
+			path:        "m/12381/3600/3/0",
+			nextAccount: 4,
+		},
+		{
+			name:        "Skipped",
+			accountName: "Account 2",
+			index:       2,
+			path:        "m/12381/3600/2/0",
+			nextAccount: 4,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			account, err := creator.CreateAccountAtIndex(test.accountName, test.index, nil)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.path, account.Path())
+			assert.Equal(t, test.nextAccount, wallet.(hd.WalletNextAccountProvider).NextAccount())
+		})
+	}
+
+	// Ensure an account re-created at an index matches the original.
+	otherWallet, err := hd.CreateWallet("other wallet", store, encryptor, hd.WithSeed(_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")))
+	require.NoError(t, err)
+	require.NoError(t, otherWallet.Unlock(nil))
+	_, err = otherWallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	original, err := otherWallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	recreatedWallet, err := hd.CreateWallet("recreated wallet", store, encryptor, hd.WithSeed(_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")))
+	require.NoError(t, err)
+	require.NoError(t, recreatedWallet.Unlock(nil))
+	recreated, err := recreatedWallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Account 1", 1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, original.PublicKey().Marshal(), recreated.PublicKey().Marshal())
+	assert.NotEqual(t, account0.PublicKey().Marshal(), recreated.PublicKey().Marshal())
+}
+
+// deletableStore is a store that supports account deletion.
+type deletableStore struct {
+	wtypes.Store
+	deleted map[uuid.UUID]bool
+}
+
+func (s *deletableStore) DeleteAccount(walletID uuid.UUID, accountID uuid.UUID) error {
+	if _, err := s.RetrieveAccount(walletID, accountID); err != nil {
+		return err
+	}
+	s.deleted[accountID] = true
+	return nil
+}
+
+func (s *deletableStore) RetrieveAccount(walletID uuid.UUID, accountID uuid.UUID) ([]byte, error) {
+	if s.deleted[accountID] {
+		return nil, errors.New("account not found")
+	}
+	return s.Store.RetrieveAccount(walletID, accountID)
+}
+
+func (s *deletableStore) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	ch := make(chan []byte, 1024)
+	go func() {
+		for data := range s.Store.RetrieveAccounts(walletID) {
+			var account struct {
+				ID uuid.UUID `json:"uuid"`
+			}
+			if err := json.Unmarshal(data, &account); err == nil && !s.deleted[account.ID] {
+				ch <- data
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func TestDeleteAccount(t *testing.T) {
+	encryptor := keystorev4.New()
+
+	store := scratch.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	deleter, isDeleter := wallet.(hd.WalletAccountDeleter)
+	require.True(t, isDeleter)
+	require.NoError(t, wallet.Unlock(nil))
+	account0, err := wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	account1, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	assert.EqualError(t, deleter.DeleteAccount("Unknown"), `no account with name "Unknown"`)
+	assert.EqualError(t, deleter.DeleteAccount("m/12381/3600/0/0"), `account "m/12381/3600/0/0" cannot be deleted`)
+	require.NoError(t, deleter.DeleteAccount("Account 0"))
+	require.NoError(t, deleter.DeleteAccount(account1.ID().String()))
+
+	// Ensure the deletions were persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	_, err = wallet.AccountByName("Account 0")
+	assert.EqualError(t, err, `no account with name "Account 0"`)
+	_, err = wallet.AccountByName("Account 1")
+	assert.EqualError(t, err, `no account with name "Account 1"`)
+	accounts := 0
+	for range wallet.Accounts() {
+		accounts++
+	}
+	assert.Equal(t, 0, accounts)
+
+	// Ensure the deleted indices are not silently re-used.
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/2/0", account.Path())
+	creator := wallet.(hd.WalletAccountAtIndexCreator)
+	_, err = creator.CreateAccountAtIndex("Another account", 0, nil)
+	assert.EqualError(t, err, `account index 0 was used by deleted account "Account 0"`)
+	account, err = creator.CreateAccountAtIndex("Account 0", 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, account0.PublicKey().Marshal(), account.PublicKey().Marshal())
+
+	// Ensure the re-created account's tombstone was cleared.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.AccountByName("Account 0")
+	require.NoError(t, err)
+	require.NoError(t, wallet.(hd.WalletAccountDeleter).DeleteAccount("Account 0"))
+	_, err = wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Another account", 0, nil)
+	assert.EqualError(t, err, `account index 0 was used by deleted account "Account 0"`)
+}
+
+func TestSoftDeleteAccount(t *testing.T) {
+	store := &deletableStore{Store: scratch.New(), deleted: make(map[uuid.UUID]bool)}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account0, err := wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	account1, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	restorer, isRestorer := wallet.(hd.WalletAccountRestorer)
+	require.True(t, isRestorer)
+	purger, isPurger := wallet.(hd.WalletAccountPurger)
+	require.True(t, isPurger)
+
+	_, err = restorer.RestoreAccount(account0.ID())
+	assert.EqualError(t, err, fmt.Sprintf("account %s is not deleted", account0.ID()))
+	assert.EqualError(t, purger.PurgeAccount(account0.ID()), fmt.Sprintf("account %s is not deleted", account0.ID()))
+
+	require.NoError(t, wallet.(hd.WalletAccountDeleter).DeleteAccount("Account 0"))
+	require.NoError(t, wallet.(hd.WalletAccountDeleter).DeleteAccount("Account 1"))
+	_, err = wallet.AccountByID(account0.ID())
+	assert.EqualError(t, err, fmt.Sprintf("account %s is deleted", account0.ID()))
+
+	// Ensure the deleted accounts are listed once the wallet is re-opened.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	deleted := make(map[string]bool)
+	for account := range wallet.(hd.WalletDeletedAccountsProvider).DeletedAccounts() {
+		deleted[account.Name()] = true
+	}
+	assert.Equal(t, map[string]bool{"Account 0": true, "Account 1": true}, deleted)
+
+	// Restoring fails if the account's name or path has been taken.
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	restorer = wallet.(hd.WalletAccountRestorer)
+	_, err = restorer.RestoreAccount(account1.ID())
+	assert.EqualError(t, err, `account with name "Account 1" already exists`)
+	require.NoError(t, wallet.(hd.WalletAccountDeleter).DeleteAccount("Account 1"))
+	recreated, err := wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Account 1", 1, nil)
+	require.NoError(t, err)
+	require.NoError(t, recreated.(hd.AccountRenamer).Rename("Renamed"))
+	_, err = restorer.RestoreAccount(account1.ID())
+	assert.EqualError(t, err, `account path m/12381/3600/1/0 is in use by account "Renamed"`)
+
+	account, err := restorer.RestoreAccount(account0.ID())
+	require.NoError(t, err)
+	assert.Equal(t, "Account 0", account.Name())
+	assert.Equal(t, account0.PublicKey().Marshal(), account.PublicKey().Marshal())
+
+	// Ensure the restoration was persisted, and the account's index can be re-used by it once more.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	account, err = wallet.AccountByName("Account 0")
+	require.NoError(t, err)
+	assert.Equal(t, account0.ID(), account.ID())
+	require.NoError(t, account.Unlock(nil))
+
+	// Purging permanently deletes the account.
+	require.NoError(t, wallet.(hd.WalletAccountPurger).PurgeAccount(account1.ID()))
+	_, err = wallet.(hd.WalletAccountRestorer).RestoreAccount(account1.ID())
+	assert.EqualError(t, err, "account not found")
+
+	// Purging requires a store that supports deletion.
+	wallet, err = hd.CreateWallet("test wallet", scratch.New(), encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err = wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	require.NoError(t, wallet.(hd.WalletAccountDeleter).DeleteAccount("Account 0"))
+	assert.EqualError(t, wallet.(hd.WalletAccountPurger).PurgeAccount(account.ID()), "store does not support account deletion")
+}
+
+// failingAccountStore is a store that can be set to fail account writes.
+type failingAccountStore struct {
+	wtypes.Store
+	fail bool
+}
+
+func (s *failingAccountStore) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	if s.fail {
+		return errors.New("store unavailable")
+	}
+	return s.Store.StoreAccount(walletID, accountID, data)
+}
+
+func TestRenameAccount(t *testing.T) {
+	store := &failingAccountStore{Store: scratch.New()}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account 1", []byte("account passphrase"))
+	require.NoError(t, err)
+	_, err = wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	renamer, isRenamer := account.(hd.AccountRenamer)
+	require.True(t, isRenamer)
+
+	assert.EqualError(t, renamer.Rename(""), "account name missing")
+	assert.EqualError(t, renamer.Rename("_bad"), `invalid account name "_bad"`)
+	assert.EqualError(t, renamer.Rename("Account 2"), `account with name "Account 2" already exists`)
+	require.NoError(t, renamer.Rename("Account 1"))
+	programmatic, err := wallet.AccountByName("m/12381/3600/5/0")
+	require.NoError(t, err)
+	assert.EqualError(t, programmatic.(hd.AccountRenamer).Rename("Programmatic"), `account "m/12381/3600/5/0" cannot be renamed`)
+
+	// Ensure a failed rename leaves the account and index untouched.
+	store.fail = true
+	assert.EqualError(t, renamer.Rename("Validator 1"), "failed to rename account: store unavailable")
+	store.fail = false
+	assert.Equal(t, "Account 1", account.Name())
+	_, err = wallet.AccountByName("Account 1")
+	require.NoError(t, err)
+
+	require.NoError(t, renamer.Rename("Validator 1"))
+	assert.Equal(t, "Validator 1", account.Name())
+
+	// Ensure the rename was persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	_, err = wallet.AccountByName("Account 1")
+	assert.EqualError(t, err, `no account with name "Account 1"`)
+	renamed, err := wallet.AccountByName("Validator 1")
+	require.NoError(t, err)
+	assert.Equal(t, account.ID(), renamed.ID())
+	assert.Equal(t, "Validator 1", renamed.Name())
+	require.NoError(t, renamed.Unlock([]byte("account passphrase")))
+}
+
+func TestRenameAccountConcurrent(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	accounts, err := wallet.(hd.WalletAccountsCreator).CreateAccounts([]string{"Account 1", "Account 2", "Account 3", "Account 4", "Account 5", "Account 6", "Account 7", "Account 8"}, nil)
+	require.NoError(t, err)
+
+	// Only one of the accounts can take the name.
+	for round := 0; round < 20; round++ {
+		newName := fmt.Sprintf("Validator %d", round)
+		var wg sync.WaitGroup
+		var renamed int32
+		for _, account := range accounts {
+			wg.Add(1)
+			go func(account wtypes.Account) {
+				defer wg.Done()
+				if err := account.(hd.AccountRenamer).Rename(newName); err == nil {
+					atomic.AddInt32(&renamed, 1)
+				} else {
+					assert.EqualError(t, err, fmt.Sprintf("account with name %q already exists", newName))
+				}
+			}(account)
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), renamed)
+	}
+}
+
+func TestRegenerateAccount(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	regenerator, isRegenerator := wallet.(hd.WalletAccountRegenerator)
+	require.True(t, isRegenerator)
+
+	_, err = regenerator.RegenerateAccount(0, "Account 0", nil)
+	assert.EqualError(t, err, "wallet must be unlocked to regenerate accounts")
+
+	require.NoError(t, wallet.Unlock(nil))
+	account0, err := wallet.CreateAccount("Account 0", []byte("old passphrase"))
+	require.NoError(t, err)
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	_, err = regenerator.RegenerateAccount(2, "Account 2", nil)
+	assert.EqualError(t, err, "account index 2 has not been used")
+	_, err = regenerator.RegenerateAccount(1, "Account 0", nil)
+	assert.EqualError(t, err, `regenerated public key does not match account "Account 0"`)
+	_, err = regenerator.RegenerateAccount(1, "Another account", nil)
+	assert.EqualError(t, err, "account at index 1 already exists")
+
+	// Regenerate an intact account with a new passphrase.
+	account, err := regenerator.RegenerateAccount(0, "Account 0", []byte("new passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, account0.ID(), account.ID())
+	assert.Equal(t, account0.PublicKey().Marshal(), account.PublicKey().Marshal())
+
+	// Corrupt the account and regenerate it.
+	require.NoError(t, store.StoreAccount(wallet.ID(), account0.ID(), []byte("corrupt")))
+	_, err = wallet.AccountByName("Account 0")
+	assert.NotNil(t, err)
+	account, err = regenerator.RegenerateAccount(0, "Account 0", []byte("account passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, account0.ID(), account.ID())
+
+	// Ensure the regenerated account was persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	account, err = wallet.AccountByName("Account 0")
+	require.NoError(t, err)
+	assert.Equal(t, account0.ID(), account.ID())
+	assert.Equal(t, account0.Path(), account.Path())
+	assert.Equal(t, account0.PublicKey().Marshal(), account.PublicKey().Marshal())
+	require.NoError(t, account.Unlock([]byte("account passphrase")))
+}
+
+func TestDisableAccount(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account", []byte("account passphrase"))
+	require.NoError(t, err)
+	disabler, isDisabler := account.(hd.AccountDisabler)
+	require.True(t, isDisabler)
+	assert.False(t, disabler.Disabled())
+
+	require.NoError(t, account.Unlock([]byte("account passphrase")))
+	_, err = account.Sign([]byte("data"))
+	require.NoError(t, err)
+
+	require.NoError(t, disabler.SetDisabled(true))
+	assert.True(t, disabler.Disabled())
+	_, err = account.Sign([]byte("data"))
+	assert.Equal(t, hd.ErrAccountDisabled, err)
+
+	// Ensure the flag was persisted, and the account can still be unlocked.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	account, err = wallet.AccountByName("Account")
+	require.NoError(t, err)
+	assert.True(t, account.(hd.AccountDisabler).Disabled())
+	require.NoError(t, account.Unlock([]byte("account passphrase")))
+	_, err = account.Sign([]byte("data"))
+	assert.Equal(t, hd.ErrAccountDisabled, err)
+
+	require.NoError(t, account.(hd.AccountDisabler).SetDisabled(false))
+	_, err = account.Sign([]byte("data"))
+	require.NoError(t, err)
+	account, err = wallet.AccountByName("Account")
+	require.NoError(t, err)
+	assert.False(t, account.(hd.AccountDisabler).Disabled())
+}
+
+func TestAccountDescription(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account", []byte("account passphrase"))
+	require.NoError(t, err)
+	describer, isDescriber := account.(hd.AccountDescriptionProvider)
+	require.True(t, isDescriber)
+	assert.Equal(t, "", describer.Description())
+
+	require.NoError(t, describer.SetDescription("Validator 1"))
+	assert.Equal(t, "Validator 1", describer.Description())
+
+	// Ensure the description and public key are in the stored keystore.
+	var keystore map[string]interface{}
+	for data := range store.RetrieveAccounts(wallet.ID()) {
+		require.NoError(t, json.Unmarshal(data, &keystore))
+	}
+	assert.Equal(t, "Validator 1", keystore["description"])
+	assert.Equal(t, fmt.Sprintf("%x", account.PublicKey().Marshal()), keystore["pubkey"])
+
+	// Ensure the description was persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	account, err = wallet.AccountByName("Account")
+	require.NoError(t, err)
+	assert.Equal(t, "Validator 1", account.(hd.AccountDescriptionProvider).Description())
+	require.NoError(t, account.Unlock([]byte("account passphrase")))
+}
+
+func TestAccountMetadataNotStored(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+
+	// Programmatic accounts are not stored, so their metadata cannot be changed.
+	programmatic, err := wallet.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/3600/5/0")
+	require.NoError(t, err)
+	assert.EqualError(t, programmatic.(hd.AccountDisabler).SetDisabled(true), `account "m/12381/3600/5/0" cannot be disabled`)
+	assert.False(t, programmatic.(hd.AccountDisabler).Disabled())
+	assert.EqualError(t, programmatic.(hd.AccountDescriptionProvider).SetDescription("Programmatic"), `account "m/12381/3600/5/0" cannot be given a description`)
+	assert.Equal(t, "", programmatic.(hd.AccountDescriptionProvider).Description())
+	records := 0
+	for range store.RetrieveAccounts(wallet.ID()) {
+		records++
+	}
+	assert.Equal(t, 1, records)
+
+	// Watch-only accounts cannot sign or hold a keystore.
+	watchOnly, err := hd.CreateWatchOnlyWallet("watch-only wallet", store, encryptor, []*hd.WatchOnlyAccount{
+		{
+			Name:      "Account",
+			Path:      account.Path(),
+			PublicKey: account.PublicKey(),
+		},
+	})
+	require.NoError(t, err)
+	watched, err := watchOnly.AccountByName("Account")
+	require.NoError(t, err)
+	assert.Equal(t, hd.ErrWatchOnly, watched.(hd.AccountDisabler).SetDisabled(true))
+	assert.Equal(t, hd.ErrWatchOnly, watched.(hd.AccountDescriptionProvider).SetDescription("Watched"))
+	watchOnly, err = hd.OpenWallet("watch-only wallet", store, encryptor)
+	require.NoError(t, err)
+	watched, err = watchOnly.AccountByName("Account")
+	require.NoError(t, err)
+	assert.False(t, watched.(hd.AccountDisabler).Disabled())
+	assert.Equal(t, "", watched.(hd.AccountDescriptionProvider).Description())
+}
@@ -0,0 +1,101 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"io"
+
+	"filippo.io/age"
+	"github.com/pkg/errors"
+)
+
+// Exports to age recipients are age v1 files, as per https://age-encryption.org/v1, encrypted to X25519 recipients
+// so that they can be decrypted with the age tool.  The plaintext is the unencrypted export, or an export protected
+// by a passphrase if one is supplied, in which case both an identity and the passphrase are required to import it.
+
+// ageMagic is the start of an age file.
+var ageMagic = []byte("age-")
+
+// ExportToRecipients exports the entire wallet to the writer encrypted to the given age X25519 recipients, for
+// example "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p", so that it can be restored by the holder
+// of any of their identities without a shared passphrase.  If passphrase is supplied the export is additionally
+// protected by the passphrase, as per ExportTo.  Such an export is imported with ImportFrom, supplying the identities
+// with WithAgeIdentities.
+func (w *wallet) ExportToRecipients(writer io.Writer, passphrase []byte, recipients ...string) error {
+	if len(recipients) == 0 {
+		return errors.New("no recipients supplied")
+	}
+	ageRecipients := make([]age.Recipient, len(recipients))
+	for i, recipient := range recipients {
+		ageRecipient, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return errors.Wrapf(err, "invalid recipient %q", recipient)
+		}
+		ageRecipients[i] = ageRecipient
+	}
+
+	ageWriter, err := age.Encrypt(writer, ageRecipients...)
+	if err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	if len(passphrase) > 0 {
+		if err := w.ExportTo(ageWriter, passphrase); err != nil {
+			return err
+		}
+	} else if err := w.exportJSON(ageWriter); err != nil {
+		return err
+	}
+	if err := ageWriter.Close(); err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	return nil
+}
+
+// decodeAgeExport decodes a wallet from an export encrypted to age recipients.
+func decodeAgeExport(reader io.Reader, passphrase []byte, identities []string) (*wallet, func() (*account, error), error) {
+	if len(identities) == 0 {
+		return nil, nil, errors.New("export is encrypted to age recipients but no identities supplied")
+	}
+	ageIdentities := make([]age.Identity, len(identities))
+	for i, identity := range identities {
+		ageIdentity, err := age.ParseX25519Identity(identity)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid identity")
+		}
+		ageIdentities[i] = ageIdentity
+	}
+	ageReader, err := age.Decrypt(reader, ageIdentities...)
+	if err != nil {
+		if _, isNoMatch := err.(*age.NoIdentityMatchError); isNoMatch {
+			return nil, nil, errors.New("no identity matches the export's recipients")
+		}
+		return nil, nil, errors.Wrap(err, "failed to decrypt export")
+	}
+
+	return decodeEnclosedExport(&ageExportReader{reader: ageReader}, passphrase)
+}
+
+// ageExportReader reports failures of the age payload, which are due to the export being truncated or modified.
+type ageExportReader struct {
+	reader io.Reader
+}
+
+// Read reads decrypted data.
+func (a *ageExportReader) Read(p []byte) (int, error) {
+	n, err := a.reader.Read(p)
+	if err != nil && err != io.EOF {
+		return n, errors.Wrap(err, "export truncated or corrupt")
+	}
+	return n, err
+}
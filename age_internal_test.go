@@ -0,0 +1,199 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+// newAgeIdentity creates an age identity and its recipient.
+func newAgeIdentity(t *testing.T) (string, string) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	return identity.String(), identity.Recipient().String()
+}
+
+func TestExportToRecipients(t *testing.T) {
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+	for i := 0; i < 250; i++ {
+		_, err = w.CreateAccount(fmt.Sprintf("Account %d", i), nil)
+		require.NoError(t, err)
+	}
+
+	identity1, recipient1 := newAgeIdentity(t)
+	identity2, recipient2 := newAgeIdentity(t)
+	identity3, _ := newAgeIdentity(t)
+
+	tests := []struct {
+		name       string
+		passphrase []byte
+		identities []string
+		err        string
+	}{
+		{
+			name:       "First",
+			identities: []string{identity1},
+		},
+		{
+			name:       "Second",
+			identities: []string{identity3, identity2},
+		},
+		{
+			name: "NoIdentities",
+			err:  "export is encrypted to age recipients but no identities supplied",
+		},
+		{
+			name:       "WrongIdentity",
+			identities: []string{identity3},
+			err:        "no identity matches the export's recipients",
+		},
+		{
+			name:       "BadIdentity",
+			identities: []string{"bad"},
+			err:        "invalid identity: malformed secret key: separator '1' at invalid position: pos=-1, len=3",
+		},
+		{
+			name:       "Passphrase",
+			passphrase: []byte("export"),
+			identities: []string{identity1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, w.ExportToRecipients(buf, test.passphrase, recipient1, recipient2))
+			require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("age-encryption.org/v1\n")))
+			data := buf.Bytes()
+
+			imported, err := ImportFrom(bytes.NewReader(data), test.passphrase, scratch.New(), keystorev4.New(), WithAgeIdentities(test.identities...))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, w.ID(), imported.ID())
+			_, err = imported.AccountByName("Account 249")
+			require.NoError(t, err)
+
+			report, err := ValidateImport(data, test.passphrase, nil, WithAgeIdentities(test.identities...))
+			require.NoError(t, err)
+			require.Len(t, report.Accounts, 250)
+		})
+	}
+}
+
+func TestExportToRecipientsTampered(t *testing.T) {
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+	_, err = w.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	identity, recipient := newAgeIdentity(t)
+	require.EqualError(t, w.ExportToRecipients(new(bytes.Buffer), nil), "no recipients supplied")
+	require.Error(t, w.ExportToRecipients(new(bytes.Buffer), nil, "age1invalid"))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, w.ExportToRecipients(buf, []byte("export"), recipient))
+	data := buf.Bytes()
+
+	// Passphrase.
+	_, err = ImportFrom(bytes.NewReader(data), []byte("wrong"), scratch.New(), keystorev4.New(), WithAgeIdentities(identity))
+	require.Error(t, err)
+
+	// Truncated.
+	_, err = ImportFrom(bytes.NewReader(data[:len(data)-1]), []byte("export"), scratch.New(), keystorev4.New(), WithAgeIdentities(identity))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "export truncated")
+
+	// Header.
+	tampered := append([]byte{}, data...)
+	tampered[len("age-encryption.org/v1\n-> X25519 ")] ^= 0x01
+	_, err = ImportFrom(bytes.NewReader(tampered), []byte("export"), scratch.New(), keystorev4.New(), WithAgeIdentities(identity))
+	require.Error(t, err)
+}
+
+// ageTestIdentity is the identity of the recipient of testdata/age-export.age, which was created with
+// "age -r age1h445pkha099hysff2jmh0s72zfqcp73kg603sr5fq8p73qwqzs3snxx0qa".
+const ageTestIdentity = "AGE-SECRET-KEY-1YN9X3KFWHSQ5DMF8XMKXUEA5VMD62R5WEW0PYGEQC7EZYQPYHL0SVWKD8T"
+
+func TestImportAgeToolExport(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "age-export.age"))
+	require.NoError(t, err)
+
+	_, err = ImportFrom(bytes.NewReader(data), nil, scratch.New(), keystorev4.New())
+	require.EqualError(t, err, "export is encrypted to age recipients but no identities supplied")
+
+	imported, err := ImportFrom(bytes.NewReader(data), nil, scratch.New(), keystorev4.New(), WithAgeIdentities(ageTestIdentity))
+	require.NoError(t, err)
+	assert.Equal(t, "5cfc2c9b-09d8-4167-81af-85f70d613169", imported.ID().String())
+	assert.Equal(t, "age wallet", imported.Name())
+	account, err := imported.(*wallet).AccountByName("Account 1")
+	require.NoError(t, err)
+	assert.Equal(t, "4d7ce30a-134b-4113-a404-5c7dc7c3e202", account.ID().String())
+	assert.Equal(t, "93ce80cc9f596983122d2701da9d41be008e292dc65f177b50e240bfb4da44b45f17dbd86eb456ff2f095133e2b857e8", fmt.Sprintf("%x", account.PublicKey().Marshal()))
+}
+
+func TestExportToRecipientsAgeTool(t *testing.T) {
+	tool, err := exec.LookPath("age")
+	if err != nil {
+		t.Skip("age tool not found")
+	}
+
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+	_, err = w.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	identity, recipient := newAgeIdentity(t)
+	dir, err := ioutil.TempDir("", "age")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	identityFile := filepath.Join(dir, "identity")
+	require.NoError(t, ioutil.WriteFile(identityFile, []byte(identity+"\n"), 0600))
+	exportFile := filepath.Join(dir, "export.age")
+	f, err := os.Create(exportFile)
+	require.NoError(t, err)
+	require.NoError(t, w.ExportToRecipients(f, nil, recipient))
+	require.NoError(t, f.Close())
+
+	// The decrypted export is the unencrypted export.
+	decrypted, err := exec.Command(tool, "-d", "-i", identityFile, exportFile).Output()
+	require.NoError(t, err)
+	expected := new(bytes.Buffer)
+	require.NoError(t, w.exportJSON(expected))
+	assert.Equal(t, expected.Bytes(), decrypted)
+}
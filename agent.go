@@ -0,0 +1,186 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// PassphraseAgent caches passphrases in memory for a limited time, so that batch operations such as signing with many
+// accounts do not require the passphrases to be re-entered.  Each passphrase is overwritten and discarded once its
+// time to live has passed since it was added.  Passphrases are keyed by the ID of the account or wallet that they
+// unlock, so the agent can also be supplied to WithPassphraseProvider.
+type PassphraseAgent struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[uuid.UUID]*agentEntry
+}
+
+// agentEntry is a passphrase cached by the agent.
+type agentEntry struct {
+	passphrase []byte
+	timer      *time.Timer
+	// keyMutex protects the fields below, serialising signing with the entry's account but not with other accounts.
+	keyMutex sync.Mutex
+	// secretKey is the account's secret key, decrypted with the passphrase the first time that the agent signs with the
+	// account and held until the passphrase is removed.
+	secretKey e2types.PrivateKey
+	publicKey []byte
+	removed   bool
+}
+
+// NewPassphraseAgent creates a passphrase agent that holds passphrases for the given time to live.
+func NewPassphraseAgent(ttl time.Duration) (*PassphraseAgent, error) {
+	if ttl <= 0 {
+		return nil, errors.New("time to live must be positive")
+	}
+	return &PassphraseAgent{
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]*agentEntry),
+	}, nil
+}
+
+// Passphrase provides the passphrase for the given ID.
+// It returns ErrPassphraseNotFound if there is no passphrase, or it has expired.
+func (p *PassphraseAgent) Passphrase(id uuid.UUID) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	entry, exists := p.entries[id]
+	if !exists {
+		return nil, ErrPassphraseNotFound
+	}
+	passphrase := make([]byte, len(entry.passphrase))
+	copy(passphrase, entry.passphrase)
+	return passphrase, nil
+}
+
+// SetPassphrase caches the passphrase for the given ID for the agent's time to live, replacing any existing passphrase.
+func (p *PassphraseAgent) SetPassphrase(id uuid.UUID, passphrase []byte) error {
+	entry := &agentEntry{
+		passphrase: make([]byte, len(passphrase)),
+	}
+	copy(entry.passphrase, passphrase)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.remove(id)
+	entry.timer = time.AfterFunc(p.ttl, func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if p.entries[id] == entry {
+			p.remove(id)
+		}
+	})
+	p.entries[id] = entry
+	return nil
+}
+
+// DeletePassphrase removes the passphrase for the given ID.
+// It returns ErrPassphraseNotFound if there is no passphrase, or it has expired.
+func (p *PassphraseAgent) DeletePassphrase(id uuid.UUID) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, exists := p.entries[id]; !exists {
+		return ErrPassphraseNotFound
+	}
+	p.remove(id)
+	return nil
+}
+
+// Clear removes all passphrases from the agent.
+func (p *PassphraseAgent) Clear() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for id := range p.entries {
+		p.remove(id)
+	}
+}
+
+// remove overwrites and removes the passphrase and secret key for the given ID, if present.
+// The caller must hold the agent's lock.
+func (p *PassphraseAgent) remove(id uuid.UUID) {
+	entry, exists := p.entries[id]
+	if !exists {
+		return
+	}
+	entry.timer.Stop()
+	entry.keyMutex.Lock()
+	zeroBytes(entry.passphrase)
+	if entry.secretKey != nil {
+		zeroPrivateKey(entry.secretKey)
+		entry.secretKey = nil
+	}
+	entry.publicKey = nil
+	entry.removed = true
+	entry.keyMutex.Unlock()
+	delete(p.entries, id)
+}
+
+// Sign signs data with the account.  If the agent holds a passphrase for the account the account's secret key is
+// decrypted with it the first time that the account is used, and held by the agent until the passphrase expires, so
+// later signatures do not repeat the decryption.  The account itself is never unlocked or locked by the agent, so its
+// state as seen by other callers is unchanged.  If the agent holds no passphrase for the account it signs only if the
+// account is already unlocked.
+func (p *PassphraseAgent) Sign(walletAccount wtypes.Account, data []byte) (e2types.Signature, error) {
+	p.mutex.Lock()
+	entry, exists := p.entries[walletAccount.ID()]
+	p.mutex.Unlock()
+	if !exists {
+		if walletAccount.IsUnlocked() {
+			return walletAccount.Sign(data)
+		}
+		return nil, ErrPassphraseNotFound
+	}
+
+	a, ok := walletAccount.(*account)
+	if !ok {
+		return nil, fmt.Errorf("account %q cannot be unlocked by the agent", walletAccount.Name())
+	}
+	if a.Disabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	entry.keyMutex.Lock()
+	defer entry.keyMutex.Unlock()
+	if entry.removed {
+		// The passphrase expired or was removed after the entry was obtained.
+		return nil, ErrPassphraseNotFound
+	}
+	if entry.secretKey == nil {
+		secretKey, err := a.secretKeyCopy(entry.passphrase)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unlock account")
+		}
+		entry.secretKey = secretKey
+		entry.publicKey = secretKey.PublicKey().Marshal()
+	}
+	// Accounts in different wallets can share an ID, so ensure that the key is that of this account.
+	if !bytes.Equal(entry.publicKey, a.publicKey.Marshal()) {
+		return nil, errors.New("account does not match the agent's key for its ID")
+	}
+
+	return entry.secretKey.Sign(data), nil
+}
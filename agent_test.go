@@ -0,0 +1,130 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestPassphraseAgent(t *testing.T) {
+	_, err := hd.NewPassphraseAgent(0)
+	assert.EqualError(t, err, "time to live must be positive")
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account", []byte("account passphrase"))
+	require.NoError(t, err)
+	account.Lock()
+
+	agent, err := hd.NewPassphraseAgent(200 * time.Millisecond)
+	require.NoError(t, err)
+	_, err = agent.Sign(account, []byte("data"))
+	assert.Equal(t, hd.ErrPassphraseNotFound, err)
+
+	require.NoError(t, agent.SetPassphrase(account.ID(), []byte("wrong passphrase")))
+	_, err = agent.Sign(account, []byte("data"))
+	assert.EqualError(t, err, "failed to unlock account: incorrect passphrase")
+
+	// Signing does not unlock the account.
+	require.NoError(t, agent.SetPassphrase(account.ID(), []byte("account passphrase")))
+	signature, err := agent.Sign(account, []byte("data"))
+	require.NoError(t, err)
+	assert.True(t, signature.Verify([]byte("data"), account.PublicKey()))
+	assert.False(t, account.IsUnlocked())
+
+	// Passphrases expire.
+	time.Sleep(400 * time.Millisecond)
+	_, err = agent.Passphrase(account.ID())
+	assert.Equal(t, hd.ErrPassphraseNotFound, err)
+	_, err = agent.Sign(account, []byte("data"))
+	assert.Equal(t, hd.ErrPassphraseNotFound, err)
+
+	require.NoError(t, agent.SetPassphrase(account.ID(), []byte("account passphrase")))
+	require.NoError(t, agent.DeletePassphrase(account.ID()))
+	assert.Equal(t, hd.ErrPassphraseNotFound, agent.DeletePassphrase(account.ID()))
+	require.NoError(t, agent.SetPassphrase(account.ID(), []byte("account passphrase")))
+	agent.Clear()
+	_, err = agent.Passphrase(account.ID())
+	assert.Equal(t, hd.ErrPassphraseNotFound, err)
+
+	// The agent can supply the wallet passphrase.
+	require.NoError(t, agent.SetPassphrase(wallet.ID(), nil))
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithPassphraseProvider(agent))
+	require.NoError(t, err)
+	require.NoError(t, wallet.(hd.WalletProviderUnlocker).UnlockWithProvider())
+}
+
+func TestPassphraseAgentSign(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	unlocks := 0
+	locks := 0
+	wallet.(hd.WalletHookRegistrar).OnAccountUnlocked(func(_ wtypes.Account) { unlocks++ })
+	wallet.(hd.WalletHookRegistrar).OnAccountLocked(func(_ wtypes.Account) { locks++ })
+
+	agent, err := hd.NewPassphraseAgent(time.Minute)
+	require.NoError(t, err)
+	accounts := make([]wtypes.Account, 4)
+	for i := range accounts {
+		accounts[i], err = wallet.CreateAccount(fmt.Sprintf("Account %d", i), []byte(fmt.Sprintf("passphrase %d", i)))
+		require.NoError(t, err)
+		require.NoError(t, agent.SetPassphrase(accounts[i].ID(), []byte(fmt.Sprintf("passphrase %d", i))))
+	}
+
+	// Accounts can be signed with concurrently, without being unlocked or locked.
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(account wtypes.Account) {
+			defer wg.Done()
+			signature, err := agent.Sign(account, []byte("data"))
+			assert.NoError(t, err)
+			assert.True(t, signature.Verify([]byte("data"), account.PublicKey()))
+		}(accounts[i%len(accounts)])
+	}
+	wg.Wait()
+	assert.Equal(t, 0, unlocks)
+	assert.Equal(t, 0, locks)
+
+	// Accounts unlocked by others remain unlocked.
+	require.NoError(t, accounts[0].Unlock([]byte("passphrase 0")))
+	_, err = agent.Sign(accounts[0], []byte("data"))
+	require.NoError(t, err)
+	assert.True(t, accounts[0].IsUnlocked())
+	assert.Equal(t, 0, locks)
+
+	// Disabled accounts do not sign.
+	require.NoError(t, accounts[1].(hd.AccountDisabler).SetDisabled(true))
+	_, err = agent.Sign(accounts[1], []byte("data"))
+	assert.Equal(t, hd.ErrAccountDisabled, err)
+
+	// Once the passphrase is removed the key is no longer held.
+	require.NoError(t, agent.DeletePassphrase(accounts[2].ID()))
+	_, err = agent.Sign(accounts[2], []byte("data"))
+	assert.Equal(t, hd.ErrPassphraseNotFound, err)
+}
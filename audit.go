@@ -0,0 +1,170 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// AuditReport contains the results of an audit of a wallet.
+type AuditReport struct {
+	// Accounts is the number of stored accounts that were audited, excluding deleted accounts.
+	Accounts int
+	// MismatchedAccounts are the accounts whose stored public key does not match the public key re-derived from the
+	// wallet's seed at their path.
+	MismatchedAccounts []*AuditEntry
+	// UnindexedAccounts are the stored accounts that are missing from the accounts index.
+	UnindexedAccounts []*AuditEntry
+	// MissingAccounts are the entries in the accounts index that do not have a stored account.
+	MissingAccounts []*AuditEntry
+	// CorruptAccounts are the stored accounts whose keystores cannot be parsed.
+	CorruptAccounts []*AuditEntry
+}
+
+// AuditEntry contains the details of an account reported by an audit.
+type AuditEntry struct {
+	// ID is the ID of the account.  It is zero if the ID cannot be parsed.
+	ID uuid.UUID
+	// Name is the name of the account, if known.
+	Name string
+	// Path is the derivation path of the account, if known.
+	Path string
+	// Error is the reason for which the account was reported, if more detail is available.
+	Error string
+}
+
+// Healthy returns true if the audit found no problems.
+func (r *AuditReport) Healthy() bool {
+	return len(r.MismatchedAccounts) == 0 &&
+		len(r.UnindexedAccounts) == 0 &&
+		len(r.MissingAccounts) == 0 &&
+		len(r.CorruptAccounts) == 0
+}
+
+// Audit checks the consistency of the wallet's stored data, re-deriving each account's public key from the wallet's
+// seed.  The passphrase is used to decrypt the seed; the wallet does not need to be unlocked, and remains locked if
+// it was locked.  Imported accounts, whose keys are not derived from the seed, are not re-derived.
+func (w *wallet) Audit(passphrase []byte) (*AuditReport, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	seed, err := w.decryptSeed(passphrase)
+	if err != nil {
+		return nil, errors.New("incorrect passphrase")
+	}
+	defer zeroBytes(seed)
+
+	report := &AuditReport{
+		MismatchedAccounts: make([]*AuditEntry, 0),
+		UnindexedAccounts:  make([]*AuditEntry, 0),
+		MissingAccounts:    make([]*AuditEntry, 0),
+		CorruptAccounts:    make([]*AuditEntry, 0),
+	}
+	stored := make(map[uuid.UUID]bool)
+	for data := range w.store.RetrieveAccounts(w.id) {
+		walletAccount, err := deserializeAccount(w, data)
+		if err != nil {
+			entry := corruptAuditEntry(data)
+			entry.Error = err.Error()
+			if entry.ID != uuid.Nil {
+				stored[entry.ID] = true
+			}
+			report.CorruptAccounts = append(report.CorruptAccounts, entry)
+			continue
+		}
+		a := walletAccount.(*account)
+		stored[a.id] = true
+		if a.deleted {
+			continue
+		}
+		report.Accounts++
+		entry := &AuditEntry{
+			ID:   a.id,
+			Name: a.name,
+			Path: a.path,
+		}
+		if !w.index.IDKnown(a.id) {
+			report.UnindexedAccounts = append(report.UnindexedAccounts, entry)
+		}
+		if a.imported {
+			continue
+		}
+		privateKey, err := privateKeyFromSeedAndPath(w.backend, seed, a.path)
+		if err != nil {
+			entry.Error = err.Error()
+			report.MismatchedAccounts = append(report.MismatchedAccounts, entry)
+			continue
+		}
+		if !bytes.Equal(privateKey.PublicKey().Marshal(), a.publicKey.Marshal()) {
+			report.MismatchedAccounts = append(report.MismatchedAccounts, entry)
+		}
+	}
+
+	entries, err := w.indexEntries()
+	if err != nil {
+		return nil, errors.Wrap(err, "accounts index corrupt")
+	}
+	for _, entry := range entries {
+		if !stored[entry.ID] {
+			report.MissingAccounts = append(report.MissingAccounts, &AuditEntry{
+				ID:   entry.ID,
+				Name: entry.Name,
+			})
+		}
+	}
+
+	for _, auditEntries := range [][]*AuditEntry{report.MismatchedAccounts, report.UnindexedAccounts, report.CorruptAccounts} {
+		sortAuditEntries(auditEntries)
+	}
+
+	return report, nil
+}
+
+// corruptAuditEntry provides what details it can of an account whose data cannot be deserialized.
+func corruptAuditEntry(data []byte) *AuditEntry {
+	info := &struct {
+		ID   string `json:"uuid"`
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}{}
+	entry := &AuditEntry{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return entry
+	}
+	if id, err := uuid.Parse(info.ID); err == nil {
+		entry.ID = id
+	}
+	entry.Name = info.Name
+	entry.Path = info.Path
+	return entry
+}
+
+// sortAuditEntries sorts audit entries by name, then ID.
+func sortAuditEntries(entries []*AuditEntry) {
+	sort.Slice(entries, func(i int, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].ID.String() < entries[j].ID.String()
+	})
+}
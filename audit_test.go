@@ -0,0 +1,99 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestAudit(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")))
+	require.NoError(t, err)
+	auditor, isAuditor := wallet.(hd.WalletAuditor)
+	require.True(t, isAuditor)
+
+	_, err = auditor.Audit([]byte("bad"))
+	assert.EqualError(t, err, "incorrect passphrase")
+
+	require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+	account1, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	account2, err := wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	account3, err := wallet.CreateAccount("Account 3", nil)
+	require.NoError(t, err)
+	wallet.Lock()
+
+	report, err := auditor.Audit([]byte("wallet passphrase"))
+	require.NoError(t, err)
+	assert.True(t, report.Healthy())
+	assert.Equal(t, 3, report.Accounts)
+	assert.False(t, wallet.IsUnlocked())
+
+	// Give account 2 the public key of account 3.
+	data, err := store.RetrieveAccount(wallet.ID(), account2.ID())
+	require.NoError(t, err)
+	data = []byte(strings.Replace(string(data), fmt.Sprintf("%x", account2.PublicKey().Marshal()), fmt.Sprintf("%x", account3.PublicKey().Marshal()), 1))
+	require.NoError(t, store.StoreAccount(wallet.ID(), account2.ID(), data))
+
+	// Store a copy of account 1 that is not in the index.
+	unindexedID := uuid.New()
+	data, err = store.RetrieveAccount(wallet.ID(), account1.ID())
+	require.NoError(t, err)
+	data = []byte(strings.Replace(string(data), account1.ID().String(), unindexedID.String(), 1))
+	data = []byte(strings.Replace(string(data), `"Account 1"`, `"Unindexed"`, 1))
+	require.NoError(t, store.StoreAccount(wallet.ID(), unindexedID, data))
+
+	// Store a corrupt account.
+	corruptID := uuid.New()
+	require.NoError(t, store.StoreAccount(wallet.ID(), corruptID, []byte(fmt.Sprintf(`{"uuid":"%s","name":"Corrupt"}`, corruptID))))
+
+	// Add an index entry without an account.
+	missingID := uuid.New()
+	indexData, err := store.RetrieveAccountsIndex(wallet.ID())
+	require.NoError(t, err)
+	var index []map[string]string
+	require.NoError(t, json.Unmarshal(indexData, &index))
+	index = append(index, map[string]string{"uuid": missingID.String(), "name": "Missing"})
+	indexData, err = json.Marshal(index)
+	require.NoError(t, err)
+	require.NoError(t, store.StoreAccountsIndex(wallet.ID(), indexData))
+
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	report, err = reopened.(hd.WalletAuditor).Audit([]byte("wallet passphrase"))
+	require.NoError(t, err)
+	assert.False(t, report.Healthy())
+	assert.Equal(t, 4, report.Accounts)
+	require.Len(t, report.MismatchedAccounts, 1)
+	assert.Equal(t, &hd.AuditEntry{ID: account2.ID(), Name: "Account 2", Path: "m/12381/3600/1/0"}, report.MismatchedAccounts[0])
+	require.Len(t, report.UnindexedAccounts, 1)
+	assert.Equal(t, &hd.AuditEntry{ID: unindexedID, Name: "Unindexed", Path: "m/12381/3600/0/0"}, report.UnindexedAccounts[0])
+	require.Len(t, report.MissingAccounts, 1)
+	assert.Equal(t, &hd.AuditEntry{ID: missingID, Name: "Missing"}, report.MissingAccounts[0])
+	require.Len(t, report.CorruptAccounts, 1)
+	assert.Equal(t, &hd.AuditEntry{ID: corruptID, Name: "Corrupt", Error: "account pubkey missing"}, report.CorruptAccounts[0])
+}
@@ -0,0 +1,68 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"math/big"
+
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	util "github.com/wealdtech/go-eth2-util"
+)
+
+// BLSBackend is the interface for backends that provide BLS key derivation and keys.  A backend can be supplied with
+// WithBLSBackend to use an alternative BLS library in place of the default, which is based on go-eth2-util.
+//
+// A backend must derive the same keys and produce the same signatures as the default, which ConformanceCheck verifies
+// for derivation.  No blst backend is provided: blst implements a later revision of EIP-2333, and go-eth2-types signs
+// with the draft-05 hash-to-curve rather than the final standard implemented by blst, so the keys and signatures it
+// produces would not match those of the default backend.
+type BLSBackend interface {
+	// DeriveMasterSK derives the master secret key from a seed, as per EIP-2333.
+	DeriveMasterSK(seed []byte) (*big.Int, error)
+
+	// DeriveChildSK derives the child secret key with the given index from its parent secret key, as per EIP-2333.
+	DeriveChildSK(parentSK *big.Int, index uint32) (*big.Int, error)
+
+	// PrivateKeyFromBytes creates a private key from its 32-byte big-endian representation.
+	PrivateKeyFromBytes(data []byte) (e2types.PrivateKey, error)
+
+	// PublicKeyFromBytes creates a public key from its compressed representation.
+	PublicKeyFromBytes(data []byte) (e2types.PublicKey, error)
+}
+
+// defaultBackend is the BLS backend used if no other backend is supplied.
+var defaultBackend BLSBackend = &utilBackend{}
+
+// utilBackend is the BLS backend based on go-eth2-util and go-eth2-types.
+type utilBackend struct{}
+
+// DeriveMasterSK derives the master secret key from a seed.
+func (b *utilBackend) DeriveMasterSK(seed []byte) (*big.Int, error) {
+	return util.DeriveMasterSK(seed)
+}
+
+// DeriveChildSK derives the child secret key from its parent secret key.
+func (b *utilBackend) DeriveChildSK(parentSK *big.Int, index uint32) (*big.Int, error) {
+	return util.DeriveChildSK(parentSK, index)
+}
+
+// PrivateKeyFromBytes creates a private key from its byte representation.
+func (b *utilBackend) PrivateKeyFromBytes(data []byte) (e2types.PrivateKey, error) {
+	return e2types.BLSPrivateKeyFromBytes(data)
+}
+
+// PublicKeyFromBytes creates a public key from its byte representation.
+func (b *utilBackend) PublicKeyFromBytes(data []byte) (e2types.PublicKey, error) {
+	return e2types.BLSPublicKeyFromBytes(data)
+}
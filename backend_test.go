@@ -0,0 +1,81 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	util "github.com/wealdtech/go-eth2-util"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+// countingBackend is a BLS backend that counts the keys it derives.
+type countingBackend struct {
+	derivations int
+}
+
+func (b *countingBackend) DeriveMasterSK(seed []byte) (*big.Int, error) {
+	b.derivations++
+	return util.DeriveMasterSK(seed)
+}
+
+func (b *countingBackend) DeriveChildSK(parentSK *big.Int, index uint32) (*big.Int, error) {
+	b.derivations++
+	return util.DeriveChildSK(parentSK, index)
+}
+
+func (b *countingBackend) PrivateKeyFromBytes(data []byte) (e2types.PrivateKey, error) {
+	return e2types.BLSPrivateKeyFromBytes(data)
+}
+
+func (b *countingBackend) PublicKeyFromBytes(data []byte) (e2types.PublicKey, error) {
+	return e2types.BLSPublicKeyFromBytes(data)
+}
+
+func TestBLSBackend(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	backend := &countingBackend{}
+
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithSeed(seed), hd.WithWalletIndex(1), hd.WithBLSBackend(backend))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	// Unlocking derives the wallet's node at m/12381/3600/1.
+	assert.Equal(t, 4, backend.derivations)
+	account, err := wallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	// Creating an account derives the final two components.
+	assert.Equal(t, 6, backend.derivations)
+
+	expected, err := util.PrivateKeyFromSeedAndPath(seed, "m/12381/3600/1/0/0")
+	require.NoError(t, err)
+	assert.Equal(t, expected.PublicKey().Marshal(), account.PublicKey().Marshal())
+
+	// The backend is not stored, so the wallet opens with the default backend.
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Unlock(nil))
+	assert.Equal(t, 6, backend.derivations)
+	reopened, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithBLSBackend(backend))
+	require.NoError(t, err)
+	require.NoError(t, reopened.Unlock(nil))
+	assert.Equal(t, 10, backend.derivations)
+}
@@ -0,0 +1,107 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrUnlockBackoff is returned when an unlock is attempted too soon after a failed unlock.
+var ErrUnlockBackoff = errors.New("too many failed unlock attempts")
+
+// unlockBackoff tracks consecutive failed unlock attempts for a wallet and its accounts, and refuses further
+// attempts for an exponentially increasing period after each failure.
+type unlockBackoff struct {
+	base     time.Duration
+	max      time.Duration
+	mutex    sync.Mutex
+	failures map[uuid.UUID]*unlockFailures
+	now      func() time.Time
+}
+
+// unlockFailures are the details of consecutive failed unlock attempts.
+type unlockFailures struct {
+	count int
+	until time.Time
+}
+
+// newUnlockBackoff creates a new unlock backoff.  A base delay of 0 disables the backoff.
+func newUnlockBackoff(base time.Duration, max time.Duration) *unlockBackoff {
+	if max < base {
+		max = base
+	}
+	return &unlockBackoff{
+		base:     base,
+		max:      max,
+		failures: make(map[uuid.UUID]*unlockFailures),
+		now:      time.Now,
+	}
+}
+
+// check returns an error if an unlock attempt for the given ID should be refused.
+func (b *unlockBackoff) check(id uuid.UUID) error {
+	if b.base == 0 {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	failures, exists := b.failures[id]
+	if !exists {
+		return nil
+	}
+	if b.now().Before(failures.until) {
+		return ErrUnlockBackoff
+	}
+	return nil
+}
+
+// failed records a failed unlock attempt for the given ID.
+func (b *unlockBackoff) failed(id uuid.UUID) {
+	if b.base == 0 {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	failures, exists := b.failures[id]
+	if !exists {
+		failures = &unlockFailures{}
+		b.failures[id] = failures
+	}
+	failures.count++
+	delay := b.max
+	// Avoid overflow when calculating the delay for a large number of failures.
+	if failures.count <= 32 {
+		if d := b.base << uint(failures.count-1); d > 0 && d < b.max {
+			delay = d
+		}
+	}
+	failures.until = b.now().Add(delay)
+}
+
+// succeeded records a successful unlock for the given ID, resetting its failures.
+func (b *unlockBackoff) succeeded(id uuid.UUID) {
+	if b.base == 0 {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.failures, id)
+}
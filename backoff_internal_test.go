@@ -0,0 +1,60 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnlockBackoff(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	backoff := newUnlockBackoff(time.Second, 5*time.Second)
+	backoff.now = func() time.Time { return now }
+	id := uuid.New()
+	other := uuid.New()
+
+	require.NoError(t, backoff.check(id))
+
+	// Delays double with each failure, up to the maximum.
+	for _, delay := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		backoff.failed(id)
+		assert.Equal(t, ErrUnlockBackoff, backoff.check(id))
+		// Other IDs are unaffected.
+		assert.NoError(t, backoff.check(other))
+		now = now.Add(delay - time.Millisecond)
+		assert.Equal(t, ErrUnlockBackoff, backoff.check(id))
+		now = now.Add(time.Millisecond)
+		assert.NoError(t, backoff.check(id))
+	}
+
+	// Success resets the failures.
+	backoff.succeeded(id)
+	backoff.failed(id)
+	now = now.Add(time.Second)
+	assert.NoError(t, backoff.check(id))
+}
+
+func TestUnlockBackoffDisabled(t *testing.T) {
+	backoff := newUnlockBackoff(0, 0)
+	id := uuid.New()
+	for i := 0; i < 10; i++ {
+		backoff.failed(id)
+		require.NoError(t, backoff.check(id))
+	}
+}
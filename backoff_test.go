@@ -0,0 +1,56 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestWalletUnlockBackoff(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	passphrase := []byte("passphrase")
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase(passphrase))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+	account, err := wallet.CreateAccount("Account 1", passphrase)
+	require.NoError(t, err)
+	wallet.Lock()
+
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithUnlockBackoff(100*time.Millisecond, time.Second))
+	require.NoError(t, err)
+	assert.EqualError(t, wallet.Unlock([]byte("wrong")), "incorrect passphrase")
+	// Even the correct passphrase is refused during the backoff.
+	assert.Equal(t, hd.ErrUnlockBackoff, wallet.Unlock(passphrase))
+	assert.False(t, wallet.IsUnlocked())
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, wallet.Unlock(passphrase))
+
+	// Account failures are tracked across retrievals of the account.
+	account, err = wallet.AccountByName("Account 1")
+	require.NoError(t, err)
+	assert.EqualError(t, account.Unlock([]byte("wrong")), "incorrect passphrase")
+	account, err = wallet.AccountByName("Account 1")
+	require.NoError(t, err)
+	assert.Equal(t, hd.ErrUnlockBackoff, account.Unlock(passphrase))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, account.Unlock(passphrase))
+}
@@ -0,0 +1,133 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// bech32Charset is the Bech32 alphabet, as per BIP-173.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Polymod calculates the Bech32 checksum of the values.
+func bech32Polymod(values []byte) uint32 {
+	generator := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32HRPExpand expands the human-readable part for the checksum.
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// bech32ConvertBits regroups data from groups of fromBits bits to groups of toBits bits.
+func bech32ConvertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	maxValue := uint32(1)<<toBits - 1
+	res := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, b := range data {
+		if uint32(b)>>fromBits != 0 {
+			return nil, errors.New("invalid data")
+		}
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			res = append(res, byte(acc>>bits&maxValue))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			res = append(res, byte(acc<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxValue != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return res, nil
+}
+
+// bech32Encode encodes data with the human-readable part.
+func bech32Encode(hrp string, data []byte) (string, error) {
+	values, err := bech32ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	checksum := bech32Polymod(append(append(bech32HRPExpand(hrp), values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	var res strings.Builder
+	res.WriteString(hrp)
+	res.WriteByte('1')
+	for _, v := range values {
+		res.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		res.WriteByte(bech32Charset[(checksum>>uint(5*(5-i)))&31])
+	}
+	return res.String(), nil
+}
+
+// bech32Decode decodes a Bech32 string, returning its lower-case human-readable part and data.  Unlike BIP-173 there
+// is no limit on the length of the string.
+func bech32Decode(str string) (string, []byte, error) {
+	if strings.ToLower(str) != str && strings.ToUpper(str) != str {
+		return "", nil, errors.New("mixed case")
+	}
+	str = strings.ToLower(str)
+	pos := strings.LastIndex(str, "1")
+	if pos < 1 || pos+7 > len(str) {
+		return "", nil, errors.New("invalid separator")
+	}
+	hrp := str[:pos]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, errors.New("invalid character")
+		}
+	}
+	values := make([]byte, 0, len(str)-pos-1)
+	for i := pos + 1; i < len(str); i++ {
+		v := strings.IndexByte(bech32Charset, str[i])
+		if v == -1 {
+			return "", nil, errors.New("invalid character")
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, errors.New("invalid checksum")
+	}
+	data, err := bech32ConvertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, data, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBech32(t *testing.T) {
+	// Test vector from the age specification.
+	hrp, data, err := bech32Decode("age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj")
+	require.NoError(t, err)
+	assert.Equal(t, "age", hrp)
+	assert.Len(t, data, 32)
+	encoded, err := bech32Encode("age", data)
+	require.NoError(t, err)
+	assert.Equal(t, "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj", encoded)
+
+	_, _, err = bech32Decode("age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwa")
+	assert.EqualError(t, err, "invalid checksum")
+	_, _, err = bech32Decode("AGE1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj")
+	assert.EqualError(t, err, "mixed case")
+	_, _, err = bech32Decode("bad")
+	assert.EqualError(t, err, "invalid separator")
+}
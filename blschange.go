@@ -0,0 +1,96 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/prysmaticlabs/go-ssz"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// domainBLSToExecutionChange is the domain type for BLS to execution changes.
+var domainBLSToExecutionChange = e2types.DomainType{0x0a, 0, 0, 0}
+
+// SignedBLSToExecutionChange is a signed change of a validator's withdrawal credentials from BLS to execution-layer
+// credentials, in the JSON format of the beacon node API so that it can be submitted to a beacon node.
+type SignedBLSToExecutionChange struct {
+	Message   *BLSToExecutionChange `json:"message"`
+	Signature string                `json:"signature"`
+}
+
+// BLSToExecutionChange is a change of a validator's withdrawal credentials from BLS to execution-layer credentials.
+type BLSToExecutionChange struct {
+	ValidatorIndex     string `json:"validator_index"`
+	FromBLSPubkey      string `json:"from_bls_pubkey"`
+	ToExecutionAddress string `json:"to_execution_address"`
+}
+
+// blsToExecutionChange is the SSZ container for a BLS to execution change.
+type blsToExecutionChange struct {
+	ValidatorIndex     uint64
+	FromBLSPubkey      []byte `ssz-size:"48"`
+	ToExecutionAddress []byte `ssz-size:"20"`
+}
+
+// BLSToExecutionChange provides a signed change of the withdrawal credentials of the validator with the given index
+// from the BLS credentials of this account to the given execution-layer address.  The account must be a withdrawal
+// account, whose key is the validator's BLS withdrawal key, as changes signed with any other key are rejected by the
+// chain.  The change is signed with the chain's genesis fork version and genesis validators root, as it is valid
+// across forks.  The account must be unlocked.
+func (a *account) BLSToExecutionChange(validatorIndex uint64, executionAddress []byte, genesisForkVersion []byte, genesisValidatorsRoot []byte) (*SignedBLSToExecutionChange, error) {
+	if !a.isWithdrawalAccount() {
+		return nil, fmt.Errorf("account %q is not a withdrawal account", a.name)
+	}
+	if len(executionAddress) != 20 {
+		return nil, errors.New("execution address must be 20 bytes")
+	}
+	if len(genesisForkVersion) != 4 {
+		return nil, errors.New("genesis fork version must be 4 bytes")
+	}
+	if len(genesisValidatorsRoot) != 32 {
+		return nil, errors.New("genesis validators root must be 32 bytes")
+	}
+
+	publicKey := a.PublicKey().Marshal()
+	messageRoot, err := ssz.HashTreeRoot(&blsToExecutionChange{
+		ValidatorIndex:     validatorIndex,
+		FromBLSPubkey:      publicKey,
+		ToExecutionAddress: executionAddress,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate change root")
+	}
+	signingRoot, err := ssz.HashTreeRoot(&signingData{
+		ObjectRoot: messageRoot[:],
+		Domain:     e2types.Domain(domainBLSToExecutionChange, genesisForkVersion, genesisValidatorsRoot),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate signing root")
+	}
+	signature, err := a.Sign(signingRoot[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign change")
+	}
+
+	return &SignedBLSToExecutionChange{
+		Message: &BLSToExecutionChange{
+			ValidatorIndex:     fmt.Sprintf("%d", validatorIndex),
+			FromBLSPubkey:      fmt.Sprintf("%#x", publicKey),
+			ToExecutionAddress: fmt.Sprintf("%#x", executionAddress),
+		},
+		Signature: fmt.Sprintf("%#x", signature.Marshal()),
+	}, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestBLSToExecutionChange(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.(hd.WalletWithdrawalAccountCreator).CreateWithdrawalAccount("Withdrawal", nil)
+	require.NoError(t, err)
+	changer := account.(hd.AccountBLSToExecutionChanger)
+
+	address := _byteArray("a1b2c3d4e5f60718293a4b5c6d7e8f9001020304")
+	forkVersion := _byteArray("00000000")
+	genesisValidatorsRoot := _byteArray("4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95")
+	_, err = changer.BLSToExecutionChange(1234, address, forkVersion, genesisValidatorsRoot)
+	assert.EqualError(t, err, "failed to sign change: cannot sign when account is locked")
+	require.NoError(t, account.Unlock(nil))
+	_, err = changer.BLSToExecutionChange(1234, address[1:], forkVersion, genesisValidatorsRoot)
+	assert.EqualError(t, err, "execution address must be 20 bytes")
+	_, err = changer.BLSToExecutionChange(1234, address, forkVersion[1:], genesisValidatorsRoot)
+	assert.EqualError(t, err, "genesis fork version must be 4 bytes")
+	_, err = changer.BLSToExecutionChange(1234, address, forkVersion, genesisValidatorsRoot[1:])
+	assert.EqualError(t, err, "genesis validators root must be 32 bytes")
+
+	change, err := changer.BLSToExecutionChange(1234, address, forkVersion, genesisValidatorsRoot)
+	require.NoError(t, err)
+	publicKey := account.PublicKey().Marshal()
+	data, err := json.Marshal(change)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`{"message":{"validator_index":"1234","from_bls_pubkey":"%#x","to_execution_address":"0xa1b2c3d4e5f60718293a4b5c6d7e8f9001020304"},"signature":"%s"}`, publicKey, change.Signature), string(data))
+
+	// Merkleize the change by hand to confirm the signature.
+	index := make([]byte, 8)
+	binary.LittleEndian.PutUint64(index, 1234)
+	messageRoot := hashPair(hashPair(chunk(index), hashPair(publicKey[:32], chunk(publicKey[32:]))), hashPair(chunk(address), make([]byte, 32)))
+	domain := append([]byte{0x0a, 0x00, 0x00, 0x00}, hashPair(chunk(forkVersion), genesisValidatorsRoot)[:28]...)
+	signature, err := e2types.BLSSignatureFromBytes(_byteArray(change.Signature[2:]))
+	require.NoError(t, err)
+	assert.True(t, signature.Verify(hashPair(messageRoot, domain), account.PublicKey()))
+}
+
+func TestBLSToExecutionChangeNotWithdrawal(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New(), hd.WithWalletIndex(1))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+
+	address := _byteArray("a1b2c3d4e5f60718293a4b5c6d7e8f9001020304")
+	forkVersion := _byteArray("00000000")
+	genesisValidatorsRoot := _byteArray("4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95")
+
+	signing, err := wallet.CreateAccount("Signing", nil)
+	require.NoError(t, err)
+	require.NoError(t, signing.Unlock(nil))
+	_, err = signing.(hd.AccountBLSToExecutionChanger).BLSToExecutionChange(1234, address, forkVersion, genesisValidatorsRoot)
+	assert.EqualError(t, err, `account "Signing" is not a withdrawal account`)
+
+	extended, err := wallet.(hd.WalletExtendedAccountCreator).CreateExtendedAccount("Extended", []uint64{1}, nil)
+	require.NoError(t, err)
+	require.NoError(t, extended.Unlock(nil))
+	_, err = extended.(hd.AccountBLSToExecutionChanger).BLSToExecutionChange(1234, address, forkVersion, genesisValidatorsRoot)
+	assert.EqualError(t, err, `account "Extended" is not a withdrawal account`)
+
+	// Programmatic accounts at withdrawal paths are withdrawal accounts.
+	programmatic, err := wallet.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/3600/1/5/0")
+	require.NoError(t, err)
+	_, err = programmatic.(hd.AccountBLSToExecutionChanger).BLSToExecutionChange(1234, address, forkVersion, genesisValidatorsRoot)
+	assert.EqualError(t, err, `account "m/12381/3600/1/5/0" is not a withdrawal account`)
+	programmatic, err = wallet.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/3600/1/5")
+	require.NoError(t, err)
+	_, err = programmatic.(hd.AccountBLSToExecutionChanger).BLSToExecutionChange(1234, address, forkVersion, genesisValidatorsRoot)
+	require.NoError(t, err)
+}
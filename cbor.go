@@ -0,0 +1,321 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// JSON records are converted to CBOR, as per RFC 8949, by encoding the values decoded from the JSON.  Strings that
+// are lower-case hex, which make up most of an encrypted keystore, are encoded as byte strings tagged as expecting
+// conversion to base16 so that they are restored exactly.  Integers are encoded as such and other numbers as
+// double-precision floats.  The encoded record is prefixed with the CBOR self-describe tag, which distinguishes it
+// from JSON.
+const (
+	cborUnsigned  = 0
+	cborNegative  = 1
+	cborBytes     = 2
+	cborText      = 3
+	cborArray     = 4
+	cborMap       = 5
+	cborTag       = 6
+	cborSimple    = 7
+	cborFalse     = 20
+	cborTrue      = 21
+	cborNull      = 22
+	cborFloat64   = 27
+	cborBase16Tag = 23
+	cborMaxDepth  = 64
+)
+
+// cborMagic is the CBOR self-describe tag, which starts CBOR records.
+var cborMagic = []byte{0xd9, 0xd9, 0xf7}
+
+// jsonToCBOR converts a JSON value to a CBOR record.
+func jsonToCBOR(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, errors.Wrap(err, "invalid JSON")
+	}
+	if decoder.More() {
+		return nil, errors.New("invalid JSON: data after value")
+	}
+	buf := bytes.NewBuffer(append(make([]byte, 0, len(data)), cborMagic...))
+	if err := cborEncode(buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cborToJSON converts a CBOR record to JSON.
+func cborToJSON(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, cborMagic) {
+		return nil, errors.New("not a CBOR record")
+	}
+	decoder := &cborDecoder{data: data[len(cborMagic):]}
+	value, err := decoder.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoder.data) != 0 {
+		return nil, errors.New("data after CBOR value")
+	}
+	return json.Marshal(value)
+}
+
+// cborHead writes the initial byte of an item and its argument.
+func cborHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.Write([]byte{major<<5 | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		_ = binary.Write(buf, binary.BigEndian, uint16(arg))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		_ = binary.Write(buf, binary.BigEndian, uint32(arg))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		_ = binary.Write(buf, binary.BigEndian, arg)
+	}
+}
+
+// cborEncode encodes a value decoded from JSON.
+func cborEncode(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte(cborSimple<<5 | cborNull)
+	case bool:
+		if v {
+			buf.WriteByte(cborSimple<<5 | cborTrue)
+		} else {
+			buf.WriteByte(cborSimple<<5 | cborFalse)
+		}
+	case json.Number:
+		if n, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			cborHead(buf, cborUnsigned, n)
+			return nil
+		}
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			cborHead(buf, cborNegative, uint64(-1-n))
+			return nil
+		}
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid number %s", v)
+		}
+		buf.WriteByte(cborSimple<<5 | cborFloat64)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		if isLowerHex(v) {
+			decoded, err := hex.DecodeString(v)
+			if err != nil {
+				return err
+			}
+			cborHead(buf, cborTag, cborBase16Tag)
+			cborHead(buf, cborBytes, uint64(len(decoded)))
+			buf.Write(decoded)
+			return nil
+		}
+		cborHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		cborHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := cborEncode(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		cborHead(buf, cborMap, uint64(len(v)))
+		for _, key := range keys {
+			cborHead(buf, cborText, uint64(len(key)))
+			buf.WriteString(key)
+			if err := cborEncode(buf, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", value)
+	}
+	return nil
+}
+
+// isLowerHex returns true if the string is non-empty lower-case hex of whole bytes.
+func isLowerHex(s string) bool {
+	if len(s) == 0 || len(s)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// cborDecoder decodes the subset of CBOR written by cborEncode.
+type cborDecoder struct {
+	data []byte
+}
+
+// head reads the initial byte of an item and its argument.
+func (d *cborDecoder) head() (byte, byte, uint64, error) {
+	if len(d.data) == 0 {
+		return 0, 0, 0, errors.New("CBOR record truncated")
+	}
+	major := d.data[0] >> 5
+	info := d.data[0] & 0x1f
+	d.data = d.data[1:]
+	var size int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, 0, errors.New("unsupported CBOR item")
+	}
+	if len(d.data) < size {
+		return 0, 0, 0, errors.New("CBOR record truncated")
+	}
+	var arg uint64
+	for _, b := range d.data[:size] {
+		arg = arg<<8 | uint64(b)
+	}
+	d.data = d.data[size:]
+	return major, info, arg, nil
+}
+
+// bytes reads the content of a byte or text string.
+func (d *cborDecoder) bytes(length uint64) ([]byte, error) {
+	if length > uint64(len(d.data)) {
+		return nil, errors.New("CBOR record truncated")
+	}
+	res := d.data[:length]
+	d.data = d.data[length:]
+	return res, nil
+}
+
+// decode decodes an item to the value that would be decoded from JSON.
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, errors.New("CBOR record too deep")
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUnsigned:
+		return json.Number(strconv.FormatUint(arg, 10)), nil
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("CBOR integer out of range")
+		}
+		return json.Number(strconv.FormatInt(-1-int64(arg), 10)), nil
+	case cborText:
+		text, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	case cborTag:
+		if arg != cborBase16Tag {
+			return nil, fmt.Errorf("unsupported CBOR tag %d", arg)
+		}
+		major, _, length, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborBytes {
+			return nil, errors.New("CBOR base16 tag not on byte string")
+		}
+		data, err := d.bytes(length)
+		if err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(data), nil
+	case cborArray:
+		// Each item takes at least one byte, which bounds the allocation.
+		if arg > uint64(len(d.data)) {
+			return nil, errors.New("CBOR record truncated")
+		}
+		items := make([]interface{}, arg)
+		for i := range items {
+			if items[i], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborMap:
+		if arg > uint64(len(d.data)) {
+			return nil, errors.New("CBOR record truncated")
+		}
+		items := make(map[string]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			major, _, length, err := d.head()
+			if err != nil {
+				return nil, err
+			}
+			if major != cborText {
+				return nil, errors.New("CBOR map key not text")
+			}
+			key, err := d.bytes(length)
+			if err != nil {
+				return nil, err
+			}
+			if items[string(key)], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborSimple:
+		switch info {
+		case cborFalse:
+			return false, nil
+		case cborTrue:
+			return true, nil
+		case cborNull:
+			return nil, nil
+		case cborFloat64:
+			return json.Number(strconv.FormatFloat(math.Float64frombits(arg), 'g', -1, 64)), nil
+		}
+	}
+	return nil, errors.New("unsupported CBOR item")
+}
@@ -0,0 +1,94 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCBORConversion(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		res  string
+		err  string
+	}{
+		{
+			name: "Empty",
+			json: `{}`,
+		},
+		{
+			name: "Types",
+			json: `{"a":[1,-1,0,18446744073709551615,-9223372036854775808,1.5,true,false,null],"b":"text","c":"0a1b","d":{"e":""}}`,
+		},
+		{
+			name: "NotLowerHex",
+			json: `["0A1B","abc","0x0a","12"]`,
+		},
+		{
+			name: "Unicode",
+			json: `{"name":"Ünïcödé ✓"}`,
+		},
+		{
+			name: "FloatNormalised",
+			json: `[1.0,2e3]`,
+			res:  `[1,2000]`,
+		},
+		{
+			name: "Invalid",
+			json: `{"a":`,
+			err:  "invalid JSON: unexpected EOF",
+		},
+		{
+			name: "Trailing",
+			json: `{} {}`,
+			err:  "invalid JSON: data after value",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := jsonToCBOR([]byte(test.json))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			res, err := cborToJSON(data)
+			require.NoError(t, err)
+			expected := test.json
+			if test.res != "" {
+				expected = test.res
+			}
+			assert.JSONEq(t, expected, string(res))
+		})
+	}
+}
+
+func TestCBORInvalid(t *testing.T) {
+	data, err := jsonToCBOR([]byte(`{"a":["0a1b","text"]}`))
+	require.NoError(t, err)
+	for i := len(cborMagic); i < len(data); i++ {
+		_, err := cborToJSON(data[:i])
+		assert.Error(t, err)
+	}
+	_, err = cborToJSON([]byte("{}"))
+	assert.EqualError(t, err, "not a CBOR record")
+	// An array claiming more items than there are bytes.
+	_, err = cborToJSON(append(append([]byte{}, cborMagic...), 0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff))
+	assert.EqualError(t, err, "CBOR record truncated")
+}
@@ -0,0 +1,194 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// cborStore is a store that holds wallet and account data as CBOR rather than JSON.
+type cborStore struct {
+	store wtypes.Store
+}
+
+// NewCBORStore creates a store that holds wallet, account and index data in the underlying store as CBOR, which is
+// more compact than JSON, converting it back to JSON as it is retrieved.  Data already held in the underlying store
+// as JSON is retrieved unchanged, and converted to CBOR when it is next stored, so existing stores can be used.
+// This reduces the size of stored data only: data is still provided as JSON, so that wallets can be opened by code
+// that reads their JSON, and retrieving it costs a conversion on top of parsing the JSON.  Exports are not affected,
+// as they are compressed.
+// Stores that find data by decoding its JSON cannot find data held as CBOR, in which case this store finds it by
+// retrieving all wallets or accounts, so lookups by name, and by ID with such stores, take longer than with JSON.
+func NewCBORStore(store wtypes.Store) wtypes.Store {
+	return &cborStore{
+		store: store,
+	}
+}
+
+// toCBOR converts data to CBOR for storing.
+func toCBOR(data []byte) ([]byte, error) {
+	res, err := jsonToCBOR(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert data to CBOR")
+	}
+	return res, nil
+}
+
+// fromCBOR converts stored data back to JSON.  Data that is not CBOR is returned unchanged.
+func fromCBOR(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, cborMagic) {
+		return data, nil
+	}
+	res, err := cborToJSON(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert data from CBOR")
+	}
+	return res, nil
+}
+
+// fromCBORChannel converts stored data provided by a channel back to JSON.  Data that cannot be converted is passed
+// on unchanged, to be rejected by the recipient.
+func fromCBORChannel(in <-chan []byte) <-chan []byte {
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		for data := range in {
+			if res, err := fromCBOR(data); err == nil {
+				data = res
+			}
+			out <- data
+		}
+	}()
+	return out
+}
+
+// cborRecordInfo is the information used to find a wallet or account.
+type cborRecordInfo struct {
+	ID   uuid.UUID `json:"uuid"`
+	Name string    `json:"name"`
+}
+
+// findRecord finds the record provided by the channel that matches, returning the underlying store's error if none do.
+func (s *cborStore) findRecord(storeErr error, records <-chan []byte, match func(info *cborRecordInfo) bool) ([]byte, error) {
+	var res []byte
+	for data := range records {
+		info := &cborRecordInfo{}
+		if res == nil && json.Unmarshal(data, info) == nil && match(info) {
+			res = data
+		}
+	}
+	if res == nil {
+		return nil, storeErr
+	}
+	return res, nil
+}
+
+// Name provides the name of the store.
+func (s *cborStore) Name() string {
+	return s.store.Name()
+}
+
+// StoreWallet stores wallet data as CBOR.
+func (s *cborStore) StoreWallet(walletID uuid.UUID, walletName string, data []byte) error {
+	data, err := toCBOR(data)
+	if err != nil {
+		return err
+	}
+	return s.store.StoreWallet(walletID, walletName, data)
+}
+
+// RetrieveWallets retrieves wallet data for all wallets.
+func (s *cborStore) RetrieveWallets() <-chan []byte {
+	return fromCBORChannel(s.store.RetrieveWallets())
+}
+
+// RetrieveWallet retrieves wallet data for a wallet with a given name.
+func (s *cborStore) RetrieveWallet(walletName string) ([]byte, error) {
+	data, err := s.store.RetrieveWallet(walletName)
+	if err != nil {
+		return s.findRecord(err, s.RetrieveWallets(), func(info *cborRecordInfo) bool {
+			return info.Name == walletName
+		})
+	}
+	return fromCBOR(data)
+}
+
+// RetrieveWalletByID retrieves wallet data for a wallet with a given ID.
+func (s *cborStore) RetrieveWalletByID(walletID uuid.UUID) ([]byte, error) {
+	data, err := s.store.RetrieveWalletByID(walletID)
+	if err != nil {
+		return s.findRecord(err, s.RetrieveWallets(), func(info *cborRecordInfo) bool {
+			return info.ID == walletID
+		})
+	}
+	return fromCBOR(data)
+}
+
+// StoreAccount stores account data as CBOR.
+func (s *cborStore) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	data, err := toCBOR(data)
+	if err != nil {
+		return err
+	}
+	return s.store.StoreAccount(walletID, accountID, data)
+}
+
+// DeleteAccount deletes account data.
+// The underlying store must support account deletion.
+func (s *cborStore) DeleteAccount(walletID uuid.UUID, accountID uuid.UUID) error {
+	deleter, isDeleter := s.store.(AccountDeleter)
+	if !isDeleter {
+		return errors.New("store does not support account deletion")
+	}
+	return deleter.DeleteAccount(walletID, accountID)
+}
+
+// RetrieveAccounts retrieves account information for all accounts.
+func (s *cborStore) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	return fromCBORChannel(s.store.RetrieveAccounts(walletID))
+}
+
+// RetrieveAccount retrieves account data for a wallet with a given ID.
+func (s *cborStore) RetrieveAccount(walletID uuid.UUID, accountID uuid.UUID) ([]byte, error) {
+	data, err := s.store.RetrieveAccount(walletID, accountID)
+	if err != nil {
+		return s.findRecord(err, s.RetrieveAccounts(walletID), func(info *cborRecordInfo) bool {
+			return info.ID == accountID
+		})
+	}
+	return fromCBOR(data)
+}
+
+// StoreAccountsIndex stores the index of accounts for a given wallet as CBOR.
+func (s *cborStore) StoreAccountsIndex(walletID uuid.UUID, data []byte) error {
+	data, err := toCBOR(data)
+	if err != nil {
+		return err
+	}
+	return s.store.StoreAccountsIndex(walletID, data)
+}
+
+// RetrieveAccountsIndex retrieves the index of accounts for a given wallet.
+func (s *cborStore) RetrieveAccountsIndex(walletID uuid.UUID) ([]byte, error) {
+	data, err := s.store.RetrieveAccountsIndex(walletID)
+	if err != nil {
+		return nil, err
+	}
+	return fromCBOR(data)
+}
@@ -0,0 +1,96 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestCBORStore(t *testing.T) {
+	underlying := scratch.New()
+	store := hd.NewCBORStore(underlying)
+	assert.Equal(t, underlying.Name(), store.Name())
+
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+	account, err := wallet.CreateAccount("test account", []byte("account passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	// The underlying store holds CBOR, which is smaller than the JSON it replaces.
+	cborData := <-underlying.RetrieveAccounts(wallet.ID())
+	assert.True(t, bytes.HasPrefix(cborData, []byte{0xd9, 0xd9, 0xf7}))
+	jsonData, err := store.RetrieveAccount(wallet.ID(), account.ID())
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(jsonData, []byte("{")))
+	assert.Less(t, len(cborData), len(jsonData)*3/4)
+	walletData := <-underlying.RetrieveWallets()
+	assert.True(t, bytes.HasPrefix(walletData, []byte{0xd9, 0xd9, 0xf7}))
+
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID(), reopened.ID())
+	require.NoError(t, reopened.Unlock([]byte("wallet passphrase")))
+	reopenedAccount, err := reopened.AccountByName("test account")
+	require.NoError(t, err)
+	assert.Equal(t, account.PublicKey().Marshal(), reopenedAccount.PublicKey().Marshal())
+	require.NoError(t, reopenedAccount.Unlock([]byte("account passphrase")))
+	accounts := 0
+	for range reopened.Accounts() {
+		accounts++
+	}
+	assert.Equal(t, 1, accounts)
+}
+
+func TestCBORStoreExistingJSON(t *testing.T) {
+	underlying := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", underlying, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	// A store holding JSON can be used, with new data stored as CBOR.
+	store := hd.NewCBORStore(underlying)
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Unlock(nil))
+	_, err = reopened.AccountByName("Account 1")
+	require.NoError(t, err)
+	_, err = reopened.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	cborAccounts := 0
+	for data := range underlying.RetrieveAccounts(wallet.ID()) {
+		if bytes.HasPrefix(data, []byte{0xd9, 0xd9, 0xf7}) {
+			cborAccounts++
+		}
+	}
+	assert.Equal(t, 1, cborAccounts)
+
+	accounts := 0
+	for range reopened.Accounts() {
+		accounts++
+	}
+	assert.Equal(t, 2, accounts)
+}
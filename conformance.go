@@ -0,0 +1,127 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// derivationVector is an EIP-2333 key derivation test vector.
+type derivationVector struct {
+	seed       string
+	masterSK   string
+	childIndex uint32
+	childSK    string
+}
+
+// derivationVectors are the EIP-2333 test vectors for the version of EIP-2333 implemented by the default backend.
+var derivationVectors = []*derivationVector{
+	{
+		seed:       "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		masterSK:   "12513733877922233913083619867448865075222526338446857121953625441395088009793",
+		childIndex: 0,
+		childSK:    "7419543105316279183937430842449358701327973165530407166294956473095303972104",
+	},
+	{
+		seed:       "3141592653589793238462643383279502884197169399375105820974944592",
+		masterSK:   "46029459550803682895343812821003080589696405386150182061394330539196052371668",
+		childIndex: 3141592653,
+		childSK:    "43469287647733616183478983885105537266268532274998688773496918571876759327260",
+	},
+	{
+		seed:       "0099ff991111002299dd7744ee3355bbdd8844115566cc55663355668888cc00",
+		masterSK:   "45379166311535261329029945990467475187325618028073620882733843918126031931161",
+		childIndex: 4294967295,
+		childSK:    "46475244006136701976831062271444482037125148379128114617927607151318277762946",
+	},
+	{
+		seed:       "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+		childIndex: 42,
+		childSK:    "51041472511529980987749393477251359993058329222191894694692317000136653813011",
+	},
+}
+
+// pathVector is an EIP-2334 path derivation test vector.
+type pathVector struct {
+	seed string
+	path string
+	sk   string
+}
+
+// pathVectors are the EIP-2334 path derivation test vectors.
+var pathVectors = []*pathVector{
+	{
+		seed: "0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		path: "m/12381/3600/0/0",
+		sk:   "31676788419929922777864946442677915531199062343799598297489487887255736884383",
+	},
+}
+
+// ConformanceCheck runs EIP-2333 and EIP-2334 test vectors against a BLS backend, returning an error if the backend
+// derives any key other than that expected.  This allows a miscompiled or incompatible BLS library to be detected
+// before it is used to generate keys.  If backend is nil the default backend is checked.
+func ConformanceCheck(backend BLSBackend) error {
+	if backend == nil {
+		backend = defaultBackend
+	}
+
+	for i, vector := range derivationVectors {
+		seed, err := hex.DecodeString(vector.seed)
+		if err != nil {
+			return errors.Wrapf(err, "derivation vector %d invalid", i)
+		}
+		masterSK, err := backend.DeriveMasterSK(seed)
+		if err != nil {
+			return errors.Wrapf(err, "derivation vector %d: failed to derive master key", i)
+		}
+		if vector.masterSK != "" && masterSK.String() != vector.masterSK {
+			return fmt.Errorf("derivation vector %d: master key %s does not match expected %s", i, masterSK, vector.masterSK)
+		}
+		childSK, err := backend.DeriveChildSK(masterSK, vector.childIndex)
+		if err != nil {
+			return errors.Wrapf(err, "derivation vector %d: failed to derive child key", i)
+		}
+		if childSK.String() != vector.childSK {
+			return fmt.Errorf("derivation vector %d: child key %s does not match expected %s", i, childSK, vector.childSK)
+		}
+	}
+
+	for i, vector := range pathVectors {
+		seed, err := hex.DecodeString(vector.seed)
+		if err != nil {
+			return errors.Wrapf(err, "path vector %d invalid", i)
+		}
+		privateKey, err := privateKeyFromSeedAndPath(backend, seed, vector.path)
+		if err != nil {
+			return errors.Wrapf(err, "path vector %d: failed to derive key", i)
+		}
+		sk, ok := new(big.Int).SetString(vector.sk, 10)
+		if !ok {
+			return fmt.Errorf("path vector %d invalid", i)
+		}
+		expected := make([]byte, 32)
+		skBytes := sk.Bytes()
+		copy(expected[32-len(skBytes):], skBytes)
+		if !bytes.Equal(privateKey.Marshal(), expected) {
+			return fmt.Errorf("path vector %d: key %x at path %s does not match expected %x", i, privateKey.Marshal(), vector.path, expected)
+		}
+	}
+
+	return nil
+}
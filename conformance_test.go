@@ -0,0 +1,37 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+)
+
+// offByOneBackend is a BLS backend that derives incorrect child keys.
+type offByOneBackend struct {
+	countingBackend
+}
+
+func (b *offByOneBackend) DeriveChildSK(parentSK *big.Int, index uint32) (*big.Int, error) {
+	return b.countingBackend.DeriveChildSK(parentSK, index+1)
+}
+
+func TestConformanceCheck(t *testing.T) {
+	assert.NoError(t, hd.ConformanceCheck(nil))
+	assert.NoError(t, hd.ConformanceCheck(&countingBackend{}))
+	assert.EqualError(t, hd.ConformanceCheck(&offByOneBackend{}), "derivation vector 0: child key 10371057761532106384496072663695520314544561721194776233161199026610380647191 does not match expected 7419543105316279183937430842449358701327973165530407166294956473095303972104")
+}
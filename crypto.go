@@ -0,0 +1,221 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// keyLength is the length of the symmetric keys generated by the wallet, including the data keys with which earlier
+// versions protected secrets that were not of this length.
+const keyLength = 32
+
+// normalisePassphrase normalises a passphrase as per EIP-2335, converting it to
+// Unicode NFKD form and removing control codes, so that the same passphrase
+// entered on different systems encrypts and decrypts identically.
+func normalisePassphrase(passphrase []byte) []byte {
+	res := make([]byte, 0, len(passphrase))
+	for _, r := range norm.NFKD.String(string(passphrase)) {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			continue
+		}
+		res = append(res, string(r)...)
+	}
+	return res
+}
+
+// encryptSecret encrypts a secret with the encryptor.  Secrets of any length are encrypted directly, so that they
+// can be read by other EIP-2335 tooling.  The passphrase is normalised before use.
+func encryptSecret(encryptor wtypes.Encryptor, secret []byte, passphrase []byte) (map[string]interface{}, error) {
+	return encryptor.Encrypt(secret, normalisePassphrase(passphrase))
+}
+
+// encryptData encrypts a secret with AES-256-GCM under the data key, providing the data section of the crypto.
+func encryptData(dataKey []byte, secret []byte) (map[string]interface{}, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	return map[string]interface{}{
+		"function": "aes-256-gcm",
+		"params": map[string]interface{}{
+			"nonce": hex.EncodeToString(nonce),
+		},
+		"message": hex.EncodeToString(aead.Seal(nil, nonce, secret, nil)),
+	}, nil
+}
+
+// decryptSecret decrypts a secret encrypted with encryptSecret, or by earlier versions that encrypted secrets other
+// than keys with AES-256-GCM under a data key, and the data key with the encryptor.
+// The normalised passphrase is tried first; if that fails the passphrase is
+// tried as supplied, for secrets encrypted before passphrases were normalised.
+func decryptSecret(encryptor wtypes.Encryptor, crypto map[string]interface{}, passphrase []byte) ([]byte, error) {
+	normalised := normalisePassphrase(passphrase)
+	secret, err := decryptSecretWithPassphrase(encryptor, crypto, normalised)
+	if err != nil && !bytes.Equal(normalised, passphrase) {
+		if legacySecret, legacyErr := decryptSecretWithPassphrase(encryptor, crypto, passphrase); legacyErr == nil {
+			return legacySecret, nil
+		}
+	}
+	return secret, err
+}
+
+// decryptSecretWithPassphrase decrypts a secret with the passphrase exactly as supplied.
+func decryptSecretWithPassphrase(encryptor wtypes.Encryptor, crypto map[string]interface{}, passphrase []byte) ([]byte, error) {
+	val, exists := crypto["data"]
+	if !exists {
+		if message, err := keystoreCipherMessage(crypto); err == nil && len(message) != keyLength {
+			// keystorev4 only decrypts secrets of keyLength bytes.
+			return decryptKeystoreSecret(crypto, passphrase)
+		}
+		return encryptor.Decrypt(crypto, passphrase)
+	}
+	data, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("crypto data invalid")
+	}
+	keyCrypto, ok := crypto["key"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("crypto key invalid")
+	}
+	nonce, message, err := parseData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := encryptor.Decrypt(keyCrypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(dataKey)
+	return openData(dataKey, nonce, message)
+}
+
+// parseData parses the data section of the crypto, providing its nonce and encrypted message.
+func parseData(data map[string]interface{}) ([]byte, []byte, error) {
+	if function, ok := data["function"].(string); !ok || function != "aes-256-gcm" {
+		return nil, nil, errors.New("crypto data function unsupported")
+	}
+	params, ok := data["params"].(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.New("crypto data params invalid")
+	}
+	nonceStr, ok := params["nonce"].(string)
+	if !ok {
+		return nil, nil, errors.New("crypto data nonce invalid")
+	}
+	nonce, err := hex.DecodeString(nonceStr)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "crypto data nonce invalid")
+	}
+	messageStr, ok := data["message"].(string)
+	if !ok {
+		return nil, nil, errors.New("crypto data message invalid")
+	}
+	message, err := hex.DecodeString(messageStr)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "crypto data message invalid")
+	}
+	return nonce, message, nil
+}
+
+// openData decrypts a message encrypted with encryptData.
+func openData(dataKey []byte, nonce []byte, message []byte) ([]byte, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, errors.New("crypto data nonce invalid")
+	}
+	secret, err := aead.Open(nil, nonce, message, nil)
+	if err != nil {
+		return nil, errors.New("crypto data corrupt")
+	}
+
+	return secret, nil
+}
+
+// zeroBytes overwrites a secret held in a byte slice with zeros.
+func zeroBytes(secret []byte) {
+	for i := range secret {
+		secret[i] = 0
+	}
+}
+
+// zeroInt overwrites a secret held in a big integer with zeros.
+func zeroInt(secret *big.Int) {
+	if secret == nil {
+		return
+	}
+	words := secret.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	secret.SetInt64(0)
+}
+
+// privateKeyZeroer is the interface for private keys that can overwrite themselves with zeros.
+type privateKeyZeroer interface {
+	// Zero overwrites the private key with zeros.
+	Zero()
+}
+
+// zeroPrivateKey overwrites a private key with zeros.  Keys of the default backend are overwritten in place, and keys
+// of other backends are overwritten if they have a Zero() method; any other keys cannot be overwritten, so are left
+// for the garbage collector.
+func zeroPrivateKey(key e2types.PrivateKey) {
+	switch k := key.(type) {
+	case *e2types.BLSPrivateKey:
+		*k = e2types.BLSPrivateKey{}
+	case privateKeyZeroer:
+		k.Zero()
+	}
+}
+
+// copyPrivateKey provides a copy of a private key that does not share memory with the original, so that either can be
+// overwritten without affecting the other.
+func copyPrivateKey(backend BLSBackend, key e2types.PrivateKey) (e2types.PrivateKey, error) {
+	if k, ok := key.(*e2types.BLSPrivateKey); ok {
+		keyCopy := *k
+		return &keyCopy, nil
+	}
+	keyBytes := key.Marshal()
+	defer zeroBytes(keyBytes)
+	return backend.PrivateKeyFromBytes(keyBytes)
+}
+
+// newAEAD creates an AES-256-GCM cipher for the given data key.
+func newAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
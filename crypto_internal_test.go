@@ -0,0 +1,180 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret []byte
+	}{
+		{
+			name:   "Short",
+			secret: []byte{0x01, 0x02, 0x03},
+		},
+		{
+			name:   "Key",
+			secret: make([]byte, 32),
+		},
+		{
+			name:   "Seed",
+			secret: make([]byte, 64),
+		},
+		{
+			name:   "Long",
+			secret: []byte("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"),
+		},
+	}
+
+	encryptor := keystorev4.New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			crypto, err := encryptSecret(encryptor, test.secret, []byte("passphrase"))
+			require.NoError(t, err)
+			// Secrets are encrypted directly, so can be decrypted as plain EIP-2335 keystore crypto.
+			secret, err := decryptKeystoreSecret(crypto, []byte("passphrase"))
+			require.NoError(t, err)
+			assert.Equal(t, test.secret, secret)
+
+			// Round-trip through JSON as per storage.
+			data, err := json.Marshal(crypto)
+			require.NoError(t, err)
+			stored := make(map[string]interface{})
+			require.NoError(t, json.Unmarshal(data, &stored))
+
+			_, err = decryptSecret(encryptor, stored, []byte("wrong"))
+			assert.NotNil(t, err)
+			secret, err = decryptSecret(encryptor, stored, []byte("passphrase"))
+			require.NoError(t, err)
+			assert.Equal(t, test.secret, secret)
+		})
+	}
+}
+
+func TestDecryptLegacySecret(t *testing.T) {
+	encryptor := keystorev4.New()
+	secret := make([]byte, 64)
+	_, err := rand.Read(secret)
+	require.NoError(t, err)
+
+	// Earlier versions encrypted secrets that were not keys under a data key.
+	dataKey := make([]byte, keyLength)
+	_, err = rand.Read(dataKey)
+	require.NoError(t, err)
+	keyCrypto, err := encryptor.Encrypt(dataKey, []byte("passphrase"))
+	require.NoError(t, err)
+	dataCrypto, err := encryptData(dataKey, secret)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]interface{}{
+		"key":  keyCrypto,
+		"data": dataCrypto,
+	})
+	require.NoError(t, err)
+	stored := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(data, &stored))
+
+	_, err = decryptSecret(encryptor, stored, []byte("wrong"))
+	assert.NotNil(t, err)
+	decrypted, err := decryptSecret(encryptor, stored, []byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, secret, decrypted)
+}
+
+func TestNormalisePassphrase(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		expected   string
+	}{
+		{
+			name:       "ASCII",
+			passphrase: "passphrase",
+			expected:   "passphrase",
+		},
+		{
+			name:       "Composed",
+			passphrase: "caf\u00e9",
+			expected:   "cafe\u0301",
+		},
+		{
+			name:       "Decomposed",
+			passphrase: "cafe\u0301",
+			expected:   "cafe\u0301",
+		},
+		{
+			name:       "ControlCodes",
+			passphrase: "pass\nphrase\u007f\u0085",
+			expected:   "passphrase",
+		},
+		{
+			// Test vector from EIP-2335.
+			name:       "EIP2335",
+			passphrase: "\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511",
+			expected:   "testpassword\U0001f511",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, []byte(test.expected), normalisePassphrase([]byte(test.passphrase)))
+		})
+	}
+}
+
+func TestDecryptSecretNormalisation(t *testing.T) {
+	encryptor := keystorev4.New()
+	secret := make([]byte, 32)
+
+	// Secrets encrypted with a composed passphrase decrypt with the decomposed form, and vice versa.
+	crypto, err := encryptSecret(encryptor, secret, []byte("caf\u00e9"))
+	require.NoError(t, err)
+	decrypted, err := decryptSecret(encryptor, crypto, []byte("cafe\u0301"))
+	require.NoError(t, err)
+	assert.Equal(t, secret, decrypted)
+
+	// Secrets encrypted with an unnormalised passphrase still decrypt.
+	legacyCrypto, err := encryptor.Encrypt(secret, []byte("caf\u00e9"))
+	require.NoError(t, err)
+	decrypted, err = decryptSecret(encryptor, legacyCrypto, []byte("caf\u00e9"))
+	require.NoError(t, err)
+	assert.Equal(t, secret, decrypted)
+	_, err = decryptSecret(encryptor, legacyCrypto, []byte("wrong"))
+	assert.NotNil(t, err)
+}
+
+func TestVerifyKeystoreChecksum(t *testing.T) {
+	for _, encryptor := range []*keystorev4.Encryptor{keystorev4.New(), keystorev4.New(keystorev4.WithCipher("scrypt"))} {
+		crypto, err := encryptor.Encrypt(make([]byte, 32), []byte("passphrase"))
+		require.NoError(t, err)
+
+		valid, err := verifyKeystoreChecksum(crypto, []byte("passphrase"))
+		require.NoError(t, err)
+		assert.True(t, valid)
+		valid, err = verifyKeystoreChecksum(crypto, []byte("wrong"))
+		require.NoError(t, err)
+		assert.False(t, valid)
+	}
+
+	_, err := verifyKeystoreChecksum(map[string]interface{}{}, []byte("passphrase"))
+	assert.EqualError(t, err, "kdf invalid")
+}
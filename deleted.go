@@ -0,0 +1,121 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// DeletedAccounts provides the accounts that have been deleted from the wallet but not yet purged.
+func (w *wallet) DeletedAccounts() <-chan wtypes.Account {
+	ch := make(chan wtypes.Account, 1024)
+	go func() {
+		defer close(ch)
+		for data := range w.store.RetrieveAccounts(w.ID()) {
+			a, err := deserializeAccount(w, data)
+			if err != nil || !a.(*account).deleted {
+				continue
+			}
+			ch <- a
+		}
+	}()
+	return ch
+}
+
+// RestoreAccount restores an account that has been deleted but not yet purged.
+// This will error if another account has since taken the account's name or path.
+func (w *wallet) RestoreAccount(id uuid.UUID) (wtypes.Account, error) {
+	a, err := w.deletedAccount(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.AccountByName(a.name); err == nil {
+		return nil, fmt.Errorf("account with name %q already exists", a.name)
+	}
+	if a.path != "" {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		for existing := range w.AccountsWithContext(ctx) {
+			if existing.Path() == a.path {
+				return nil, fmt.Errorf("account path %s is in use by account %q", a.path, existing.Name())
+			}
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	a.setDeleted(false)
+	if err := w.storeAccountData(a); err != nil {
+		a.setDeleted(true)
+		return nil, errors.Wrapf(err, "failed to restore account %q", a.name)
+	}
+	// Remove the tombstone recorded when the account was deleted.
+	if w.tombstones[a.path] == a.name {
+		delete(w.tombstones, a.path)
+		if err := w.storeWallet(); err != nil {
+			return nil, errors.Wrapf(err, "failed to restore account %q", a.name)
+		}
+	}
+	w.index.Add(a.id, a.name)
+	if err := w.storeAccountsIndex(); err != nil {
+		return nil, errors.Wrapf(err, "failed to restore account %q", a.name)
+	}
+
+	return a, nil
+}
+
+// PurgeAccount permanently deletes an account that has been deleted.  This cannot be undone.
+// The wallet's store must support account deletion.
+func (w *wallet) PurgeAccount(id uuid.UUID) error {
+	deleter, isDeleter := w.store.(AccountDeleter)
+	if !isDeleter {
+		return errors.New("store does not support account deletion")
+	}
+	a, err := w.deletedAccount(id)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if err := deleter.DeleteAccount(w.id, a.id); err != nil {
+		return errors.Wrapf(err, "failed to purge account %q", a.name)
+	}
+
+	return nil
+}
+
+// deletedAccount provides the deleted account with the given ID.
+func (w *wallet) deletedAccount(id uuid.UUID) (*account, error) {
+	data, err := w.store.RetrieveAccount(w.id, id)
+	if err != nil {
+		return nil, err
+	}
+	walletAccount, err := deserializeAccount(w, data)
+	if err != nil {
+		return nil, err
+	}
+	a := walletAccount.(*account)
+	if !a.deleted {
+		return nil, fmt.Errorf("account %s is not deleted", id)
+	}
+	return a, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/prysmaticlabs/go-ssz"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+const (
+	// minDepositAmount is the minimum amount of a deposit, in Gwei.
+	minDepositAmount = 1000000000
+	// compoundingWithdrawalPrefix is the prefix for compounding execution-layer withdrawal credentials.
+	compoundingWithdrawalPrefix = 0x02
+	// depositCLIVersion is the deposit CLI version declared in deposit data, which the launchpad requires.
+	depositCLIVersion = "2.7.0"
+)
+
+// depositNetworks are the names of the networks with known fork versions, as used by the launchpad.
+var depositNetworks = map[string]string{
+	"00000000": "mainnet",
+	"00001020": "goerli",
+	"90000069": "sepolia",
+	"01017000": "holesky",
+	"10000910": "hoodi",
+}
+
+// DepositData is the signed deposit data for a validator, in the format of the deposit CLI's deposit_data files.
+// A JSON array of DepositData can be uploaded to the launchpad.
+type DepositData struct {
+	PublicKey             string `json:"pubkey"`
+	WithdrawalCredentials string `json:"withdrawal_credentials"`
+	Amount                uint64 `json:"amount"`
+	Signature             string `json:"signature"`
+	DepositMessageRoot    string `json:"deposit_message_root"`
+	DepositDataRoot       string `json:"deposit_data_root"`
+	ForkVersion           string `json:"fork_version"`
+	NetworkName           string `json:"network_name,omitempty"`
+	DepositCLIVersion     string `json:"deposit_cli_version"`
+}
+
+// depositMessage is the SSZ container for a deposit message.
+type depositMessage struct {
+	PublicKey             []byte `ssz-size:"48"`
+	WithdrawalCredentials []byte `ssz-size:"32"`
+	Amount                uint64
+}
+
+// depositData is the SSZ container for deposit data.
+type depositData struct {
+	PublicKey             []byte `ssz-size:"48"`
+	WithdrawalCredentials []byte `ssz-size:"32"`
+	Amount                uint64
+	Signature             []byte `ssz-size:"96"`
+}
+
+// signingData is the SSZ container for the data signed for an object.
+type signingData struct {
+	ObjectRoot []byte `ssz-size:"32"`
+	Domain     []byte `ssz-size:"32"`
+}
+
+// DepositData provides signed deposit data for the account as a validator with the given withdrawal credentials,
+// deposit amount in Gwei and fork version, so that deposits can be made without a separate tool with access to the
+// account's key.  The account must be unlocked.
+func (a *account) DepositData(withdrawalCredentials []byte, amount uint64, forkVersion []byte) (*DepositData, error) {
+	if len(withdrawalCredentials) != 32 {
+		return nil, errors.New("withdrawal credentials must be 32 bytes")
+	}
+	switch withdrawalCredentials[0] {
+	case blsWithdrawalPrefix, executionWithdrawalPrefix, compoundingWithdrawalPrefix:
+	default:
+		return nil, fmt.Errorf("unsupported withdrawal credentials prefix %#02x", withdrawalCredentials[0])
+	}
+	if amount < minDepositAmount {
+		return nil, fmt.Errorf("deposit amount must be at least %d Gwei", minDepositAmount)
+	}
+	if len(forkVersion) != 4 {
+		return nil, errors.New("fork version must be 4 bytes")
+	}
+
+	publicKey := a.PublicKey().Marshal()
+	messageRoot, err := ssz.HashTreeRoot(&depositMessage{
+		PublicKey:             publicKey,
+		WithdrawalCredentials: withdrawalCredentials,
+		Amount:                amount,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate deposit message root")
+	}
+	signingRoot, err := ssz.HashTreeRoot(&signingData{
+		ObjectRoot: messageRoot[:],
+		Domain:     e2types.Domain(e2types.DomainDeposit, forkVersion, e2types.ZeroGenesisValidatorsRoot),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate signing root")
+	}
+	signature, err := a.Sign(signingRoot[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign deposit")
+	}
+	dataRoot, err := ssz.HashTreeRoot(&depositData{
+		PublicKey:             publicKey,
+		WithdrawalCredentials: withdrawalCredentials,
+		Amount:                amount,
+		Signature:             signature.Marshal(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate deposit data root")
+	}
+
+	return &DepositData{
+		PublicKey:             fmt.Sprintf("%x", publicKey),
+		WithdrawalCredentials: fmt.Sprintf("%x", withdrawalCredentials),
+		Amount:                amount,
+		Signature:             fmt.Sprintf("%x", signature.Marshal()),
+		DepositMessageRoot:    fmt.Sprintf("%x", messageRoot),
+		DepositDataRoot:       fmt.Sprintf("%x", dataRoot),
+		ForkVersion:           fmt.Sprintf("%x", forkVersion),
+		NetworkName:           depositNetworks[fmt.Sprintf("%x", forkVersion)],
+		DepositCLIVersion:     depositCLIVersion,
+	}, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+// hashPair provides the SHA-256 hash of two 32-byte chunks, as used in SSZ merkleization.
+func hashPair(left []byte, right []byte) []byte {
+	hash := sha256.Sum256(append(append([]byte{}, left...), right...))
+	return hash[:]
+}
+
+// chunk pads data to a 32-byte chunk.
+func chunk(data []byte) []byte {
+	res := make([]byte, 32)
+	copy(res, data)
+	return res
+}
+
+func TestDepositData(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Validator", []byte("passphrase"))
+	require.NoError(t, err)
+	provider := account.(hd.AccountDepositDataProvider)
+
+	withdrawalCredentials := _byteArray("010000000000000000000000a1b2c3d4e5f60718293a4b5c6d7e8f9001020304")
+	forkVersion := _byteArray("00000000")
+	_, err = provider.DepositData(withdrawalCredentials, 32000000000, forkVersion)
+	assert.EqualError(t, err, "failed to sign deposit: cannot sign when account is locked")
+	require.NoError(t, account.Unlock([]byte("passphrase")))
+
+	_, err = provider.DepositData(withdrawalCredentials[1:], 32000000000, forkVersion)
+	assert.EqualError(t, err, "withdrawal credentials must be 32 bytes")
+	_, err = provider.DepositData(withdrawalCredentials, 100, forkVersion)
+	assert.EqualError(t, err, "deposit amount must be at least 1000000000 Gwei")
+	_, err = provider.DepositData(withdrawalCredentials, 32000000000, forkVersion[1:])
+	assert.EqualError(t, err, "fork version must be 4 bytes")
+
+	depositData, err := provider.DepositData(withdrawalCredentials, 32000000000, forkVersion)
+	require.NoError(t, err)
+	assert.Equal(t, "mainnet", depositData.NetworkName)
+	assert.Equal(t, "00000000", depositData.ForkVersion)
+	assert.Equal(t, uint64(32000000000), depositData.Amount)
+	assert.Equal(t, hex.EncodeToString(withdrawalCredentials), depositData.WithdrawalCredentials)
+
+	// Merkleize the deposit by hand to confirm the roots.
+	publicKey := account.PublicKey().Marshal()
+	assert.Equal(t, hex.EncodeToString(publicKey), depositData.PublicKey)
+	amount := make([]byte, 8)
+	binary.LittleEndian.PutUint64(amount, 32000000000)
+	publicKeyRoot := hashPair(publicKey[:32], chunk(publicKey[32:]))
+	messageRoot := hashPair(hashPair(publicKeyRoot, withdrawalCredentials), hashPair(chunk(amount), make([]byte, 32)))
+	assert.Equal(t, hex.EncodeToString(messageRoot), depositData.DepositMessageRoot)
+
+	domain := append([]byte{0x03, 0x00, 0x00, 0x00}, hashPair(chunk(forkVersion), make([]byte, 32))[:28]...)
+	signingRoot := hashPair(messageRoot, domain)
+	signatureBytes, err := hex.DecodeString(depositData.Signature)
+	require.NoError(t, err)
+	signature, err := e2types.BLSSignatureFromBytes(signatureBytes)
+	require.NoError(t, err)
+	assert.True(t, signature.Verify(signingRoot, account.PublicKey()))
+
+	signatureRoot := hashPair(hashPair(signatureBytes[:32], signatureBytes[32:64]), hashPair(signatureBytes[64:], make([]byte, 32)))
+	dataRoot := hashPair(hashPair(publicKeyRoot, withdrawalCredentials), hashPair(chunk(amount), signatureRoot))
+	assert.Equal(t, hex.EncodeToString(dataRoot), depositData.DepositDataRoot)
+
+	// Unknown networks are not named.
+	depositData, err = provider.DepositData(withdrawalCredentials, 32000000000, _byteArray("12345678"))
+	require.NoError(t, err)
+	assert.Empty(t, depositData.NetworkName)
+}
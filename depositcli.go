@@ -0,0 +1,97 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// depositCLIPathTemplate is the path template of the signing keys generated by the Ethereum deposit CLI.
+const depositCLIPathTemplate = "m/12381/3600/%a/0/0"
+
+// ImportFromDepositCLI creates a wallet containing the validator signing keys that the Ethereum deposit CLI generates
+// from the given mnemonic, for validator indices startIndex to startIndex+count-1.  As with the deposit CLI the seed
+// is the 64-byte BIP-39 seed of the mnemonic, and the mnemonic password, if any, is supplied with
+// WithMnemonicPassphrase.  Accounts use the deposit CLI's paths m/12381/3600/index/0/0, are named after their
+// validator index and, as with the deposit CLI's keystores, are encrypted with passphrase, which also protects the
+// wallet's seed unless WithPassphrase is supplied.
+func ImportFromDepositCLI(name string, store wtypes.Store, encryptor wtypes.Encryptor, mnemonic string, passphrase []byte, startIndex uint64, count uint64, opts ...Option) (wtypes.Wallet, error) {
+	opts = append([]Option{WithPassphrase(passphrase)}, opts...)
+	options := options{
+		minSeedLength: minSeedLength,
+	}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	if mnemonic == "" {
+		return nil, errors.New("mnemonic missing")
+	}
+	if options.seed != nil || options.mnemonic != "" {
+		return nil, errors.New("cannot supply seed or mnemonic when importing from the deposit CLI")
+	}
+	if count == 0 {
+		return nil, errors.New("count must be at least 1")
+	}
+	options.mnemonic = mnemonic
+
+	return createDerivedWallet(name, store, encryptor, &options, passphrase, startIndex, count)
+}
+
+// createDerivedWallet creates a wallet with the deposit CLI's paths, containing accounts for the account numbers
+// startIndex to startIndex+count-1 encrypted with passphrase.  Accounts are named after their account number.
+func createDerivedWallet(name string, store wtypes.Store, encryptor wtypes.Encryptor, options *options, passphrase []byte, startIndex uint64, count uint64) (wtypes.Wallet, error) {
+	if options.pathTemplate != "" || options.pathProvider != nil || options.walletIndex != nil {
+		return nil, errors.New("cannot supply paths for a derived wallet")
+	}
+	if startIndex > math.MaxInt32 || count > math.MaxInt32-startIndex+1 {
+		return nil, errors.New("too many accounts")
+	}
+	options.pathTemplate = depositCLIPathTemplate
+
+	// First, try to open the wallet.
+	_, err := OpenWallet(name, store, encryptor)
+	if err == nil || !strings.Contains(err.Error(), "wallet not found") {
+		return nil, fmt.Errorf("wallet %q already exists", name)
+	}
+
+	w, seed, err := newWalletFromOptions(name, store, encryptor, options)
+	if err != nil {
+		return nil, err
+	}
+	w.nextAccount = startIndex
+	if err := w.storeWallet(); err != nil {
+		return nil, err
+	}
+	if err := w.setSeed(seed); err != nil {
+		return nil, err
+	}
+	defer w.Lock()
+	if count == 0 {
+		return w, nil
+	}
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%d", startIndex+uint64(i))
+	}
+	if _, err := w.CreateAccounts(names, passphrase); err != nil {
+		return nil, errors.Wrap(err, "failed to import accounts")
+	}
+
+	return w, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestImportFromDepositCLI(t *testing.T) {
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	encryptor := keystorev4.New()
+
+	_, err := hd.ImportFromDepositCLI("test wallet", scratch.New(), encryptor, mnemonic, nil, 0, 0)
+	assert.EqualError(t, err, "count must be at least 1")
+	_, err = hd.ImportFromDepositCLI("test wallet", scratch.New(), encryptor, mnemonic, nil, 0, 1, hd.WithWalletIndex(1))
+	assert.EqualError(t, err, "cannot supply paths for a derived wallet")
+	_, err = hd.ImportFromDepositCLI("test wallet", scratch.New(), encryptor, "abandon abandon", nil, 0, 1)
+	assert.EqualError(t, err, "mnemonic is invalid")
+
+	wallet, err := hd.ImportFromDepositCLI("test wallet", scratch.New(), encryptor, mnemonic, []byte("keystore password"), 5, 3, hd.WithMnemonicPassphrase([]byte("TREZOR")))
+	require.NoError(t, err)
+	assert.False(t, wallet.IsUnlocked())
+
+	// The deposit CLI uses the full 64-byte BIP-39 seed.
+	seed := _byteArray("c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04")
+	reference, err := hd.CreateWallet("reference wallet", scratch.New(), encryptor, hd.WithSeed(seed), hd.WithPathTemplate("m/12381/3600/%a/0/0"))
+	require.NoError(t, err)
+	require.NoError(t, reference.Unlock(nil))
+
+	for i := uint64(5); i < 8; i++ {
+		account, err := wallet.AccountByName(fmt.Sprintf("%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("m/12381/3600/%d/0/0", i), account.Path())
+		require.NoError(t, account.Unlock([]byte("keystore password")))
+		referenceAccount, err := reference.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex(fmt.Sprintf("account %d", i), i, nil)
+		require.NoError(t, err)
+		assert.Equal(t, referenceAccount.PublicKey().Marshal(), account.PublicKey().Marshal())
+	}
+	_, err = wallet.AccountByName("8")
+	assert.Error(t, err)
+	assert.Equal(t, uint64(8), wallet.(hd.WalletNextAccountProvider).NextAccount())
+
+	// The wallet is protected by the keystore password.
+	require.NoError(t, wallet.Unlock([]byte("keystore password")))
+}
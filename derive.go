@@ -0,0 +1,165 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/awnumar/memguard"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// derivationNode is an intermediate node in the wallet's derivation tree.
+type derivationNode struct {
+	path string
+	key  *big.Int
+}
+
+// setSeed sets the wallet's seed, and caches the node from which its accounts are derived.
+// The caller must hold the wallet's write lock.
+// Any previous seed and node are overwritten.  If the wallet seals its seed then the seed is encrypted under a
+// memory key, or if it uses guarded memory then the seed is moved in to guarded memory; in both cases the supplied
+// buffer is overwritten, and no node is cached as it would hold a plaintext key.
+func (w *wallet) setSeed(seed []byte) error {
+	w.clearSeed()
+	if w.sealSeed {
+		sealed, err := newSealedSeed(seed)
+		if err != nil {
+			return err
+		}
+		w.sealed = sealed
+		return nil
+	}
+	if w.guardedMemory {
+		w.seedBuffer = memguard.NewBufferFromBytes(seed)
+		w.seedBuffer.Freeze()
+		w.seed = w.seedBuffer.Bytes()
+		return nil
+	}
+	w.seed = seed
+	if nodePath := w.nodePath(); nodePath != "" {
+		if key, err := deriveKey(w.backend, seed, nil, nodePath); err == nil {
+			w.node = &derivationNode{path: nodePath, key: key}
+		}
+	}
+	return nil
+}
+
+// clearSeed overwrites and removes the wallet's seed and cached node.
+// The caller must hold the wallet's write lock.
+func (w *wallet) clearSeed() {
+	if w.seedBuffer != nil {
+		w.seedBuffer.Destroy()
+		w.seedBuffer = nil
+	} else {
+		zeroBytes(w.seed)
+	}
+	w.seed = nil
+	if w.sealed != nil {
+		w.sealed.destroy()
+		w.sealed = nil
+	}
+	if w.node != nil {
+		zeroInt(w.node.key)
+	}
+	w.node = nil
+	zeroBytes(w.passphrase)
+	w.passphrase = nil
+}
+
+// nodePath provides the path of the deepest node common to all of the wallet's account paths, for example
+// m/12381/3600/walletIndex for the default indexed path template.  It is empty if the wallet has a path provider.
+func (w *wallet) nodePath() string {
+	if w.pathProvider != nil {
+		return ""
+	}
+	components := strings.Split(w.pathTemplate, "/")
+	for i, component := range components {
+		if strings.Contains(component, "%a") {
+			return expandPathTemplate(strings.Join(components[:i], "/"), w.walletIndex, 0)
+		}
+	}
+	return ""
+}
+
+// derivePrivateKey derives the private key at the given path from the wallet's seed.
+// If the path is below the wallet's cached node then only the components below the node are derived.
+// Paths in the branch that the wallet reserves for its own keys are rejected, so that no account can hold them.
+// The caller must hold the wallet's lock.
+func (w *wallet) derivePrivateKey(path string) (e2types.PrivateKey, error) {
+	if isInternalPath(path) {
+		return nil, fmt.Errorf("path %q is reserved for use by the wallet", path)
+	}
+	if w.node == nil || !strings.HasPrefix(path, w.node.path+"/") {
+		var privateKey e2types.PrivateKey
+		err := w.useSeed(func(seed []byte) error {
+			var err error
+			privateKey, err = privateKeyFromSeedAndPath(w.backend, seed, path)
+			return err
+		})
+		return privateKey, err
+	}
+	key, err := deriveKey(w.backend, nil, w.node.key, strings.TrimPrefix(path, w.node.path+"/"))
+	if err != nil {
+		return nil, err
+	}
+	return privateKeyFromInt(w.backend, key)
+}
+
+// privateKeyFromSeedAndPath derives the private key at the given path from a seed.
+func privateKeyFromSeedAndPath(backend BLSBackend, seed []byte, path string) (e2types.PrivateKey, error) {
+	key, err := deriveKey(backend, seed, nil, path)
+	if err != nil {
+		return nil, err
+	}
+	return privateKeyFromInt(backend, key)
+}
+
+// privateKeyFromInt creates a private key from its integer representation.
+func privateKeyFromInt(backend BLSBackend, key *big.Int) (e2types.PrivateKey, error) {
+	// The key can be shorter than 32 bytes so left-pad it here.
+	keyBytes := make([]byte, 32)
+	bytes := key.Bytes()
+	copy(keyBytes[32-len(bytes):], bytes)
+	return backend.PrivateKeyFromBytes(keyBytes)
+}
+
+// deriveKey derives the key at the given path, which is either a full path starting "m" derived from the seed or a
+// path relative to the given parent key.
+func deriveKey(backend BLSBackend, seed []byte, parent *big.Int, path string) (*big.Int, error) {
+	key := parent
+	for i, component := range strings.Split(path, "/") {
+		if component == "m" && i == 0 {
+			var err error
+			if key, err = backend.DeriveMasterSK(seed); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if key == nil {
+			return nil, fmt.Errorf("not master at path component %d", i)
+		}
+		index, err := strconv.ParseUint(component, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q at path component %d", component, i)
+		}
+		if key, err = backend.DeriveChildSK(key, uint32(index)); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
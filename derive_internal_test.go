@@ -0,0 +1,74 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	util "github.com/wealdtech/go-eth2-util"
+)
+
+func TestDerivePrivateKey(t *testing.T) {
+	seed, err := hex.DecodeString("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		pathTemplate string
+		walletIndex  uint64
+		nodePath     string
+		paths        []string
+	}{
+		{
+			name:         "Legacy",
+			pathTemplate: legacyPathTemplate,
+			nodePath:     "m/12381/3600",
+			paths:        []string{"m/12381/3600/0/0", "m/12381/3600/5", "m/12381/60/0/0", "m/12381/36000/0/0"},
+		},
+		{
+			name:         "Indexed",
+			pathTemplate: indexedPathTemplate,
+			walletIndex:  3,
+			nodePath:     "m/12381/3600/3",
+			paths:        []string{"m/12381/3600/3/0/0", "m/12381/3600/3/1/0/7", "m/12381/3600/4/0/0", "m/12381/3600/3"},
+		},
+		{
+			name:         "Master",
+			pathTemplate: "m/%a",
+			nodePath:     "m",
+			paths:        []string{"m/0", "m/12381/3600/0/0"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newWallet()
+			w.pathTemplate = test.pathTemplate
+			w.walletIndex = test.walletIndex
+			w.setSeed(seed)
+			require.NotNil(t, w.node)
+			assert.Equal(t, test.nodePath, w.node.path)
+			for _, path := range test.paths {
+				expected, err := util.PrivateKeyFromSeedAndPath(seed, path)
+				require.NoError(t, err)
+				privateKey, err := w.derivePrivateKey(path)
+				require.NoError(t, err)
+				assert.Equal(t, expected.Marshal(), privateKey.Marshal(), path)
+			}
+		})
+	}
+}
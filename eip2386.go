@@ -0,0 +1,83 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// eip2386Version is the wallet version defined by EIP-2386.
+const eip2386Version = 1
+
+// MarshalEIP2386 marshals the wallet in the format defined by EIP-2386, for use by other EIP-2386 tooling.
+// Wallets that cannot be represented in that format, such as those with custom path templates or seeds protected
+// by a data key as earlier versions of this module did for seeds that were not 32 bytes long, return an error.
+func (w *wallet) MarshalEIP2386() ([]byte, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if w.watchOnly {
+		return nil, errors.New("watch-only wallets cannot be represented in EIP-2386 format")
+	}
+	if w.pathTemplate != legacyPathTemplate || w.walletIndex != 0 {
+		return nil, errors.New("wallet path template cannot be represented in EIP-2386 format")
+	}
+	if _, exists := w.crypto["data"]; exists {
+		return nil, errors.New("wallet seed cannot be represented in EIP-2386 format")
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"crypto":      w.crypto,
+		"name":        w.name,
+		"nextaccount": w.nextAccount,
+		"type":        walletType,
+		"uuid":        w.id.String(),
+		"version":     eip2386Version,
+	})
+}
+
+// ImportEIP2386 imports a wallet in the format defined by EIP-2386 in to the store.
+// The wallet retains the EIP-2386 format until it is migrated with MigrateWallet().
+func ImportEIP2386(data []byte, store wtypes.Store, encryptor wtypes.Encryptor) (wtypes.Wallet, error) {
+	w := newWallet()
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, errors.Wrap(err, "wallet corrupt")
+	}
+	if w.version != eip2386Version {
+		return nil, fmt.Errorf("wallet version %d is not EIP-2386", w.version)
+	}
+	if w.pathTemplate != legacyPathTemplate || w.watchOnly {
+		return nil, errors.New("wallet is not EIP-2386")
+	}
+	w.store = store
+	w.encryptor = encryptor
+
+	// See if the wallet already exists.
+	if _, err := OpenWallet(w.name, store, encryptor); err == nil {
+		return nil, fmt.Errorf("wallet %q already exists", w.name)
+	}
+	if _, err := store.RetrieveWalletByID(w.id); err == nil {
+		return nil, fmt.Errorf("wallet with ID %s already exists", w.id)
+	}
+
+	if err := w.storeWallet(); err != nil {
+		return nil, errors.Wrapf(err, "failed to store wallet %q", w.name)
+	}
+
+	return w, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestMarshalEIP2386(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	tests := []struct {
+		name string
+		opts []hd.Option
+		err  string
+	}{
+		{
+			name: "Good",
+			opts: []hd.Option{hd.WithSeed(seed)},
+		},
+		{
+			name: "WalletIndex",
+			opts: []hd.Option{hd.WithSeed(seed), hd.WithWalletIndex(1)},
+			err:  "wallet path template cannot be represented in EIP-2386 format",
+		},
+		{
+			name: "PathTemplate",
+			opts: []hd.Option{hd.WithSeed(seed), hd.WithPathTemplate("m/12381/3600/%a/0/0")},
+			err:  "wallet path template cannot be represented in EIP-2386 format",
+		},
+		{
+			name: "LongSeed",
+			opts: []hd.Option{hd.WithSeed(append(seed, seed...))},
+		},
+	}
+
+	encryptor := keystorev4.New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wallet, err := hd.CreateWallet(test.name, scratch.New(), encryptor, test.opts...)
+			require.NoError(t, err)
+			data, err := wallet.(hd.WalletEIP2386Marshaler).MarshalEIP2386()
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			var fields map[string]interface{}
+			require.NoError(t, json.Unmarshal(data, &fields))
+			assert.Len(t, fields, 6)
+			for _, field := range []string{"crypto", "name", "nextaccount", "type", "uuid", "version"} {
+				assert.Contains(t, fields, field)
+			}
+			assert.Equal(t, float64(1), fields["version"])
+		})
+	}
+}
+
+func TestImportEIP2386(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	encryptor := keystorev4.New()
+	originalStore := scratch.New()
+	wallet, err := hd.CreateWallet("test wallet", originalStore, encryptor, hd.WithSeed(seed), hd.WithPassphrase([]byte("secret")))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	account, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	data, err := wallet.(hd.WalletEIP2386Marshaler).MarshalEIP2386()
+	require.NoError(t, err)
+
+	store := scratch.New()
+	imported, err := hd.ImportEIP2386(data, store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID(), imported.ID())
+	assert.Equal(t, uint(1), imported.Version())
+
+	_, err = hd.ImportEIP2386(data, store, encryptor)
+	assert.EqualError(t, err, `wallet "test wallet" already exists`)
+
+	// Ensure the imported wallet continues derivation where the original left off.
+	imported, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, imported.Unlock([]byte("secret")))
+	importedAccount, err := imported.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/1/0", importedAccount.Path())
+	account2, err := wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, account.PublicKey().Marshal(), importedAccount.PublicKey().Marshal())
+	assert.Equal(t, account2.PublicKey().Marshal(), importedAccount.PublicKey().Marshal())
+
+	// Wallets in the native format are not EIP-2386.
+	nativeData, err := originalStore.RetrieveWallet("test wallet")
+	require.NoError(t, err)
+	_, err = hd.ImportEIP2386(nativeData, scratch.New(), encryptor)
+	assert.EqualError(t, err, "wallet version 2 is not EIP-2386")
+}
+
+func TestImportEIP2386LongSeed(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f200102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor, hd.WithSeed(seed), hd.WithPassphrase([]byte("secret")))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("secret")))
+	account, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	data, err := wallet.(hd.WalletEIP2386Marshaler).MarshalEIP2386()
+	require.NoError(t, err)
+
+	// The seed is encrypted directly, so the keystore's cipher message is the length of the seed.
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &fields))
+	crypto := fields["crypto"].(map[string]interface{})
+	assert.NotContains(t, crypto, "data")
+	assert.Len(t, crypto["cipher"].(map[string]interface{})["message"], 2*len(seed))
+
+	store := scratch.New()
+	_, err = hd.ImportEIP2386(data, store, encryptor)
+	require.NoError(t, err)
+	imported, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, imported.Unlock([]byte("secret")))
+	importedAccount, err := imported.(hd.WalletAccountByPathProvider).AccountByPath(account.Path())
+	require.NoError(t, err)
+	assert.Equal(t, account.PublicKey().Marshal(), importedAccount.PublicKey().Marshal())
+}
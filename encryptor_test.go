@@ -0,0 +1,79 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// legacyEncryptor stands in for an older keystore version.
+type legacyEncryptor struct {
+	wtypes.Encryptor
+}
+
+func (e *legacyEncryptor) Version() uint {
+	return 3
+}
+
+func TestOpenWalletWithEncryptors(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	legacy := &legacyEncryptor{Encryptor: keystorev4.New()}
+	passphrase := []byte("passphrase")
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase(passphrase))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+	_, err = wallet.CreateAccount("Account 1", passphrase)
+	require.NoError(t, err)
+
+	// Create an account with the legacy encryptor to give a wallet with mixed keystore versions.
+	wallet, err = hd.OpenWallet("test wallet", store, legacy)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+	_, err = wallet.CreateAccount("Account 2", passphrase)
+	require.NoError(t, err)
+
+	// Without the legacy encryptor the legacy account cannot be read.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	_, err = wallet.AccountByName("Account 2")
+	assert.EqualError(t, err, "unsupported keystore version")
+
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithEncryptors(legacy))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+	for _, name := range []string{"Account 1", "Account 2"} {
+		account, err := wallet.AccountByName(name)
+		require.NoError(t, err)
+		require.NoError(t, account.Unlock(passphrase))
+	}
+
+	// The wallet's declared encryptor is selected from those supplied, so new accounts use it.
+	wallet, err = hd.OpenWallet("test wallet", store, legacy, hd.WithEncryptors(encryptor))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+	_, err = wallet.CreateAccount("Account 3", passphrase)
+	require.NoError(t, err)
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	_, err = wallet.AccountByName("Account 3")
+	require.NoError(t, err)
+}
@@ -0,0 +1,112 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// KeyWrapper is the interface for key management services that wrap and unwrap data keys, for example AWS KMS, GCP
+// KMS or HashiCorp Vault.  A key wrapper can be supplied with WithKeyWrapper, in which case the wallet's seed is
+// encrypted with a data key that is wrapped by the service rather than with a passphrase.
+type KeyWrapper interface {
+	// Name provides the name of the key wrapper, for example the identifier of its key.  It is stored with the
+	// wrapped data key, and must be the same when the key is unwrapped.
+	Name() string
+
+	// WrapKey wraps a data key.
+	WrapKey(key []byte) ([]byte, error)
+
+	// UnwrapKey unwraps a data key wrapped by WrapKey.
+	UnwrapKey(wrappedKey []byte) ([]byte, error)
+}
+
+// wrapSecret encrypts a secret with AES-256-GCM under a random data key, and wraps the data key with the key wrapper.
+func wrapSecret(wrapper KeyWrapper, secret []byte) (map[string]interface{}, error) {
+	dataKey := make([]byte, keyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, errors.Wrap(err, "failed to generate data key")
+	}
+	defer zeroBytes(dataKey)
+	wrappedKey, err := wrapper.WrapKey(dataKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wrap data key")
+	}
+	data, err := encryptData(dataKey, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"wrapper": wrapper.Name(),
+		"key":     hex.EncodeToString(wrappedKey),
+		"data":    data,
+	}, nil
+}
+
+// unwrapSecret decrypts a secret encrypted with wrapSecret.
+func unwrapSecret(wrapper KeyWrapper, crypto map[string]interface{}) ([]byte, error) {
+	name, ok := crypto["wrapper"].(string)
+	if !ok {
+		return nil, errors.New("crypto wrapper invalid")
+	}
+	if name != wrapper.Name() {
+		return nil, fmt.Errorf("crypto wrapped by %q rather than %q", name, wrapper.Name())
+	}
+	keyStr, ok := crypto["key"].(string)
+	if !ok {
+		return nil, errors.New("crypto key invalid")
+	}
+	wrappedKey, err := hex.DecodeString(keyStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "crypto key invalid")
+	}
+	data, ok := crypto["data"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("crypto data invalid")
+	}
+	nonce, message, err := parseData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := wrapper.UnwrapKey(wrappedKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unwrap data key")
+	}
+	defer zeroBytes(dataKey)
+	return openData(dataKey, nonce, message)
+}
+
+// isWrapped returns true if the crypto was created by wrapSecret.
+func isWrapped(crypto map[string]interface{}) bool {
+	_, exists := crypto["wrapper"]
+	return exists
+}
+
+// openSecret decrypts a wallet secret, using the wallet's key wrapper if the secret is wrapped and the passphrase
+// otherwise.
+func (w *wallet) openSecret(crypto map[string]interface{}, passphrase []byte) ([]byte, error) {
+	if isWrapped(crypto) {
+		if w.keyWrapper == nil {
+			return nil, errors.New("wallet requires a key wrapper")
+		}
+		return unwrapSecret(w.keyWrapper, crypto)
+	}
+	return decryptSecret(w.encryptor, crypto, passphrase)
+}
@@ -0,0 +1,97 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// xorKeyWrapper is a key wrapper that stands in for a key management service.
+type xorKeyWrapper struct {
+	name    string
+	mask    byte
+	offline bool
+}
+
+func (w *xorKeyWrapper) Name() string {
+	return w.name
+}
+
+func (w *xorKeyWrapper) WrapKey(key []byte) ([]byte, error) {
+	return w.xor(key)
+}
+
+func (w *xorKeyWrapper) UnwrapKey(wrappedKey []byte) ([]byte, error) {
+	return w.xor(wrappedKey)
+}
+
+func (w *xorKeyWrapper) xor(data []byte) ([]byte, error) {
+	if w.offline {
+		return nil, errors.New("service unavailable")
+	}
+	res := make([]byte, len(data))
+	for i := range data {
+		res[i] = data[i] ^ w.mask
+	}
+	return res, nil
+}
+
+func TestKeyWrapper(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wrapper := &xorKeyWrapper{name: "test key", mask: 0x5a}
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"
+
+	_, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithKeyWrapper(wrapper), hd.WithPassphrase([]byte("passphrase")))
+	assert.EqualError(t, err, "cannot supply both passphrase and key wrapper")
+
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithKeyWrapper(wrapper), hd.WithMnemonic(mnemonic), hd.WithStoreMnemonic(true))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	key, err := wallet.(wtypes.WalletKeyProvider).Key()
+	require.NoError(t, err)
+
+	// Without the wrapper the wallet cannot be unlocked.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.EqualError(t, wallet.Unlock(nil), "failed to unwrap seed: wallet requires a key wrapper")
+
+	// A different wrapper cannot unlock the wallet.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithKeyWrapper(&xorKeyWrapper{name: "other key", mask: 0x5a}))
+	require.NoError(t, err)
+	assert.EqualError(t, wallet.Unlock(nil), `failed to unwrap seed: crypto wrapped by "test key" rather than "other key"`)
+
+	// An unavailable wrapper cannot unlock the wallet.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithKeyWrapper(&xorKeyWrapper{name: "test key", offline: true}))
+	require.NoError(t, err)
+	assert.EqualError(t, wallet.Unlock(nil), "failed to unwrap seed: failed to unwrap data key: service unavailable")
+
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithKeyWrapper(wrapper))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	unwrappedKey, err := wallet.(wtypes.WalletKeyProvider).Key()
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrappedKey)
+	storedMnemonic, err := wallet.(hd.WalletMnemonicProvider).Mnemonic(nil)
+	require.NoError(t, err)
+	assert.Equal(t, mnemonic, storedMnemonic)
+}
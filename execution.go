@@ -0,0 +1,116 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// executionCoinType is the coin type component of the paths of execution-layer keys.
+	executionCoinType = 60
+	// executionWithdrawalPrefix is the prefix for execution-layer withdrawal credentials.
+	executionWithdrawalPrefix = 0x01
+)
+
+// ExecutionKey is an execution-layer secp256k1 key derived from the wallet's seed.
+type ExecutionKey struct {
+	// Path is the derivation path of the key.
+	Path string
+	// PrivateKey is the 32-byte secp256k1 private key.
+	PrivateKey []byte
+	// Address is the 20-byte execution-layer address of the key.
+	Address []byte
+	// WithdrawalCredentials are the execution-layer (0x01) withdrawal credentials for the address.
+	WithdrawalCredentials []byte
+}
+
+// ExecutionKey provides the execution-layer key associated with an account in the wallet.
+// The key is derived as per EIP-2333 at the account's path with its coin type replaced by 60, so for the default
+// path template the key for account n is at m/12381/60/walletIndex/n/0, and is used as a secp256k1 private key.  This
+// allows 0x01 withdrawal credentials for a validator to be generated from the same seed as its keys.
+// The wallet must be unlocked, and the account's key must have been derived from the wallet's seed.
+func (w *wallet) ExecutionKey(account wtypes.Account) (*ExecutionKey, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if imported, ok := account.(AccountImportedProvider); ok && imported.Imported() {
+		return nil, errors.New("imported accounts do not have execution keys")
+	}
+	path, err := executionPath(account.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to provide execution keys")
+	}
+	var key *big.Int
+	err = w.useSeed(func(seed []byte) error {
+		var err error
+		key, err = deriveKey(w.backend, seed, nil, path)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create execution key for path %q", path)
+	}
+	privateKey := make([]byte, 32)
+	keyBytes := key.Bytes()
+	copy(privateKey[32-len(keyBytes):], keyBytes)
+	address := executionAddress(privateKey)
+
+	return &ExecutionKey{
+		Path:                  path,
+		PrivateKey:            privateKey,
+		Address:               address,
+		WithdrawalCredentials: executionWithdrawalCredentials(address),
+	}, nil
+}
+
+// executionPath provides the path of the execution-layer key for an account path.
+func executionPath(path string) (string, error) {
+	if _, err := ParsePath(path); err != nil {
+		return "", errors.Wrap(err, "account path invalid")
+	}
+	components := strings.Split(path, "/")
+	components[2] = strconv.Itoa(executionCoinType)
+	return strings.Join(components, "/"), nil
+}
+
+// executionAddress provides the execution-layer address for a secp256k1 private key, which is the last 20 bytes of
+// the Keccak-256 hash of the uncompressed public key without its prefix.
+func executionAddress(privateKey []byte) []byte {
+	publicKey := secp256k1.PrivKeyFromBytes(privateKey).PubKey().SerializeUncompressed()
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write(publicKey[1:])
+	return hash.Sum(nil)[12:]
+}
+
+// executionWithdrawalCredentials provides the execution-layer withdrawal credentials for an address.
+func executionWithdrawalCredentials(address []byte) []byte {
+	credentials := make([]byte, 32)
+	credentials[0] = executionWithdrawalPrefix
+	copy(credentials[12:], address)
+	return credentials
+}
@@ -0,0 +1,49 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionAddress(t *testing.T) {
+	tests := []struct {
+		name       string
+		privateKey string
+		address    string
+	}{
+		{
+			name:       "One",
+			privateKey: "0000000000000000000000000000000000000000000000000000000000000001",
+			address:    "7e5f4552091a69125d5dfcb7b8c2659029395bdf",
+		},
+		{
+			name:       "Two",
+			privateKey: "0000000000000000000000000000000000000000000000000000000000000002",
+			address:    "2b5ad5c4795c026514f8317c7a215e218dccd6cf",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			privateKey, err := hex.DecodeString(test.privateKey)
+			require.NoError(t, err)
+			assert.Equal(t, test.address, hex.EncodeToString(executionAddress(privateKey)))
+		})
+	}
+}
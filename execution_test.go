@@ -0,0 +1,75 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	util "github.com/wealdtech/go-eth2-util"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestExecutionKey(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New(), hd.WithSeed(seed), hd.WithWalletIndex(2))
+	require.NoError(t, err)
+	provider, isProvider := wallet.(hd.WalletExecutionKeyProvider)
+	require.True(t, isProvider)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	wallet.Lock()
+
+	_, err = provider.ExecutionKey(account)
+	assert.EqualError(t, err, "wallet must be unlocked to provide execution keys")
+	require.NoError(t, wallet.Unlock(nil))
+
+	key, err := provider.ExecutionKey(account)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/60/2/0/0", key.Path)
+	expected, err := util.PrivateKeyFromSeedAndPath(seed, "m/12381/60/2/0/0")
+	require.NoError(t, err)
+	assert.Equal(t, expected.Marshal(), key.PrivateKey)
+	require.Len(t, key.Address, 20)
+	require.Len(t, key.WithdrawalCredentials, 32)
+	assert.Equal(t, byte(0x01), key.WithdrawalCredentials[0])
+	assert.Equal(t, make([]byte, 11), key.WithdrawalCredentials[1:12])
+	assert.Equal(t, key.Address, key.WithdrawalCredentials[12:])
+
+	// Keys are deterministic.
+	key2, err := provider.ExecutionKey(account)
+	require.NoError(t, err)
+	assert.Equal(t, key, key2)
+
+	programmatic, err := wallet.AccountByName("m/12381/3600/2/1/0")
+	require.NoError(t, err)
+	key3, err := provider.ExecutionKey(programmatic)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/60/2/1/0", key3.Path)
+	assert.NotEqual(t, key.Address, key3.Address)
+}
+
+func TestExecutionKeyCustomPath(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New(), hd.WithPathTemplate("m/12381/3601/%a"))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	_, err = wallet.(hd.WalletExecutionKeyProvider).ExecutionKey(account)
+	assert.EqualError(t, err, "account path invalid: path coin type 3601 invalid; must be 3600")
+}
@@ -27,7 +27,7 @@ import (
 func TestExportWallet(t *testing.T) {
 	store := scratch.New()
 	encryptor := keystorev4.New()
-	wallet, err := hd.CreateWallet("test wallet", []byte{}, store, encryptor)
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
 	require.Nil(t, err)
 	err = wallet.Unlock([]byte{})
 	require.Nil(t, err)
@@ -63,17 +63,61 @@ func TestExportWallet(t *testing.T) {
 	require.NotNil(t, err)
 }
 
+func TestImportRenamed(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	dump, err := wallet.(wtypes.WalletExporter).Export([]byte("dump"))
+	require.NoError(t, err)
+
+	// Restore alongside the original wallet.
+	restored, err := hd.Import(dump, []byte("dump"), store, encryptor, hd.WithConflictRename(" (restored)"))
+	require.NoError(t, err)
+	assert.Equal(t, "test wallet (restored)", restored.Name())
+	assert.NotEqual(t, wallet.ID(), restored.ID())
+	restoredAccount, err := restored.AccountByName("Account 1")
+	require.NoError(t, err)
+	assert.Equal(t, account.PublicKey().Marshal(), restoredAccount.PublicKey().Marshal())
+
+	// The original wallet is untouched.
+	original, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID(), original.ID())
+	_, err = original.AccountByName("Account 1")
+	require.NoError(t, err)
+
+	_, err = hd.Import(dump, []byte("dump"), store, encryptor, hd.WithConflictRename(" (restored)"))
+	assert.EqualError(t, err, `wallet "test wallet (restored)" already exists`)
+
+	renamed, err := hd.Import(dump, []byte("dump"), store, encryptor, hd.WithNewWalletName("verification wallet"))
+	require.NoError(t, err)
+	assert.Equal(t, "verification wallet", renamed.Name())
+	assert.NotEqual(t, wallet.ID(), renamed.ID())
+
+	// The ID is retained if it is not in use.
+	renamed, err = hd.Import(dump, []byte("dump"), scratch.New(), encryptor, hd.WithNewWalletName("verification wallet"))
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID(), renamed.ID())
+
+	_, err = hd.Import(dump, []byte("dump"), store, encryptor, hd.WithNewWalletName("_hidden"))
+	assert.EqualError(t, err, `invalid wallet name "_hidden"`)
+}
+
 func TestWalletFromSeed(t *testing.T) {
 	store := scratch.New()
 	encryptor := keystorev4.New()
-	wallet, err := hd.CreateWallet("test wallet", []byte{}, store, encryptor)
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
 	require.Nil(t, err)
 	err = wallet.Unlock([]byte{})
 	require.Nil(t, err)
 	seed, err := wallet.(wtypes.WalletKeyProvider).Key()
 	require.Nil(t, err)
 
-	importedWallet, err := hd.CreateWalletFromSeed("imported wallet", []byte{}, store, encryptor, seed)
+	importedWallet, err := hd.CreateWallet("imported wallet", store, encryptor, hd.WithSeed(seed))
 	require.Nil(t, err)
 	err = importedWallet.Unlock([]byte{})
 	require.Nil(t, err)
@@ -82,3 +126,37 @@ func TestWalletFromSeed(t *testing.T) {
 
 	assert.Equal(t, seed, importedSeed)
 }
+
+func TestImportExistingWallet(t *testing.T) {
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	dump, err := wallet.(wtypes.WalletExporter).Export([]byte("dump"))
+	require.NoError(t, err)
+
+	// A wallet that cannot be opened without its path provider still exists.
+	store := scratch.New()
+	provider := &shardPathProvider{shard: 7}
+	existing, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPathProvider(provider))
+	require.NoError(t, err)
+	_, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.Error(t, err)
+	_, err = hd.Import(dump, []byte("dump"), store, encryptor)
+	assert.EqualError(t, err, `wallet "test wallet" already exists`)
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor, hd.WithPathProvider(provider))
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID(), reopened.ID())
+
+	// A wallet with the same ID under a different name exists.
+	store = scratch.New()
+	_, err = hd.Import(dump, []byte("dump"), store, encryptor)
+	require.NoError(t, err)
+	imported, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, imported.(hd.WalletRenamer).Rename("renamed wallet"))
+	_, err = hd.Import(dump, []byte("dump"), store, encryptor)
+	assert.EqualError(t, err, "wallet with ID "+wallet.ID().String()+" already exists")
+}
@@ -0,0 +1,111 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// Export shares hold the encrypted export split with Shamir's secret sharing.  Each share is a magic value, a version
+// byte, the threshold, an identifier for the export and the share itself.  The identifier is the start of the SHA-256
+// hash of the export, so shares of different exports cannot be combined and a combined export is confirmed as intact.
+const (
+	exportShareVersion  = 1
+	exportShareIDLength = 8
+)
+
+// exportShareMagic is the magic value at the start of an export share.
+var exportShareMagic = []byte("e2wk")
+
+// exportShareHeaderLength is the length of the header of an export share.
+var exportShareHeaderLength = len(exportShareMagic) + 2 + exportShareIDLength
+
+// ExportShares exports the entire wallet, protected by an additional passphrase as per Export, and splits the export
+// in to the given number of shares such that any threshold of them can re-create it, for distribution to independent
+// custodians.  The wallet is imported from the shares with ImportShares.
+func (w *wallet) ExportShares(passphrase []byte, threshold int, shares int) ([][]byte, error) {
+	if threshold > 255 {
+		return nil, errors.New("threshold must be at most 255")
+	}
+	data, err := w.Export(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	split, err := splitSecret(data, threshold, shares)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+	res := make([][]byte, len(split))
+	for i := range split {
+		res[i] = make([]byte, 0, exportShareHeaderLength+len(split[i]))
+		res[i] = append(res[i], exportShareMagic...)
+		res[i] = append(res[i], exportShareVersion, byte(threshold))
+		res[i] = append(res[i], hash[:exportShareIDLength]...)
+		res[i] = append(res[i], split[i]...)
+	}
+	return res, nil
+}
+
+// ImportShares imports a wallet from at least the threshold of the shares provided by ExportShares, as per Import.
+func ImportShares(shares [][]byte, passphrase []byte, store wtypes.Store, encryptor wtypes.Encryptor, opts ...Option) (wtypes.Wallet, error) {
+	data, err := combineExportShares(shares)
+	if err != nil {
+		return nil, err
+	}
+	return Import(data, passphrase, store, encryptor, opts...)
+}
+
+// combineExportShares re-creates an export from its shares.
+func combineExportShares(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares supplied")
+	}
+	var header []byte
+	split := make([][]byte, len(shares))
+	for i, share := range shares {
+		if len(share) <= exportShareHeaderLength || !bytes.HasPrefix(share, exportShareMagic) {
+			return nil, fmt.Errorf("share %d is not an export share", i)
+		}
+		if share[len(exportShareMagic)] != exportShareVersion {
+			return nil, fmt.Errorf("share %d has unsupported version %d", i, share[len(exportShareMagic)])
+		}
+		if header == nil {
+			header = share[:exportShareHeaderLength]
+		} else if !bytes.Equal(share[:exportShareHeaderLength], header) {
+			return nil, errors.New("shares are from different exports")
+		}
+		split[i] = share[exportShareHeaderLength:]
+	}
+	threshold := int(header[len(exportShareMagic)+1])
+	if len(shares) < threshold {
+		return nil, fmt.Errorf("%d shares required but %d supplied", threshold, len(shares))
+	}
+
+	data, err := combineShares(split)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	if !bytes.Equal(hash[:exportShareIDLength], header[len(exportShareMagic)+2:]) {
+		return nil, errors.New("shares do not re-create the export")
+	}
+	return data, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestExportShares(t *testing.T) {
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	exporter := wallet.(hd.WalletShareExporter)
+
+	_, err = exporter.ExportShares([]byte("export"), 1, 3)
+	assert.EqualError(t, err, "threshold must be at least 2")
+	_, err = exporter.ExportShares([]byte("export"), 4, 3)
+	assert.EqualError(t, err, "number of shares must be at least the threshold")
+
+	shares, err := exporter.ExportShares([]byte("export"), 2, 3)
+	require.NoError(t, err)
+	require.Len(t, shares, 3)
+	otherShares, err := exporter.ExportShares([]byte("export"), 2, 3)
+	require.NoError(t, err)
+
+	tampered := append([]byte{}, shares[1]...)
+	tampered[len(tampered)-1] ^= 0x01
+
+	tests := []struct {
+		name   string
+		shares [][]byte
+		err    string
+	}{
+		{
+			name:   "First",
+			shares: [][]byte{shares[0], shares[1]},
+		},
+		{
+			name:   "Second",
+			shares: [][]byte{shares[2], shares[0]},
+		},
+		{
+			name:   "All",
+			shares: shares,
+		},
+		{
+			name: "None",
+			err:  "no shares supplied",
+		},
+		{
+			name:   "Insufficient",
+			shares: [][]byte{shares[0]},
+			err:    "2 shares required but 1 supplied",
+		},
+		{
+			name:   "Duplicate",
+			shares: [][]byte{shares[0], shares[0]},
+			err:    "share coordinates invalid",
+		},
+		{
+			name:   "DifferentExports",
+			shares: [][]byte{shares[0], otherShares[1]},
+			err:    "shares are from different exports",
+		},
+		{
+			name:   "Tampered",
+			shares: [][]byte{shares[0], tampered},
+			err:    "shares do not re-create the export",
+		},
+		{
+			name:   "NotShare",
+			shares: [][]byte{shares[0], []byte("bad")},
+			err:    "share 1 is not an export share",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			imported, err := hd.ImportShares(test.shares, []byte("export"), scratch.New(), encryptor)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, wallet.ID(), imported.ID())
+			_, err = imported.AccountByName("Account 1")
+			require.NoError(t, err)
+		})
+	}
+}
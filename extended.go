@@ -0,0 +1,116 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// CreateExtendedAccount creates an account in the wallet using the next account number, with additional components
+// appended to the account's path.  For example with the default path template and an extension of [7] the path is
+// m/12381/3600/walletIndex/n/0/7.  This allows operators to encode additional information such as shard or operator
+// IDs in the path.  The extension is held in the account's path, so is stored with the account.
+func (w *wallet) CreateExtendedAccount(name string, extension []uint64, passphrase []byte) (wtypes.Account, error) {
+	a, err := w.createExtendedAccount(name, extension, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	w.hooks.accountCreated(a)
+
+	return a, nil
+}
+
+// createExtendedAccount creates an extended account in the wallet without calling hooks.
+func (w *wallet) createExtendedAccount(name string, extension []uint64, passphrase []byte) (wtypes.Account, error) {
+	if name == "" {
+		return nil, errors.New("account name missing")
+	}
+	if strings.HasPrefix(name, "_") {
+		return nil, fmt.Errorf("invalid account name %q", name)
+	}
+	if len(extension) == 0 {
+		return nil, errors.New("path extension missing")
+	}
+	for _, component := range extension {
+		if component > math.MaxInt32 {
+			return nil, fmt.Errorf("path extension component %d too large", component)
+		}
+	}
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+	passphrase, err := w.accountPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+
+	// Ensure that we don't already have an account with this name
+	if _, err := w.AccountByName(name); err == nil {
+		return nil, fmt.Errorf("account with name %q already exists", name)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+
+	// Check the path before using the account number, so that an extension cannot reach the wallet's own keys.
+	path := extendPath(w.accountPath(w.nextAccount), extension)
+	if isInternalPath(path) {
+		return nil, fmt.Errorf("path %q is reserved for use by the wallet", path)
+	}
+
+	w.nextAccount++
+	if err := w.storeWallet(); err != nil {
+		return nil, errors.Wrapf(err, "failed to create account %q", name)
+	}
+
+	a, err := w.deriveAccountAtPath(name, path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	w.index.Add(a.id, a.name)
+
+	if err := a.storeAccount(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// extendPath appends the components of an extension to a path.
+func extendPath(path string, extension []uint64) string {
+	var builder strings.Builder
+	builder.WriteString(path)
+	for _, component := range extension {
+		builder.WriteString("/")
+		builder.WriteString(strconv.FormatUint(component, 10))
+	}
+	return builder.String()
+}
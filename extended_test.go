@@ -0,0 +1,114 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestCreateExtendedAccount(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithWalletIndex(2))
+	require.NoError(t, err)
+	creator, isCreator := wallet.(hd.WalletExtendedAccountCreator)
+	require.True(t, isCreator)
+
+	_, err = creator.CreateExtendedAccount("Account", []uint64{7}, nil)
+	assert.EqualError(t, err, "wallet must be unlocked to create accounts")
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = creator.CreateExtendedAccount("", []uint64{7}, nil)
+	assert.EqualError(t, err, "account name missing")
+	_, err = creator.CreateExtendedAccount("Account", nil, nil)
+	assert.EqualError(t, err, "path extension missing")
+	_, err = creator.CreateExtendedAccount("Account", []uint64{math.MaxInt32 + 1}, nil)
+	assert.EqualError(t, err, "path extension component 2147483648 too large")
+
+	_, err = wallet.CreateAccount("Plain", nil)
+	require.NoError(t, err)
+	account, err := creator.CreateExtendedAccount("Account", []uint64{7, 3}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/2/1/0/7/3", account.Path())
+	_, err = creator.CreateExtendedAccount("Account", []uint64{7}, nil)
+	assert.EqualError(t, err, `account with name "Account" already exists`)
+
+	// Ensure the extended path is stored with the account.
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	stored, err := reopened.AccountByName("Account")
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/2/1/0/7/3", stored.Path())
+	assert.Equal(t, uint64(2), reopened.(hd.WalletNextAccountProvider).NextAccount())
+
+	// Ensure the key is that at the extended path.
+	require.NoError(t, reopened.Unlock(nil))
+	programmatic, err := reopened.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/3600/2/1/0/7/3")
+	require.NoError(t, err)
+	assert.Equal(t, programmatic.PublicKey().Marshal(), stored.PublicKey().Marshal())
+
+	derivationPath, err := stored.(hd.AccountDerivationPathProvider).DerivationPath()
+	require.NoError(t, err)
+	signing := uint64(0)
+	assert.Equal(t, &hd.DerivationPath{Purpose: 12381, Coin: 3600, WalletIndex: 2, AccountIndex: 1, Use: &signing, Extension: []uint64{7, 3}}, derivationPath)
+}
+
+func TestCreateExtendedAccountLegacy(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	creator := wallet.(hd.WalletExtendedAccountCreator)
+
+	// Extensions of the legacy template's paths stay outside of the wallet's own keys.
+	account, err := creator.CreateExtendedAccount("Account 0", []uint64{2}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/0/0/2", account.Path())
+	account, err = creator.CreateExtendedAccount("Account 1", []uint64{1}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/1/0/1", account.Path())
+	assert.Equal(t, uint64(2), wallet.(hd.WalletNextAccountProvider).NextAccount())
+
+	data, err := wallet.(hd.WalletManifestExporter).ExportManifest()
+	require.NoError(t, err)
+	manifest, err := hd.VerifyManifest(data)
+	require.NoError(t, err)
+	for _, manifestAccount := range manifest.Accounts {
+		assert.NotEqual(t, manifest.PubKey, manifestAccount.PubKey)
+	}
+}
+
+func TestCreateExtendedAccountReservedPath(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPathTemplate("m/12381/%a"))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	require.NoError(t, wallet.(hd.WalletNextAccountSetter).SetNextAccount(math.MaxInt32))
+	creator := wallet.(hd.WalletExtendedAccountCreator)
+
+	_, err = creator.CreateExtendedAccount("Account", []uint64{1, 0}, nil)
+	assert.EqualError(t, err, `path "m/12381/2147483647/1/0" is reserved for use by the wallet`)
+	_, err = creator.CreateExtendedAccount("Account", []uint64{2, 0}, nil)
+	assert.EqualError(t, err, `path "m/12381/2147483647/2/0" is reserved for use by the wallet`)
+	// The account number is not used by a rejected extension.
+	assert.Equal(t, uint64(math.MaxInt32), wallet.(hd.WalletNextAccountProvider).NextAccount())
+}
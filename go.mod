@@ -3,9 +3,16 @@ module github.com/wealdtech/go-eth2-wallet-hd/v2
 go 1.13
 
 require (
+	filippo.io/age v1.0.0
+	github.com/ProtonMail/go-crypto v1.0.0
+	github.com/awnumar/memguard v0.22.2
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/google/uuid v1.1.1
+	github.com/miekg/pkcs11 v1.1.1
 	github.com/pkg/errors v0.9.1
-	github.com/stretchr/testify v1.4.0
+	github.com/prysmaticlabs/go-ssz v0.0.0-20200101200214-e24db4d9e963
+	github.com/stretchr/testify v1.5.1
+	github.com/tyler-smith/go-bip39 v1.0.2
 	github.com/wealdtech/go-ecodec v1.1.0
 	github.com/wealdtech/go-eth2-types/v2 v2.3.1
 	github.com/wealdtech/go-eth2-util v1.1.5
@@ -13,4 +20,8 @@ require (
 	github.com/wealdtech/go-eth2-wallet-store-scratch v1.3.3
 	github.com/wealdtech/go-eth2-wallet-types/v2 v2.0.2
 	github.com/wealdtech/go-indexer v1.0.0
+	github.com/zalando/go-keyring v0.1.1
+	golang.org/x/crypto v0.7.0
+	golang.org/x/text v0.8.0
+	gopkg.in/yaml.v2 v2.2.2
 )
@@ -0,0 +1,62 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestGuardedMemory(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithSeed(seed), hd.WithGuardedMemory(true))
+	require.NoError(t, err)
+	refWallet, err := hd.CreateWallet("reference wallet", scratch.New(), encryptor, hd.WithSeed(seed))
+	require.NoError(t, err)
+
+	require.NoError(t, wallet.Unlock(nil))
+	key, err := wallet.(wtypes.WalletKeyProvider).Key()
+	require.NoError(t, err)
+	assert.Equal(t, seed, key)
+	account, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	require.NoError(t, refWallet.Unlock(nil))
+	refAccount, err := refWallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, refAccount.PublicKey().Marshal(), account.PublicKey().Marshal())
+
+	wallet.Lock()
+	assert.False(t, wallet.IsUnlocked())
+	_, err = wallet.(wtypes.WalletKeyProvider).Key()
+	assert.EqualError(t, err, "wallet must be unlocked to provide seed")
+
+	// Guarded memory can be used when reopening the wallet.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithGuardedMemory(true))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err = wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	refAccount, err = refWallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, refAccount.PublicKey().Marshal(), account.PublicKey().Marshal())
+	wallet.Lock()
+}
@@ -0,0 +1,144 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"sync"
+
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// AccountHook is a function called with the details of an account when an operation on it completes.
+type AccountHook func(account wtypes.Account)
+
+// WalletHook is a function called with the details of a wallet when an operation on it completes.
+type WalletHook func(wallet wtypes.Wallet)
+
+// hooks contains the hooks registered with a wallet.
+type hooks struct {
+	onAccountCreated  []AccountHook
+	onAccountDeleted  []AccountHook
+	onWalletUnlocked  []WalletHook
+	onWalletLocked    []WalletHook
+	onAccountUnlocked []AccountHook
+	onAccountLocked   []AccountHook
+	mutex             sync.RWMutex
+}
+
+// OnAccountCreated registers a hook that is called after each account is created in the wallet.
+// Hooks are held by this instance of the wallet and are not stored.  They are called synchronously in the order in
+// which they were registered, once the wallet is no longer locked for the operation, so they can use the wallet.
+func (w *wallet) OnAccountCreated(hook AccountHook) {
+	w.hooks.mutex.Lock()
+	defer w.hooks.mutex.Unlock()
+	w.hooks.onAccountCreated = append(w.hooks.onAccountCreated, hook)
+}
+
+// OnAccountDeleted registers a hook that is called after an account is deleted from the wallet.
+// The account has been deleted from the store by the time the hook is called.
+func (w *wallet) OnAccountDeleted(hook AccountHook) {
+	w.hooks.mutex.Lock()
+	defer w.hooks.mutex.Unlock()
+	w.hooks.onAccountDeleted = append(w.hooks.onAccountDeleted, hook)
+}
+
+// OnWalletUnlocked registers a hook that is called after the wallet is unlocked.
+func (w *wallet) OnWalletUnlocked(hook WalletHook) {
+	w.hooks.mutex.Lock()
+	defer w.hooks.mutex.Unlock()
+	w.hooks.onWalletUnlocked = append(w.hooks.onWalletUnlocked, hook)
+}
+
+// OnWalletLocked registers a hook that is called after the wallet is locked, for example so that monitoring can alert
+// if a signer unexpectedly locks.  It is not called if the wallet was already locked.
+func (w *wallet) OnWalletLocked(hook WalletHook) {
+	w.hooks.mutex.Lock()
+	defer w.hooks.mutex.Unlock()
+	w.hooks.onWalletLocked = append(w.hooks.onWalletLocked, hook)
+}
+
+// OnAccountUnlocked registers a hook that is called after an account in the wallet is unlocked.
+func (w *wallet) OnAccountUnlocked(hook AccountHook) {
+	w.hooks.mutex.Lock()
+	defer w.hooks.mutex.Unlock()
+	w.hooks.onAccountUnlocked = append(w.hooks.onAccountUnlocked, hook)
+}
+
+// OnAccountLocked registers a hook that is called after an account in the wallet is locked.
+// It is not called if the account was already locked.
+func (w *wallet) OnAccountLocked(hook AccountHook) {
+	w.hooks.mutex.Lock()
+	defer w.hooks.mutex.Unlock()
+	w.hooks.onAccountLocked = append(w.hooks.onAccountLocked, hook)
+}
+
+// accountCreated calls the hooks for a created account.
+func (h *hooks) accountCreated(account wtypes.Account) {
+	h.mutex.RLock()
+	accountHooks := h.onAccountCreated
+	h.mutex.RUnlock()
+	for _, hook := range accountHooks {
+		hook(account)
+	}
+}
+
+// accountDeleted calls the hooks for a deleted account.
+func (h *hooks) accountDeleted(account wtypes.Account) {
+	h.mutex.RLock()
+	accountHooks := h.onAccountDeleted
+	h.mutex.RUnlock()
+	for _, hook := range accountHooks {
+		hook(account)
+	}
+}
+
+// walletUnlocked calls the hooks for an unlocked wallet.
+func (h *hooks) walletUnlocked(wallet wtypes.Wallet) {
+	h.mutex.RLock()
+	walletHooks := h.onWalletUnlocked
+	h.mutex.RUnlock()
+	for _, hook := range walletHooks {
+		hook(wallet)
+	}
+}
+
+// walletLocked calls the hooks for a locked wallet.
+func (h *hooks) walletLocked(wallet wtypes.Wallet) {
+	h.mutex.RLock()
+	walletHooks := h.onWalletLocked
+	h.mutex.RUnlock()
+	for _, hook := range walletHooks {
+		hook(wallet)
+	}
+}
+
+// accountUnlocked calls the hooks for an unlocked account.
+func (h *hooks) accountUnlocked(account wtypes.Account) {
+	h.mutex.RLock()
+	accountHooks := h.onAccountUnlocked
+	h.mutex.RUnlock()
+	for _, hook := range accountHooks {
+		hook(account)
+	}
+}
+
+// accountLocked calls the hooks for a locked account.
+func (h *hooks) accountLocked(account wtypes.Account) {
+	h.mutex.RLock()
+	accountHooks := h.onAccountLocked
+	h.mutex.RUnlock()
+	for _, hook := range accountHooks {
+		hook(account)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestHooks(t *testing.T) {
+	store := &deletableStore{Store: scratch.New(), deleted: make(map[uuid.UUID]bool)}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	registrar, isRegistrar := wallet.(hd.WalletHookRegistrar)
+	require.True(t, isRegistrar)
+
+	unlocked := 0
+	created := make([]string, 0)
+	deleted := make([]string, 0)
+	registrar.OnWalletUnlocked(func(w wtypes.Wallet) {
+		assert.Equal(t, wallet.ID(), w.ID())
+		unlocked++
+	})
+	registrar.OnAccountCreated(func(account wtypes.Account) {
+		created = append(created, account.Name())
+	})
+	registrar.OnAccountDeleted(func(account wtypes.Account) {
+		deleted = append(deleted, account.Name())
+	})
+
+	// Failed operations do not call hooks.
+	require.Error(t, wallet.Unlock([]byte("wrong passphrase")))
+	assert.Equal(t, 0, unlocked)
+	require.NoError(t, wallet.Unlock(nil))
+	assert.Equal(t, 1, unlocked)
+
+	_, err = wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	_, err = wallet.CreateAccount("Account 0", nil)
+	require.Error(t, err)
+	_, err = wallet.(hd.WalletAccountsCreator).CreateAccounts([]string{"Account 1", "Account 2"}, nil)
+	require.NoError(t, err)
+	_, err = wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Account 5", 5, nil)
+	require.NoError(t, err)
+	_, err = wallet.(hd.WalletValidatorPairCreator).CreateValidatorPair("Validator", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Account 0", "Account 1", "Account 2", "Account 5", "Validator withdrawal", "Validator"}, created)
+
+	require.NoError(t, wallet.(hd.WalletAccountDeleter).DeleteAccount("Account 1"))
+	require.Error(t, wallet.(hd.WalletAccountDeleter).DeleteAccount("Account 1"))
+	assert.Equal(t, []string{"Account 1"}, deleted)
+
+	// Hooks can use the wallet.
+	registrar.OnAccountCreated(func(account wtypes.Account) {
+		_, err := wallet.AccountByName(account.Name())
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(8), wallet.(hd.WalletNextAccountProvider).NextAccount())
+	})
+	_, err = wallet.CreateAccount("Account 7", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Account 7", created[len(created)-1])
+
+	// Hooks are not stored with the wallet.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	assert.Equal(t, 1, unlocked)
+}
+
+func TestLockHooks(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	registrar := wallet.(hd.WalletHookRegistrar)
+
+	events := make([]string, 0)
+	registrar.OnWalletUnlocked(func(w wtypes.Wallet) {
+		events = append(events, "wallet unlocked")
+	})
+	registrar.OnWalletLocked(func(w wtypes.Wallet) {
+		assert.False(t, w.IsUnlocked())
+		events = append(events, "wallet locked")
+	})
+	registrar.OnAccountUnlocked(func(account wtypes.Account) {
+		events = append(events, account.Name()+" unlocked")
+	})
+	registrar.OnAccountLocked(func(account wtypes.Account) {
+		assert.False(t, account.IsUnlocked())
+		events = append(events, account.Name()+" locked")
+	})
+
+	// Locking a locked wallet is not a transition.
+	wallet.Lock()
+	assert.Empty(t, events)
+
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account", []byte("passphrase"))
+	require.NoError(t, err)
+	require.Error(t, account.Unlock([]byte("wrong passphrase")))
+	require.NoError(t, account.Unlock([]byte("passphrase")))
+	account.Lock()
+	account.Lock()
+	wallet.Lock()
+	wallet.Lock()
+	assert.Equal(t, []string{
+		"wallet unlocked",
+		"Account unlocked",
+		"Account locked",
+		"wallet locked",
+	}, events)
+}
@@ -0,0 +1,160 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// ImportReport contains the results of validating an export prior to import.
+type ImportReport struct {
+	// WalletID is the ID of the exported wallet.
+	WalletID uuid.UUID
+	// WalletName is the name of the exported wallet.
+	WalletName string
+	// Accounts are the exported accounts.
+	Accounts []*ImportReportAccount
+	// Conflicts are the reasons for which the export cannot be imported in to the store as-is.
+	Conflicts []*ImportConflict
+	// IntegrityTagged is true if the export carries an integrity tag, which it does only if the wallet was unlocked
+	// when it was exported.  Imports of exports without a tag cannot be verified with WithIntegrityCheck.
+	IntegrityTagged bool
+}
+
+// ImportReportAccount contains the details of an exported account.
+type ImportReportAccount struct {
+	// ID is the ID of the account.
+	ID uuid.UUID
+	// Name is the name of the account.
+	Name string
+	// Path is the derivation path of the account.  It is empty for imported accounts.
+	Path string
+	// PublicKey is the account's public key.
+	PublicKey e2types.PublicKey
+}
+
+// ImportConflict contains the details of a wallet or account in an export that conflicts with the store.
+type ImportConflict struct {
+	// ID is the ID of the wallet or account.
+	ID uuid.UUID
+	// Name is the name of the wallet or account.
+	Name string
+	// Reason is the reason for the conflict.
+	Reason string
+}
+
+// Importable returns true if the export can be imported without conflicts.
+func (r *ImportReport) Importable() bool {
+	return len(r.Conflicts) == 0
+}
+
+// ValidateImport decrypts and parses an export as per Import, reporting its wallet and accounts and any conflicts
+// with the contents of the store, without writing anything to the store.  If store is nil conflicts are not checked.
+// Exports encrypted to age recipients require WithAgeIdentities, and those encrypted to OpenPGP keys WithOpenPGPKeys.
+func ValidateImport(data []byte, passphrase []byte, store wtypes.Store, opts ...Option) (*ImportReport, error) {
+	options := options{}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	w, nextAccount, err := decodeExport(bytes.NewReader(data), passphrase, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		WalletID:   w.ID(),
+		WalletName: w.Name(),
+		Accounts:   make([]*ImportReportAccount, 0),
+		Conflicts:  make([]*ImportConflict, 0),
+	}
+
+	// Obtain the accounts already stored for the wallet, if any.
+	existingNames := make(map[string]bool)
+	existingIDs := make(map[uuid.UUID]bool)
+	if store != nil {
+		if _, err := store.RetrieveWallet(w.Name()); err == nil {
+			report.Conflicts = append(report.Conflicts, &ImportConflict{
+				ID:     w.ID(),
+				Name:   w.Name(),
+				Reason: "wallet with the same name already exists",
+			})
+		}
+		if _, err := store.RetrieveWalletByID(w.ID()); err == nil {
+			report.Conflicts = append(report.Conflicts, &ImportConflict{
+				ID:     w.ID(),
+				Name:   w.Name(),
+				Reason: "wallet with the same ID already exists",
+			})
+			for data := range store.RetrieveAccounts(w.ID()) {
+				stored := &struct {
+					ID   uuid.UUID `json:"uuid"`
+					Name string    `json:"name"`
+				}{}
+				if err := json.Unmarshal(data, stored); err != nil {
+					continue
+				}
+				existingNames[stored.Name] = true
+				existingIDs[stored.ID] = true
+			}
+		}
+	}
+
+	names := make(map[string]bool)
+	ids := make(map[uuid.UUID]bool)
+	for {
+		acc, err := nextAccount()
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			break
+		}
+		report.Accounts = append(report.Accounts, &ImportReportAccount{
+			ID:        acc.id,
+			Name:      acc.name,
+			Path:      acc.path,
+			PublicKey: acc.publicKey,
+		})
+
+		conflict := ""
+		switch {
+		case names[acc.name]:
+			conflict = "account name duplicated in export"
+		case ids[acc.id]:
+			conflict = "account ID duplicated in export"
+		case existingNames[acc.name]:
+			conflict = "account with the same name already exists"
+		case existingIDs[acc.id]:
+			conflict = "account with the same ID already exists"
+		}
+		if conflict != "" {
+			report.Conflicts = append(report.Conflicts, &ImportConflict{
+				ID:     acc.id,
+				Name:   acc.name,
+				Reason: conflict,
+			})
+		}
+		names[acc.name] = true
+		ids[acc.id] = true
+	}
+	// The integrity record follows the accounts, so is known once they have all been decoded.
+	report.IntegrityTagged = w.exportIntegrity != nil
+
+	return report, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestValidateImport(t *testing.T) {
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account1, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	account2, err := wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	data, err := wallet.(wtypes.WalletExporter).Export([]byte("export"))
+	require.NoError(t, err)
+
+	_, err = hd.ValidateImport(data, []byte("wrong"), nil)
+	assert.EqualError(t, err, "failed to import wallet: invalid key")
+
+	store := scratch.New()
+	report, err := hd.ValidateImport(data, []byte("export"), store)
+	require.NoError(t, err)
+	assert.True(t, report.Importable())
+	assert.Equal(t, wallet.ID(), report.WalletID)
+	assert.Equal(t, "test wallet", report.WalletName)
+	require.Len(t, report.Accounts, 2)
+	// Accounts are reported in the order in which they are exported, which depends on the store.
+	accounts := make(map[string]*hd.ImportReportAccount)
+	for _, account := range report.Accounts {
+		accounts[account.Name] = account
+	}
+	require.Contains(t, accounts, "Account 1")
+	assert.Equal(t, account1.ID(), accounts["Account 1"].ID)
+	assert.Equal(t, account1.Path(), accounts["Account 1"].Path)
+	assert.Equal(t, account1.PublicKey().Marshal(), accounts["Account 1"].PublicKey.Marshal())
+	require.Contains(t, accounts, "Account 2")
+	assert.Equal(t, account2.PublicKey().Marshal(), accounts["Account 2"].PublicKey.Marshal())
+
+	// Nothing is written to the store.
+	_, err = store.RetrieveWallet("test wallet")
+	assert.Error(t, err)
+
+	// Once imported, the wallet and its accounts conflict.
+	_, err = hd.Import(data, []byte("export"), store, encryptor)
+	require.NoError(t, err)
+	report, err = hd.ValidateImport(data, []byte("export"), store)
+	require.NoError(t, err)
+	assert.False(t, report.Importable())
+	reasons := make([]string, 0)
+	for _, conflict := range report.Conflicts {
+		reasons = append(reasons, conflict.Reason)
+	}
+	assert.Equal(t, []string{
+		"wallet with the same name already exists",
+		"wallet with the same ID already exists",
+		"account with the same name already exists",
+		"account with the same name already exists",
+	}, reasons)
+}
@@ -0,0 +1,105 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Exports of unlocked wallets end with an integrity record, a JSON value holding an HMAC-SHA256 tag over the SHA-256
+// digest of the preceding JSON values.  The key for the tag is derived from the wallet's seed, so only the holder of
+// the wallet's passphrase can create or verify it, regardless of how the export is encrypted.
+
+// exportIntegrityInfo is the HKDF info for the key of an export's integrity tag.
+const exportIntegrityInfo = "e2wallet-export-integrity"
+
+// integrityRecord is the final JSON value of an export of an unlocked wallet.
+type integrityRecord struct {
+	Integrity string `json:"integrity"`
+}
+
+// exportIntegrity is the integrity tag of an export, along with the digest of the export over which it was calculated.
+type exportIntegrity struct {
+	digest []byte
+	tag    []byte
+}
+
+// integrityKey derives the key for the integrity tag of an export from the wallet's seed.
+func integrityKey(seed []byte) ([]byte, error) {
+	key := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, seed, nil, []byte(exportIntegrityInfo)), key); err != nil {
+		return nil, errors.Wrap(err, "failed to derive integrity key")
+	}
+	return key, nil
+}
+
+// integrityTag calculates the integrity tag for the digest of an export with the integrity key.
+func integrityTag(key []byte, digest []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(digest)
+	return mac.Sum(nil)
+}
+
+// exportIntegrityRecord provides the integrity record for the digest of an export, or nil if the wallet is locked.
+func (w *wallet) exportIntegrityRecord(digest []byte) (*integrityRecord, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	if !w.hasSeed() {
+		return nil, nil
+	}
+	var tag []byte
+	if err := w.useSeed(func(seed []byte) error {
+		key, err := integrityKey(seed)
+		if err != nil {
+			return err
+		}
+		defer zeroBytes(key)
+		tag = integrityTag(key, digest)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &integrityRecord{
+		Integrity: hex.EncodeToString(tag),
+	}, nil
+}
+
+// importIntegrityKey provides the key with which to verify the integrity tag of the export from which the wallet is
+// being imported, from the seed decrypted with the passphrase.
+func importIntegrityKey(w *wallet, passphrase []byte) ([]byte, error) {
+	seed, err := w.decryptSeed(passphrase)
+	if err != nil {
+		return nil, errors.New("incorrect passphrase for imported wallet")
+	}
+	defer zeroBytes(seed)
+	return integrityKey(seed)
+}
+
+// verifyExportIntegrity verifies the integrity tag of the export from which the wallet was decoded with the integrity
+// key.  All accounts must have been decoded.
+func verifyExportIntegrity(w *wallet, key []byte) error {
+	if w.exportIntegrity == nil {
+		return errors.New("export has no integrity tag")
+	}
+	if !hmac.Equal(integrityTag(key, w.exportIntegrity.digest), w.exportIntegrity.tag) {
+		return errors.New("export integrity check failed")
+	}
+	return nil
+}
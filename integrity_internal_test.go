@@ -0,0 +1,179 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestExportIntegrity(t *testing.T) {
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+	_, err = w.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	_, err = w.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, w.exportJSON(buf))
+	data := buf.Bytes()
+	lines := bytes.SplitAfter(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	require.Len(t, lines, 5)
+	require.True(t, bytes.HasPrefix(lines[4], []byte(`{"integrity":"`)))
+
+	w.Lock()
+	buf = new(bytes.Buffer)
+	require.NoError(t, w.exportJSON(buf))
+	locked := buf.Bytes()
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase []byte
+		err        string
+	}{
+		{
+			name: "Good",
+			data: data,
+		},
+		{
+			name:       "WrongPassphrase",
+			data:       data,
+			passphrase: []byte("wrong"),
+			err:        "incorrect passphrase for imported wallet",
+		},
+		{
+			name: "Locked",
+			data: locked,
+			err:  "export has no integrity tag",
+		},
+		{
+			name: "AccountRemoved",
+			data: bytes.Join([][]byte{lines[0], lines[1], lines[3], lines[4]}, nil),
+			err:  "export integrity check failed",
+		},
+		{
+			name: "AccountModified",
+			data: bytes.Replace(data, []byte(`"name":"Account 2"`), []byte(`"name":"Account 3"`), 1),
+			err:  "export integrity check failed",
+		},
+		{
+			name: "CountModified",
+			data: bytes.Replace(data, []byte(`{"accounts":2}`), []byte(`{"accounts":3}`), 1),
+			err:  "export integrity check failed",
+		},
+		{
+			name: "RecordRemoved",
+			data: bytes.Join(lines[:4], nil),
+			err:  "export has no integrity tag",
+		},
+		{
+			name: "DataAfterRecord",
+			data: bytes.Join([][]byte{lines[0], lines[1], lines[2], lines[4], lines[3]}, nil),
+			err:  "failed to import account: data after integrity record",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := scratch.New()
+			imported, nextAccount, err := decodeJSON(bytes.NewReader(test.data))
+			require.NoError(t, err)
+			_, err = importWallet(imported, nextAccount, store, keystorev4.New(), []Option{WithIntegrityCheck(test.passphrase)})
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				// Nothing is written to the store.
+				_, err = store.RetrieveWallet("test wallet")
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			_, err = imported.AccountByName("Account 2")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestImportTruncatedWritesNothing(t *testing.T) {
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+	// Create enough accounts for the compressed export to span multiple chunks, so that accounts are decoded before
+	// the truncation is found.
+	names := make([]string, 750)
+	for i := range names {
+		names[i] = fmt.Sprintf("Account %d", i)
+	}
+	_, err = w.CreateAccounts(names, nil)
+	require.NoError(t, err)
+
+	data, err := w.Export([]byte("export"))
+	require.NoError(t, err)
+	require.Greater(t, len(data), 2*exportChunkSize)
+
+	store := scratch.New()
+	_, err = Import(data[:len(data)-100], []byte("export"), store, keystorev4.New())
+	require.EqualError(t, err, "failed to import account: export truncated")
+	_, err = store.RetrieveWallet("test wallet")
+	assert.Error(t, err)
+	_, err = store.RetrieveWalletByID(w.ID())
+	assert.Error(t, err)
+
+	// The import can be retried.
+	imported, err := Import(data, []byte("export"), store, keystorev4.New())
+	require.NoError(t, err)
+	_, err = imported.(*wallet).AccountByName("Account 749")
+	require.NoError(t, err)
+}
+
+func TestImportLockedExport(t *testing.T) {
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+	_, err = w.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	unlocked, err := w.Export([]byte("export"))
+	require.NoError(t, err)
+	report, err := ValidateImport(unlocked, []byte("export"), nil)
+	require.NoError(t, err)
+	assert.True(t, report.IntegrityTagged)
+
+	w.Lock()
+	locked, err := w.Export([]byte("export"))
+	require.NoError(t, err)
+	report, err = ValidateImport(locked, []byte("export"), nil)
+	require.NoError(t, err)
+	assert.False(t, report.IntegrityTagged)
+
+	store := scratch.New()
+	_, err = Import(locked, []byte("export"), store, keystorev4.New(), WithIntegrityCheck(nil))
+	require.EqualError(t, err, "export has no integrity tag")
+	_, err = store.RetrieveWallet("test wallet")
+	assert.Error(t, err)
+}
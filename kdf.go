@@ -0,0 +1,182 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// kdfKeyLength is the length of the key derived by the key derivation function.
+	kdfKeyLength = 32
+	// defaultScryptR and defaultScryptP are the scrypt parameters recommended by EIP-2335.
+	defaultScryptR = 8
+	defaultScryptP = 1
+)
+
+// KDFParams are the key derivation function cost parameters used to encrypt a wallet's seed and its accounts' keys,
+// allowing lighter parameters for throwaway test wallets and heavier ones for production.  Only the key derivation
+// functions defined by EIP-2335, scrypt and PBKDF2, are supported.
+type KDFParams struct {
+	// Function is the key derivation function, either "scrypt" or "pbkdf2".
+	Function string
+	// N is the scrypt CPU/memory cost, which must be a power of 2.
+	N int
+	// R is the scrypt block size; it defaults to 8.
+	R int
+	// P is the scrypt parallelisation; it defaults to 1.
+	P int
+	// C is the PBKDF2 iteration count.
+	C int
+}
+
+// validate checks the KDF parameters, filling in defaults.
+func (p *KDFParams) validate() error {
+	switch p.Function {
+	case "scrypt":
+		if p.N < 2 || p.N&(p.N-1) != 0 {
+			return errors.New("scrypt N must be a power of 2 greater than 1")
+		}
+		if p.R == 0 {
+			p.R = defaultScryptR
+		}
+		if p.P == 0 {
+			p.P = defaultScryptP
+		}
+		if p.R < 0 || p.P < 0 {
+			return errors.New("scrypt R and P must be positive")
+		}
+	case "pbkdf2":
+		if p.C < 1 {
+			return errors.New("PBKDF2 C must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported key derivation function %q", p.Function)
+	}
+	return nil
+}
+
+// kdfEncryptor is a keystore version 4 encryptor that encrypts with the given KDF parameters.
+// Decryption is carried out by the underlying encryptor, as the parameters are held in the keystore.
+type kdfEncryptor struct {
+	wtypes.Encryptor
+	params KDFParams
+}
+
+// tunedEncryptor provides an encryptor that encrypts with the KDF parameters and decrypts with the encryptor.
+func tunedEncryptor(encryptor wtypes.Encryptor, params KDFParams) (wtypes.Encryptor, error) {
+	if tuned, ok := encryptor.(*kdfEncryptor); ok {
+		encryptor = tuned.Encryptor
+	}
+	if encryptor.Name() != "keystore" || encryptor.Version() != 4 {
+		return nil, errors.New("KDF parameters require a keystore version 4 encryptor")
+	}
+	if err := params.validate(); err != nil {
+		return nil, errors.Wrap(err, "KDF parameters invalid")
+	}
+	return &kdfEncryptor{
+		Encryptor: encryptor,
+		params:    params,
+	}, nil
+}
+
+// Encrypt encrypts the secret following EIP-2335.
+func (e *kdfEncryptor) Encrypt(secret []byte, passphrase []byte) (map[string]interface{}, error) {
+	if secret == nil {
+		return nil, errors.New("no secret")
+	}
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "failed to generate salt")
+	}
+	var key []byte
+	kdfParams := map[string]interface{}{
+		"dklen": kdfKeyLength,
+		"salt":  hex.EncodeToString(salt),
+	}
+	switch e.params.Function {
+	case "scrypt":
+		var err error
+		key, err = scrypt.Key(passphrase, salt, e.params.N, e.params.R, e.params.P, kdfKeyLength)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to derive key")
+		}
+		kdfParams["n"] = e.params.N
+		kdfParams["r"] = e.params.R
+		kdfParams["p"] = e.params.P
+	case "pbkdf2":
+		key = pbkdf2.Key(passphrase, salt, e.params.C, kdfKeyLength, sha256.New)
+		kdfParams["c"] = e.params.C
+		kdfParams["prf"] = "hmac-sha256"
+	}
+	defer zeroBytes(key)
+
+	iv := make([]byte, 16)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, errors.Wrap(err, "failed to generate IV")
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, len(secret))
+	cipher.NewCTR(block, iv).XORKeyStream(message, secret)
+
+	h := sha256.New()
+	h.Write(key[16:32])
+	h.Write(message)
+
+	keystore := map[string]interface{}{
+		"kdf": map[string]interface{}{
+			"function": e.params.Function,
+			"params":   kdfParams,
+			"message":  "",
+		},
+		"checksum": map[string]interface{}{
+			"function": "sha256",
+			"params":   map[string]interface{}{},
+			"message":  hex.EncodeToString(h.Sum(nil)),
+		},
+		"cipher": map[string]interface{}{
+			"function": "aes-128-ctr",
+			"params": map[string]interface{}{
+				"iv": hex.EncodeToString(iv),
+			},
+			"message": hex.EncodeToString(message),
+		},
+	}
+
+	// Go via JSON so that the result has the same types as that of other encryptors.
+	data, err := json.Marshal(keystore)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]interface{})
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
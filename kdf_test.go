@@ -0,0 +1,105 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestKDFParams(t *testing.T) {
+	tests := []struct {
+		name      string
+		encryptor wtypes.Encryptor
+		params    hd.KDFParams
+		stored    string
+		err       string
+	}{
+		{
+			name:      "UnknownFunction",
+			encryptor: keystorev4.New(),
+			params:    hd.KDFParams{Function: "argon2id"},
+			err:       `KDF parameters invalid: unsupported key derivation function "argon2id"`,
+		},
+		{
+			name:      "ScryptNInvalid",
+			encryptor: keystorev4.New(),
+			params:    hd.KDFParams{Function: "scrypt", N: 1000},
+			err:       "KDF parameters invalid: scrypt N must be a power of 2 greater than 1",
+		},
+		{
+			name:      "PBKDF2CMissing",
+			encryptor: keystorev4.New(),
+			params:    hd.KDFParams{Function: "pbkdf2"},
+			err:       "KDF parameters invalid: PBKDF2 C must be positive",
+		},
+		{
+			name:      "LegacyEncryptor",
+			encryptor: &legacyEncryptor{Encryptor: keystorev4.New()},
+			params:    hd.KDFParams{Function: "pbkdf2", C: 2},
+			err:       "KDF parameters require a keystore version 4 encryptor",
+		},
+		{
+			name:      "Scrypt",
+			encryptor: keystorev4.New(),
+			params:    hd.KDFParams{Function: "scrypt", N: 16},
+			stored:    `"n":16,"p":1,"r":8`,
+		},
+		{
+			name:      "PBKDF2",
+			encryptor: keystorev4.New(),
+			params:    hd.KDFParams{Function: "pbkdf2", C: 2},
+			stored:    `"c":2`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := scratch.New()
+			passphrase := []byte("passphrase")
+			wallet, err := hd.CreateWallet("test wallet", store, test.encryptor,
+				hd.WithPassphrase(passphrase),
+				hd.WithKDFParams(test.params))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, wallet.Unlock(passphrase))
+			account, err := wallet.CreateAccount("Account 1", passphrase)
+			require.NoError(t, err)
+
+			data, err := store.RetrieveWallet("test wallet")
+			require.NoError(t, err)
+			assert.Contains(t, string(data), test.stored)
+			data, err = store.RetrieveAccount(wallet.ID(), account.ID())
+			require.NoError(t, err)
+			assert.Contains(t, string(data), test.stored)
+
+			// The wallet and account can be unlocked without the parameters.
+			wallet, err = hd.OpenWallet("test wallet", store, keystorev4.New())
+			require.NoError(t, err)
+			require.NoError(t, wallet.Unlock(passphrase))
+			account, err = wallet.AccountByName("Account 1")
+			require.NoError(t, err)
+			require.NoError(t, account.Unlock(passphrase))
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"encoding/hex"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	keyring "github.com/zalando/go-keyring"
+)
+
+// ErrPassphraseNotFound is returned when a passphrase provider does not hold a passphrase for a wallet.
+var ErrPassphraseNotFound = errors.New("passphrase not found")
+
+// PassphraseProvider is the interface for stores that hold wallet passphrases on behalf of the user, so that tooling
+// does not need to prompt for the passphrase on every operation.  A passphrase provider can be supplied with
+// WithPassphraseProvider.
+type PassphraseProvider interface {
+	// Passphrase provides the passphrase for the wallet with the given ID.
+	// It returns ErrPassphraseNotFound if there is no passphrase for the wallet.
+	Passphrase(id uuid.UUID) ([]byte, error)
+
+	// SetPassphrase stores the passphrase for the wallet with the given ID.
+	SetPassphrase(id uuid.UUID, passphrase []byte) error
+
+	// DeletePassphrase removes the passphrase for the wallet with the given ID.
+	// It returns ErrPassphraseNotFound if there is no passphrase for the wallet.
+	DeletePassphrase(id uuid.UUID) error
+}
+
+// defaultKeychainService is the service under which passphrases are held in the operating system's keychain.
+const defaultKeychainService = "go-eth2-wallet-hd"
+
+// KeychainPassphraseProvider is a passphrase provider that holds passphrases in the operating system's keychain: the
+// Keychain on macOS, the Credential Manager (DPAPI) on Windows and the Secret Service (libsecret) on Linux.
+type KeychainPassphraseProvider struct {
+	service string
+}
+
+// NewKeychainPassphraseProvider creates a passphrase provider that holds passphrases in the operating system's
+// keychain under the given service name.  If the service name is empty then "go-eth2-wallet-hd" is used.
+func NewKeychainPassphraseProvider(service string) *KeychainPassphraseProvider {
+	if service == "" {
+		service = defaultKeychainService
+	}
+	return &KeychainPassphraseProvider{
+		service: service,
+	}
+}
+
+// Passphrase provides the passphrase for the wallet with the given ID.
+func (p *KeychainPassphraseProvider) Passphrase(id uuid.UUID) ([]byte, error) {
+	data, err := keyring.Get(p.service, id.String())
+	if err != nil {
+		if err == keyring.ErrNotFound {
+			return nil, ErrPassphraseNotFound
+		}
+		return nil, errors.Wrap(err, "failed to obtain passphrase from keychain")
+	}
+	passphrase, err := hex.DecodeString(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "passphrase in keychain invalid")
+	}
+	return passphrase, nil
+}
+
+// SetPassphrase stores the passphrase for the wallet with the given ID.
+// The passphrase is hex-encoded, as keychains hold strings and passphrases need not be valid UTF-8.
+func (p *KeychainPassphraseProvider) SetPassphrase(id uuid.UUID, passphrase []byte) error {
+	if err := keyring.Set(p.service, id.String(), hex.EncodeToString(passphrase)); err != nil {
+		return errors.Wrap(err, "failed to store passphrase in keychain")
+	}
+	return nil
+}
+
+// DeletePassphrase removes the passphrase for the wallet with the given ID.
+func (p *KeychainPassphraseProvider) DeletePassphrase(id uuid.UUID) error {
+	if err := keyring.Delete(p.service, id.String()); err != nil {
+		if err == keyring.ErrNotFound {
+			return ErrPassphraseNotFound
+		}
+		return errors.Wrap(err, "failed to remove passphrase from keychain")
+	}
+	return nil
+}
+
+// UnlockWithProvider unlocks the wallet with the passphrase held by the wallet's passphrase provider.
+func (w *wallet) UnlockWithProvider() error {
+	if w.passphraseProvider == nil {
+		return errors.New("no passphrase provider supplied")
+	}
+	passphrase, err := w.passphraseProvider.Passphrase(w.id)
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(passphrase)
+
+	return w.Unlock(passphrase)
+}
+
+// RememberPassphrase stores the passphrase with the wallet's passphrase provider, so that the wallet can subsequently
+// be unlocked with UnlockWithProvider.  The passphrase must unlock the wallet.
+func (w *wallet) RememberPassphrase(passphrase []byte) error {
+	if w.passphraseProvider == nil {
+		return errors.New("no passphrase provider supplied")
+	}
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	if w.threshold > 0 {
+		return errors.New("wallet requires threshold unlock")
+	}
+
+	w.mutex.RLock()
+	seed, err := w.decryptSeed(passphrase)
+	w.mutex.RUnlock()
+	if err != nil {
+		return errors.New("incorrect passphrase")
+	}
+	zeroBytes(seed)
+
+	return w.passphraseProvider.SetPassphrase(w.id, passphrase)
+}
+
+// ForgetPassphrase removes the wallet's passphrase from the wallet's passphrase provider.
+func (w *wallet) ForgetPassphrase() error {
+	if w.passphraseProvider == nil {
+		return errors.New("no passphrase provider supplied")
+	}
+	return w.passphraseProvider.DeletePassphrase(w.id)
+}
@@ -0,0 +1,64 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	keyring "github.com/zalando/go-keyring"
+)
+
+func TestKeychainPassphraseProvider(t *testing.T) {
+	keyring.MockInit()
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	provider := hd.NewKeychainPassphraseProvider("")
+	passphrase := []byte("wallet passphrase\xff")
+
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase(passphrase), hd.WithPassphraseProvider(provider))
+	require.NoError(t, err)
+	unlocker, ok := wallet.(hd.WalletProviderUnlocker)
+	require.True(t, ok)
+
+	assert.Equal(t, hd.ErrPassphraseNotFound, unlocker.UnlockWithProvider())
+	assert.Equal(t, hd.ErrPassphraseNotFound, unlocker.ForgetPassphrase())
+	assert.EqualError(t, unlocker.RememberPassphrase([]byte("wrong")), "incorrect passphrase")
+	require.NoError(t, unlocker.RememberPassphrase(passphrase))
+
+	// Reopen the wallet and unlock it with the stored passphrase.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithPassphraseProvider(provider))
+	require.NoError(t, err)
+	unlocker = wallet.(hd.WalletProviderUnlocker)
+	require.NoError(t, unlocker.UnlockWithProvider())
+	assert.True(t, wallet.IsUnlocked())
+
+	// A provider with a different service does not hold the passphrase.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithPassphraseProvider(hd.NewKeychainPassphraseProvider("other")))
+	require.NoError(t, err)
+	assert.Equal(t, hd.ErrPassphraseNotFound, wallet.(hd.WalletProviderUnlocker).UnlockWithProvider())
+
+	require.NoError(t, unlocker.ForgetPassphrase())
+	assert.Equal(t, hd.ErrPassphraseNotFound, unlocker.UnlockWithProvider())
+
+	// Without a provider the wallet cannot be unlocked from it.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.EqualError(t, wallet.(hd.WalletProviderUnlocker).UnlockWithProvider(), "no passphrase provider supplied")
+}
@@ -0,0 +1,192 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// keystore is an EIP-2335 keystore.
+type keystore struct {
+	Crypto      map[string]interface{} `json:"crypto"`
+	Description string                 `json:"description"`
+	PublicKey   string                 `json:"pubkey"`
+	Path        string                 `json:"path"`
+	UUID        string                 `json:"uuid"`
+	Version     uint                   `json:"version"`
+}
+
+// ImportAccount imports the key held in an EIP-2335 keystore in to the wallet as a new account with the given name.
+// The key is decrypted with the keystore's passphrase and re-encrypted with the supplied passphrase.  The account is
+// flagged as imported, as its key is not derived from the wallet's seed; it has no path, and it is not changed if the
+// wallet is reseeded.  The wallet does not need to be unlocked.
+func (w *wallet) ImportAccount(keystoreJSON []byte, keystorePassphrase []byte, name string, passphrase []byte) (wtypes.Account, error) {
+	a, err := w.importAccount(keystoreJSON, keystorePassphrase, name, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	w.hooks.accountCreated(a)
+
+	return a, nil
+}
+
+// importAccount imports the key held in an EIP-2335 keystore without calling hooks.
+func (w *wallet) importAccount(keystoreJSON []byte, keystorePassphrase []byte, name string, passphrase []byte) (wtypes.Account, error) {
+	if name == "" {
+		return nil, errors.New("account name missing")
+	}
+	if strings.HasPrefix(name, "_") {
+		return nil, fmt.Errorf("invalid account name %q", name)
+	}
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+
+	if err := w.checkPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+
+	ks := &keystore{}
+	if err := json.Unmarshal(keystoreJSON, ks); err != nil {
+		return nil, errors.Wrap(err, "keystore invalid")
+	}
+	if ks.Crypto == nil {
+		return nil, errors.New("keystore crypto missing")
+	}
+	encryptor := keystorev4.New()
+	if ks.Version != encryptor.Version() {
+		return nil, fmt.Errorf("keystore version %d unsupported", ks.Version)
+	}
+	secret, err := decryptSecret(encryptor, ks.Crypto, keystorePassphrase)
+	if err != nil {
+		return nil, errors.New("incorrect keystore passphrase")
+	}
+	privateKey, err := w.backend.PrivateKeyFromBytes(secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore secret invalid")
+	}
+	publicKey := privateKey.PublicKey()
+	if ks.PublicKey != "" {
+		keystorePublicKey, err := hex.DecodeString(strings.TrimPrefix(ks.PublicKey, "0x"))
+		if err != nil {
+			return nil, errors.Wrap(err, "keystore public key invalid")
+		}
+		if !bytes.Equal(keystorePublicKey, publicKey.Marshal()) {
+			return nil, errors.New("keystore public key does not correspond to secret key")
+		}
+	}
+
+	// Ensure that we don't already have an account with this name or key
+	if _, err := w.AccountByName(name); err == nil {
+		return nil, fmt.Errorf("account with name %q already exists", name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for existing := range w.AccountsWithContext(ctx) {
+		if bytes.Equal(existing.PublicKey().Marshal(), publicKey.Marshal()) {
+			return nil, fmt.Errorf("key already present in account %q", existing.Name())
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	a := newAccount()
+	if a.id, err = w.accountID(publicKey); err != nil {
+		return nil, err
+	}
+	a.name = name
+	a.publicKey = publicKey
+	a.description = ks.Description
+	a.imported = true
+	a.crypto, err = encryptSecret(w.encryptor, privateKey.Marshal(), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	a.encryptor = w.encryptor
+	a.version = w.encryptor.Version()
+	a.wallet = w
+
+	w.index.Add(a.id, a.name)
+	if err := a.storeAccount(); err != nil {
+		w.index.Remove(a.id, a.name)
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Export exports the account as an EIP-2335 keystore, including its path, public key and description, so that it can
+// be handed to another client without exporting the whole wallet.  The passphrase must be the account's passphrase,
+// and also encrypts the keystore.
+func (a *account) Export(passphrase []byte) ([]byte, error) {
+	return a.exportKeystore(passphrase, passphrase)
+}
+
+// exportKeystore exports the account as an EIP-2335 keystore encrypted with keystorePassphrase.
+func (a *account) exportKeystore(passphrase []byte, keystorePassphrase []byte) ([]byte, error) {
+	if a.watchOnly {
+		return nil, ErrWatchOnly
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var secret []byte
+	if a.heldKey != nil {
+		// Programmatic accounts hold their key rather than encrypting it.
+		secret = a.heldKey.Marshal()
+	} else {
+		backoff := a.unlockBackoff()
+		if err := backoff.check(a.id); err != nil {
+			return nil, err
+		}
+		var err error
+		secret, err = decryptSecret(a.encryptor, a.crypto, passphrase)
+		if err != nil {
+			backoff.failed(a.id)
+			return nil, errors.New("incorrect passphrase")
+		}
+		backoff.succeeded(a.id)
+	}
+	defer zeroBytes(secret)
+
+	// Use the account's encryptor if it produces EIP-2335 keystores, to retain any KDF parameters.
+	var encryptor wtypes.Encryptor = keystorev4.New()
+	if a.encryptor != nil && a.encryptor.Name() == encryptor.Name() && a.encryptor.Version() == encryptor.Version() {
+		encryptor = a.encryptor
+	}
+	crypto, err := encryptSecret(encryptor, secret, keystorePassphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt keystore")
+	}
+
+	return json.Marshal(&keystore{
+		Crypto:      crypto,
+		Description: a.description,
+		PublicKey:   fmt.Sprintf("%x", a.publicKey.Marshal()),
+		Path:        a.path,
+		UUID:        a.id.String(),
+		Version:     encryptor.Version(),
+	})
+}
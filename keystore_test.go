@@ -0,0 +1,201 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func _keystore(t *testing.T, privateKey e2types.PrivateKey, passphrase []byte, pubKey string, version uint) []byte {
+	crypto, err := keystorev4.New().Encrypt(privateKey.Marshal(), passphrase)
+	require.NoError(t, err)
+	data, err := json.Marshal(map[string]interface{}{
+		"crypto":      crypto,
+		"description": "Imported validator",
+		"pubkey":      pubKey,
+		"path":        "m/12381/3600/0/0/0",
+		"uuid":        "a2a6a3b7-1b38-4c4b-9c8d-bb2e1c23e7f1",
+		"version":     version,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestImportAccount(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	derived, err := wallet.CreateAccount("Derived", nil)
+	require.NoError(t, err)
+	require.NoError(t, derived.Unlock(nil))
+	derivedKey, err := derived.(wtypes.AccountPrivateKeyProvider).PrivateKey()
+	require.NoError(t, err)
+	importer, isImporter := wallet.(hd.WalletAccountImporter)
+	require.True(t, isImporter)
+
+	privateKey, err := e2types.GenerateBLSPrivateKey()
+	require.NoError(t, err)
+	otherKey, err := e2types.GenerateBLSPrivateKey()
+	require.NoError(t, err)
+	pubKey := fmt.Sprintf("%x", privateKey.PublicKey().Marshal())
+
+	tests := []struct {
+		name       string
+		keystore   []byte
+		passphrase []byte
+		account    string
+		err        string
+	}{
+		{
+			name:     "NameMissing",
+			keystore: _keystore(t, privateKey, []byte("keystore passphrase"), pubKey, 4),
+			err:      "account name missing",
+		},
+		{
+			name:     "NotJSON",
+			keystore: []byte("bad"),
+			account:  "Imported",
+			err:      "keystore invalid: invalid character 'b' looking for beginning of value",
+		},
+		{
+			name:     "CryptoMissing",
+			keystore: []byte(`{"version":4}`),
+			account:  "Imported",
+			err:      "keystore crypto missing",
+		},
+		{
+			name:       "VersionUnsupported",
+			keystore:   _keystore(t, privateKey, []byte("keystore passphrase"), pubKey, 3),
+			passphrase: []byte("keystore passphrase"),
+			account:    "Imported",
+			err:        "keystore version 3 unsupported",
+		},
+		{
+			name:       "PassphraseIncorrect",
+			keystore:   _keystore(t, privateKey, []byte("keystore passphrase"), pubKey, 4),
+			passphrase: []byte("wrong passphrase"),
+			account:    "Imported",
+			err:        "incorrect keystore passphrase",
+		},
+		{
+			name:       "PublicKeyMismatch",
+			keystore:   _keystore(t, privateKey, []byte("keystore passphrase"), fmt.Sprintf("%x", otherKey.PublicKey().Marshal()), 4),
+			passphrase: []byte("keystore passphrase"),
+			account:    "Imported",
+			err:        "keystore public key does not correspond to secret key",
+		},
+		{
+			name:       "NameExists",
+			keystore:   _keystore(t, privateKey, []byte("keystore passphrase"), pubKey, 4),
+			passphrase: []byte("keystore passphrase"),
+			account:    "Derived",
+			err:        `account with name "Derived" already exists`,
+		},
+		{
+			name:       "KeyExists",
+			keystore:   _keystore(t, derivedKey, []byte("keystore passphrase"), "", 4),
+			passphrase: []byte("keystore passphrase"),
+			account:    "Imported",
+			err:        `key already present in account "Derived"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := importer.ImportAccount(test.keystore, test.passphrase, test.account, []byte("account passphrase"))
+			assert.EqualError(t, err, test.err)
+		})
+	}
+
+	account, err := importer.ImportAccount(_keystore(t, privateKey, []byte("keystore passphrase"), pubKey, 4), []byte("keystore passphrase"), "Imported", []byte("account passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, privateKey.PublicKey().Marshal(), account.PublicKey().Marshal())
+	assert.Equal(t, "", account.Path())
+	assert.True(t, account.(hd.AccountImportedProvider).Imported())
+	assert.False(t, derived.(hd.AccountImportedProvider).Imported())
+	assert.Equal(t, "Imported validator", account.(hd.AccountDescriptionProvider).Description())
+
+	// Ensure the account was persisted, and is encrypted with the new passphrase.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	account, err = wallet.AccountByName("Imported")
+	require.NoError(t, err)
+	assert.True(t, account.(hd.AccountImportedProvider).Imported())
+	assert.EqualError(t, account.Unlock([]byte("keystore passphrase")), "incorrect passphrase")
+	require.NoError(t, account.Unlock([]byte("account passphrase")))
+	signature, err := account.Sign([]byte("data"))
+	require.NoError(t, err)
+	assert.True(t, signature.Verify([]byte("data"), privateKey.PublicKey()))
+
+	// Reseeding leaves the imported account unchanged.
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.(hd.WalletReseeder).Reseed(_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"), nil)
+	require.NoError(t, err)
+	account, err = wallet.AccountByName("Imported")
+	require.NoError(t, err)
+	assert.Equal(t, privateKey.PublicKey().Marshal(), account.PublicKey().Marshal())
+	require.NoError(t, account.Unlock([]byte("account passphrase")))
+}
+
+func TestExportAccount(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Validator", []byte("account passphrase"))
+	require.NoError(t, err)
+	require.NoError(t, account.(hd.AccountDescriptionProvider).SetDescription("Validator 1"))
+	exporter, ok := account.(hd.AccountKeystoreExporter)
+	require.True(t, ok)
+
+	_, err = exporter.Export([]byte("wrong passphrase"))
+	assert.EqualError(t, err, "incorrect passphrase")
+
+	data, err := exporter.Export([]byte("account passphrase"))
+	require.NoError(t, err)
+	ks := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(data, &ks))
+	assert.Equal(t, "Validator 1", ks["description"])
+	assert.Equal(t, fmt.Sprintf("%x", account.PublicKey().Marshal()), ks["pubkey"])
+	assert.Equal(t, account.Path(), ks["path"])
+	assert.Equal(t, account.ID().String(), ks["uuid"])
+	assert.Equal(t, float64(4), ks["version"])
+
+	// The keystore can be decrypted by any EIP-2335 implementation.
+	secret, err := keystorev4.New().Decrypt(ks["crypto"].(map[string]interface{}), []byte("account passphrase"))
+	require.NoError(t, err)
+	privateKey, err := e2types.BLSPrivateKeyFromBytes(secret)
+	require.NoError(t, err)
+	assert.Equal(t, account.PublicKey().Marshal(), privateKey.PublicKey().Marshal())
+
+	// The keystore can be imported in to another wallet.
+	wallet2, err := hd.CreateWallet("test wallet 2", store, encryptor)
+	require.NoError(t, err)
+	imported, err := wallet2.(hd.WalletAccountImporter).ImportAccount(data, []byte("account passphrase"), "Validator", []byte("new passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, account.PublicKey().Marshal(), imported.PublicKey().Marshal())
+	assert.Equal(t, "Validator 1", imported.(hd.AccountDescriptionProvider).Description())
+}
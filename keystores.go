@@ -0,0 +1,134 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// keystoresDir is the directory within a keystores archive that holds the keystores, as per the deposit CLI.
+const keystoresDir = "validator_keys"
+
+// KeystoreManifestEntry is the entry for a single keystore in the manifest of a keystores archive.
+type KeystoreManifestEntry struct {
+	Name     string `json:"name"`
+	UUID     string `json:"uuid"`
+	PubKey   string `json:"pubkey"`
+	Path     string `json:"path,omitempty"`
+	Keystore string `json:"keystore"`
+}
+
+// ExportKeystores exports accounts as a zip archive of EIP-2335 keystores in a validator_keys directory, named as per
+// the deposit CLI so that they can be loaded directly by validator clients, along with a manifest.json listing each
+// keystore's account.  If no accounts are supplied then all accounts in the wallet are exported.  The passphrase must
+// unlock each account, and also encrypts each keystore.
+func (w *wallet) ExportKeystores(passphrase []byte, accounts ...wtypes.Account) ([]byte, error) {
+	exportAccounts, err := w.exportAccounts(accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	manifest := make([]*KeystoreManifestEntry, 0, len(exportAccounts))
+	for _, a := range exportAccounts {
+		data, err := a.Export(passphrase)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to export account %q", a.name)
+		}
+		entry := &KeystoreManifestEntry{
+			Name:     a.name,
+			UUID:     a.id.String(),
+			PubKey:   fmt.Sprintf("%x", a.publicKey.Marshal()),
+			Path:     a.path,
+			Keystore: fmt.Sprintf("%s/%s", keystoresDir, keystoreFilename(a, now)),
+		}
+		if err := addArchiveFile(archive, entry.Keystore, data, now); err != nil {
+			return nil, err
+		}
+		manifest = append(manifest, entry)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := addArchiveFile(archive, "manifest.json", data, now); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to create archive")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// exportAccounts provides the wallet's accounts to export, sorted by name.  If no accounts are supplied then all
+// accounts in the wallet are provided.
+func (w *wallet) exportAccounts(accounts []wtypes.Account) ([]*account, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if len(accounts) == 0 {
+		for account := range w.Accounts() {
+			accounts = append(accounts, account)
+		}
+	}
+	exportAccounts := make([]*account, 0, len(accounts))
+	for _, walletAccount := range accounts {
+		a, ok := walletAccount.(*account)
+		if !ok || a.wallet == nil || a.wallet.ID() != w.id {
+			return nil, fmt.Errorf("account %q is not in the wallet", walletAccount.Name())
+		}
+		exportAccounts = append(exportAccounts, a)
+	}
+	sort.Slice(exportAccounts, func(i int, j int) bool {
+		return exportAccounts[i].name < exportAccounts[j].name
+	})
+	return exportAccounts, nil
+}
+
+// keystoreFilename provides the filename for an account's keystore.  Accounts with a path are named as per the
+// deposit CLI; imported accounts, which do not have a path, are named by their ID.
+func keystoreFilename(a *account, now time.Time) string {
+	if a.path == "" {
+		return fmt.Sprintf("keystore-%s-%d.json", a.id, now.Unix())
+	}
+	return fmt.Sprintf("keystore-%s-%d.json", strings.ReplaceAll(a.path, "/", "_"), now.Unix())
+}
+
+// addArchiveFile adds a file to a zip archive.
+func addArchiveFile(archive *zip.Writer, name string, data []byte, modified time.Time) error {
+	writer, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to add %s to archive", name)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return errors.Wrapf(err, "failed to add %s to archive", name)
+	}
+	return nil
+}
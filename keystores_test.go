@@ -0,0 +1,143 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestExportKeystores(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	passphrase := []byte("passphrase")
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	accounts, err := wallet.(hd.WalletAccountsCreator).CreateAccounts([]string{"Validator 1", "Validator 2"}, passphrase)
+	require.NoError(t, err)
+	_, err = wallet.CreateAccount("Other", []byte("other passphrase"))
+	require.NoError(t, err)
+	exporter, ok := wallet.(hd.WalletKeystoresExporter)
+	require.True(t, ok)
+
+	// All accounts must be unlocked by the passphrase.
+	_, err = exporter.ExportKeystores(passphrase)
+	assert.EqualError(t, err, `failed to export account "Other": incorrect passphrase`)
+
+	// Accounts must be in the wallet.
+	otherWallet, err := hd.CreateWallet("other wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, otherWallet.Unlock(nil))
+	otherAccount, err := otherWallet.CreateAccount("Validator 3", passphrase)
+	require.NoError(t, err)
+	_, err = exporter.ExportKeystores(passphrase, otherAccount)
+	assert.EqualError(t, err, `account "Validator 3" is not in the wallet`)
+
+	data, err := exporter.ExportKeystores(passphrase, accounts...)
+	require.NoError(t, err)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		files[file.Name], err = ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+	}
+	require.Len(t, files, 3)
+
+	manifest := make([]*hd.KeystoreManifestEntry, 0)
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	require.Len(t, manifest, 2)
+	for i, entry := range manifest {
+		account := accounts[i]
+		assert.Equal(t, account.Name(), entry.Name)
+		assert.Equal(t, account.ID().String(), entry.UUID)
+		assert.True(t, strings.HasPrefix(entry.Keystore, "validator_keys/keystore-"+strings.ReplaceAll(account.Path(), "/", "_")+"-"))
+
+		// Each keystore can be imported with the passphrase.
+		importWallet, err := hd.CreateWallet(entry.Name, store, encryptor)
+		require.NoError(t, err)
+		imported, err := importWallet.(hd.WalletAccountImporter).ImportAccount(files[entry.Keystore], passphrase, entry.Name, passphrase)
+		require.NoError(t, err)
+		assert.Equal(t, account.PublicKey().Marshal(), imported.PublicKey().Marshal())
+	}
+}
+
+func TestExportTekuKeystores(t *testing.T) {
+	encryptor := keystorev4.New()
+	passphrase := []byte("passphrase")
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	accounts, err := wallet.(hd.WalletAccountsCreator).CreateAccounts([]string{"Validator 1", "Validator 2"}, passphrase)
+	require.NoError(t, err)
+
+	data, err := wallet.(hd.WalletTekuExporter).ExportTekuKeystores(passphrase)
+	require.NoError(t, err)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		files[file.Name] = contents
+	}
+	require.Len(t, files, 4)
+
+	// Each keystore has a matching password file, with which it can be imported.
+	imported, err := hd.CreateWallet("imported wallet", scratch.New(), encryptor)
+	require.NoError(t, err)
+	i := 0
+	for name, keystore := range files {
+		if !strings.HasPrefix(name, "keys/") {
+			continue
+		}
+		require.True(t, strings.HasSuffix(name, ".json"))
+		password, exists := files["passwords/"+strings.TrimSuffix(strings.TrimPrefix(name, "keys/"), ".json")+".txt"]
+		require.True(t, exists)
+		assert.NotEqual(t, passphrase, password)
+		_, err = imported.(hd.WalletAccountImporter).ImportAccount(keystore, passphrase, "wrong", nil)
+		assert.EqualError(t, err, "incorrect keystore passphrase")
+		account, err := imported.(hd.WalletAccountImporter).ImportAccount(keystore, password, name, nil)
+		require.NoError(t, err)
+		found := false
+		for _, original := range accounts {
+			if bytes.Equal(original.PublicKey().Marshal(), account.PublicKey().Marshal()) {
+				found = true
+			}
+		}
+		assert.True(t, found)
+		i++
+	}
+	assert.Equal(t, 2, i)
+
+	_, err = wallet.(hd.WalletTekuExporter).ExportTekuKeystores([]byte("wrong"))
+	assert.EqualError(t, err, `failed to export account "Validator 1": incorrect passphrase`)
+}
@@ -0,0 +1,145 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// lighthouseValidatorDefinition is an entry in Lighthouse's validator_definitions.yml.
+type lighthouseValidatorDefinition struct {
+	Enabled                    bool   `yaml:"enabled"`
+	VotingPublicKey            string `yaml:"voting_public_key"`
+	Description                string `yaml:"description"`
+	Type                       string `yaml:"type"`
+	VotingKeystorePath         string `yaml:"voting_keystore_path"`
+	VotingKeystorePasswordPath string `yaml:"voting_keystore_password_path"`
+	VotingKeystorePassword     string `yaml:"voting_keystore_password"`
+}
+
+// ImportLighthouseValidators imports the validators listed in a Lighthouse validator_definitions.yml file in to the
+// wallet, returning the accounts created.  Each local keystore validator becomes an account named after its public
+// key.  Where the keystore's path is one of the wallet's account paths and the wallet's seed derives the keystore's
+// key at that path the account is created as a derived account at that path; otherwise the key is decrypted with the
+// keystore's password and imported, as per ImportAccount.  Validators of other types, such as remote signers, are
+// skipped.  Relative paths are resolved against the directory of the definitions file.  The wallet must be unlocked.
+func (w *wallet) ImportLighthouseValidators(definitionsPath string, passphrase []byte) ([]wtypes.Account, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to import validators")
+	}
+
+	data, err := ioutil.ReadFile(definitionsPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read validator definitions")
+	}
+	definitions := make([]*lighthouseValidatorDefinition, 0)
+	if err := yaml.Unmarshal(data, &definitions); err != nil {
+		return nil, errors.Wrap(err, "validator definitions invalid")
+	}
+
+	dir := filepath.Dir(definitionsPath)
+	accounts := make([]wtypes.Account, 0, len(definitions))
+	for _, definition := range definitions {
+		if definition.Type != "local_keystore" {
+			continue
+		}
+		a, err := w.importLighthouseValidator(dir, definition, passphrase)
+		if err != nil {
+			return accounts, errors.Wrapf(err, "failed to import validator %s", definition.VotingPublicKey)
+		}
+		accounts = append(accounts, a)
+	}
+
+	return accounts, nil
+}
+
+// importLighthouseValidator imports a single Lighthouse local keystore validator.
+func (w *wallet) importLighthouseValidator(dir string, definition *lighthouseValidatorDefinition, passphrase []byte) (wtypes.Account, error) {
+	keystoreJSON, err := ioutil.ReadFile(resolvePath(dir, definition.VotingKeystorePath))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read keystore")
+	}
+	ks := &keystore{}
+	if err := json.Unmarshal(keystoreJSON, ks); err != nil {
+		return nil, errors.Wrap(err, "keystore invalid")
+	}
+	publicKey, err := hex.DecodeString(strings.TrimPrefix(ks.PublicKey, "0x"))
+	if err != nil {
+		return nil, errors.Wrap(err, "keystore public key invalid")
+	}
+	name := fmt.Sprintf("%#x", publicKey)
+
+	if index, exists := w.accountNumberForPath(ks.Path); exists {
+		w.mutex.RLock()
+		privateKey, err := w.derivePrivateKey(ks.Path)
+		w.mutex.RUnlock()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create private key for path %q", ks.Path)
+		}
+		if bytes.Equal(privateKey.PublicKey().Marshal(), publicKey) {
+			return w.CreateAccountAtIndex(name, index, passphrase)
+		}
+	}
+
+	keystorePassphrase := []byte(definition.VotingKeystorePassword)
+	if definition.VotingKeystorePasswordPath != "" {
+		keystorePassphrase, err = ioutil.ReadFile(resolvePath(dir, definition.VotingKeystorePasswordPath))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read keystore password")
+		}
+		keystorePassphrase = bytes.TrimRight(keystorePassphrase, "\r\n")
+	}
+	return w.ImportAccount(keystoreJSON, keystorePassphrase, name, passphrase)
+}
+
+// accountNumberForPath provides the account number for which the wallet's path template generates the given path.
+func (w *wallet) accountNumberForPath(path string) (uint64, bool) {
+	if path == "" || w.pathProvider != nil {
+		return 0, false
+	}
+	template := strings.Replace(w.pathTemplate, "%w", strconv.FormatUint(w.walletIndex, 10), -1)
+	parts := strings.SplitN(template, "%a", 2)
+	if len(parts) != 2 || len(path) <= len(parts[0])+len(parts[1]) ||
+		!strings.HasPrefix(path, parts[0]) || !strings.HasSuffix(path, parts[1]) {
+		return 0, false
+	}
+	accountNum, err := strconv.ParseUint(path[len(parts[0]):len(path)-len(parts[1])], 10, 64)
+	if err != nil || accountNum > math.MaxInt32 || w.accountPath(accountNum) != path {
+		return 0, false
+	}
+	return accountNum, true
+}
+
+// resolvePath resolves a path relative to a directory, leaving absolute paths unchanged.
+func resolvePath(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
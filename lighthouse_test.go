@@ -0,0 +1,97 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestImportLighthouseValidators(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	encryptor := keystorev4.New()
+
+	// Keystores for a validator derived from the wallet's seed and for one that is not.
+	source, err := hd.CreateWallet("source wallet", scratch.New(), encryptor, hd.WithSeed(seed), hd.WithPathTemplate("m/12381/3600/%a/0/0"))
+	require.NoError(t, err)
+	require.NoError(t, source.Unlock(nil))
+	derived, err := source.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("derived", 2, []byte("derived password"))
+	require.NoError(t, err)
+	derivedKeystore, err := derived.(hd.AccountKeystoreExporter).Export([]byte("derived password"))
+	require.NoError(t, err)
+	other, err := hd.CreateWallet("other wallet", scratch.New(), encryptor, hd.WithPathTemplate("m/12381/3600/%a/0/0"))
+	require.NoError(t, err)
+	require.NoError(t, other.Unlock(nil))
+	imported, err := other.CreateAccount("imported", []byte("imported password"))
+	require.NoError(t, err)
+	importedKeystore, err := imported.(hd.AccountKeystoreExporter).Export([]byte("imported password"))
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "lighthouse")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "derived.json"), derivedKeystore, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "imported.json"), importedKeystore, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "imported.txt"), []byte("imported password\n"), 0600))
+	definitions := fmt.Sprintf(`---
+- enabled: true
+  voting_public_key: "%#x"
+  description: ""
+  type: local_keystore
+  voting_keystore_path: derived.json
+  voting_keystore_password: derived password
+- enabled: true
+  voting_public_key: "%#x"
+  description: ""
+  type: local_keystore
+  voting_keystore_path: %s
+  voting_keystore_password_path: imported.txt
+- enabled: true
+  voting_public_key: "0xa99a76ed7796f7be22d5b7e85deeb7c5677e88e511e0b337618f8c4eb61349b4bf2d153f649f7b53359fe8b94a38e44c"
+  description: ""
+  type: web3signer
+  url: "https://signer.example.com"
+`, derived.PublicKey().Marshal(), imported.PublicKey().Marshal(), filepath.Join(dir, "imported.json"))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "validator_definitions.yml"), []byte(definitions), 0600))
+
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor, hd.WithSeed(seed), hd.WithPathTemplate("m/12381/3600/%a/0/0"))
+	require.NoError(t, err)
+	importer := wallet.(hd.WalletLighthouseImporter)
+	_, err = importer.ImportLighthouseValidators(filepath.Join(dir, "validator_definitions.yml"), nil)
+	assert.EqualError(t, err, "wallet must be unlocked to import validators")
+	require.NoError(t, wallet.Unlock(nil))
+
+	accounts, err := importer.ImportLighthouseValidators(filepath.Join(dir, "validator_definitions.yml"), []byte("account passphrase"))
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+
+	assert.Equal(t, fmt.Sprintf("%#x", derived.PublicKey().Marshal()), accounts[0].Name())
+	assert.Equal(t, derived.PublicKey().Marshal(), accounts[0].PublicKey().Marshal())
+	assert.Equal(t, "m/12381/3600/2/0/0", accounts[0].Path())
+	assert.False(t, accounts[0].(hd.AccountImportedProvider).Imported())
+	require.NoError(t, accounts[0].Unlock([]byte("account passphrase")))
+
+	assert.Equal(t, imported.PublicKey().Marshal(), accounts[1].PublicKey().Marshal())
+	assert.True(t, accounts[1].(hd.AccountImportedProvider).Imported())
+	require.NoError(t, accounts[1].Unlock([]byte("account passphrase")))
+}
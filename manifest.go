@@ -0,0 +1,147 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// manifestKeyPath is the path of the key with which manifests are signed.
+// It is in the wallet's internal branch, so no account can hold the key and sign manifests of its own.
+const manifestKeyPath = internalPathRoot + "/2/0"
+
+// manifestVersion is the version of the manifest format.
+const manifestVersion = 1
+
+// Manifest lists the public details of a wallet's accounts.  It is signed by a key derived from the wallet's seed,
+// over the SHA-256 hash of the JSON encoding of the manifest with an empty signature.
+type Manifest struct {
+	Version    uint               `json:"version"`
+	WalletUUID string             `json:"wallet_uuid"`
+	WalletName string             `json:"wallet_name"`
+	Accounts   []*ManifestAccount `json:"accounts"`
+	PubKey     string             `json:"pubkey"`
+	Signature  string             `json:"signature"`
+}
+
+// ManifestAccount is the entry for a single account in a manifest.
+type ManifestAccount struct {
+	Name   string `json:"name"`
+	UUID   string `json:"uuid"`
+	Path   string `json:"path,omitempty"`
+	PubKey string `json:"pubkey"`
+}
+
+// ExportManifest provides a signed JSON manifest of the names, paths and public keys of the wallet's accounts,
+// containing no secrets, for auditors and monitoring systems.  The wallet must be unlocked to sign the manifest.
+// The manifest is checked with VerifyManifest.
+func (w *wallet) ExportManifest() ([]byte, error) {
+	accounts, err := w.exportAccounts(nil)
+	if err != nil {
+		return nil, err
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to export a manifest")
+	}
+
+	w.mutex.RLock()
+	var key e2types.PrivateKey
+	err = w.useSeed(func(seed []byte) error {
+		var err error
+		key, err = privateKeyFromSeedAndPath(w.backend, seed, manifestKeyPath)
+		return err
+	})
+	w.mutex.RUnlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive manifest key")
+	}
+
+	manifest := &Manifest{
+		Version:    manifestVersion,
+		WalletUUID: w.id.String(),
+		WalletName: w.name,
+		Accounts:   make([]*ManifestAccount, len(accounts)),
+		PubKey:     fmt.Sprintf("%x", key.PublicKey().Marshal()),
+	}
+	for i, a := range accounts {
+		manifest.Accounts[i] = &ManifestAccount{
+			Name:   a.name,
+			UUID:   a.id.String(),
+			Path:   a.path,
+			PubKey: fmt.Sprintf("%x", a.publicKey.Marshal()),
+		}
+	}
+	root, err := manifest.signingRoot()
+	if err != nil {
+		return nil, err
+	}
+	manifest.Signature = fmt.Sprintf("%x", key.Sign(root).Marshal())
+
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+// signingRoot provides the data over which the manifest is signed.
+func (m *Manifest) signingRoot() ([]byte, error) {
+	unsigned := *m
+	unsigned.Signature = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode manifest")
+	}
+	root := sha256.Sum256(data)
+	return root[:], nil
+}
+
+// VerifyManifest parses a manifest provided by ExportManifest and verifies its signature.  The signature shows only
+// that the manifest has not been altered since it was signed by the holder of the key in it, so the public key of the
+// manifest should also be compared with that of a manifest known to come from the wallet.
+func VerifyManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.Wrap(err, "invalid manifest")
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	pubKeyBytes, err := hex.DecodeString(manifest.PubKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid manifest public key")
+	}
+	pubKey, err := e2types.BLSPublicKeyFromBytes(pubKeyBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid manifest public key")
+	}
+	sigBytes, err := hex.DecodeString(manifest.Signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid manifest signature")
+	}
+	sig, err := e2types.BLSSignatureFromBytes(sigBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid manifest signature")
+	}
+	root, err := manifest.signingRoot()
+	if err != nil {
+		return nil, err
+	}
+	if !sig.Verify(root, pubKey) {
+		return nil, errors.New("manifest signature invalid")
+	}
+	return manifest, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestExportManifest(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	exporter := wallet.(hd.WalletManifestExporter)
+
+	_, err = exporter.ExportManifest()
+	assert.EqualError(t, err, "wallet must be unlocked to export a manifest")
+
+	require.NoError(t, wallet.Unlock(nil))
+	account2, err := wallet.CreateAccount("Account 2", []byte("secret"))
+	require.NoError(t, err)
+	account1, err := wallet.CreateAccount("Account 1", []byte("secret"))
+	require.NoError(t, err)
+
+	data, err := exporter.ExportManifest()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "crypto")
+
+	manifest, err := hd.VerifyManifest(data)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID().String(), manifest.WalletUUID)
+	assert.Equal(t, "test wallet", manifest.WalletName)
+	require.Len(t, manifest.Accounts, 2)
+	assert.Equal(t, "Account 1", manifest.Accounts[0].Name)
+	assert.Equal(t, account1.ID().String(), manifest.Accounts[0].UUID)
+	assert.Equal(t, "m/12381/3600/1/0", manifest.Accounts[0].Path)
+	assert.Equal(t, fmt.Sprintf("%x", account1.PublicKey().Marshal()), manifest.Accounts[0].PubKey)
+	assert.Equal(t, "Account 2", manifest.Accounts[1].Name)
+	assert.Equal(t, fmt.Sprintf("%x", account2.PublicKey().Marshal()), manifest.Accounts[1].PubKey)
+
+	// The manifest is signed by the same key each time.
+	data2, err := exporter.ExportManifest()
+	require.NoError(t, err)
+	manifest2, err := hd.VerifyManifest(data2)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.PubKey, manifest2.PubKey)
+
+	// Altering the manifest invalidates the signature.
+	altered := bytes.Replace(data, []byte(manifest.Accounts[1].PubKey), []byte(manifest.Accounts[0].PubKey), 1)
+	_, err = hd.VerifyManifest(altered)
+	assert.EqualError(t, err, "manifest signature invalid")
+	altered = bytes.Replace(data, []byte(`"version": 1`), []byte(`"version": 2`), 1)
+	_, err = hd.VerifyManifest(altered)
+	assert.EqualError(t, err, "unsupported manifest version 2")
+	_, err = hd.VerifyManifest([]byte("bad"))
+	assert.Error(t, err)
+}
+
+func TestManifestKeyNotAnAccount(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+
+	// Obtain the keys of the accounts closest to the manifest key that can be created in the legacy path template.
+	extendedAccount, err := wallet.(hd.WalletExtendedAccountCreator).CreateExtendedAccount("Account 0", []uint64{2}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "m/12381/3600/0/0/2", extendedAccount.Path())
+	programmaticAccount, err := wallet.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/3600/0/0/2")
+	require.NoError(t, err)
+
+	data, err := wallet.(hd.WalletManifestExporter).ExportManifest()
+	require.NoError(t, err)
+	manifest, err := hd.VerifyManifest(data)
+	require.NoError(t, err)
+	assert.NotEqual(t, fmt.Sprintf("%x", extendedAccount.PublicKey().Marshal()), manifest.PubKey)
+	assert.NotEqual(t, fmt.Sprintf("%x", programmaticAccount.PublicKey().Marshal()), manifest.PubKey)
+}
@@ -0,0 +1,105 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// mergeCheckPath is the path of the key derived from each wallet's seed to confirm that they are the same.
+const mergeCheckPath = "m/12381/3600/0/0/0"
+
+// mergeWallet merges the accounts of an imported wallet in to an existing wallet with the same seed.  Accounts whose
+// public keys are already in the existing wallet are skipped.
+func mergeWallet(existing *wallet, w *wallet, nextAccount func() (*account, error), passphrase []byte) (wtypes.Wallet, error) {
+	if err := confirmSameSeed(existing, w, passphrase); err != nil {
+		return nil, err
+	}
+
+	publicKeys := make(map[string]bool)
+	for existingAccount := range existing.Accounts() {
+		publicKeys[string(existingAccount.PublicKey().Marshal())] = true
+	}
+
+	for {
+		acc, err := nextAccount()
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			break
+		}
+		if publicKeys[string(acc.publicKey.Marshal())] {
+			continue
+		}
+		if _, exists := existing.index.ID(acc.name); exists {
+			return nil, fmt.Errorf("account %q already exists with a different public key", acc.name)
+		}
+		if existing.index.IDKnown(acc.id) {
+			return nil, fmt.Errorf("account ID %s already exists with a different public key", acc.id)
+		}
+		acc.wallet = existing
+		acc.encryptor = existing.encryptor
+		acc.mutex = new(sync.RWMutex)
+		existing.index.Add(acc.id, acc.name)
+		if err := acc.storeAccount(); err != nil {
+			return nil, fmt.Errorf("failed to store account %q", acc.Name())
+		}
+		publicKeys[string(acc.publicKey.Marshal())] = true
+	}
+
+	// Ensure that accounts created in future do not clash with those merged.
+	if w.nextAccount > existing.nextAccount {
+		existing.nextAccount = w.nextAccount
+		if err := existing.storeWallet(); err != nil {
+			return nil, fmt.Errorf("failed to store wallet %q", existing.Name())
+		}
+	}
+
+	return existing, nil
+}
+
+// confirmSameSeed confirms that two wallets have the same seed, by deriving a check key from each.
+func confirmSameSeed(existing *wallet, w *wallet, passphrase []byte) error {
+	existing.mutex.RLock()
+	existingSeed, err := existing.decryptSeed(passphrase)
+	existing.mutex.RUnlock()
+	if err != nil {
+		return errors.New("incorrect passphrase for existing wallet")
+	}
+	defer zeroBytes(existingSeed)
+	seed, err := w.decryptSeed(passphrase)
+	if err != nil {
+		return errors.New("incorrect passphrase for imported wallet")
+	}
+	defer zeroBytes(seed)
+
+	existingKey, err := privateKeyFromSeedAndPath(existing.backend, existingSeed, mergeCheckPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to derive check key for existing wallet")
+	}
+	key, err := privateKeyFromSeedAndPath(existing.backend, seed, mergeCheckPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to derive check key for imported wallet")
+	}
+	if !bytes.Equal(existingKey.PublicKey().Marshal(), key.PublicKey().Marshal()) {
+		return fmt.Errorf("wallet %q has a different seed", existing.Name())
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestMergeImport(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor, hd.WithSeed(seed), hd.WithPassphrase([]byte("wallet passphrase")))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+	for _, name := range []string{"Account 1", "Account 2", "Account 3"} {
+		_, err := wallet.CreateAccount(name, nil)
+		require.NoError(t, err)
+	}
+	data, err := wallet.(wtypes.WalletExporter).Export([]byte("export"))
+	require.NoError(t, err)
+
+	// The existing wallet has the first account.
+	store := scratch.New()
+	existing, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithSeed(seed), hd.WithPassphrase([]byte("wallet passphrase")))
+	require.NoError(t, err)
+	require.NoError(t, existing.Unlock([]byte("wallet passphrase")))
+	_, err = existing.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	_, err = hd.Import(data, []byte("export"), store, encryptor)
+	assert.EqualError(t, err, `wallet "test wallet" already exists`)
+	_, err = hd.Import(data, []byte("export"), store, encryptor, hd.WithMergeImport([]byte("wrong")))
+	assert.EqualError(t, err, "incorrect passphrase for existing wallet")
+
+	merged, err := hd.Import(data, []byte("export"), store, encryptor, hd.WithMergeImport([]byte("wallet passphrase")))
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID(), merged.ID())
+	names := make(map[string]bool)
+	for account := range merged.Accounts() {
+		names[account.Name()] = true
+	}
+	assert.Equal(t, map[string]bool{"Account 1": true, "Account 2": true, "Account 3": true}, names)
+
+	// Accounts created after the merge follow on from those merged.
+	require.NoError(t, merged.Unlock([]byte("wallet passphrase")))
+	account, err := merged.CreateAccount("Account 4", nil)
+	require.NoError(t, err)
+	reference, err := wallet.CreateAccount("Account 4", nil)
+	require.NoError(t, err)
+	assert.Equal(t, reference.PublicKey().Marshal(), account.PublicKey().Marshal())
+
+	// Merging again is a no-op.
+	_, err = hd.Import(data, []byte("export"), store, encryptor, hd.WithMergeImport([]byte("wallet passphrase")))
+	require.NoError(t, err)
+}
+
+func TestMergeImportConflicts(t *testing.T) {
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor, hd.WithSeed(_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	for _, name := range []string{"Account 1", "Account 2"} {
+		_, err := wallet.CreateAccount(name, nil)
+		require.NoError(t, err)
+	}
+	data, err := wallet.(wtypes.WalletExporter).Export([]byte("export"))
+	require.NoError(t, err)
+
+	// A wallet with a different seed cannot be merged.
+	store := scratch.New()
+	_, err = hd.CreateWallet("test wallet", store, encryptor, hd.WithSeed(_byteArray("202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f")))
+	require.NoError(t, err)
+	_, err = hd.Import(data, []byte("export"), store, encryptor, hd.WithMergeImport(nil))
+	assert.EqualError(t, err, `wallet "test wallet" has a different seed`)
+
+	// An account with the same name but a different key cannot be merged.
+	store = scratch.New()
+	existing, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithSeed(_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")))
+	require.NoError(t, err)
+	require.NoError(t, existing.Unlock(nil))
+	_, err = existing.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	_, err = hd.Import(data, []byte("export"), store, encryptor, hd.WithMergeImport(nil))
+	assert.EqualError(t, err, `account "Account 2" already exists with a different public key`)
+}
@@ -0,0 +1,83 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// MigrateWallet upgrades the named wallet in the store to the current wallet format.
+// The wallet's seed is not re-encrypted, so no passphrase is required.  Wallets already
+// in the current format are returned unchanged.  The encryptor is recorded as that which
+// encrypted the wallet's seed, so must be that with which the wallet was created.  The
+// creation time of the wallet is not known so is not recorded.
+func MigrateWallet(name string, store wtypes.Store, encryptor wtypes.Encryptor) (wtypes.Wallet, error) {
+	openedWallet, err := OpenWallet(name, store, encryptor)
+	if err != nil {
+		return nil, err
+	}
+	w, ok := openedWallet.(*wallet)
+	if !ok {
+		return nil, errors.New("wallet type unexpected")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.version >= version {
+		return w, nil
+	}
+	if !w.watchOnly {
+		if err := checkSeedEncryptor(w.crypto, encryptor); err != nil {
+			return nil, err
+		}
+	}
+
+	w.version = version
+	w.encryptorName = encryptor.Name()
+	w.encryptorVersion = encryptor.Version()
+	if err := w.storeWallet(); err != nil {
+		return nil, errors.Wrap(err, "failed to store migrated wallet")
+	}
+
+	return w, nil
+}
+
+// checkSeedEncryptor checks that the crypto of a wallet's seed has the fields of that provided by the encryptor, as
+// wallets before version 2 do not record the encryptor with which their seed was encrypted.  Encryptors that provide
+// the same fields cannot be told apart without the passphrase.
+func checkSeedEncryptor(crypto map[string]interface{}, encryptor wtypes.Encryptor) error {
+	expected, err := encryptor.Encrypt(make([]byte, 32), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to check encryptor")
+	}
+	if fmt.Sprint(cryptoFields(crypto)) != fmt.Sprint(cryptoFields(expected)) {
+		return fmt.Errorf("wallet seed was not encrypted by encryptor %q", encryptor.Name())
+	}
+	return nil
+}
+
+// cryptoFields provides the sorted names of the fields of a crypto.
+func cryptoFields(crypto map[string]interface{}) []string {
+	fields := make([]string, 0, len(crypto))
+	for field := range crypto {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,135 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// web3Encryptor stands in for an encryptor whose crypto has different fields.
+type web3Encryptor struct {
+	wtypes.Encryptor
+}
+
+func (e *web3Encryptor) Name() string {
+	return "web3"
+}
+
+func (e *web3Encryptor) Encrypt(secret []byte, passphrase []byte) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"cipher":     "aes-128-ctr",
+		"ciphertext": "",
+		"kdf":        "scrypt",
+		"mac":        "",
+	}, nil
+}
+
+func TestMigrateWallet(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+
+	// Create a version 1 wallet by hand.
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	crypto, err := encryptor.Encrypt(seed, []byte("wallet passphrase"))
+	require.NoError(t, err)
+	id := uuid.New()
+	data, err := json.Marshal(map[string]interface{}{
+		"id":          id.String(),
+		"name":        "test wallet",
+		"type":        "hierarchical deterministic",
+		"version":     1,
+		"crypto":      crypto,
+		"nextaccount": 0,
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.StoreWallet(id, "test wallet", data))
+
+	v1Wallet, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), v1Wallet.Version())
+	require.NoError(t, v1Wallet.Unlock([]byte("wallet passphrase")))
+	v1Account, err := v1Wallet.CreateAccount("Account 1", []byte("account passphrase"))
+	require.NoError(t, err)
+	v1Wallet.Lock()
+
+	_, err = hd.MigrateWallet("unknown wallet", store, encryptor)
+	assert.NotNil(t, err)
+
+	wallet, err := hd.MigrateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), wallet.Version())
+	assert.Equal(t, id, wallet.ID())
+
+	// The creation time of the wallet is not known.
+	stored, err := store.RetrieveWallet("test wallet")
+	require.NoError(t, err)
+	storedData := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(stored, &storedData))
+	assert.NotContains(t, storedData, "created")
+
+	// Ensure the migration was persisted and the wallet still operates as before.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), wallet.Version())
+	account, err := wallet.AccountByName("Account 1")
+	require.NoError(t, err)
+	assert.Equal(t, v1Account.Path(), account.Path())
+	assert.Equal(t, v1Account.PublicKey().Marshal(), account.PublicKey().Marshal())
+	require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+	account2, err := wallet.CreateAccount("Account 2", []byte("account passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/1/0", account2.Path())
+
+	// Migrating a current wallet is a no-op.
+	wallet, err = hd.MigrateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), wallet.Version())
+}
+
+func TestMigrateWalletEncryptorMismatch(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+
+	// Create a version 1 wallet by hand.
+	crypto, err := encryptor.Encrypt(_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20"), nil)
+	require.NoError(t, err)
+	id := uuid.New()
+	data, err := json.Marshal(map[string]interface{}{
+		"id":          id.String(),
+		"name":        "test wallet",
+		"type":        "hierarchical deterministic",
+		"version":     1,
+		"crypto":      crypto,
+		"nextaccount": 0,
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.StoreWallet(id, "test wallet", data))
+
+	_, err = hd.MigrateWallet("test wallet", store, &web3Encryptor{Encryptor: encryptor})
+	assert.EqualError(t, err, `wallet seed was not encrypted by encryptor "web3"`)
+
+	// The wallet is unchanged.
+	wallet, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), wallet.Version())
+}
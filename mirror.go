@@ -0,0 +1,152 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// mirroredStore is a store that writes to both a primary and a mirror store.
+type mirroredStore struct {
+	primary wtypes.Store
+	mirror  wtypes.Store
+}
+
+// NewMirroredStore creates a store that applies all writes to both the primary and the mirror store.
+// Reads are served by the primary store, falling back to the mirror store if the primary fails.
+// Wallets opened or created with this store will have their wallet and account data mirrored.
+func NewMirroredStore(primary wtypes.Store, mirror wtypes.Store) wtypes.Store {
+	return &mirroredStore{
+		primary: primary,
+		mirror:  mirror,
+	}
+}
+
+// Name provides the name of the store.
+func (s *mirroredStore) Name() string {
+	return s.primary.Name()
+}
+
+// StoreWallet stores wallet data in both stores.
+func (s *mirroredStore) StoreWallet(walletID uuid.UUID, walletName string, data []byte) error {
+	if err := s.primary.StoreWallet(walletID, walletName, data); err != nil {
+		return err
+	}
+	if err := s.mirror.StoreWallet(walletID, walletName, data); err != nil {
+		return errors.Wrap(err, "failed to mirror wallet")
+	}
+	return nil
+}
+
+// RetrieveWallets retrieves wallet data for all wallets from the primary store.
+func (s *mirroredStore) RetrieveWallets() <-chan []byte {
+	return s.primary.RetrieveWallets()
+}
+
+// RetrieveWallet retrieves wallet data for a wallet with a given name.
+func (s *mirroredStore) RetrieveWallet(walletName string) ([]byte, error) {
+	data, err := s.primary.RetrieveWallet(walletName)
+	if err != nil {
+		if data, mirrorErr := s.mirror.RetrieveWallet(walletName); mirrorErr == nil {
+			return data, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// RetrieveWalletByID retrieves wallet data for a wallet with a given ID.
+func (s *mirroredStore) RetrieveWalletByID(walletID uuid.UUID) ([]byte, error) {
+	data, err := s.primary.RetrieveWalletByID(walletID)
+	if err != nil {
+		if data, mirrorErr := s.mirror.RetrieveWalletByID(walletID); mirrorErr == nil {
+			return data, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// StoreAccount stores account data in both stores.
+func (s *mirroredStore) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	if err := s.primary.StoreAccount(walletID, accountID, data); err != nil {
+		return err
+	}
+	if err := s.mirror.StoreAccount(walletID, accountID, data); err != nil {
+		return errors.Wrap(err, "failed to mirror account")
+	}
+	return nil
+}
+
+// DeleteAccount deletes account data from both stores.
+// Both stores must support account deletion.
+func (s *mirroredStore) DeleteAccount(walletID uuid.UUID, accountID uuid.UUID) error {
+	primary, isDeleter := s.primary.(AccountDeleter)
+	if !isDeleter {
+		return errors.New("store does not support account deletion")
+	}
+	mirror, isDeleter := s.mirror.(AccountDeleter)
+	if !isDeleter {
+		return errors.New("mirror store does not support account deletion")
+	}
+	if err := primary.DeleteAccount(walletID, accountID); err != nil {
+		return err
+	}
+	if err := mirror.DeleteAccount(walletID, accountID); err != nil {
+		return errors.Wrap(err, "failed to mirror account deletion")
+	}
+	return nil
+}
+
+// RetrieveAccounts retrieves account information for all accounts from the primary store.
+func (s *mirroredStore) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	return s.primary.RetrieveAccounts(walletID)
+}
+
+// RetrieveAccount retrieves account data for a wallet with a given ID.
+func (s *mirroredStore) RetrieveAccount(walletID uuid.UUID, accountID uuid.UUID) ([]byte, error) {
+	data, err := s.primary.RetrieveAccount(walletID, accountID)
+	if err != nil {
+		if data, mirrorErr := s.mirror.RetrieveAccount(walletID, accountID); mirrorErr == nil {
+			return data, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// StoreAccountsIndex stores the index of accounts for a given wallet in both stores.
+func (s *mirroredStore) StoreAccountsIndex(walletID uuid.UUID, data []byte) error {
+	if err := s.primary.StoreAccountsIndex(walletID, data); err != nil {
+		return err
+	}
+	if err := s.mirror.StoreAccountsIndex(walletID, data); err != nil {
+		return errors.Wrap(err, "failed to mirror accounts index")
+	}
+	return nil
+}
+
+// RetrieveAccountsIndex retrieves the index of accounts for a given wallet.
+func (s *mirroredStore) RetrieveAccountsIndex(walletID uuid.UUID) ([]byte, error) {
+	data, err := s.primary.RetrieveAccountsIndex(walletID)
+	if err != nil {
+		if data, mirrorErr := s.mirror.RetrieveAccountsIndex(walletID); mirrorErr == nil {
+			return data, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestMirroredStore(t *testing.T) {
+	primary := scratch.New()
+	mirror := scratch.New()
+	store := hd.NewMirroredStore(primary, mirror)
+	assert.Equal(t, primary.Name(), store.Name())
+
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+	account, err := wallet.CreateAccount("test account", []byte("account passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	// Both stores should hold the wallet and its account independently.
+	for _, s := range []wtypes.Store{primary, mirror} {
+		reopened, err := hd.OpenWallet("test wallet", s, encryptor)
+		require.NoError(t, err)
+		mirroredAccount, err := reopened.AccountByName("test account")
+		require.NoError(t, err)
+		assert.Equal(t, account.ID(), mirroredAccount.ID())
+		require.NoError(t, mirroredAccount.Unlock([]byte("account passphrase")))
+	}
+
+	// Reads fall back to the mirror if the primary does not have the data.
+	mirrorOnly := hd.NewMirroredStore(scratch.New(), mirror)
+	reopened, err := hd.OpenWallet("test wallet", mirrorOnly, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID(), reopened.ID())
+	mirroredAccount, err := reopened.AccountByName("test account")
+	require.NoError(t, err)
+	assert.Equal(t, account.ID(), mirroredAccount.ID())
+}
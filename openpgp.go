@@ -0,0 +1,113 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/pkg/errors"
+)
+
+// openPGPMessageType is the armor type of an OpenPGP message.
+const openPGPMessageType = "PGP MESSAGE"
+
+// openPGPMagic is the start of an armored OpenPGP message.
+var openPGPMagic = []byte("-----BEGIN " + openPGPMessageType)
+
+// ExportToOpenPGP exports the entire wallet to the writer as an armored OpenPGP message encrypted to the given
+// armored public keys, so that it can be held in escrow by the holders of the matching private keys and decrypted
+// with tools such as gpg.  If passphrase is supplied the export is additionally protected by the passphrase, as per
+// ExportTo.  Such an export is imported with ImportFrom, supplying the private keys with WithOpenPGPKeys.
+func (w *wallet) ExportToOpenPGP(writer io.Writer, passphrase []byte, publicKeys ...[]byte) error {
+	if len(publicKeys) == 0 {
+		return errors.New("no public keys supplied")
+	}
+	recipients := make(openpgp.EntityList, 0, len(publicKeys))
+	for i, publicKey := range publicKeys {
+		entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+		if err != nil {
+			return errors.Wrapf(err, "invalid public key %d", i)
+		}
+		recipients = append(recipients, entities...)
+	}
+
+	armored, err := armor.Encode(writer, openPGPMessageType, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	plaintext, err := openpgp.Encrypt(armored, recipients, nil, &openpgp.FileHints{IsBinary: true}, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt export")
+	}
+	if len(passphrase) > 0 {
+		if err := w.ExportTo(plaintext, passphrase); err != nil {
+			return err
+		}
+	} else if err := w.exportJSON(plaintext); err != nil {
+		return err
+	}
+	if err := plaintext.Close(); err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	if err := armored.Close(); err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	return nil
+}
+
+// decodeOpenPGPExport decodes a wallet from an export encrypted to OpenPGP public keys.  If the private keys are
+// themselves encrypted they are decrypted with keyPassphrase.
+func decodeOpenPGPExport(reader io.Reader, passphrase []byte, keyring []byte, keyPassphrase []byte) (*wallet, func() (*account, error), error) {
+	if len(keyring) == 0 {
+		return nil, nil, errors.New("export is encrypted to OpenPGP keys but no private keys supplied")
+	}
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyring))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid private keys")
+	}
+
+	block, err := armor.Decode(reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read export")
+	}
+	if block.Type != openPGPMessageType {
+		return nil, nil, errors.New("export is not an OpenPGP message")
+	}
+
+	// The prompt is called again if none of the keys could be used, so only try to decrypt the keys once.
+	prompted := false
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		if symmetric || prompted {
+			return nil, errors.New("no private key matches the export's recipients")
+		}
+		prompted = true
+		for _, key := range keys {
+			if key.PrivateKey != nil && key.PrivateKey.Encrypted {
+				if err := key.PrivateKey.Decrypt(keyPassphrase); err != nil {
+					return nil, errors.New("incorrect passphrase for OpenPGP key")
+				}
+			}
+		}
+		return nil, nil
+	}
+	md, err := openpgp.ReadMessage(block.Body, entities, prompt, nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decrypt export")
+	}
+
+	return decodeEnclosedExport(md.UnverifiedBody, passphrase)
+}
@@ -0,0 +1,122 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+// armoredOpenPGPKeys provides the armored public or private keys of the entities as a single keyring.
+func armoredOpenPGPKeys(t *testing.T, private bool, entities ...*openpgp.Entity) []byte {
+	buf := new(bytes.Buffer)
+	blockType := openpgp.PublicKeyType
+	if private {
+		blockType = openpgp.PrivateKeyType
+	}
+	writer, err := armor.Encode(buf, blockType, nil)
+	require.NoError(t, err)
+	for _, entity := range entities {
+		if private {
+			require.NoError(t, entity.SerializePrivate(writer, nil))
+		} else {
+			require.NoError(t, entity.Serialize(writer))
+		}
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestExportToOpenPGP(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	entities := make([]*openpgp.Entity, 3)
+	for i := range entities {
+		entities[i], err = openpgp.NewEntity(fmt.Sprintf("Escrow %d", i), "", fmt.Sprintf("escrow%d@example.com", i), nil)
+		require.NoError(t, err)
+	}
+	public1 := armoredOpenPGPKeys(t, false, entities[0])
+	public2 := armoredOpenPGPKeys(t, false, entities[1])
+
+	exporter := wallet.(hd.WalletOpenPGPExporter)
+	assert.EqualError(t, exporter.ExportToOpenPGP(new(bytes.Buffer), nil), "no public keys supplied")
+	assert.Error(t, exporter.ExportToOpenPGP(new(bytes.Buffer), nil, []byte("bad")))
+
+	tests := []struct {
+		name       string
+		passphrase []byte
+		keyring    []byte
+		err        string
+	}{
+		{
+			name:    "First",
+			keyring: armoredOpenPGPKeys(t, true, entities[0]),
+		},
+		{
+			name:    "Second",
+			keyring: armoredOpenPGPKeys(t, true, entities[1]),
+		},
+		{
+			name:    "Keyring",
+			keyring: armoredOpenPGPKeys(t, true, entities[2], entities[1]),
+		},
+		{
+			name: "NoKeys",
+			err:  "export is encrypted to OpenPGP keys but no private keys supplied",
+		},
+		{
+			name:    "WrongKey",
+			keyring: armoredOpenPGPKeys(t, true, entities[2]),
+			err:     "failed to decrypt export: openpgp: incorrect key",
+		},
+		{
+			name:       "Passphrase",
+			passphrase: []byte("export"),
+			keyring:    armoredOpenPGPKeys(t, true, entities[0]),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			require.NoError(t, exporter.ExportToOpenPGP(buf, test.passphrase, public1, public2))
+			require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("-----BEGIN PGP MESSAGE-----")))
+
+			imported, err := hd.ImportFrom(bytes.NewReader(buf.Bytes()), test.passphrase, scratch.New(), encryptor, hd.WithOpenPGPKeys(test.keyring, nil))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, wallet.ID(), imported.ID())
+			_, err = imported.AccountByName("Account 1")
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"time"
+
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// options are the options for wallet creation.
+type options struct {
+	passphrase         []byte
+	seed               []byte
+	mnemonic           string
+	mnemonicPassphrase []byte
+	storeMnemonic      bool
+	deterministicIDs   bool
+	walletIndex        *uint64
+	pathTemplate       string
+	pathProvider       PathProvider
+	constrainPaths     bool
+	noProgrammatic     bool
+	cacheSize          *int
+	backend            BLSBackend
+	workers            int
+	encryptors         []wtypes.Encryptor
+	unlockBackoffBase  time.Duration
+	unlockBackoffMax   time.Duration
+	passphrasePolicy   PassphrasePolicy
+	guardedMemory      bool
+	sealSeed           bool
+	keyWrapper         KeyWrapper
+	passphraseProvider PassphraseProvider
+	kdfParams          *KDFParams
+	sharedPassphrase   bool
+	minSeedLength      int
+	gapLimit           int
+	mergeImport        bool
+	mergePassphrase    []byte
+	conflictSuffix     string
+	newWalletName      string
+	ageIdentities      []string
+	pgpKeyring         []byte
+	pgpKeyPassphrase   []byte
+	verifyIntegrity    bool
+	verifyPassphrase   []byte
+	progress           ProgressFunc
+}
+
+// Option gives options to CreateWallet and OpenWallet.
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(*options)
+
+func (f optionFunc) apply(o *options) {
+	f(o)
+}
+
+// WithPassphrase sets the passphrase used to encrypt the wallet's seed.
+func WithPassphrase(passphrase []byte) Option {
+	return optionFunc(func(o *options) {
+		o.passphrase = passphrase
+	})
+}
+
+// WithSeed sets the seed for the wallet.
+// If neither this nor WithMnemonic is supplied a random seed is generated.
+func WithSeed(seed []byte) Option {
+	return optionFunc(func(o *options) {
+		o.seed = seed
+	})
+}
+
+// WithMnemonic sets the BIP-39 mnemonic from which the wallet's seed is generated.
+func WithMnemonic(mnemonic string) Option {
+	return optionFunc(func(o *options) {
+		o.mnemonic = mnemonic
+	})
+}
+
+// WithMnemonicPassphrase sets the optional BIP-39 passphrase (sometimes known as the "25th word")
+// used alongside the mnemonic to generate the wallet's seed.
+func WithMnemonicPassphrase(passphrase []byte) Option {
+	return optionFunc(func(o *options) {
+		o.mnemonicPassphrase = passphrase
+	})
+}
+
+// WithStoreMnemonic stores the mnemonic supplied by WithMnemonic in the wallet, encrypted with the wallet's
+// passphrase, so that it can be recovered later with Mnemonic().
+func WithStoreMnemonic(storeMnemonic bool) Option {
+	return optionFunc(func(o *options) {
+		o.storeMnemonic = storeMnemonic
+	})
+}
+
+// WithWalletIndex sets the wallet index, which is used in the path template in place of "%w".
+// If this is supplied without a path template, accounts use the path m/12381/3600/walletIndex/n/0.
+func WithWalletIndex(walletIndex uint64) Option {
+	return optionFunc(func(o *options) {
+		o.walletIndex = &walletIndex
+	})
+}
+
+// WithPathTemplate sets the template used to generate account paths.
+// The template must contain "%a", which is replaced by the account number, and can contain "%w", which is
+// replaced by the wallet index.
+func WithPathTemplate(pathTemplate string) Option {
+	return optionFunc(func(o *options) {
+		o.pathTemplate = pathTemplate
+	})
+}
+
+// WithPathProvider sets the provider used to generate account paths in place of the path template.
+// Paths generated by the provider are not recorded by the wallet, so the same provider must be supplied to OpenWallet
+// to create further accounts.
+func WithPathProvider(pathProvider PathProvider) Option {
+	return optionFunc(func(o *options) {
+		o.pathProvider = pathProvider
+	})
+}
+
+// WithConstrainedProgrammaticAccounts restricts programmatic accounts, obtained with AccountByPath, to paths whose
+// wallet index component (the third component with the default path template) is the wallet's index.  This prevents
+// the wallet from providing keys that belong to a different logical wallet.  Wallets whose path template does not
+// contain "%w", such as those created without a wallet index, have no wallet index component so cannot use this
+// option.  It is not stored, so must be supplied each time the wallet is opened.
+func WithConstrainedProgrammaticAccounts(constrainPaths bool) Option {
+	return optionFunc(func(o *options) {
+		o.constrainPaths = constrainPaths
+	})
+}
+
+// WithProgrammaticAccounts sets whether programmatic accounts, obtained with AccountByPath, are available.  They are
+// available by default; disabling them ensures that only accounts created in the wallet can be obtained from it.  It
+// is not stored, so must be supplied each time the wallet is opened.
+func WithProgrammaticAccounts(enabled bool) Option {
+	return optionFunc(func(o *options) {
+		o.noProgrammatic = !enabled
+	})
+}
+
+// WithProgrammaticAccountCacheSize sets the number of programmatic accounts that the wallet caches, so that repeated
+// requests for the same path do not re-derive the account.  It defaults to 256; a size of 0 disables the cache.
+// It is not stored, so must be supplied each time the wallet is opened.
+func WithProgrammaticAccountCacheSize(size int) Option {
+	return optionFunc(func(o *options) {
+		o.cacheSize = &size
+	})
+}
+
+// WithBLSBackend sets the backend used to derive and create BLS keys, in place of the default backend based on
+// go-eth2-util.  It is not stored, so must be supplied each time the wallet is opened.
+func WithBLSBackend(backend BLSBackend) Option {
+	return optionFunc(func(o *options) {
+		o.backend = backend
+	})
+}
+
+// WithDerivationWorkers sets the number of workers used to derive accounts in parallel when creating or previewing
+// multiple accounts.  It defaults to the number of CPUs; a value of 1 derives accounts serially.  It is not stored, so
+// must be supplied each time the wallet is opened.
+func WithDerivationWorkers(workers int) Option {
+	return optionFunc(func(o *options) {
+		o.workers = workers
+	})
+}
+
+// WithEncryptors supplies additional encryptors with which to decrypt the wallet and its accounts.  The encryptor
+// for the wallet, and for each account, is selected by matching the encryptor name and version declared in its
+// crypto section, so that wallets containing accounts encrypted with different encryptors remain readable.  New
+// secrets continue to be encrypted with the selected wallet encryptor.  It is not stored, so must be supplied each
+// time the wallet is opened.
+func WithEncryptors(encryptors ...wtypes.Encryptor) Option {
+	return optionFunc(func(o *options) {
+		o.encryptors = encryptors
+	})
+}
+
+// WithUnlockBackoff refuses unlock attempts on the wallet and its accounts for a period after a failed attempt,
+// returning ErrUnlockBackoff.  The period starts at base and doubles with each consecutive failure up to max, and
+// is reset by a successful unlock.  It is disabled by default.  It is not stored, so must be supplied each time the
+// wallet is opened.
+func WithUnlockBackoff(base time.Duration, max time.Duration) Option {
+	return optionFunc(func(o *options) {
+		o.unlockBackoffBase = base
+		o.unlockBackoffMax = max
+	})
+}
+
+// WithPassphrasePolicy enforces a passphrase policy on the passphrases used to encrypt the wallet and its accounts,
+// including when the wallet is created, when accounts are created or imported, and when the wallet is reseeded.  It is
+// not stored, so must be supplied each time the wallet is opened.
+func WithPassphrasePolicy(policy PassphrasePolicy) Option {
+	return optionFunc(func(o *options) {
+		o.passphrasePolicy = policy
+	})
+}
+
+// WithGuardedMemory holds the wallet's seed, when unlocked, in guarded memory provided by memguard, which is locked so
+// that it cannot be swapped to disk and is surrounded by guard pages and canaries.  The process must be permitted to
+// lock sufficient memory.  It is not stored, so must be supplied each time the wallet is opened.
+func WithGuardedMemory(enabled bool) Option {
+	return optionFunc(func(o *options) {
+		o.guardedMemory = enabled
+	})
+}
+
+// WithSealedSeed keeps the wallet's seed, when unlocked, encrypted in memory under a random key generated at unlock.
+// The seed is only decrypted for the duration of each operation that requires it, such as creating an account, which
+// reduces the time for which the plaintext seed is present in memory at the cost of slower account creation.  It
+// takes precedence over WithGuardedMemory.  It is not stored, so must be supplied each time the wallet is opened.
+func WithSealedSeed(enabled bool) Option {
+	return optionFunc(func(o *options) {
+		o.sealSeed = enabled
+	})
+}
+
+// WithKeyWrapper encrypts the wallet's seed with a data key wrapped by the key wrapper, such as a cloud key management
+// service, rather than with a passphrase, so that a host can unlock the wallet without a passphrase.  When creating a
+// wallet it cannot be supplied alongside WithPassphrase.  It is not stored, so must be supplied each time the wallet is
+// opened; the passphrase supplied to Unlock is then ignored.
+func WithKeyWrapper(wrapper KeyWrapper) Option {
+	return optionFunc(func(o *options) {
+		o.keyWrapper = wrapper
+	})
+}
+
+// WithPassphraseProvider sets the provider, such as the operating system's keychain, that holds the wallet's
+// passphrase for UnlockWithProvider.  It is not stored, so must be supplied each time the wallet is opened.
+func WithPassphraseProvider(provider PassphraseProvider) Option {
+	return optionFunc(func(o *options) {
+		o.passphraseProvider = provider
+	})
+}
+
+// WithKDFParams sets the key derivation function cost parameters with which the wallet's seed and its accounts' keys
+// are encrypted, in place of those of the encryptor, which must be a keystore version 4 encryptor.  Keys encrypted
+// with other parameters remain readable.  It is not stored, so must be supplied each time the wallet is opened.
+func WithKDFParams(params KDFParams) Option {
+	return optionFunc(func(o *options) {
+		o.kdfParams = &params
+	})
+}
+
+// WithWalletPassphraseForAccounts encrypts accounts created without a passphrase with the passphrase with which the
+// wallet was unlocked, for deployments that treat the wallet as the single secrecy boundary.  The wallet passphrase is
+// held in memory while the wallet is unlocked.  It is not stored, so must be supplied each time the wallet is opened.
+func WithWalletPassphraseForAccounts(enabled bool) Option {
+	return optionFunc(func(o *options) {
+		o.sharedPassphrase = enabled
+	})
+}
+
+// WithMinSeedLength sets the minimum length of seed accepted by WithSeed.
+// This must be between 32 (the minimum permitted by EIP-2333) and 64 bytes; it defaults to 32.
+func WithMinSeedLength(length int) Option {
+	return optionFunc(func(o *options) {
+		o.minSeedLength = length
+	})
+}
+
+// WithGapLimit sets the number of consecutive unused accounts after which RecoverWallet stops scanning.
+// It defaults to 20.
+func WithGapLimit(gapLimit int) Option {
+	return optionFunc(func(o *options) {
+		o.gapLimit = gapLimit
+	})
+}
+
+// WithDeterministicAccountIDs generates the IDs of the wallet's accounts from their public keys rather than at random,
+// so that the same key has the same ID regardless of the machine on which its account is created.
+func WithDeterministicAccountIDs(deterministicIDs bool) Option {
+	return optionFunc(func(o *options) {
+		o.deterministicIDs = deterministicIDs
+	})
+}
+
+// WithMergeImport allows Import to merge the accounts of an exported wallet in to an existing wallet of the same name,
+// rather than refusing the import.  The passphrase must decrypt the seeds of both the existing and exported wallets,
+// which must be the same.  Accounts whose public keys are already in the existing wallet are skipped.
+func WithMergeImport(passphrase []byte) Option {
+	return optionFunc(func(o *options) {
+		o.mergeImport = true
+		o.mergePassphrase = passphrase
+	})
+}
+
+// WithConflictRename allows Import to import a wallet whose name is already in use by appending the suffix to its name,
+// so that an export can be restored alongside the original wallet.  The renamed wallet is given a new ID if its ID is
+// also in use.
+func WithConflictRename(suffix string) Option {
+	return optionFunc(func(o *options) {
+		o.conflictSuffix = suffix
+	})
+}
+
+// WithNewWalletName sets the name under which Import imports the wallet, in place of the name in the export.  The
+// renamed wallet is given a new ID if its ID is already in use.
+func WithNewWalletName(name string) Option {
+	return optionFunc(func(o *options) {
+		o.newWalletName = name
+	})
+}
+
+// WithAgeIdentities supplies the age X25519 identities, for example "AGE-SECRET-KEY-1...", with which Import decrypts
+// an export made with ExportToRecipients.
+func WithAgeIdentities(identities ...string) Option {
+	return optionFunc(func(o *options) {
+		o.ageIdentities = identities
+	})
+}
+
+// WithOpenPGPKeys supplies the armored OpenPGP private keys with which Import decrypts an export made with
+// ExportToOpenPGP.  If the keys are protected by a passphrase it must also be supplied.
+func WithOpenPGPKeys(keyring []byte, keyPassphrase []byte) Option {
+	return optionFunc(func(o *options) {
+		o.pgpKeyring = keyring
+		o.pgpKeyPassphrase = keyPassphrase
+	})
+}
+
+// WithIntegrityCheck requires Import to verify the integrity tag of an export before anything is written to the
+// store.  The tag is present only if the wallet was unlocked when exported, as reported by ValidateImport, and exports
+// without a tag are rejected.  The tag is verified with a key derived from the wallet's seed, so the passphrase must
+// decrypt the seed of the exported wallet.
+func WithIntegrityCheck(passphrase []byte) Option {
+	return optionFunc(func(o *options) {
+		o.verifyIntegrity = true
+		o.verifyPassphrase = passphrase
+	})
+}
+
+// WithProgress sets a function that is called as each account is processed when the wallet is exported, or when it
+// is imported by Import, so that progress can be shown for wallets with many accounts.
+func WithProgress(fn ProgressFunc) Option {
+	return optionFunc(func(o *options) {
+		o.progress = fn
+	})
+}
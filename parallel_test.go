@@ -0,0 +1,55 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestDerivationWorkers(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	names := make([]string, 10)
+	for i := range names {
+		names[i] = fmt.Sprintf("Account %d", i)
+	}
+
+	publicKeys := make(map[int][][]byte)
+	for _, workers := range []int{1, 4, 16} {
+		wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New(), hd.WithSeed(seed), hd.WithDerivationWorkers(workers))
+		require.NoError(t, err)
+		require.NoError(t, wallet.Unlock(nil))
+
+		previews, err := wallet.(hd.WalletAccountsPreviewer).PreviewAccounts(len(names))
+		require.NoError(t, err)
+		accounts, err := wallet.(hd.WalletAccountsCreator).CreateAccounts(names, nil)
+		require.NoError(t, err)
+		require.Len(t, accounts, len(names))
+		for i, account := range accounts {
+			assert.Equal(t, names[i], account.Name())
+			assert.Equal(t, fmt.Sprintf("m/12381/3600/%d/0", i), account.Path())
+			assert.Equal(t, previews[i].Path, account.Path())
+			assert.Equal(t, previews[i].PublicKey.Marshal(), account.PublicKey().Marshal())
+			publicKeys[workers] = append(publicKeys[workers], account.PublicKey().Marshal())
+		}
+	}
+	assert.Equal(t, publicKeys[1], publicKeys[4])
+	assert.Equal(t, publicKeys[1], publicKeys[16])
+}
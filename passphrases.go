@@ -0,0 +1,128 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"github.com/pkg/errors"
+)
+
+// AddPassphrase adds a passphrase with which the wallet can be unlocked, alongside its existing passphrases.  This
+// allows, for example, an operator passphrase and a sealed recovery passphrase to both unlock the wallet.  The
+// supplied passphrase can be any of the wallet's existing passphrases.
+//
+// Only the wallet's primary passphrase, with which it was created, provides access to a stored mnemonic.
+func (w *wallet) AddPassphrase(passphrase []byte, newPassphrase []byte) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	if w.version < 2 {
+		return errors.New("wallet must be migrated to support additional passphrases")
+	}
+	if w.threshold > 0 {
+		return errors.New("wallet requires threshold unlock")
+	}
+	if err := w.checkPassphrase(newPassphrase); err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	seed, err := w.decryptSeed(passphrase)
+	if err != nil {
+		return errors.New("incorrect passphrase")
+	}
+	defer zeroBytes(seed)
+	if _, err := w.decryptSeed(newPassphrase); err == nil {
+		return errors.New("passphrase already unlocks the wallet")
+	}
+
+	crypto, err := encryptSecret(w.encryptor, seed, newPassphrase)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt seed")
+	}
+	w.additionalCrypto = append(w.additionalCrypto, crypto)
+	if err := w.storeWallet(); err != nil {
+		w.additionalCrypto = w.additionalCrypto[:len(w.additionalCrypto)-1]
+		return errors.Wrap(err, "failed to store wallet")
+	}
+
+	return nil
+}
+
+// RemovePassphrase removes a passphrase added with AddPassphrase, so that it no longer unlocks the wallet.  The
+// wallet's primary passphrase cannot be removed.
+func (w *wallet) RemovePassphrase(passphrase []byte) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if seed, err := decryptSecret(w.encryptor, w.crypto, passphrase); err == nil {
+		zeroBytes(seed)
+		return errors.New("cannot remove the primary passphrase")
+	}
+	for i := range w.additionalCrypto {
+		seed, err := decryptSecret(w.encryptor, w.additionalCrypto[i], passphrase)
+		if err != nil {
+			continue
+		}
+		zeroBytes(seed)
+		remaining := make([]map[string]interface{}, 0, len(w.additionalCrypto)-1)
+		remaining = append(remaining, w.additionalCrypto[:i]...)
+		remaining = append(remaining, w.additionalCrypto[i+1:]...)
+		additionalCrypto := w.additionalCrypto
+		w.additionalCrypto = remaining
+		if err := w.storeWallet(); err != nil {
+			w.additionalCrypto = additionalCrypto
+			return errors.Wrap(err, "failed to store wallet")
+		}
+		return nil
+	}
+
+	return errors.New("incorrect passphrase")
+}
+
+// decryptSeed decrypts the wallet's seed with any of its passphrases.
+// The caller must hold the wallet's lock.
+func (w *wallet) decryptSeed(passphrase []byte) ([]byte, error) {
+	seed, err := w.openSecret(w.crypto, passphrase)
+	if err == nil {
+		return seed, nil
+	}
+	for _, crypto := range w.additionalCrypto {
+		if seed, additionalErr := decryptSecret(w.encryptor, crypto, passphrase); additionalErr == nil {
+			return seed, nil
+		}
+	}
+	return nil, err
+}
+
+// accountPassphrase provides the passphrase with which to encrypt a new account.  If the wallet uses its passphrase
+// for accounts and no passphrase is supplied then this is the wallet passphrase.
+func (w *wallet) accountPassphrase(passphrase []byte) ([]byte, error) {
+	if !w.sharedPassphrase || len(passphrase) != 0 {
+		return passphrase, nil
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if w.passphrase == nil {
+		return nil, errors.New("wallet passphrase not available for accounts")
+	}
+	return w.passphrase, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestMultiplePassphrases(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	primary := []byte("primary passphrase")
+	recovery := []byte("recovery passphrase")
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase(primary))
+	require.NoError(t, err)
+	manager, isManager := wallet.(hd.WalletPassphraseManager)
+	require.True(t, isManager)
+
+	assert.EqualError(t, manager.AddPassphrase([]byte("wrong"), recovery), "incorrect passphrase")
+	assert.EqualError(t, manager.AddPassphrase(primary, primary), "passphrase already unlocks the wallet")
+	require.NoError(t, manager.AddPassphrase(primary, recovery))
+	assert.EqualError(t, manager.AddPassphrase(recovery, recovery), "passphrase already unlocks the wallet")
+
+	// Either passphrase unlocks the wallet once reopened, and both provide the same accounts.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(primary))
+	account, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	wallet.Lock()
+	require.NoError(t, wallet.Unlock(recovery))
+	key1, err := wallet.(hd.WalletNextAccountPublicKeyProvider).NextAccountPublicKey()
+	require.NoError(t, err)
+	wallet.Lock()
+	require.NoError(t, wallet.Unlock(primary))
+	key2, err := wallet.(hd.WalletNextAccountPublicKeyProvider).NextAccountPublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, key2.Marshal(), key1.Marshal())
+	assert.NotEqual(t, account.PublicKey().Marshal(), key1.Marshal())
+	assert.NotNil(t, wallet.Unlock([]byte("wrong")))
+
+	manager = wallet.(hd.WalletPassphraseManager)
+	assert.EqualError(t, manager.RemovePassphrase(primary), "cannot remove the primary passphrase")
+	assert.EqualError(t, manager.RemovePassphrase([]byte("wrong")), "incorrect passphrase")
+	require.NoError(t, manager.RemovePassphrase(recovery))
+
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.EqualError(t, wallet.Unlock(recovery), "incorrect passphrase")
+	require.NoError(t, wallet.Unlock(primary))
+}
+
+func TestWalletPassphraseForAccounts(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	passphrase := []byte("wallet passphrase")
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor,
+		hd.WithPassphrase(passphrase),
+		hd.WithWalletPassphraseForAccounts(true))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+
+	// An account created without a passphrase uses the wallet passphrase.
+	account, err := wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	require.Error(t, account.Unlock(nil))
+	require.NoError(t, account.Unlock(passphrase))
+
+	// An account created with a passphrase uses that passphrase.
+	account, err = wallet.CreateAccount("Account 2", []byte("account passphrase"))
+	require.NoError(t, err)
+	require.NoError(t, account.Unlock([]byte("account passphrase")))
+
+	accounts, err := wallet.(hd.WalletAccountsCreator).CreateAccounts([]string{"Account 3", "Account 4"}, nil)
+	require.NoError(t, err)
+	for _, account := range accounts {
+		require.NoError(t, account.Unlock(passphrase))
+	}
+
+	// The wallet passphrase is not held once the wallet is locked.
+	wallet.Lock()
+	_, err = wallet.CreateAccount("Account 5", nil)
+	assert.EqualError(t, err, "wallet must be unlocked to create accounts")
+
+	// Without the option accounts are encrypted with the supplied passphrase.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+	account, err = wallet.CreateAccount("Account 5", nil)
+	require.NoError(t, err)
+	require.NoError(t, account.Unlock(nil))
+}
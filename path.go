@@ -0,0 +1,181 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// eip2334Purpose is the purpose component of EIP-2334 paths.
+	eip2334Purpose = 12381
+	// eip2334Coin is the coin type component of EIP-2334 paths.
+	eip2334Coin = 3600
+	// minPathDepth is the minimum number of components after "m" in an EIP-2334 path: purpose, coin type and account.
+	minPathDepth = 3
+	// maxPathDepth is the maximum number of components after "m" in a path.  EIP-2334 paths have up to five components
+	// (purpose, coin type, wallet index, account and use) but paths can be extended with further components.
+	maxPathDepth = 16
+	// internalPathRoot is the root of the branch from which the wallet derives keys for its own use, such as sub-wallet
+	// seeds.  Its coin type is not that of EIP-2334, so it cannot be reached by AccountByPath, and account keys are
+	// never derived beneath it whatever the wallet's path template.
+	internalPathRoot = "m/12381/2147483647"
+)
+
+// walletIndexComponent provides the position of the wallet index in the components of the wallet's paths as returned
+// by ParsePath, or -1 if its paths do not contain the wallet index, as is the case for wallets with the legacy path
+// template or a path provider.
+func (w *wallet) walletIndexComponent() int {
+	if w.pathProvider != nil {
+		return -1
+	}
+	for i, component := range strings.Split(w.pathTemplate, "/") {
+		if component == "%w" {
+			return i - 1
+		}
+	}
+	return -1
+}
+
+// ParsePath parses an EIP-2334 path, returning its components after "m".
+// All components of EIP-2334 paths are hardened, so are written without the "'" marker and must be less than 2^31.
+// This will error if the path does not start with the EIP-2334 purpose and coin type, or if it is too short or too
+// long.
+func ParsePath(path string) ([]uint64, error) {
+	components := strings.Split(path, "/")
+	if components[0] != "m" {
+		return nil, fmt.Errorf(`path %q does not start with "m"`, path)
+	}
+	components = components[1:]
+	if len(components) < minPathDepth || len(components) > maxPathDepth {
+		return nil, fmt.Errorf("path %q must have between %d and %d components", path, minPathDepth, maxPathDepth)
+	}
+	values := make([]uint64, len(components))
+	for i, component := range components {
+		if strings.HasSuffix(component, "'") {
+			return nil, fmt.Errorf("path component %q must not be marked as hardened", component)
+		}
+		value, err := strconv.ParseUint(component, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("path component %q invalid", component)
+		}
+		values[i] = value
+	}
+	if values[0] != eip2334Purpose {
+		return nil, fmt.Errorf("path purpose %d invalid; must be %d", values[0], eip2334Purpose)
+	}
+	if values[1] != eip2334Coin {
+		return nil, fmt.Errorf("path coin type %d invalid; must be %d", values[1], eip2334Coin)
+	}
+	return values, nil
+}
+
+// ValidatePath ensures that a path is a valid EIP-2334 path.
+func ValidatePath(path string) error {
+	_, err := ParsePath(path)
+	return err
+}
+
+// isInternalPath returns true if the path is in the branch reserved for keys that the wallet uses itself.
+func isInternalPath(path string) bool {
+	return path == internalPathRoot || strings.HasPrefix(path, internalPathRoot+"/")
+}
+
+// DerivationPath is the parsed form of an account's derivation path.
+type DerivationPath struct {
+	// Purpose is the purpose component of the path, which is 12381 for EIP-2334 paths.
+	Purpose uint64
+	// Coin is the coin type component of the path, which is 3600 for EIP-2334 paths.
+	Coin uint64
+	// WalletIndex is the wallet index of the path.  It is the wallet's index if the path template does not contain
+	// the wallet index.
+	WalletIndex uint64
+	// AccountIndex is the account number of the path.
+	AccountIndex uint64
+	// Use is the final component of the path, if the path template ends with a fixed component after the account
+	// number; for the default templates 0 is the signing key.  It is nil for withdrawal accounts.
+	Use *uint64
+	// Extension is the additional components appended to the path of an extended account.
+	Extension []uint64
+}
+
+// DerivationPath provides the parsed derivation path of the account.
+// This will error if the account's path was not generated from its wallet's path template.
+func (a *account) DerivationPath() (*DerivationPath, error) {
+	w, ok := a.wallet.(*wallet)
+	if !ok {
+		return nil, fmt.Errorf("path %q cannot be parsed", a.path)
+	}
+	return w.parsePath(a.path)
+}
+
+// parsePath parses a path generated from the wallet's path template, or a withdrawal path generated from it.
+func (w *wallet) parsePath(path string) (*DerivationPath, error) {
+	templateComponents := strings.Split(w.pathTemplate, "/")
+	components := strings.Split(path, "/")
+	var extension []string
+	if len(components) > len(templateComponents) {
+		// Extended path.
+		extension = components[len(templateComponents):]
+		components = components[:len(templateComponents)]
+	}
+	withdrawal := false
+	if len(components) == len(templateComponents)-1 && strings.HasSuffix(w.pathTemplate, "/0") {
+		// Withdrawal path.
+		withdrawal = true
+		templateComponents = templateComponents[:len(templateComponents)-1]
+	}
+	if len(components) != len(templateComponents) || len(components) < 3 || components[0] != "m" {
+		return nil, fmt.Errorf("path %q does not match path template %q", path, w.pathTemplate)
+	}
+
+	values := make([]uint64, len(components))
+	derivationPath := &DerivationPath{
+		WalletIndex: w.walletIndex,
+	}
+	for i := 1; i < len(components); i++ {
+		value, err := strconv.ParseUint(components[i], 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("path component %q invalid", components[i])
+		}
+		values[i] = value
+		switch templateComponents[i] {
+		case "%a":
+			derivationPath.AccountIndex = value
+		case "%w":
+			derivationPath.WalletIndex = value
+		default:
+			if templateComponents[i] != components[i] {
+				return nil, fmt.Errorf("path %q does not match path template %q", path, w.pathTemplate)
+			}
+		}
+	}
+	derivationPath.Purpose = values[1]
+	derivationPath.Coin = values[2]
+	last := len(templateComponents) - 1
+	if !withdrawal && templateComponents[last] != "%a" && templateComponents[last] != "%w" {
+		derivationPath.Use = &values[last]
+	}
+	for _, component := range extension {
+		value, err := strconv.ParseUint(component, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("path component %q invalid", component)
+		}
+		derivationPath.Extension = append(derivationPath.Extension, value)
+	}
+
+	return derivationPath, nil
+}
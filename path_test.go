@@ -0,0 +1,313 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestDerivationPath(t *testing.T) {
+	signing := uint64(0)
+	tests := []struct {
+		name       string
+		opts       []hd.Option
+		withdrawal bool
+		path       string
+		res        *hd.DerivationPath
+	}{
+		{
+			name: "Legacy",
+			path: "m/12381/3600/2/0",
+			res:  &hd.DerivationPath{Purpose: 12381, Coin: 3600, AccountIndex: 2, Use: &signing},
+		},
+		{
+			name: "Indexed",
+			opts: []hd.Option{hd.WithWalletIndex(5)},
+			path: "m/12381/3600/5/2/0",
+			res:  &hd.DerivationPath{Purpose: 12381, Coin: 3600, WalletIndex: 5, AccountIndex: 2, Use: &signing},
+		},
+		{
+			name:       "Withdrawal",
+			opts:       []hd.Option{hd.WithWalletIndex(5)},
+			withdrawal: true,
+			path:       "m/12381/3600/5/2",
+			res:        &hd.DerivationPath{Purpose: 12381, Coin: 3600, WalletIndex: 5, AccountIndex: 2},
+		},
+		{
+			name: "CustomTemplate",
+			opts: []hd.Option{hd.WithWalletIndex(5), hd.WithPathTemplate("m/12381/60/%a/%w")},
+			path: "m/12381/60/2/5",
+			res:  &hd.DerivationPath{Purpose: 12381, Coin: 60, WalletIndex: 5, AccountIndex: 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New(), test.opts...)
+			require.NoError(t, err)
+			require.NoError(t, wallet.Unlock(nil))
+			require.NoError(t, wallet.(hd.WalletNextAccountSetter).SetNextAccount(2))
+			var account wtypes.Account
+			if test.withdrawal {
+				account, err = wallet.(hd.WalletWithdrawalAccountCreator).CreateWithdrawalAccount("Withdrawal", nil)
+			} else {
+				account, err = wallet.CreateAccount("Account", nil)
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.path, account.Path())
+			provider, isProvider := account.(hd.AccountDerivationPathProvider)
+			require.True(t, isProvider)
+			res, err := provider.DerivationPath()
+			require.NoError(t, err)
+			assert.Equal(t, test.res, res)
+		})
+	}
+}
+
+func TestDerivationPathProgrammatic(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.AccountByName("m/12381/3600/1/2/3")
+	require.NoError(t, err)
+	_, err = account.(hd.AccountDerivationPathProvider).DerivationPath()
+	assert.EqualError(t, err, `path "m/12381/3600/1/2/3" does not match path template "m/12381/3600/%a/0"`)
+}
+
+// shardPathProvider is a path provider that encodes a shard in the path.
+type shardPathProvider struct {
+	shard uint64
+}
+
+func (p *shardPathProvider) PathFor(walletIndex uint64, accountIndex uint64) string {
+	return fmt.Sprintf("m/12381/3600/%d/%d/%d", walletIndex, p.shard, accountIndex)
+}
+
+func TestPathProvider(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	provider := &shardPathProvider{shard: 7}
+
+	_, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPathProvider(provider), hd.WithPathTemplate("m/12381/3600/%a"))
+	assert.EqualError(t, err, "cannot supply both path template and path provider")
+
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithWalletIndex(3), hd.WithPathProvider(provider))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account, err := wallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/3/7/0", account.Path())
+	_, err = wallet.(hd.WalletWithdrawalAccountCreator).CreateWithdrawalAccount("Withdrawal", nil)
+	assert.EqualError(t, err, "path template does not support withdrawal accounts")
+
+	_, err = hd.OpenWallet("test wallet", store, encryptor)
+	assert.EqualError(t, err, "wallet requires a path provider")
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor, hd.WithPathProvider(provider))
+	require.NoError(t, err)
+	require.NoError(t, reopened.Unlock(nil))
+	account, err = reopened.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/3/7/1", account.Path())
+
+	_, err = hd.CreateWallet("template wallet", store, encryptor)
+	require.NoError(t, err)
+	_, err = hd.OpenWallet("template wallet", store, encryptor, hd.WithPathProvider(provider))
+	assert.EqualError(t, err, "wallet does not use a path provider")
+}
+
+// badPathProvider is a path provider that generates invalid paths.
+type badPathProvider struct{}
+
+func (p *badPathProvider) PathFor(walletIndex uint64, accountIndex uint64) string {
+	return fmt.Sprintf("n/%d/%d", walletIndex, accountIndex)
+}
+
+func TestPathProviderInvalid(t *testing.T) {
+	_, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New(), hd.WithPathProvider(&badPathProvider{}))
+	assert.EqualError(t, err, `path provider invalid: path must start with "m/"`)
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		res  []uint64
+		err  string
+	}{
+		{
+			name: "Empty",
+			path: "",
+			err:  `path "" does not start with "m"`,
+		},
+		{
+			name: "NoMaster",
+			path: "12381/3600/0/0",
+			err:  `path "12381/3600/0/0" does not start with "m"`,
+		},
+		{
+			name: "TooShort",
+			path: "m/12381/3600",
+			err:  `path "m/12381/3600" must have between 3 and 16 components`,
+		},
+		{
+			name: "TooLong",
+			path: "m/12381/3600/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0",
+			err:  `path "m/12381/3600/0/0/0/0/0/0/0/0/0/0/0/0/0/0/0" must have between 3 and 16 components`,
+		},
+		{
+			name: "HardenedMarker",
+			path: "m/12381/3600/0'/0",
+			err:  `path component "0'" must not be marked as hardened`,
+		},
+		{
+			name: "ComponentInvalid",
+			path: "m/12381/3600/x/0",
+			err:  `path component "x" invalid`,
+		},
+		{
+			name: "ComponentTooLarge",
+			path: "m/12381/3600/2147483648/0",
+			err:  `path component "2147483648" invalid`,
+		},
+		{
+			name: "PurposeInvalid",
+			path: "m/44/3600/0/0",
+			err:  "path purpose 44 invalid; must be 12381",
+		},
+		{
+			name: "CoinInvalid",
+			path: "m/12381/60/0/0",
+			err:  "path coin type 60 invalid; must be 3600",
+		},
+		{
+			name: "Withdrawal",
+			path: "m/12381/3600/1",
+			res:  []uint64{12381, 3600, 1},
+		},
+		{
+			name: "Signing",
+			path: "m/12381/3600/1/0",
+			res:  []uint64{12381, 3600, 1, 0},
+		},
+		{
+			name: "Indexed",
+			path: "m/12381/3600/2/1/0",
+			res:  []uint64{12381, 3600, 2, 1, 0},
+		},
+		{
+			name: "Extended",
+			path: "m/12381/3600/2/1/0/7",
+			res:  []uint64{12381, 3600, 2, 1, 0, 7},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := hd.ParsePath(test.path)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				assert.EqualError(t, hd.ValidatePath(test.path), test.err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.res, res)
+				assert.NoError(t, hd.ValidatePath(test.path))
+			}
+		})
+	}
+}
+
+func TestProgrammaticAccountInvalidPath(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.AccountByName("m/12381/60/1/0")
+	assert.EqualError(t, err, "invalid account path: path coin type 60 invalid; must be 3600")
+}
+
+func TestConstrainedProgrammaticAccounts(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithWalletIndex(2), hd.WithConstrainedProgrammaticAccounts(true))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.AccountByName("m/12381/3600/3/0/0")
+	assert.EqualError(t, err, `path "m/12381/3600/3/0/0" is outside of wallet index 2`)
+	account, err := wallet.AccountByName("m/12381/3600/2/0/0")
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/2/0/0", account.Path())
+
+	// The constraint is not stored.
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Unlock(nil))
+	_, err = reopened.AccountByName("m/12381/3600/3/0/0")
+	require.NoError(t, err)
+	reopened, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithConstrainedProgrammaticAccounts(true))
+	require.NoError(t, err)
+	require.NoError(t, reopened.Unlock(nil))
+	_, err = reopened.AccountByName("m/12381/3600/3/0/0")
+	assert.EqualError(t, err, `path "m/12381/3600/3/0/0" is outside of wallet index 2`)
+}
+
+func TestConstrainedProgrammaticAccountsLegacy(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+
+	// Legacy paths have no wallet index component to constrain.
+	_, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithConstrainedProgrammaticAccounts(true))
+	assert.EqualError(t, err, "constrained programmatic accounts require a path template containing the wallet index")
+	_, err = hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	_, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithConstrainedProgrammaticAccounts(true))
+	assert.EqualError(t, err, "constrained programmatic accounts require a path template containing the wallet index")
+
+	// The wallet index component is found from the path template.
+	wallet, err := hd.CreateWallet("custom wallet", store, encryptor, hd.WithWalletIndex(2), hd.WithPathTemplate("m/12381/3600/%a/%w/0"), hd.WithConstrainedProgrammaticAccounts(true))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.AccountByName("m/12381/3600/2/5/0")
+	assert.EqualError(t, err, `path "m/12381/3600/2/5/0" is outside of wallet index 2`)
+	_, err = wallet.AccountByName("m/12381/3600/5/2/0")
+	require.NoError(t, err)
+	_, err = wallet.AccountByName("m/12381/3600/5")
+	assert.EqualError(t, err, `path "m/12381/3600/5" is outside of wallet index 2`)
+}
+
+func TestProgrammaticAccountsDisabled(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithProgrammaticAccounts(false))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.AccountByName("m/12381/3600/0/0")
+	assert.Equal(t, hd.ErrProgrammaticAccountsDisabled, err)
+	account, err := wallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	_, err = wallet.AccountByName("Account")
+	require.NoError(t, err)
+
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor, hd.WithProgrammaticAccounts(false))
+	require.NoError(t, err)
+	require.NoError(t, reopened.Unlock(nil))
+	_, err = reopened.AccountByName(account.Path())
+	assert.Equal(t, hd.ErrProgrammaticAccountsDisabled, err)
+}
@@ -0,0 +1,186 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+)
+
+// pkcs11NonceSize is the size of the AES-GCM nonce used when wrapping keys on a PKCS#11 token.
+const pkcs11NonceSize = 12
+
+// PKCS11KeyWrapper is a key wrapper that wraps data keys with an AES secret key held on a PKCS#11 token, such as a
+// hardware security module, using AES-GCM.  The secret key never leaves the token, so the wallet can only be unlocked
+// with the token present and its PIN.
+type PKCS11KeyWrapper struct {
+	name    string
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	key     pkcs11.ObjectHandle
+	mutex   sync.Mutex
+}
+
+// NewPKCS11KeyWrapper creates a key wrapper for the AES secret key with the given label on the token with the given
+// label, logging in to the token with the PIN.  modulePath is the path to the token's PKCS#11 library.  The key
+// wrapper should be closed when it is no longer required.
+func NewPKCS11KeyWrapper(modulePath string, tokenLabel string, pin string, keyLabel string) (*PKCS11KeyWrapper, error) {
+	ctx := pkcs11.New(modulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %q", modulePath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, errors.Wrap(err, "failed to initialise PKCS#11 module")
+	}
+	w := &PKCS11KeyWrapper{
+		name: fmt.Sprintf("pkcs11:token=%s;object=%s", tokenLabel, keyLabel),
+		ctx:  ctx,
+	}
+	if err := w.open(tokenLabel, pin, keyLabel); err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// open opens a session on the token and finds the key.
+func (w *PKCS11KeyWrapper) open(tokenLabel string, pin string, keyLabel string) error {
+	slots, err := w.ctx.GetSlotList(true)
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain PKCS#11 slots")
+	}
+	found := false
+	var slot uint
+	for _, candidate := range slots {
+		info, err := w.ctx.GetTokenInfo(candidate)
+		if err == nil && info.Label == tokenLabel {
+			slot = candidate
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("PKCS#11 token %q not found", tokenLabel)
+	}
+
+	w.session, err = w.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return errors.Wrap(err, "failed to open PKCS#11 session")
+	}
+	if err := w.ctx.Login(w.session, pkcs11.CKU_USER, pin); err != nil {
+		w.ctx.CloseSession(w.session)
+		return errors.Wrap(err, "failed to log in to PKCS#11 token")
+	}
+
+	if err := w.ctx.FindObjectsInit(w.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyLabel),
+	}); err != nil {
+		w.closeSession()
+		return errors.Wrap(err, "failed to search for PKCS#11 key")
+	}
+	objects, _, err := w.ctx.FindObjects(w.session, 1)
+	finalErr := w.ctx.FindObjectsFinal(w.session)
+	if err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		w.closeSession()
+		return errors.Wrap(err, "failed to search for PKCS#11 key")
+	}
+	if len(objects) == 0 {
+		w.closeSession()
+		return fmt.Errorf("PKCS#11 key %q not found", keyLabel)
+	}
+	w.key = objects[0]
+
+	return nil
+}
+
+// Name provides the name of the key wrapper.
+func (w *PKCS11KeyWrapper) Name() string {
+	return w.name
+}
+
+// WrapKey wraps a data key with the token's key.
+func (w *PKCS11KeyWrapper) WrapKey(key []byte) ([]byte, error) {
+	nonce := make([]byte, pkcs11NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	params := pkcs11.NewGCMParams(nonce, nil, 128)
+	defer params.Free()
+	if err := w.ctx.EncryptInit(w.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, w.key); err != nil {
+		return nil, errors.Wrap(err, "failed to initialise PKCS#11 encryption")
+	}
+	wrappedKey, err := w.ctx.Encrypt(w.session, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt with PKCS#11 token")
+	}
+	// Some tokens generate their own nonce, so use the nonce that was actually used.
+	if iv := params.IV(); len(iv) == pkcs11NonceSize {
+		nonce = iv
+	}
+
+	return append(nonce, wrappedKey...), nil
+}
+
+// UnwrapKey unwraps a data key with the token's key.
+func (w *PKCS11KeyWrapper) UnwrapKey(wrappedKey []byte) ([]byte, error) {
+	if len(wrappedKey) <= pkcs11NonceSize {
+		return nil, errors.New("wrapped key too short")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	params := pkcs11.NewGCMParams(wrappedKey[:pkcs11NonceSize], nil, 128)
+	defer params.Free()
+	if err := w.ctx.DecryptInit(w.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, w.key); err != nil {
+		return nil, errors.Wrap(err, "failed to initialise PKCS#11 decryption")
+	}
+	key, err := w.ctx.Decrypt(w.session, wrappedKey[pkcs11NonceSize:])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt with PKCS#11 token")
+	}
+
+	return key, nil
+}
+
+// Close logs out of the token and releases the PKCS#11 module.
+func (w *PKCS11KeyWrapper) Close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.closeSession()
+	w.ctx.Finalize()
+	w.ctx.Destroy()
+}
+
+// closeSession logs out of the token and closes the session.
+func (w *PKCS11KeyWrapper) closeSession() {
+	w.ctx.Logout(w.session)
+	w.ctx.CloseSession(w.session)
+}
@@ -0,0 +1,57 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestPKCS11KeyWrapperMissingModule(t *testing.T) {
+	_, err := hd.NewPKCS11KeyWrapper("/nonexistent/libpkcs11.so", "token", "1234", "key")
+	assert.EqualError(t, err, `failed to load PKCS#11 module "/nonexistent/libpkcs11.so"`)
+}
+
+// TestPKCS11KeyWrapper runs against a token, such as one provided by SoftHSM, given by environment variables.
+// The token must hold an AES secret key with the given label.
+func TestPKCS11KeyWrapper(t *testing.T) {
+	module := os.Getenv("PKCS11_MODULE")
+	if module == "" {
+		t.Skip("PKCS11_MODULE not set")
+	}
+	wrapper, err := hd.NewPKCS11KeyWrapper(module, os.Getenv("PKCS11_TOKEN"), os.Getenv("PKCS11_PIN"), os.Getenv("PKCS11_KEY"))
+	require.NoError(t, err)
+	defer wrapper.Close()
+
+	key := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	wrappedKey, err := wrapper.WrapKey(key)
+	require.NoError(t, err)
+	unwrappedKey, err := wrapper.UnwrapKey(wrappedKey)
+	require.NoError(t, err)
+	assert.Equal(t, key, unwrappedKey)
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	_, err = hd.CreateWallet("test wallet", store, encryptor, hd.WithKeyWrapper(wrapper))
+	require.NoError(t, err)
+	wallet, err := hd.OpenWallet("test wallet", store, encryptor, hd.WithKeyWrapper(wrapper))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+}
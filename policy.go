@@ -0,0 +1,108 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// PassphrasePolicy is the interface for policies that passphrases must meet.  A policy can be supplied with
+// WithPassphrasePolicy, in which case it is enforced whenever a wallet or account is encrypted with a new passphrase.
+type PassphrasePolicy interface {
+	// Check returns an error if the passphrase does not meet the policy.
+	Check(passphrase []byte) error
+}
+
+// BasicPassphrasePolicy is a passphrase policy with a minimum length, a minimum estimated entropy and a list of
+// denied passphrases.  Zero values disable the relevant check.
+type BasicPassphrasePolicy struct {
+	// MinLength is the minimum number of characters in the passphrase.
+	MinLength int
+	// MinEntropy is the minimum estimated entropy of the passphrase, in bits.
+	MinEntropy float64
+	// Denylist contains passphrases that are not permitted, compared without regard to case.
+	Denylist []string
+}
+
+// Check returns an error if the passphrase does not meet the policy.
+func (p *BasicPassphrasePolicy) Check(passphrase []byte) error {
+	if !utf8.Valid(passphrase) {
+		return errors.New("passphrase is not valid UTF-8")
+	}
+	if length := utf8.RuneCount(passphrase); length < p.MinLength {
+		return fmt.Errorf("passphrase must be at least %d characters", p.MinLength)
+	}
+	for _, denied := range p.Denylist {
+		if strings.EqualFold(string(passphrase), denied) {
+			return errors.New("passphrase is not permitted")
+		}
+	}
+	if p.MinEntropy > 0 && passphraseEntropy(string(passphrase)) < p.MinEntropy {
+		return fmt.Errorf("passphrase must have an estimated entropy of at least %v bits", p.MinEntropy)
+	}
+	return nil
+}
+
+// passphraseEntropy estimates the entropy of a passphrase, in bits, from its length and the classes of characters
+// that it contains.
+func passphraseEntropy(passphrase string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range passphrase {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case r < unicode.MaxASCII && unicode.IsDigit(r):
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	poolSize := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{
+		{lower, 26},
+		{upper, 26},
+		{digit, 10},
+		{symbol, 33},
+		{other, 100},
+	} {
+		if class.present {
+			poolSize += class.size
+		}
+	}
+	if poolSize == 0 {
+		return 0
+	}
+	return float64(utf8.RuneCountInString(passphrase)) * math.Log2(float64(poolSize))
+}
+
+// checkPassphrase returns an error if the passphrase does not meet the wallet's passphrase policy.
+func (w *wallet) checkPassphrase(passphrase []byte) error {
+	if w.passphrasePolicy == nil {
+		return nil
+	}
+	return w.passphrasePolicy.Check(passphrase)
+}
@@ -0,0 +1,111 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestBasicPassphrasePolicy(t *testing.T) {
+	policy := &hd.BasicPassphrasePolicy{
+		MinLength:  8,
+		MinEntropy: 50,
+		Denylist:   []string{"Password123!"},
+	}
+	tests := []struct {
+		name       string
+		passphrase string
+		err        string
+	}{
+		{
+			name:       "Short",
+			passphrase: "abc",
+			err:        "passphrase must be at least 8 characters",
+		},
+		{
+			name:       "Denied",
+			passphrase: "password123!",
+			err:        "passphrase is not permitted",
+		},
+		{
+			name:       "LowEntropy",
+			passphrase: "abcdefgh",
+			err:        "passphrase must have an estimated entropy of at least 50 bits",
+		},
+		{
+			name:       "InvalidUTF8",
+			passphrase: "\xff\xfe\xfd\xfc\xfb\xfa\xf9\xf8",
+			err:        "passphrase is not valid UTF-8",
+		},
+		{
+			name:       "Good",
+			passphrase: "correct horse battery staple",
+		},
+		{
+			name:       "Mixed",
+			passphrase: "Tr0ub4dor&3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := policy.Check([]byte(test.passphrase))
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPassphrasePolicy(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	policy := &hd.BasicPassphrasePolicy{MinLength: 8}
+	good := []byte("long passphrase")
+
+	_, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("short")), hd.WithPassphrasePolicy(policy))
+	assert.EqualError(t, err, "passphrase must be at least 8 characters")
+
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase(good), hd.WithPassphrasePolicy(policy))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(good))
+	_, err = wallet.CreateAccount("Account 1", []byte("short"))
+	assert.EqualError(t, err, "passphrase must be at least 8 characters")
+	_, err = wallet.(hd.WalletAccountsCreator).CreateAccounts([]string{"Account 1", "Account 2"}, []byte("short"))
+	assert.EqualError(t, err, "passphrase must be at least 8 characters")
+	_, err = wallet.CreateAccount("Account 1", good)
+	require.NoError(t, err)
+	// The failed attempts did not use up account numbers.
+	assert.Equal(t, uint64(1), wallet.(hd.WalletNextAccountProvider).NextAccount())
+
+	// The policy is not stored.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(good))
+	_, err = wallet.CreateAccount("Account 2", []byte("short"))
+	require.NoError(t, err)
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor, hd.WithPassphrasePolicy(policy))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(good))
+	_, err = wallet.CreateAccount("Account 3", []byte("short"))
+	assert.EqualError(t, err, "passphrase must be at least 8 characters")
+}
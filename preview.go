@@ -0,0 +1,80 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// AccountPreview contains the details of an account that has not yet been created.
+type AccountPreview struct {
+	// Path is the path from which the account's key will be derived.
+	Path string
+	// PublicKey is the account's public key.
+	PublicKey e2types.PublicKey
+}
+
+// NextAccountPublicKey provides the public key of the account that will be created next by the wallet,
+// without creating the account.  The wallet must be unlocked.
+func (w *wallet) NextAccountPublicKey() (e2types.PublicKey, error) {
+	previews, err := w.PreviewAccounts(1)
+	if err != nil {
+		return nil, err
+	}
+	return previews[0].PublicKey, nil
+}
+
+// PreviewAccounts provides the details of the next count accounts that will be created by the wallet,
+// without creating the accounts.  The wallet must be unlocked.
+func (w *wallet) PreviewAccounts(count int) ([]*AccountPreview, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to derive public keys")
+	}
+	if count < 1 {
+		return nil, errors.New("count must be at least 1")
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to derive public keys")
+	}
+
+	if uint64(count) > math.MaxInt32+1-w.nextAccount {
+		return nil, errors.New("count too large")
+	}
+	previews := make([]*AccountPreview, count)
+	if err := w.parallelise(count, func(i int) error {
+		path := w.accountPath(w.nextAccount + uint64(i))
+		privateKey, err := w.derivePrivateKey(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create private key for path %s", path)
+		}
+		previews[i] = &AccountPreview{
+			Path:      path,
+			PublicKey: privateKey.PublicKey(),
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return previews, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestNextAccountPublicKey(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	provider, isProvider := wallet.(hd.WalletNextAccountPublicKeyProvider)
+	require.True(t, isProvider)
+
+	_, err = provider.NextAccountPublicKey()
+	assert.EqualError(t, err, "wallet must be unlocked to derive public keys")
+
+	require.NoError(t, wallet.Unlock(nil))
+	for i := 0; i < 2; i++ {
+		publicKey, err := provider.NextAccountPublicKey()
+		require.NoError(t, err)
+		// Peeking does not consume the account.
+		again, err := provider.NextAccountPublicKey()
+		require.NoError(t, err)
+		assert.Equal(t, publicKey.Marshal(), again.Marshal())
+
+		account, err := wallet.CreateAccount(fmt.Sprintf("Account %d", i), nil)
+		require.NoError(t, err)
+		assert.Equal(t, publicKey.Marshal(), account.PublicKey().Marshal())
+	}
+	count, err := wallet.(hd.WalletAccountCounter).AccountCount()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestPreviewAccounts(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithWalletIndex(2))
+	require.NoError(t, err)
+	previewer, isPreviewer := wallet.(hd.WalletAccountsPreviewer)
+	require.True(t, isPreviewer)
+
+	_, err = previewer.PreviewAccounts(3)
+	assert.EqualError(t, err, "wallet must be unlocked to derive public keys")
+
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = previewer.PreviewAccounts(0)
+	assert.EqualError(t, err, "count must be at least 1")
+	_, err = previewer.PreviewAccounts(math.MaxInt32 + 2)
+	assert.EqualError(t, err, "count too large")
+
+	_, err = wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	previews, err := previewer.PreviewAccounts(3)
+	require.NoError(t, err)
+	require.Len(t, previews, 3)
+	count, err := wallet.(hd.WalletAccountCounter).AccountCount()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	for i, preview := range previews {
+		account, err := wallet.CreateAccount(fmt.Sprintf("Account %d", i+1), nil)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("m/12381/3600/2/%d/0", i+1), preview.Path)
+		assert.Equal(t, account.Path(), preview.Path)
+		assert.Equal(t, account.PublicKey().Marshal(), preview.PublicKey.Marshal())
+	}
+}
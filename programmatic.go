@@ -0,0 +1,193 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// defaultProgrammaticCacheSize is the default number of programmatic accounts cached by a wallet.
+const defaultProgrammaticCacheSize = 256
+
+// AccountByPath provides a programmatic account, calculated on the fly from the wallet's seed given its EIP-2334 path.
+// Programmatic accounts are not stored in the wallet, and their private keys are held in memory; they are unlocked
+// and can be relocked and unlocked with an empty passphrase.  The wallet must be unlocked.
+func (w *wallet) AccountByPath(path string) (wtypes.Account, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if w.noProgrammatic {
+		return nil, ErrProgrammaticAccountsDisabled
+	}
+	components, err := ParsePath(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid account path")
+	}
+	if w.constrainPaths {
+		position := w.walletIndexComponent()
+		if position < 0 || position >= len(components) || components[position] != w.walletIndex {
+			return nil, fmt.Errorf("path %q is outside of wallet index %d", path, w.walletIndex)
+		}
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to provide accounts by path")
+	}
+	if a, exists := w.accountCache.get(path); exists {
+		return a, nil
+	}
+	privateKey, err := w.derivePrivateKey(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create private key for path %q", path)
+	}
+	a := newAccount()
+	a.path = path
+	a.publicKey = privateKey.PublicKey()
+	a.id, err = w.accountID(a.publicKey)
+	if err != nil {
+		return nil, err
+	}
+	a.name = path
+	// Programmatic accounts are not stored, so the private key is held rather than encrypted.
+	a.secretKey = privateKey
+	a.heldKey = privateKey
+	a.encryptor = w.encryptor
+	a.version = w.encryptor.Version()
+	a.wallet = w
+	w.accountCache.add(path, a)
+
+	return a, nil
+}
+
+// accountCache is a least-recently-used cache of programmatic accounts, keyed by path.
+// The cache holds its own copies of the accounts' keys, which are overwritten when they leave the cache.
+type accountCache struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	mutex   sync.Mutex
+}
+
+// accountCacheEntry is an entry in the account cache.
+type accountCacheEntry struct {
+	path    string
+	account *account
+}
+
+// newAccountCache creates a new account cache holding up to size accounts.
+// A cache with a size of 0 holds no accounts.
+func newAccountCache(size int) *accountCache {
+	return &accountCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get provides the cached account for the given path.
+// The account is a copy, so that locking it does not affect the cached account.
+func (c *accountCache) get(path string) (*account, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, exists := c.entries[path]
+	if !exists {
+		return nil, false
+	}
+	a, err := copyAccount(element.Value.(*accountCacheEntry).account)
+	if err != nil {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return a, true
+}
+
+// add adds the account for the given path to the cache, evicting the least recently used account if the cache is full.
+func (c *accountCache) add(path string, a *account) {
+	if c.size <= 0 {
+		return
+	}
+	cached, err := copyAccount(a)
+	if err != nil {
+		// The cache is an optimisation, so an account that cannot be copied is not cached.
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, exists := c.entries[path]; exists {
+		entry := element.Value.(*accountCacheEntry)
+		zeroPrivateKey(entry.account.heldKey)
+		entry.account = cached
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[path] = c.order.PushFront(&accountCacheEntry{path: path, account: cached})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		entry := oldest.Value.(*accountCacheEntry)
+		zeroPrivateKey(entry.account.heldKey)
+		delete(c.entries, entry.path)
+	}
+}
+
+// clear overwrites the keys of and removes all accounts from the cache.
+func (c *accountCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		zeroPrivateKey(element.Value.(*accountCacheEntry).account.heldKey)
+	}
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
+
+// copyAccount provides a copy of a programmatic account.
+// The copy has its own copy of the account's key, so that overwriting one key does not affect the other.
+func copyAccount(a *account) (*account, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	res := newAccount()
+	res.id = a.id
+	res.name = a.name
+	res.publicKey = a.publicKey
+	res.crypto = a.crypto
+	if a.heldKey != nil {
+		heldKey, err := copyPrivateKey(a.backend(), a.heldKey)
+		if err != nil {
+			return nil, err
+		}
+		res.heldKey = heldKey
+		if a.secretKey != nil {
+			res.secretKey = heldKey
+		}
+	}
+	res.version = a.version
+	res.path = a.path
+	res.wallet = a.wallet
+	res.encryptor = a.encryptor
+	return res, nil
+}
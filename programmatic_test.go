@@ -0,0 +1,131 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestProgrammaticAccountCache(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+
+	account1, err := wallet.AccountByName("m/12381/3600/1/0")
+	require.NoError(t, err)
+	account2, err := wallet.AccountByName("m/12381/3600/1/0")
+	require.NoError(t, err)
+	assert.Equal(t, account1.ID(), account2.ID())
+
+	// Locking a cached account does not lock the account provided by later requests.
+	account2.Lock()
+	account3, err := wallet.AccountByName("m/12381/3600/1/0")
+	require.NoError(t, err)
+	assert.True(t, account3.IsUnlocked())
+
+	// Locking the wallet clears the cache.
+	wallet.Lock()
+	_, err = wallet.AccountByName("m/12381/3600/1/0")
+	assert.Error(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account4, err := wallet.AccountByName("m/12381/3600/1/0")
+	require.NoError(t, err)
+	assert.NotEqual(t, account1.ID(), account4.ID())
+	assert.Equal(t, account1.PublicKey().Marshal(), account4.PublicKey().Marshal())
+}
+
+func TestProgrammaticAccountCacheSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		sameIDs bool
+	}{
+		{
+			name:    "Disabled",
+			size:    0,
+			sameIDs: false,
+		},
+		{
+			name:    "Evicted",
+			size:    1,
+			sameIDs: false,
+		},
+		{
+			name:    "Retained",
+			size:    2,
+			sameIDs: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New(), hd.WithProgrammaticAccountCacheSize(test.size))
+			require.NoError(t, err)
+			require.NoError(t, wallet.Unlock(nil))
+			account1, err := wallet.AccountByName("m/12381/3600/1/0")
+			require.NoError(t, err)
+			_, err = wallet.AccountByName("m/12381/3600/2/0")
+			require.NoError(t, err)
+			account2, err := wallet.AccountByName("m/12381/3600/1/0")
+			require.NoError(t, err)
+			assert.Equal(t, test.sameIDs, account1.ID() == account2.ID())
+		})
+	}
+}
+
+func TestProgrammaticAccountUnlock(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+
+	account, err := wallet.AccountByName("m/12381/3600/1/0")
+	require.NoError(t, err)
+	require.True(t, account.IsUnlocked())
+	account.Lock()
+	require.False(t, account.IsUnlocked())
+	assert.EqualError(t, account.Unlock([]byte("bad")), "incorrect passphrase")
+	require.NoError(t, account.Unlock(nil))
+	require.True(t, account.IsUnlocked())
+	_, err = account.Sign([]byte("data"))
+	require.NoError(t, err)
+}
+
+func TestAccountByPath(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	provider, isProvider := wallet.(hd.WalletAccountByPathProvider)
+	require.True(t, isProvider)
+
+	_, err = provider.AccountByPath("m/12381/3600/1/0")
+	assert.EqualError(t, err, "wallet must be unlocked to provide accounts by path")
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = provider.AccountByPath("m/12381/3600/1")
+	require.NoError(t, err)
+	_, err = provider.AccountByPath("Account")
+	assert.EqualError(t, err, `invalid account path: path "Account" does not start with "m"`)
+
+	account, err := provider.AccountByPath("m/12381/3600/1/0")
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/1/0", account.Path())
+	assert.Equal(t, "m/12381/3600/1/0", account.Name())
+	byName, err := wallet.AccountByName("m/12381/3600/1/0")
+	require.NoError(t, err)
+	assert.Equal(t, account.PublicKey().Marshal(), byName.PublicKey().Marshal())
+}
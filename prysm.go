@@ -0,0 +1,97 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+const (
+	// prysmDerivedDir is the directory of a Prysm wallet holding its derived keymanager.
+	prysmDerivedDir = "derived"
+	// prysmKeymanagerOptsFile is the file holding the options of a Prysm derived keymanager.
+	prysmKeymanagerOptsFile = "keymanageropts.json"
+	// prysmSeedFile is the file holding the encrypted seed and account count of a Prysm derived keymanager.
+	prysmSeedFile = "seed.encrypted.json"
+	// prysmPathStructure is the only derivation path structure used by Prysm derived keymanagers.
+	prysmPathStructure = "m / purpose / coin_type / account_index / withdrawal_key / validating_key"
+)
+
+// prysmKeymanagerOpts are the options of a Prysm derived keymanager.
+type prysmKeymanagerOpts struct {
+	PathStructure string `json:"derived_path_structure"`
+	EIPNumber     string `json:"derived_eip_number"`
+}
+
+// prysmEncryptedSeed is the encrypted seed of a Prysm derived keymanager.
+type prysmEncryptedSeed struct {
+	Crypto      map[string]interface{} `json:"crypto"`
+	NextAccount uint64                 `json:"next_account"`
+}
+
+// ImportPrysmWallet creates a wallet equivalent to the Prysm derived wallet in the given wallet directory.  The Prysm
+// wallet's keymanager options are read from derived/keymanageropts.json, and its seed and number of accounts from
+// derived/seed.encrypted.json, the seed being decrypted with passphrase.  Accounts keep their Prysm derivation indices,
+// and so their paths m/12381/3600/index/0/0, are named after their index and are encrypted with passphrase, which
+// also protects the wallet's seed unless WithPassphrase is supplied.
+func ImportPrysmWallet(name string, store wtypes.Store, encryptor wtypes.Encryptor, dir string, passphrase []byte, opts ...Option) (wtypes.Wallet, error) {
+	opts = append([]Option{WithPassphrase(passphrase)}, opts...)
+	options := options{
+		minSeedLength: minSeedLength,
+	}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	if options.seed != nil || options.mnemonic != "" {
+		return nil, errors.New("cannot supply seed or mnemonic when importing a Prysm wallet")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, prysmDerivedDir, prysmKeymanagerOptsFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read Prysm keymanager options")
+	}
+	keymanagerOpts := &prysmKeymanagerOpts{}
+	if err := json.Unmarshal(data, keymanagerOpts); err != nil {
+		return nil, errors.Wrap(err, "Prysm keymanager options invalid")
+	}
+	if keymanagerOpts.EIPNumber != "2334" || keymanagerOpts.PathStructure != prysmPathStructure {
+		return nil, fmt.Errorf("unsupported Prysm derivation path structure %q", keymanagerOpts.PathStructure)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, prysmDerivedDir, prysmSeedFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read Prysm seed")
+	}
+	encryptedSeed := &prysmEncryptedSeed{}
+	if err := json.Unmarshal(data, encryptedSeed); err != nil {
+		return nil, errors.Wrap(err, "Prysm seed invalid")
+	}
+	if encryptedSeed.Crypto == nil {
+		return nil, errors.New("Prysm seed crypto missing")
+	}
+	seed, err := decryptKeystoreSecret(encryptedSeed.Crypto, passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt Prysm seed")
+	}
+	defer zeroBytes(seed)
+	options.seed = seed
+
+	return createDerivedWallet(name, store, encryptor, &options, passphrase, 0, encryptedSeed.NextAccount)
+}
@@ -0,0 +1,96 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+// writePrysmWallet writes a Prysm derived wallet to a temporary directory.
+func writePrysmWallet(t *testing.T, seed []byte, passphrase []byte, nextAccount uint64) string {
+	dir, err := ioutil.TempDir("", "prysm")
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "derived"), 0700))
+
+	opts, err := json.Marshal(map[string]string{
+		"derived_path_structure": "m / purpose / coin_type / account_index / withdrawal_key / validating_key",
+		"derived_eip_number":     "2334",
+	})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "derived", "keymanageropts.json"), opts, 0600))
+
+	crypto, err := keystorev4.New().Encrypt(seed, passphrase)
+	require.NoError(t, err)
+	encryptedSeed, err := json.Marshal(map[string]interface{}{
+		"crypto":       crypto,
+		"id":           "3d9eeb4c-5cd4-4e4d-8c2c-c4d8e8bba6a9",
+		"next_account": nextAccount,
+		"version":      4,
+		"name":         "derived",
+	})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "derived", "seed.encrypted.json"), encryptedSeed, 0600))
+
+	return dir
+}
+
+func TestImportPrysmWallet(t *testing.T) {
+	seed := _byteArray("c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04")
+	dir := writePrysmWallet(t, seed, []byte("prysm password"), 3)
+	defer os.RemoveAll(dir)
+	encryptor := keystorev4.New()
+
+	_, err := hd.ImportPrysmWallet("test wallet", scratch.New(), encryptor, dir, []byte("wrong"))
+	assert.EqualError(t, err, "failed to decrypt Prysm seed: incorrect passphrase")
+	_, err = hd.ImportPrysmWallet("test wallet", scratch.New(), encryptor, filepath.Join(dir, "missing"), []byte("prysm password"))
+	assert.Error(t, err)
+
+	wallet, err := hd.ImportPrysmWallet("test wallet", scratch.New(), encryptor, dir, []byte("prysm password"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), wallet.(hd.WalletNextAccountProvider).NextAccount())
+
+	reference, err := hd.CreateWallet("reference wallet", scratch.New(), encryptor, hd.WithSeed(seed), hd.WithPathTemplate("m/12381/3600/%a/0/0"))
+	require.NoError(t, err)
+	require.NoError(t, reference.Unlock(nil))
+	for i := uint64(0); i < 3; i++ {
+		account, err := wallet.AccountByName(fmt.Sprintf("%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("m/12381/3600/%d/0/0", i), account.Path())
+		require.NoError(t, account.Unlock([]byte("prysm password")))
+		referenceAccount, err := reference.CreateAccount(fmt.Sprintf("account %d", i), nil)
+		require.NoError(t, err)
+		assert.Equal(t, referenceAccount.PublicKey().Marshal(), account.PublicKey().Marshal())
+	}
+	require.NoError(t, wallet.Unlock([]byte("prysm password")))
+}
+
+func TestImportPrysmWalletPathStructure(t *testing.T) {
+	dir := writePrysmWallet(t, make([]byte, 32), nil, 0)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "derived", "keymanageropts.json"), []byte(`{"derived_path_structure":"m / purpose / coin_type / account_index","derived_eip_number":"2334"}`), 0600))
+
+	_, err := hd.ImportPrysmWallet("test wallet", scratch.New(), keystorev4.New(), dir, nil)
+	assert.EqualError(t, err, `unsupported Prysm derivation path structure "m / purpose / coin_type / account_index"`)
+}
@@ -0,0 +1,160 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// AccountCount provides the number of accounts in the wallet.
+// The count is obtained from the accounts index, so no accounts are retrieved from the store.
+func (w *wallet) AccountCount() (int, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	entries, err := w.indexEntries()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to obtain accounts index")
+	}
+	return len(entries), nil
+}
+
+// AccountsByPrefix provides the accounts whose names start with the given prefix, ordered by name.
+// Names are matched against the accounts index, so only matching accounts are retrieved from the store.
+func (w *wallet) AccountsByPrefix(prefix string) ([]wtypes.Account, error) {
+	return w.accountsMatching(func(name string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+}
+
+// AccountsMatching provides the accounts whose names match the given regular expression, ordered by name.
+// Names are matched against the accounts index, so only matching accounts are retrieved from the store.
+func (w *wallet) AccountsMatching(re *regexp.Regexp) ([]wtypes.Account, error) {
+	if re == nil {
+		return nil, errors.New("regular expression missing")
+	}
+	return w.accountsMatching(re.MatchString)
+}
+
+// AccountsPage provides up to limit accounts, ordered by name, starting at the given offset.
+// Names are ordered against the accounts index, so only the accounts in the page are retrieved from the store.
+func (w *wallet) AccountsPage(offset int, limit int) ([]wtypes.Account, error) {
+	if offset < 0 {
+		return nil, errors.New("offset cannot be negative")
+	}
+	if limit < 1 {
+		return nil, errors.New("limit must be at least 1")
+	}
+
+	w.mutex.RLock()
+	entries, err := w.indexEntries()
+	w.mutex.RUnlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain accounts index")
+	}
+
+	if offset >= len(entries) {
+		return []wtypes.Account{}, nil
+	}
+	entries = entries[offset:]
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return w.accountsForEntries(entries)
+}
+
+// AccountOrder is the order in which accounts are provided.
+type AccountOrder int
+
+const (
+	// AccountOrderName orders accounts by name.
+	AccountOrderName AccountOrder = iota
+	// AccountOrderIndex orders accounts by account number.  Accounts whose paths do not match the wallet's
+	// path template are placed after all others, ordered by path.
+	AccountOrderIndex
+)
+
+// OrderedAccounts provides all accounts in the wallet in the given order.
+func (w *wallet) OrderedAccounts(order AccountOrder) ([]wtypes.Account, error) {
+	switch order {
+	case AccountOrderName:
+		return w.AccountsByPrefix("")
+	case AccountOrderIndex:
+		accounts := make([]wtypes.Account, 0)
+		for account := range w.Accounts() {
+			accounts = append(accounts, account)
+		}
+		accountNumbers := make(map[string]uint64, len(accounts))
+		w.mutex.RLock()
+		for _, account := range accounts {
+			if accountNum, exists := w.accountNumber(account.Path()); exists {
+				accountNumbers[account.Path()] = accountNum
+			}
+		}
+		w.mutex.RUnlock()
+		sort.Slice(accounts, func(i int, j int) bool {
+			iNum, iKnown := accountNumbers[accounts[i].Path()]
+			jNum, jKnown := accountNumbers[accounts[j].Path()]
+			if iKnown != jKnown {
+				return iKnown
+			}
+			if iKnown && iNum != jNum {
+				return iNum < jNum
+			}
+			if accounts[i].Path() != accounts[j].Path() {
+				return accounts[i].Path() < accounts[j].Path()
+			}
+			return accounts[i].Name() < accounts[j].Name()
+		})
+		return accounts, nil
+	default:
+		return nil, fmt.Errorf("account order %d unsupported", order)
+	}
+}
+
+// accountsMatching provides the accounts whose names satisfy the match function, ordered by name.
+func (w *wallet) accountsMatching(match func(name string) bool) ([]wtypes.Account, error) {
+	w.mutex.RLock()
+	entries, err := w.indexEntries()
+	w.mutex.RUnlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to obtain accounts index")
+	}
+
+	matched := make([]*indexEntry, 0)
+	for _, entry := range entries {
+		if match(entry.Name) {
+			matched = append(matched, entry)
+		}
+	}
+	return w.accountsForEntries(matched)
+}
+
+// accountsForEntries retrieves the accounts for the given index entries.
+func (w *wallet) accountsForEntries(entries []*indexEntry) ([]wtypes.Account, error) {
+	accounts := make([]wtypes.Account, 0, len(entries))
+	for _, entry := range entries {
+		account, err := w.AccountByID(entry.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to obtain account %q", entry.Name)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
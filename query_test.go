@@ -0,0 +1,286 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// listingStore is a store that counts the number of times all accounts are listed.
+type listingStore struct {
+	wtypes.Store
+	listings int
+}
+
+func (s *listingStore) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	s.listings++
+	return s.Store.RetrieveAccounts(walletID)
+}
+
+func TestAccountsQueries(t *testing.T) {
+	store := &listingStore{Store: scratch.New()}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	for i := 5; i > 0; i-- {
+		_, err := wallet.CreateAccount(fmt.Sprintf("val-%04d", i), nil)
+		require.NoError(t, err)
+	}
+	_, err = wallet.CreateAccount("withdrawal", nil)
+	require.NoError(t, err)
+	querier, isQuerier := wallet.(hd.WalletAccountsQuerier)
+	require.True(t, isQuerier)
+
+	tests := []struct {
+		name     string
+		byPrefix bool
+		prefix   string
+		re       *regexp.Regexp
+		err      string
+		names    []string
+	}{
+		{
+			name:     "Prefix",
+			byPrefix: true,
+			prefix:   "val-",
+			names:    []string{"val-0001", "val-0002", "val-0003", "val-0004", "val-0005"},
+		},
+		{
+			name:     "PrefixNone",
+			byPrefix: true,
+			prefix:   "deposit",
+			names:    []string{},
+		},
+		{
+			name:     "PrefixEmpty",
+			byPrefix: true,
+			prefix:   "",
+			names:    []string{"val-0001", "val-0002", "val-0003", "val-0004", "val-0005", "withdrawal"},
+		},
+		{
+			name:  "Regexp",
+			re:    regexp.MustCompile(`^val-000[24]$`),
+			names: []string{"val-0002", "val-0004"},
+		},
+		{
+			name: "RegexpMissing",
+			err:  "regular expression missing",
+		},
+	}
+
+	store.listings = 0
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var accounts []wtypes.Account
+			var err error
+			if test.byPrefix {
+				accounts, err = querier.AccountsByPrefix(test.prefix)
+			} else {
+				accounts, err = querier.AccountsMatching(test.re)
+			}
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, len(accounts))
+			for i, account := range accounts {
+				names[i] = account.Name()
+			}
+			assert.Equal(t, test.names, names)
+		})
+	}
+	assert.Equal(t, 0, store.listings)
+}
+
+func TestAccountsPage(t *testing.T) {
+	store := &listingStore{Store: scratch.New()}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	for i := 5; i > 0; i-- {
+		_, err := wallet.CreateAccount(fmt.Sprintf("val-%04d", i), nil)
+		require.NoError(t, err)
+	}
+	pager, isPager := wallet.(hd.WalletAccountsPager)
+	require.True(t, isPager)
+
+	tests := []struct {
+		name   string
+		offset int
+		limit  int
+		err    string
+		names  []string
+	}{
+		{
+			name:   "NegativeOffset",
+			offset: -1,
+			limit:  2,
+			err:    "offset cannot be negative",
+		},
+		{
+			name:  "ZeroLimit",
+			limit: 0,
+			err:   "limit must be at least 1",
+		},
+		{
+			name:  "First",
+			limit: 2,
+			names: []string{"val-0001", "val-0002"},
+		},
+		{
+			name:   "Second",
+			offset: 2,
+			limit:  2,
+			names:  []string{"val-0003", "val-0004"},
+		},
+		{
+			name:   "Last",
+			offset: 4,
+			limit:  2,
+			names:  []string{"val-0005"},
+		},
+		{
+			name:   "Beyond",
+			offset: 5,
+			limit:  2,
+			names:  []string{},
+		},
+		{
+			name:  "All",
+			limit: 100,
+			names: []string{"val-0001", "val-0002", "val-0003", "val-0004", "val-0005"},
+		},
+	}
+
+	store.listings = 0
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accounts, err := pager.AccountsPage(test.offset, test.limit)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, len(accounts))
+			for i, account := range accounts {
+				names[i] = account.Name()
+			}
+			assert.Equal(t, test.names, names)
+		})
+	}
+	assert.Equal(t, 0, store.listings)
+}
+
+func TestOrderedAccounts(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	for _, name := range []string{"zulu", "alpha", "mike"} {
+		_, err := wallet.CreateAccount(name, nil)
+		require.NoError(t, err)
+	}
+	_, err = wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("bravo", 10, nil)
+	require.NoError(t, err)
+	_, err = wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("yankee", 5, nil)
+	require.NoError(t, err)
+	provider, isProvider := wallet.(hd.WalletOrderedAccountsProvider)
+	require.True(t, isProvider)
+
+	tests := []struct {
+		name  string
+		order hd.AccountOrder
+		err   string
+		names []string
+	}{
+		{
+			name:  "Name",
+			order: hd.AccountOrderName,
+			names: []string{"alpha", "bravo", "mike", "yankee", "zulu"},
+		},
+		{
+			name:  "Index",
+			order: hd.AccountOrderIndex,
+			names: []string{"zulu", "alpha", "mike", "yankee", "bravo"},
+		},
+		{
+			name:  "Unknown",
+			order: hd.AccountOrder(99),
+			err:   "account order 99 unsupported",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				accounts, err := provider.OrderedAccounts(test.order)
+				if test.err != "" {
+					require.EqualError(t, err, test.err)
+					return
+				}
+				require.NoError(t, err)
+				names := make([]string, len(accounts))
+				for i, account := range accounts {
+					names[i] = account.Name()
+				}
+				assert.Equal(t, test.names, names)
+			}
+		})
+	}
+}
+
+func TestAccountCount(t *testing.T) {
+	store := &listingStore{Store: scratch.New()}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	counter, isCounter := wallet.(hd.WalletAccountCounter)
+	require.True(t, isCounter)
+
+	count, err := counter.AccountCount()
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	require.NoError(t, wallet.Unlock(nil))
+	for i := 0; i < 3; i++ {
+		_, err := wallet.CreateAccount(fmt.Sprintf("Account %d", i), nil)
+		require.NoError(t, err)
+	}
+	store.listings = 0
+	count, err = counter.AccountCount()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// Ensure the count survives reopening the wallet.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	count, err = wallet.(hd.WalletAccountCounter).AccountCount()
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, 0, store.listings)
+}
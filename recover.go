@@ -0,0 +1,103 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// defaultGapLimit is the default number of consecutive unused accounts after which recovery stops.
+const defaultGapLimit = 20
+
+// ScanFunc is called by RecoverWallet for each candidate account, and returns true if the account is in use.
+type ScanFunc func(path string, publicKey e2types.PublicKey) (bool, error)
+
+// RecoverWallet re-creates a wallet from its seed or mnemonic, supplied with WithSeed or WithMnemonic, and then
+// re-creates its accounts.  Candidate accounts are derived in order and passed to scanFn, which decides if each
+// is in use; scanning stops after the number of consecutive unused accounts set by WithGapLimit.
+// Recovered accounts are named after their account number, and are encrypted with the wallet's passphrase.
+func RecoverWallet(name string, store wtypes.Store, encryptor wtypes.Encryptor, scanFn ScanFunc, opts ...Option) (wtypes.Wallet, error) {
+	options := options{
+		minSeedLength: minSeedLength,
+		gapLimit:      defaultGapLimit,
+	}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	if options.seed == nil && options.mnemonic == "" {
+		return nil, errors.New("seed or mnemonic required to recover wallet")
+	}
+	if options.gapLimit < 1 {
+		return nil, errors.New("gap limit must be at least 1")
+	}
+	if scanFn == nil {
+		return nil, errors.New("scan function missing")
+	}
+
+	// First, try to open the wallet.
+	_, err := OpenWallet(name, store, encryptor)
+	if err == nil || !strings.Contains(err.Error(), "wallet not found") {
+		return nil, fmt.Errorf("wallet %q already exists", name)
+	}
+
+	w, seed, err := newWalletFromOptions(name, store, encryptor, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Scan before storing anything, so that a failed scan leaves the store untouched.
+	usedAccounts := make([]uint64, 0)
+	for accountNum, gap := uint64(0), 0; gap < options.gapLimit && accountNum <= math.MaxInt32; accountNum++ {
+		path := w.accountPath(accountNum)
+		privateKey, err := privateKeyFromSeedAndPath(w.backend, seed, path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create private key for path %s", path)
+		}
+		used, err := scanFn(path, privateKey.PublicKey())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to scan path %s", path)
+		}
+		if used {
+			usedAccounts = append(usedAccounts, accountNum)
+			gap = 0
+		} else {
+			gap++
+		}
+	}
+
+	if err := w.storeWallet(); err != nil {
+		return nil, err
+	}
+	if err := w.setSeed(seed); err != nil {
+		return nil, err
+	}
+	defer w.Lock()
+	for _, accountNum := range usedAccounts {
+		// Used accounts are in order, so the next account is never lowered.
+		if err := w.SetNextAccount(accountNum); err != nil {
+			return nil, errors.Wrapf(err, "failed to recover account %d", accountNum)
+		}
+		if _, err := w.CreateAccount(fmt.Sprintf("%d", accountNum), options.passphrase); err != nil {
+			return nil, errors.Wrapf(err, "failed to recover account %d", accountNum)
+		}
+	}
+
+	return w, nil
+}
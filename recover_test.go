@@ -0,0 +1,124 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestRecoverWallet(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	encryptor := keystorev4.New()
+
+	// Create the original wallet, with accounts 0, 1 and 4 in use.
+	original, err := hd.CreateWallet("original", scratch.New(), encryptor, hd.WithSeed(seed))
+	require.NoError(t, err)
+	require.NoError(t, original.Unlock(nil))
+	inUse := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		account, err := original.CreateAccount(fmt.Sprintf("Account %d", i), nil)
+		require.NoError(t, err)
+		if i != 2 && i != 3 {
+			inUse[fmt.Sprintf("%x", account.PublicKey().Marshal())] = true
+		}
+	}
+	scanned := 0
+	scanFn := func(path string, publicKey e2types.PublicKey) (bool, error) {
+		scanned++
+		return inUse[fmt.Sprintf("%x", publicKey.Marshal())], nil
+	}
+
+	tests := []struct {
+		name     string
+		opts     []hd.Option
+		scanFn   hd.ScanFunc
+		err      string
+		accounts []string
+		scanned  int
+	}{
+		{
+			name:   "NoSeed",
+			scanFn: scanFn,
+			err:    "seed or mnemonic required to recover wallet",
+		},
+		{
+			name:   "BadGapLimit",
+			opts:   []hd.Option{hd.WithSeed(seed), hd.WithGapLimit(0)},
+			scanFn: scanFn,
+			err:    "gap limit must be at least 1",
+		},
+		{
+			name: "NoScanFunc",
+			opts: []hd.Option{hd.WithSeed(seed)},
+			err:  "scan function missing",
+		},
+		{
+			name: "ScanError",
+			opts: []hd.Option{hd.WithSeed(seed)},
+			scanFn: func(path string, publicKey e2types.PublicKey) (bool, error) {
+				return false, errors.New("beacon node unavailable")
+			},
+			err: "failed to scan path m/12381/3600/0/0: beacon node unavailable",
+		},
+		{
+			name:     "Default",
+			opts:     []hd.Option{hd.WithSeed(seed)},
+			scanFn:   scanFn,
+			accounts: []string{"0", "1", "4"},
+			scanned:  25,
+		},
+		{
+			name:     "SmallGapLimit",
+			opts:     []hd.Option{hd.WithSeed(seed), hd.WithGapLimit(2)},
+			scanFn:   scanFn,
+			accounts: []string{"0", "1"},
+			scanned:  4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := scratch.New()
+			scanned = 0
+			wallet, err := hd.RecoverWallet("recovered", store, encryptor, test.scanFn, test.opts...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				_, err = hd.OpenWallet("recovered", store, encryptor)
+				assert.NotNil(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.scanned, scanned)
+			assert.False(t, wallet.IsUnlocked())
+
+			wallet, err = hd.OpenWallet("recovered", store, encryptor)
+			require.NoError(t, err)
+			accounts := make([]string, 0)
+			for account := range wallet.Accounts() {
+				assert.True(t, inUse[fmt.Sprintf("%x", account.PublicKey().Marshal())])
+				accounts = append(accounts, account.Name())
+			}
+			assert.ElementsMatch(t, test.accounts, accounts)
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A recovery code is the wallet's seed encrypted with a passphrase, encoded with Bech32 so that it uses only
+// unambiguous lower-case letters and digits and carries a checksum.  The encoded data is a version byte, a salt and
+// the seed encrypted with AES-256-GCM, keyed as per exports.  Codes are printed in groups, which are ignored when a
+// code is entered, and a single mistyped character is corrected.
+const (
+	recoveryCodeHRP        = "e2rc"
+	recoveryCodeVersion    = 1
+	recoveryCodeSaltLength = 16
+	recoveryCodeGroupSize  = 4
+	recoveryCodeLineGroups = 6
+)
+
+// RecoveryCode provides the wallet's seed encrypted with the passphrase as a code suitable for printing and entering
+// by hand, as "e2rc" followed by lines of groups of four characters.  The wallet must be unlocked.  The seed is recovered from the code with
+// SeedFromRecoveryCode.
+func (w *wallet) RecoveryCode(passphrase []byte) (string, error) {
+	if len(passphrase) == 0 {
+		return "", errors.New("passphrase required")
+	}
+	salt := make([]byte, recoveryCodeSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.Wrap(err, "failed to generate salt")
+	}
+	aead, err := exportAEAD(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	data := append([]byte{recoveryCodeVersion}, salt...)
+	w.mutex.RLock()
+	err = w.useSeed(func(seed []byte) error {
+		// The key is unique to the salt, so the nonce can be fixed.
+		data = aead.Seal(data, make([]byte, aead.NonceSize()), seed, data[:1])
+		return nil
+	})
+	w.mutex.RUnlock()
+	if err != nil {
+		return "", err
+	}
+
+	code, err := bech32Encode(recoveryCodeHRP, data)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode recovery code")
+	}
+	return formatRecoveryCode(code), nil
+}
+
+// formatRecoveryCode splits a recovery code in to groups and lines for printing.
+func formatRecoveryCode(code string) string {
+	var res strings.Builder
+	res.WriteString(recoveryCodeHRP)
+	groups := 0
+	for i := len(recoveryCodeHRP); i < len(code); i += recoveryCodeGroupSize {
+		if groups%recoveryCodeLineGroups == 0 {
+			res.WriteString("\n")
+		} else {
+			res.WriteString(" ")
+		}
+		end := i + recoveryCodeGroupSize
+		if end > len(code) {
+			end = len(code)
+		}
+		res.WriteString(code[i:end])
+		groups++
+	}
+	return res.String()
+}
+
+// SeedFromRecoveryCode decrypts the seed from a recovery code provided by RecoveryCode.  The code may be entered in
+// either case, and with or without the spacing with which it is printed.  A wallet can be re-created from the seed
+// with WithSeed.
+func SeedFromRecoveryCode(code string, passphrase []byte) ([]byte, error) {
+	code = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	if !strings.HasPrefix(code, recoveryCodeHRP+"1") {
+		return nil, errors.New("not a recovery code")
+	}
+
+	hrp, data, err := bech32Decode(code)
+	if err != nil {
+		if err.Error() != "invalid checksum" {
+			return nil, errors.Wrap(err, "invalid recovery code")
+		}
+		corrected, correctErr := correctRecoveryCode(code)
+		if correctErr != nil {
+			return nil, correctErr
+		}
+		hrp, data, err = bech32Decode(corrected)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid recovery code")
+		}
+	}
+	if hrp != recoveryCodeHRP {
+		return nil, errors.New("not a recovery code")
+	}
+	if len(data) < 1+recoveryCodeSaltLength {
+		return nil, errors.New("recovery code too short")
+	}
+	if data[0] != recoveryCodeVersion {
+		return nil, fmt.Errorf("unsupported recovery code version %d", data[0])
+	}
+
+	aead, err := exportAEAD(passphrase, data[1:1+recoveryCodeSaltLength])
+	if err != nil {
+		return nil, err
+	}
+	seed, err := aead.Open(nil, make([]byte, aead.NonceSize()), data[1+recoveryCodeSaltLength:], data[:1])
+	if err != nil {
+		return nil, errors.New("incorrect passphrase for recovery code")
+	}
+	return seed, nil
+}
+
+// correctRecoveryCode corrects a single mistyped character in a recovery code, by finding the only substitution that
+// results in a valid checksum.
+func correctRecoveryCode(code string) (string, error) {
+	candidates := make([]string, 0)
+	data := []byte(code)
+	for i := len(recoveryCodeHRP) + 1; i < len(data); i++ {
+		original := data[i]
+		for j := 0; j < len(bech32Charset); j++ {
+			if bech32Charset[j] == original {
+				continue
+			}
+			data[i] = bech32Charset[j]
+			if _, _, err := bech32Decode(string(data)); err == nil {
+				candidates = append(candidates, string(data))
+			}
+		}
+		data[i] = original
+	}
+	if len(candidates) != 1 {
+		return "", errors.New("recovery code contains errors")
+	}
+	return candidates[0], nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestRecoveryCode(t *testing.T) {
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), encryptor, hd.WithSeed(_byteArray("ea4d3fc1e2fa3f6e95d2fa2c0d8b8d1b6eaa4b71a3e5bb52e0b1f1fc36fd3f4a")))
+	require.NoError(t, err)
+	provider := wallet.(hd.WalletRecoveryCodeProvider)
+
+	_, err = provider.RecoveryCode([]byte("code passphrase"))
+	assert.EqualError(t, err, "wallet is locked")
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = provider.RecoveryCode(nil)
+	assert.EqualError(t, err, "passphrase required")
+	code, err := provider.RecoveryCode([]byte("code passphrase"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(code, "e2rc\n1"))
+	for _, line := range strings.Split(code, "\n")[1:] {
+		assert.LessOrEqual(t, len(line), 29)
+	}
+
+	// Flatten the code and introduce a typo in its data.
+	flat := strings.Join(strings.Fields(code), "")
+	typo := []byte(flat)
+	if typo[20] == 'q' {
+		typo[20] = 'p'
+	} else {
+		typo[20] = 'q'
+	}
+
+	tests := []struct {
+		name       string
+		code       string
+		passphrase []byte
+		err        string
+	}{
+		{
+			name:       "Printed",
+			code:       code,
+			passphrase: []byte("code passphrase"),
+		},
+		{
+			name:       "UpperCase",
+			code:       strings.ToUpper(code),
+			passphrase: []byte("code passphrase"),
+		},
+		{
+			name:       "Flat",
+			code:       flat,
+			passphrase: []byte("code passphrase"),
+		},
+		{
+			name:       "Typo",
+			code:       string(typo),
+			passphrase: []byte("code passphrase"),
+		},
+		{
+			name:       "TwoTypos",
+			code:       flat[:10] + strings.Repeat("x", 10) + flat[20:],
+			passphrase: []byte("code passphrase"),
+			err:        "recovery code contains errors",
+		},
+		{
+			name:       "WrongPassphrase",
+			code:       code,
+			passphrase: []byte("wrong"),
+			err:        "incorrect passphrase for recovery code",
+		},
+		{
+			name:       "NotRecoveryCode",
+			code:       "age1zvkyg2lqzraa2lnjvqej32nkuu0ues2s82hzrye869xeexvn73equnujwj",
+			passphrase: []byte("code passphrase"),
+			err:        "not a recovery code",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			seed, err := hd.SeedFromRecoveryCode(test.code, test.passphrase)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, _byteArray("ea4d3fc1e2fa3f6e95d2fa2c0d8b8d1b6eaa4b71a3e5bb52e0b1f1fc36fd3f4a"), seed)
+
+			// The seed re-creates the wallet.
+			recreated, err := hd.CreateWallet("recreated wallet", scratch.New(), encryptor, hd.WithSeed(seed))
+			require.NoError(t, err)
+			require.NoError(t, recreated.Unlock(nil))
+			account, err := wallet.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/3600/0/0")
+			require.NoError(t, err)
+			recreatedAccount, err := recreated.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/3600/0/0")
+			require.NoError(t, err)
+			assert.Equal(t, account.PublicKey().Marshal(), recreatedAccount.PublicKey().Marshal())
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// Reencrypt re-encrypts the wallet's seed and the keys of all of its accounts with a new encryptor, for example
+// to move from an older keystore version or key derivation function to a newer one.  The wallet and its accounts
+// must all be protected by the supplied passphrase, which continues to protect them after re-encryption.
+//
+// Accounts are re-encrypted and stored one at a time, and the wallet itself is stored last.  Accounts that are
+// already encrypted in the same manner as the new encryptor would encrypt them are skipped, so if this fails
+// part-way through it can be called again with the same arguments to complete the operation.
+func (w *wallet) Reencrypt(passphrase []byte, newEncryptor wtypes.Encryptor) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	if newEncryptor == nil {
+		return errors.New("no encryptor supplied")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	seed, err := decryptSecret(w.encryptor, w.crypto, passphrase)
+	if err != nil {
+		return errors.New("incorrect passphrase")
+	}
+	defer zeroBytes(seed)
+	var mnemonic []byte
+	if w.mnemonicCrypto != nil {
+		mnemonic, err = decryptSecret(w.encryptor, w.mnemonicCrypto, passphrase)
+		if err != nil {
+			return errors.Wrap(err, "failed to decrypt mnemonic")
+		}
+		defer zeroBytes(mnemonic)
+	}
+
+	// Encrypt a dummy key to find out how the new encryptor encrypts account keys.
+	target, err := newEncryptor.Encrypt(make([]byte, keyLength), passphrase)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt with new encryptor")
+	}
+	targetProfile, err := cryptoProfile(target)
+	if err != nil {
+		return err
+	}
+
+	accounts := make([]*account, 0)
+	for walletAccount := range w.Accounts() {
+		a, ok := walletAccount.(*account)
+		if !ok {
+			return fmt.Errorf("account %q type unexpected", walletAccount.Name())
+		}
+		accounts = append(accounts, a)
+	}
+	// Process accounts in a fixed order, so that repeated calls progress through them in the same way.
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].id.String() < accounts[j].id.String()
+	})
+
+	for _, a := range accounts {
+		if a.crypto == nil {
+			// Watch-only accounts have no key to re-encrypt.
+			continue
+		}
+		if a.version == newEncryptor.Version() {
+			profile, err := cryptoProfile(a.crypto)
+			if err == nil && reflect.DeepEqual(profile, targetProfile) {
+				// Already re-encrypted.
+				continue
+			}
+		}
+		secret, err := decryptSecret(a.encryptor, a.crypto, passphrase)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decrypt account %q", a.name)
+		}
+		crypto, err := encryptSecret(newEncryptor, secret, passphrase)
+		zeroBytes(secret)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encrypt account %q", a.name)
+		}
+		a.crypto = crypto
+		a.encryptor = newEncryptor
+		a.version = newEncryptor.Version()
+		if err := a.storeAccount(); err != nil {
+			return errors.Wrapf(err, "failed to store account %q", a.name)
+		}
+	}
+
+	crypto, err := encryptSecret(newEncryptor, seed, passphrase)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt seed")
+	}
+	var mnemonicCrypto map[string]interface{}
+	if mnemonic != nil {
+		mnemonicCrypto, err = encryptSecret(newEncryptor, mnemonic, passphrase)
+		if err != nil {
+			return errors.Wrap(err, "failed to encrypt mnemonic")
+		}
+	}
+	w.crypto = crypto
+	w.mnemonicCrypto = mnemonicCrypto
+	w.encryptor = newEncryptor
+	w.encryptorName = newEncryptor.Name()
+	w.encryptorVersion = newEncryptor.Version()
+	if err := w.storeWallet(); err != nil {
+		return errors.Wrap(err, "failed to store wallet")
+	}
+
+	return nil
+}
+
+// cryptoProfile provides the parts of an encryptor's output that describe how a secret was encrypted, namely the
+// functions used and their numeric parameters, leaving out values such as salts that change with each encryption.
+func cryptoProfile(crypto map[string]interface{}) (map[string]interface{}, error) {
+	// Round-trip through JSON so that profiles of stored and freshly-generated crypto are comparable.
+	data, err := json.Marshal(crypto)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal crypto")
+	}
+	var normalised map[string]interface{}
+	if err := json.Unmarshal(data, &normalised); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal crypto")
+	}
+
+	profile := make(map[string]interface{})
+	addCryptoProfile(profile, "", normalised)
+	return profile, nil
+}
+
+// addCryptoProfile adds the profile of a crypto section to the supplied profile.
+func addCryptoProfile(profile map[string]interface{}, prefix string, section map[string]interface{}) {
+	for key, val := range section {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			addCryptoProfile(profile, prefix+key+".", v)
+		case float64:
+			profile[prefix+key] = v
+		case string:
+			if key == "function" {
+				profile[prefix+key] = v
+			}
+		}
+	}
+}
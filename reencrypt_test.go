@@ -0,0 +1,73 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestReencrypt(t *testing.T) {
+	store := scratch.New()
+	oldEncryptor := keystorev4.New()
+	newEncryptor := keystorev4.New(keystorev4.WithCipher("scrypt"))
+	passphrase := []byte("passphrase")
+	wallet, err := hd.CreateWallet("test wallet", store, oldEncryptor,
+		hd.WithSeed(_byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")),
+		hd.WithPassphrase(passphrase))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+	account1, err := wallet.CreateAccount("Account 1", passphrase)
+	require.NoError(t, err)
+	account2, err := wallet.CreateAccount("Account 2", passphrase)
+	require.NoError(t, err)
+	wallet.Lock()
+
+	reencrypter, isReencrypter := wallet.(hd.WalletReencrypter)
+	require.True(t, isReencrypter)
+
+	assert.EqualError(t, reencrypter.Reencrypt([]byte("wrong"), newEncryptor), "incorrect passphrase")
+	assert.EqualError(t, reencrypter.Reencrypt(passphrase, nil), "no encryptor supplied")
+
+	require.NoError(t, reencrypter.Reencrypt(passphrase, newEncryptor))
+	// Running again is harmless.
+	require.NoError(t, reencrypter.Reencrypt(passphrase, newEncryptor))
+
+	// Ensure the changes were persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, newEncryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(passphrase))
+	for _, expected := range []struct {
+		name      string
+		publicKey []byte
+	}{
+		{name: "Account 1", publicKey: account1.PublicKey().Marshal()},
+		{name: "Account 2", publicKey: account2.PublicKey().Marshal()},
+	} {
+		account, err := wallet.AccountByName(expected.name)
+		require.NoError(t, err)
+		require.NoError(t, account.Unlock(passphrase))
+		assert.Equal(t, expected.publicKey, account.PublicKey().Marshal())
+		// Ensure the account's keystore uses the new key derivation function.
+		data, err := store.RetrieveAccount(wallet.ID(), account.ID())
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"function":"scrypt"`)
+		assert.NotContains(t, string(data), `"function":"pbkdf2"`)
+	}
+}
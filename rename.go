@@ -0,0 +1,127 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RenameFunc is called by RenameAccounts for each account, and returns the new name for the account.
+type RenameFunc func(name string, accountNum uint64) string
+
+// RenameAccounts renames the wallet's accounts in a single pass, using renameFn to generate each account's new name
+// from its current name and account number.  Accounts whose paths do not have an account number are not renamed.
+// The new names are validated before any account is changed; names can be exchanged between accounts, but the new
+// names must not clash with each other or with the names of accounts that are not renamed.  The accounts index is
+// updated and stored once all of the renamed accounts have been stored.
+func (w *wallet) RenameAccounts(renameFn RenameFunc) error {
+	if renameFn == nil {
+		return errors.New("rename function missing")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// Retrieve all of the accounts before storing any, as stores may not support writes during retrieval.
+	accounts := make([]*account, 0)
+	for data := range w.store.RetrieveAccounts(w.id) {
+		walletAccount, err := deserializeAccount(w, data)
+		if err != nil {
+			continue
+		}
+		a := walletAccount.(*account)
+		if w.index.IDKnown(a.id) {
+			accounts = append(accounts, a)
+		}
+	}
+	sort.Slice(accounts, func(i int, j int) bool {
+		return accounts[i].name < accounts[j].name
+	})
+
+	newNames := make(map[*account]string)
+	finalNames := make(map[string]bool, len(accounts))
+	for _, a := range accounts {
+		newName := a.name
+		if accountNum, exists := w.accountNumber(a.path); exists {
+			newName = renameFn(a.name, accountNum)
+			if newName == "" {
+				return fmt.Errorf("account name missing for account %q", a.name)
+			}
+			if strings.HasPrefix(newName, "_") {
+				return fmt.Errorf("invalid account name %q", newName)
+			}
+		}
+		if finalNames[newName] {
+			return fmt.Errorf("duplicate account name %q", newName)
+		}
+		finalNames[newName] = true
+		if newName != a.name {
+			newNames[a] = newName
+		}
+	}
+	if len(newNames) == 0 {
+		return nil
+	}
+
+	renamed := make([]*account, 0, len(newNames))
+	oldNames := make(map[*account]string, len(newNames))
+	for _, a := range accounts {
+		newName, exists := newNames[a]
+		if !exists {
+			continue
+		}
+		oldNames[a] = a.name
+		a.setName(newName)
+		if err := w.storeAccountData(a); err != nil {
+			a.setName(oldNames[a])
+			return w.restoreRenamedAccounts(renamed, oldNames, errors.Wrapf(err, "failed to store account %q", newName))
+		}
+		renamed = append(renamed, a)
+	}
+
+	// Remove all of the old names before adding the new ones, as names can be exchanged between accounts.
+	for _, a := range renamed {
+		w.index.Remove(a.id, oldNames[a])
+	}
+	for _, a := range renamed {
+		w.index.Add(a.id, a.name)
+	}
+	if err := w.storeAccountsIndex(); err != nil {
+		for _, a := range renamed {
+			w.index.Remove(a.id, a.name)
+		}
+		for _, a := range renamed {
+			w.index.Add(a.id, oldNames[a])
+		}
+		return w.restoreRenamedAccounts(renamed, oldNames, errors.Wrap(err, "failed to store accounts index"))
+	}
+
+	return nil
+}
+
+// restoreRenamedAccounts restores the names of accounts renamed by RenameAccounts after a failure, returning the
+// error that caused the failure.
+func (w *wallet) restoreRenamedAccounts(renamed []*account, oldNames map[*account]string, err error) error {
+	for _, a := range renamed {
+		a.setName(oldNames[a])
+		if restoreErr := w.storeAccountData(a); restoreErr != nil {
+			return errors.Wrapf(err, "failed to restore account %q: %v", a.name, restoreErr)
+		}
+	}
+	return err
+}
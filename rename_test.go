@@ -0,0 +1,119 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestRenameAccounts(t *testing.T) {
+	store := &failingAccountStore{Store: scratch.New()}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.(hd.WalletAccountsCreator).CreateAccounts([]string{"Account 0", "Account 1", "Account 2"}, nil)
+	require.NoError(t, err)
+	renamer, isRenamer := wallet.(hd.WalletAccountsRenamer)
+	require.True(t, isRenamer)
+
+	tests := []struct {
+		name     string
+		renameFn hd.RenameFunc
+		err      string
+	}{
+		{
+			name: "Nil",
+			err:  "rename function missing",
+		},
+		{
+			name:     "Empty",
+			renameFn: func(name string, accountNum uint64) string { return "" },
+			err:      `account name missing for account "Account 0"`,
+		},
+		{
+			name:     "Invalid",
+			renameFn: func(name string, accountNum uint64) string { return "_" + name },
+			err:      `invalid account name "_Account 0"`,
+		},
+		{
+			name:     "Duplicate",
+			renameFn: func(name string, accountNum uint64) string { return "Validator" },
+			err:      `duplicate account name "Validator"`,
+		},
+		{
+			name: "ClashesWithUnchanged",
+			renameFn: func(name string, accountNum uint64) string {
+				if accountNum == 0 {
+					return "Account 1"
+				}
+				return name
+			},
+			err: `duplicate account name "Account 1"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := renamer.RenameAccounts(test.renameFn)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+
+	// A failure to store leaves the accounts untouched.
+	store.fail = true
+	err = renamer.RenameAccounts(func(name string, accountNum uint64) string { return fmt.Sprintf("Validator %d", accountNum) })
+	assert.EqualError(t, err, `failed to store account "Validator 0": store unavailable`)
+	store.fail = false
+	for _, name := range []string{"Account 0", "Account 1", "Account 2"} {
+		account, err := wallet.AccountByName(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, account.Name())
+	}
+
+	// Names can be exchanged between accounts.
+	require.NoError(t, renamer.RenameAccounts(func(name string, accountNum uint64) string {
+		switch accountNum {
+		case 0:
+			return "Account 1"
+		case 1:
+			return "Account 0"
+		default:
+			return fmt.Sprintf("Validator %d", accountNum)
+		}
+	}))
+
+	// Ensure the new names were persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	expected := map[string]string{
+		"Account 1":   "m/12381/3600/0/0",
+		"Account 0":   "m/12381/3600/1/0",
+		"Validator 2": "m/12381/3600/2/0",
+	}
+	for name, path := range expected {
+		account, err := wallet.AccountByName(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, account.Name())
+		assert.Equal(t, path, account.Path())
+	}
+	_, err = wallet.AccountByName("Account 2")
+	assert.EqualError(t, err, `no account with name "Account 2"`)
+}
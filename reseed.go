@@ -0,0 +1,132 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// Reseed replaces the wallet's seed with a new seed, and re-derives all of the wallet's accounts from the new seed
+// at their existing paths, including deleted accounts that have not been purged.  The new seed and the re-derived
+// account keys are encrypted with the supplied passphrase.  Any stored mnemonic is discarded, and imported accounts
+// are left unchanged.  The wallet must be unlocked.
+//
+// The returned map is keyed by the hex-encoded old public key of each account, including deleted accounts, and
+// contains the account's new public key.
+//
+// The wallet is stored before its accounts, so if this fails part-way through it can be called again with the same
+// seed to complete the re-derivation.
+func (w *wallet) Reseed(newSeed []byte, passphrase []byte) (map[string]e2types.PublicKey, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to reseed")
+	}
+	if len(newSeed) < minSeedLength || len(newSeed) > maxSeedLength {
+		return nil, fmt.Errorf("seed must be between %d and %d bytes", minSeedLength, maxSeedLength)
+	}
+	if err := w.checkPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to reseed")
+	}
+
+	crypto, err := encryptSecret(w.encryptor, newSeed, passphrase)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt seed")
+	}
+
+	// Re-derive the accounts before storing anything, so that a derivation failure leaves the wallet untouched.
+	publicKeys := make(map[string]e2types.PublicKey)
+	rederive := func(a *account) error {
+		privateKey, err := privateKeyFromSeedAndPath(w.backend, newSeed, a.path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create private key for account %q", a.name)
+		}
+		accountCrypto, err := encryptSecret(w.encryptor, privateKey.Marshal(), passphrase)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encrypt private key for account %q", a.name)
+		}
+		publicKeys[fmt.Sprintf("%x", a.publicKey.Marshal())] = privateKey.PublicKey()
+		a.publicKey = privateKey.PublicKey()
+		a.crypto = accountCrypto
+		a.version = w.encryptor.Version()
+		return nil
+	}
+	accounts := make([]*account, 0)
+	for walletAccount := range w.Accounts() {
+		a, ok := walletAccount.(*account)
+		if !ok {
+			return nil, fmt.Errorf("account %q type unexpected", walletAccount.Name())
+		}
+		if a.imported {
+			// Imported keys are not derived from the seed.
+			continue
+		}
+		if err := rederive(a); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	// Deleted accounts are re-derived as well, so that restoring one after the reseed provides a key from the new seed.
+	deletedAccounts := make([]*account, 0)
+	for walletAccount := range w.DeletedAccounts() {
+		a := walletAccount.(*account)
+		if a.imported {
+			continue
+		}
+		if err := rederive(a); err != nil {
+			return nil, err
+		}
+		deletedAccounts = append(deletedAccounts, a)
+	}
+
+	w.crypto = crypto
+	// Any stored mnemonic and additional passphrases no longer correspond to the seed.
+	w.mnemonicCrypto = nil
+	w.additionalCrypto = nil
+	// The new seed is protected by the supplied passphrase alone.
+	w.threshold = 0
+	w.shareCrypto = nil
+	seed := make([]byte, len(newSeed))
+	copy(seed, newSeed)
+	if err := w.setSeed(seed); err != nil {
+		return nil, err
+	}
+	w.accountCache.clear()
+	if err := w.storeWallet(); err != nil {
+		return nil, errors.Wrap(err, "failed to store wallet")
+	}
+	for _, a := range accounts {
+		if err := a.storeAccount(); err != nil {
+			return nil, errors.Wrapf(err, "failed to store account %q", a.name)
+		}
+	}
+	for _, a := range deletedAccounts {
+		if err := w.storeAccountData(a); err != nil {
+			return nil, errors.Wrapf(err, "failed to store deleted account %q", a.name)
+		}
+	}
+
+	return publicKeys, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestReseed(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	oldSeed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	newSeed := _byteArray("2102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithSeed(oldSeed), hd.WithPassphrase([]byte("old passphrase")))
+	require.NoError(t, err)
+	reseeder, isReseeder := wallet.(hd.WalletReseeder)
+	require.True(t, isReseeder)
+
+	_, err = reseeder.Reseed(newSeed, []byte("new passphrase"))
+	assert.EqualError(t, err, "wallet must be unlocked to reseed")
+
+	require.NoError(t, wallet.Unlock([]byte("old passphrase")))
+	account1, err := wallet.CreateAccount("Account 1", []byte("account passphrase"))
+	require.NoError(t, err)
+	account2, err := wallet.CreateAccount("Account 2", []byte("account passphrase"))
+	require.NoError(t, err)
+
+	_, err = reseeder.Reseed([]byte{0x01}, []byte("new passphrase"))
+	assert.EqualError(t, err, "seed must be between 32 and 64 bytes")
+
+	publicKeys, err := reseeder.Reseed(newSeed, []byte("new passphrase"))
+	require.NoError(t, err)
+	require.Len(t, publicKeys, 2)
+
+	// Create a reference wallet from the new seed to obtain the expected keys.
+	refWallet, err := hd.CreateWallet("reference wallet", scratch.New(), encryptor, hd.WithSeed(newSeed))
+	require.NoError(t, err)
+	require.NoError(t, refWallet.Unlock(nil))
+	refAccount1, err := refWallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	refAccount2, err := refWallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, refAccount1.PublicKey().Marshal(), publicKeys[fmt.Sprintf("%x", account1.PublicKey().Marshal())].Marshal())
+	assert.Equal(t, refAccount2.PublicKey().Marshal(), publicKeys[fmt.Sprintf("%x", account2.PublicKey().Marshal())].Marshal())
+
+	// Ensure the changes were persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.NotNil(t, wallet.Unlock([]byte("old passphrase")))
+	require.NoError(t, wallet.Unlock([]byte("new passphrase")))
+	account, err := wallet.AccountByName("Account 1")
+	require.NoError(t, err)
+	assert.Equal(t, account1.ID(), account.ID())
+	assert.Equal(t, account1.Path(), account.Path())
+	assert.Equal(t, refAccount1.PublicKey().Marshal(), account.PublicKey().Marshal())
+	assert.NotNil(t, account.Unlock([]byte("account passphrase")))
+	require.NoError(t, account.Unlock([]byte("new passphrase")))
+	account3, err := wallet.CreateAccount("Account 3", nil)
+	require.NoError(t, err)
+	refAccount3, err := refWallet.CreateAccount("Account 3", nil)
+	require.NoError(t, err)
+	assert.Equal(t, refAccount3.PublicKey().Marshal(), account3.PublicKey().Marshal())
+}
+
+func TestReseedDeletedAccount(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	oldSeed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	newSeed := _byteArray("2102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithSeed(oldSeed))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	deleted, err := wallet.CreateAccount("Deleted", []byte("account passphrase"))
+	require.NoError(t, err)
+	require.NoError(t, wallet.(hd.WalletAccountDeleter).DeleteAccount("Deleted"))
+
+	publicKeys, err := wallet.(hd.WalletReseeder).Reseed(newSeed, []byte("new passphrase"))
+	require.NoError(t, err)
+	require.Len(t, publicKeys, 2)
+
+	refWallet, err := hd.CreateWallet("reference wallet", scratch.New(), encryptor, hd.WithSeed(newSeed))
+	require.NoError(t, err)
+	require.NoError(t, refWallet.Unlock(nil))
+	refAccount, err := refWallet.(hd.WalletAccountByPathProvider).AccountByPath(deleted.Path())
+	require.NoError(t, err)
+	assert.Equal(t, refAccount.PublicKey().Marshal(), publicKeys[fmt.Sprintf("%x", deleted.PublicKey().Marshal())].Marshal())
+
+	// The restored account has the key derived from the new seed, protected by the new passphrase.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	restored, err := wallet.(hd.WalletAccountRestorer).RestoreAccount(deleted.ID())
+	require.NoError(t, err)
+	assert.Equal(t, refAccount.PublicKey().Marshal(), restored.PublicKey().Marshal())
+	assert.NotNil(t, restored.Unlock([]byte("account passphrase")))
+	require.NoError(t, restored.Unlock([]byte("new passphrase")))
+	signature, err := restored.Sign([]byte("data"))
+	require.NoError(t, err)
+	assert.True(t, signature.Verify([]byte("data"), refAccount.PublicKey()))
+}
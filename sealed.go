@@ -0,0 +1,95 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+)
+
+// sealedSeed is a seed held in memory encrypted under a random memory key, so that the plaintext seed only exists
+// while it is in use.
+type sealedSeed struct {
+	key        []byte
+	nonce      []byte
+	ciphertext []byte
+}
+
+// newSealedSeed encrypts a seed under a new random memory key.  The supplied seed is overwritten.
+func newSealedSeed(seed []byte) (*sealedSeed, error) {
+	defer zeroBytes(seed)
+
+	key := make([]byte, keyLength)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(err, "failed to generate memory key")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	return &sealedSeed{
+		key:        key,
+		nonce:      nonce,
+		ciphertext: aead.Seal(nil, nonce, seed, nil),
+	}, nil
+}
+
+// open decrypts the seed.  The caller should overwrite the returned seed when it has finished with it.
+func (s *sealedSeed) open() ([]byte, error) {
+	aead, err := newAEAD(s.key)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := aead.Open(nil, s.nonce, s.ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("sealed seed corrupt")
+	}
+	return seed, nil
+}
+
+// destroy overwrites the memory key and encrypted seed.
+func (s *sealedSeed) destroy() {
+	zeroBytes(s.key)
+	zeroBytes(s.ciphertext)
+}
+
+// hasSeed returns true if the wallet holds its seed, either directly or sealed.
+// The caller must hold the wallet's lock.
+func (w *wallet) hasSeed() bool {
+	return w.seed != nil || w.sealed != nil
+}
+
+// useSeed calls the supplied function with the wallet's seed.  If the seed is sealed it is decrypted for the
+// duration of the call only.  The function must not retain the seed.
+// The caller must hold the wallet's lock.
+func (w *wallet) useSeed(fn func(seed []byte) error) error {
+	if w.sealed == nil {
+		if w.seed == nil {
+			return errors.New("wallet is locked")
+		}
+		return fn(w.seed)
+	}
+	seed, err := w.sealed.open()
+	if err != nil {
+		return err
+	}
+	defer zeroBytes(seed)
+	return fn(seed)
+}
@@ -0,0 +1,74 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestSealedSeed(t *testing.T) {
+	seed := []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+		0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+	}
+	refWallet, _, err := newWalletFromOptions("reference wallet", scratch.New(), keystorev4.New(), &options{
+		seed:          seed,
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, refWallet.Unlock(nil))
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		seed:          seed,
+		minSeedLength: minSeedLength,
+		sealSeed:      true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+
+	// The seed is only held sealed.
+	assert.True(t, w.IsUnlocked())
+	assert.Nil(t, w.seed)
+	assert.Nil(t, w.node)
+	require.NotNil(t, w.sealed)
+	assert.NotContains(t, string(w.sealed.ciphertext), string(seed))
+
+	key, err := w.Key()
+	require.NoError(t, err)
+	assert.Equal(t, seed, key)
+
+	account, err := w.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	refAccount, err := refWallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, refAccount.PublicKey().Marshal(), account.PublicKey().Marshal())
+
+	executionKey, err := w.ExecutionKey(account)
+	require.NoError(t, err)
+	refExecutionKey, err := refWallet.ExecutionKey(refAccount)
+	require.NoError(t, err)
+	assert.Equal(t, refExecutionKey.Address, executionKey.Address)
+
+	sealed := w.sealed
+	w.Lock()
+	assert.False(t, w.IsUnlocked())
+	assert.Nil(t, w.sealed)
+	assert.Equal(t, make([]byte, len(sealed.key)), sealed.key)
+	_, err = w.Key()
+	assert.EqualError(t, err, "wallet must be unlocked to provide seed")
+}
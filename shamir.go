@@ -0,0 +1,140 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+)
+
+// gf256Exp and gf256Log are exponent and logarithm tables for GF(2^8) with the AES polynomial and generator 3.
+var gf256Exp, gf256Log = func() ([510]byte, [256]byte) {
+	var exp [510]byte
+	var log [256]byte
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		exp[i+255] = x
+		log[x] = byte(i)
+		// Multiply x by the generator 3.
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x ^= x2
+	}
+	return exp, log
+}()
+
+// gf256Mul multiplies two elements of GF(2^8).
+func gf256Mul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gf256Exp[int(gf256Log[a])+int(gf256Log[b])]
+}
+
+// gf256Div divides two elements of GF(2^8).  b must not be 0.
+func gf256Div(a byte, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gf256Exp[int(gf256Log[a])+255-int(gf256Log[b])]
+}
+
+// splitSecret splits a secret in to the given number of shares using Shamir's secret sharing scheme, such that any
+// threshold of the shares can recreate the secret.  Each share is one byte longer than the secret, the first byte
+// being the share's x coordinate.
+func splitSecret(secret []byte, threshold int, shares int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret missing")
+	}
+	if threshold < 2 {
+		return nil, errors.New("threshold must be at least 2")
+	}
+	if shares < threshold {
+		return nil, errors.New("number of shares must be at least the threshold")
+	}
+	if shares > 255 {
+		return nil, errors.New("number of shares must be at most 255")
+	}
+
+	res := make([][]byte, shares)
+	for i := range res {
+		res[i] = make([]byte, len(secret)+1)
+		res[i][0] = byte(i + 1)
+	}
+	// Each byte of the secret is the constant term of a random polynomial of degree threshold-1.
+	coefficients := make([]byte, threshold)
+	defer zeroBytes(coefficients)
+	for i := range secret {
+		coefficients[0] = secret[i]
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, errors.Wrap(err, "failed to generate coefficients")
+		}
+		for _, share := range res {
+			// Evaluate the polynomial at the share's x coordinate with Horner's method.
+			y := byte(0)
+			for j := threshold - 1; j >= 0; j-- {
+				y = gf256Mul(y, share[0]) ^ coefficients[j]
+			}
+			share[i+1] = y
+		}
+	}
+
+	return res, nil
+}
+
+// combineShares recreates a secret from shares created by splitSecret.  At least the threshold number of shares
+// must be supplied, otherwise the result will not be the secret.
+func combineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least 2 shares required")
+	}
+	length := len(shares[0])
+	if length < 2 {
+		return nil, errors.New("share invalid")
+	}
+	seen := make(map[byte]bool)
+	for _, share := range shares {
+		if len(share) != length {
+			return nil, errors.New("shares have different lengths")
+		}
+		if share[0] == 0 || seen[share[0]] {
+			return nil, errors.New("share coordinates invalid")
+		}
+		seen[share[0]] = true
+	}
+
+	// Interpolate each byte of the secret at x=0 with Lagrange's method.
+	secret := make([]byte, length-1)
+	for i := range secret {
+		value := byte(0)
+		for j, share := range shares {
+			basis := byte(1)
+			for k, other := range shares {
+				if j == k {
+					continue
+				}
+				basis = gf256Mul(basis, gf256Div(other[0], share[0]^other[0]))
+			}
+			value ^= gf256Mul(share[i+1], basis)
+		}
+		secret[i] = value
+	}
+
+	return secret, nil
+}
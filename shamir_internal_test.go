@@ -0,0 +1,70 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShamir(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	shares, err := splitSecret(secret, 3, 5)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+
+	// Any 3 shares recreate the secret.
+	for i := 0; i < 5; i++ {
+		for j := i + 1; j < 5; j++ {
+			for k := j + 1; k < 5; k++ {
+				combined, err := combineShares([][]byte{shares[k], shares[i], shares[j]})
+				require.NoError(t, err)
+				assert.Equal(t, secret, combined)
+			}
+		}
+	}
+	// All shares recreate the secret.
+	combined, err := combineShares(shares)
+	require.NoError(t, err)
+	assert.Equal(t, secret, combined)
+	// 2 shares do not.
+	combined, err = combineShares(shares[:2])
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, combined)
+}
+
+func TestShamirErrors(t *testing.T) {
+	secret := []byte("secret")
+
+	_, err := splitSecret(nil, 2, 3)
+	assert.EqualError(t, err, "secret missing")
+	_, err = splitSecret(secret, 1, 3)
+	assert.EqualError(t, err, "threshold must be at least 2")
+	_, err = splitSecret(secret, 3, 2)
+	assert.EqualError(t, err, "number of shares must be at least the threshold")
+	_, err = splitSecret(secret, 2, 256)
+	assert.EqualError(t, err, "number of shares must be at most 255")
+
+	shares, err := splitSecret(secret, 2, 3)
+	require.NoError(t, err)
+	_, err = combineShares(shares[:1])
+	assert.EqualError(t, err, "at least 2 shares required")
+	_, err = combineShares([][]byte{shares[0], shares[0]})
+	assert.EqualError(t, err, "share coordinates invalid")
+	_, err = combineShares([][]byte{shares[0], shares[1][:3]})
+	assert.EqualError(t, err, "shares have different lengths")
+}
@@ -0,0 +1,136 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// WalletStats contains summary statistics for a wallet.
+type WalletStats struct {
+	// Accounts is the number of accounts in the wallet, excluding deleted accounts.
+	Accounts int
+	// HighestAccount is the highest account number in use by the wallet's accounts.
+	// It is only meaningful if the wallet has accounts whose paths match its path template.
+	HighestAccount uint64
+	// Gaps are the ranges of account numbers below the next account number that are not in use by any account.
+	// The account numbers of deleted accounts are not gaps.
+	Gaps []*IndexGap
+	// Created is the time at which the wallet was created.  It is zero for wallets that pre-date version 2.
+	Created time.Time
+	// Size is the total size in bytes of the serialized wallet and its accounts, including deleted accounts that have
+	// not been purged.
+	Size int
+}
+
+// IndexGap is a range of account numbers that are not in use, from Start up to but not including End.
+type IndexGap struct {
+	Start uint64
+	End   uint64
+}
+
+// Stats provides summary statistics for the wallet.
+// The statistics are generated from the stored data without decrypting any keys, and the wallet does not need to
+// be unlocked.
+func (w *wallet) Stats() (*WalletStats, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	stats := &WalletStats{
+		Created: w.created,
+		Size:    len(data),
+	}
+
+	used := make(map[uint64]bool)
+	for data := range w.store.RetrieveAccounts(w.id) {
+		a, err := deserializeAccount(w, data)
+		if err != nil {
+			continue
+		}
+		stats.Size += len(data)
+		if a.(*account).deleted {
+			continue
+		}
+		stats.Accounts++
+		if accountNum, exists := w.accountNumber(a.Path()); exists {
+			used[accountNum] = true
+			if accountNum > stats.HighestAccount {
+				stats.HighestAccount = accountNum
+			}
+		}
+	}
+	stats.Gaps = w.gaps(used)
+
+	return stats, nil
+}
+
+// IndexGaps provides the ranges of account numbers below the next account number that are not in use by any account.
+// Gaps occur when account numbers are skipped with SetNextAccount(), or when an account number is reserved but its
+// account then fails to be stored.  The account numbers of deleted accounts are not gaps.
+func (w *wallet) IndexGaps() ([]*IndexGap, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	used := make(map[uint64]bool)
+	for data := range w.store.RetrieveAccounts(w.id) {
+		a, err := deserializeAccount(w, data)
+		if err != nil {
+			continue
+		}
+		if accountNum, exists := w.accountNumber(a.Path()); exists {
+			used[accountNum] = true
+		}
+	}
+
+	return w.gaps(used), nil
+}
+
+// gaps provides the ranges of account numbers below the next account number that are neither in use nor deleted.
+// The ranges are obtained from the account numbers that are in use or deleted, so are bounded by the number of
+// accounts rather than by the next account number.
+func (w *wallet) gaps(used map[uint64]bool) []*IndexGap {
+	occupied := make([]uint64, 0, len(used)+len(w.tombstones))
+	for accountNum := range used {
+		occupied = append(occupied, accountNum)
+	}
+	for path := range w.tombstones {
+		if accountNum, exists := w.accountNumber(path); exists && !used[accountNum] {
+			occupied = append(occupied, accountNum)
+		}
+	}
+	sort.Slice(occupied, func(i int, j int) bool {
+		return occupied[i] < occupied[j]
+	})
+
+	gaps := make([]*IndexGap, 0)
+	start := uint64(0)
+	for _, accountNum := range occupied {
+		if accountNum > start {
+			gaps = append(gaps, &IndexGap{Start: start, End: accountNum})
+		}
+		if accountNum+1 > start {
+			start = accountNum + 1
+		}
+	}
+	if w.nextAccount > start {
+		gaps = append(gaps, &IndexGap{Start: start, End: w.nextAccount})
+	}
+	return gaps
+}
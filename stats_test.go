@@ -0,0 +1,118 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestStats(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	statsProvider, isStatsProvider := wallet.(hd.WalletStatsProvider)
+	require.True(t, isStatsProvider)
+
+	stats, err := statsProvider.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.Accounts)
+	assert.Equal(t, uint64(0), stats.HighestAccount)
+	assert.Empty(t, stats.Gaps)
+	assert.False(t, stats.Created.IsZero())
+	emptySize := stats.Size
+	assert.True(t, emptySize > 0)
+
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	require.NoError(t, wallet.(hd.WalletNextAccountSetter).SetNextAccount(3))
+	_, err = wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	wallet.Lock()
+
+	stats, err = statsProvider.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Accounts)
+	assert.Equal(t, uint64(3), stats.HighestAccount)
+	assert.Equal(t, []*hd.IndexGap{{Start: 1, End: 3}}, stats.Gaps)
+	assert.True(t, stats.Size > emptySize)
+}
+
+func TestIndexGaps(t *testing.T) {
+	store := &failingAccountStore{Store: scratch.New()}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	provider, isProvider := wallet.(hd.WalletIndexGapsProvider)
+	require.True(t, isProvider)
+
+	gaps, err := provider.IndexGaps()
+	require.NoError(t, err)
+	assert.Empty(t, gaps)
+
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	// Fail to store an account after its account number has been reserved.
+	store.fail = true
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NotNil(t, err)
+	store.fail = false
+	_, err = wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	require.NoError(t, wallet.(hd.WalletNextAccountSetter).SetNextAccount(5))
+	_, err = wallet.CreateAccount("Account 5", nil)
+	require.NoError(t, err)
+
+	gaps, err = provider.IndexGaps()
+	require.NoError(t, err)
+	assert.Equal(t, []*hd.IndexGap{{Start: 1, End: 2}, {Start: 3, End: 5}}, gaps)
+
+	// Filling a gap removes it.
+	_, err = wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Account 1", 1, nil)
+	require.NoError(t, err)
+	gaps, err = provider.IndexGaps()
+	require.NoError(t, err)
+	assert.Equal(t, []*hd.IndexGap{{Start: 3, End: 5}}, gaps)
+}
+
+func TestIndexGapsLarge(t *testing.T) {
+	wallet, err := hd.CreateWallet("test wallet", scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	_, err = wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Account 1000", 1000, nil)
+	require.NoError(t, err)
+	_, err = wallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Account max", math.MaxInt32, nil)
+	require.NoError(t, err)
+
+	// Gaps are reported as ranges, so a high next account number does not require a gap for each account number.
+	gaps, err := wallet.(hd.WalletIndexGapsProvider).IndexGaps()
+	require.NoError(t, err)
+	assert.Equal(t, []*hd.IndexGap{{Start: 1, End: 1000}, {Start: 1001, End: math.MaxInt32}}, gaps)
+	stats, err := wallet.(hd.WalletStatsProvider).Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Accounts)
+	assert.Equal(t, uint64(math.MaxInt32), stats.HighestAccount)
+	assert.Equal(t, gaps, stats.Gaps)
+}
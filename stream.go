@@ -0,0 +1,541 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Exports start with a header containing a magic value, the format version and an identifier for the encryption
+// used, followed by a salt and a sequence of chunks.  Each chunk is a 4-byte big-endian length, the top bit of which
+// flags the final chunk, followed by the chunk encrypted with AES-256-GCM.  The nonce for each chunk is its sequence
+// number and final flag, so chunks cannot be reordered or truncated, and the header is authenticated with each chunk.
+// The decrypted chunks form a stream of JSON values: the wallet, a record of the number of accounts, and each of the
+// accounts.
+// Version 2 exports add an identifier for the compression used to the header, and the stream of JSON values is
+// compressed before it is encrypted.
+const (
+	exportVersion       = 2
+	exportSaltLength    = 32
+	exportKDFIterations = 262144
+	exportChunkSize     = 64 * 1024
+	exportFinalChunk    = uint32(1) << 31
+)
+
+// ProgressFunc is a function called with the number of accounts processed and the total number of accounts as a wallet
+// is exported or imported.  The total is 0 when importing exports made by earlier versions of this module, which do
+// not record the number of accounts.
+type ProgressFunc func(processed uint64, total uint64)
+
+// countRecord is the JSON value following the wallet in an export, giving the number of accounts in the export.
+type countRecord struct {
+	Accounts *uint64 `json:"accounts"`
+}
+
+// exportEncryptor identifies the encryption used by version 1 and 2 exports.
+const exportEncryptor = "pbkdf2-sha256-aes-256-gcm"
+
+// exportCompression identifies the compression used by version 2 exports.
+const exportCompression = "gzip"
+
+// exportMagic is the magic value at the start of a versioned export.
+var exportMagic = []byte("e2wv")
+
+// streamExportMagic is the magic value at the start of a streamed export prior to versioned exports, which has no
+// header beyond the magic value and is otherwise as per version 1.
+var streamExportMagic = []byte("e2ws")
+
+// ExportTo exports the entire wallet to the writer, protected by an additional passphrase.
+// The wallet is encrypted in chunks as it is written, so exports of wallets with many accounts are not held in memory.
+// The export carries an integrity tag only if the wallet is unlocked; exports of locked wallets cannot be imported
+// with WithIntegrityCheck.
+func (w *wallet) ExportTo(writer io.Writer, passphrase []byte) error {
+	return w.exportTo(writer, passphrase, nil)
+}
+
+// exportTo writes the header and chunks of an export, generating a random salt if one is not supplied.
+// A supplied salt makes the export reproducible, which is for testing only: the salt determines the key and the nonces
+// of every chunk, so two exports with different contents under the same passphrase and salt reuse the AES-GCM key and
+// nonces, revealing their contents and allowing their chunks to be forged.
+func (w *wallet) exportTo(writer io.Writer, passphrase []byte, salt []byte) error {
+	header := exportHeader(exportVersion, exportEncryptor, exportCompression)
+	if _, err := writer.Write(header); err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	return w.exportChunks(writer, passphrase, header, exportCompression, salt)
+}
+
+// exportHeader provides the header for an export.  Version 1 headers have no compression identifier.
+func exportHeader(version byte, encryptor string, compression string) []byte {
+	header := make([]byte, 0, len(exportMagic)+3+len(encryptor)+len(compression))
+	header = append(header, exportMagic...)
+	header = append(header, version, byte(len(encryptor)))
+	header = append(header, encryptor...)
+	if version == 1 {
+		return header
+	}
+	header = append(header, byte(len(compression)))
+	return append(header, compression...)
+}
+
+// exportChunks writes the salt and the encrypted chunks of the export, authenticating the header with each chunk.
+// The JSON values are compressed before they are encrypted if compression is supplied, and a random salt is generated
+// if salt is not supplied.
+func (w *wallet) exportChunks(writer io.Writer, passphrase []byte, header []byte, compression string, salt []byte) error {
+	if salt == nil {
+		salt = make([]byte, exportSaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return errors.Wrap(err, "failed to generate salt")
+		}
+	}
+	if _, err := writer.Write(salt); err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	aead, err := exportAEAD(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	chunks := newChunkWriter(writer, aead, header)
+	switch compression {
+	case "":
+		if err := w.exportJSON(chunks); err != nil {
+			return err
+		}
+	case exportCompression:
+		compressor := gzip.NewWriter(chunks)
+		if err := w.exportJSON(compressor); err != nil {
+			return err
+		}
+		if err := compressor.Close(); err != nil {
+			return errors.Wrap(err, "failed to compress export")
+		}
+	default:
+		return fmt.Errorf("unsupported export compression %q", compression)
+	}
+	return chunks.Close()
+}
+
+// exportJSON writes the unencrypted export, a stream of JSON values: the wallet, the number of accounts, each of the
+// accounts, and an integrity record if the wallet is unlocked.
+// The export is canonical: keys are sorted and accounts are in order of name, so the same wallet is always exported
+// the same way.
+func (w *wallet) exportJSON(writer io.Writer) error {
+	digest := sha256.New()
+	encoder := json.NewEncoder(io.MultiWriter(writer, digest))
+	if err := encoder.Encode(w); err != nil {
+		return errors.Wrap(err, "failed to export wallet")
+	}
+	// The order is taken from the accounts index, so each account is retrieved from the store as it is exported.
+	w.mutex.RLock()
+	entries, err := w.indexEntries()
+	w.mutex.RUnlock()
+	if err != nil {
+		return errors.Wrap(err, "failed to obtain accounts index")
+	}
+	count := uint64(len(entries))
+	if err := encoder.Encode(&countRecord{Accounts: &count}); err != nil {
+		return errors.Wrap(err, "failed to export wallet")
+	}
+	for i, entry := range entries {
+		acc, err := w.AccountByID(entry.ID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to obtain account %q", entry.Name)
+		}
+		if err := encoder.Encode(acc); err != nil {
+			return errors.Wrapf(err, "failed to export account %q", entry.Name)
+		}
+		if w.progress != nil {
+			w.progress(uint64(i+1), count)
+		}
+	}
+	record, err := w.exportIntegrityRecord(digest.Sum(nil))
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		if err := json.NewEncoder(writer).Encode(record); err != nil {
+			return errors.Wrap(err, "failed to export integrity record")
+		}
+	}
+	return nil
+}
+
+// ImportFrom imports the entire wallet from the reader, protected by an additional passphrase.
+// The format of the export is obtained from its header, so exports made by earlier versions of this module can be
+// imported.
+// A wallet exported from a wallet created with WithPathProvider must be imported with the same provider.
+func ImportFrom(reader io.Reader, passphrase []byte, store wtypes.Store, encryptor wtypes.Encryptor, opts ...Option) (wtypes.Wallet, error) {
+	options := options{}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	w, nextAccount, err := decodeExport(reader, passphrase, &options)
+	if err != nil {
+		return nil, err
+	}
+	return importWallet(w, nextAccount, store, encryptor, opts)
+}
+
+// decodeExport decodes the wallet from an export, providing a function that decodes its accounts in turn, returning
+// nil once all accounts have been decoded.  Exports encrypted to age recipients or OpenPGP keys are decrypted with the
+// identities or keys in the options.
+func decodeExport(reader io.Reader, passphrase []byte, options *options) (*wallet, func() (*account, error), error) {
+	buffered := bufio.NewReader(reader)
+	start, _ := buffered.Peek(len(openPGPMagic))
+	magic, err := buffered.Peek(len(exportMagic))
+	switch {
+	case err == nil && bytes.Equal(magic, ageMagic):
+		return decodeAgeExport(buffered, passphrase, options.ageIdentities)
+	case bytes.Equal(start, openPGPMagic):
+		return decodeOpenPGPExport(buffered, passphrase, options.pgpKeyring, options.pgpKeyPassphrase)
+	case err == nil && bytes.Equal(magic, exportMagic):
+		version, id, compression, header, err := readExportHeader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch version {
+		case 1:
+			if id != exportEncryptor {
+				return nil, nil, fmt.Errorf("unsupported export encryptor %q", id)
+			}
+			return decodeChunks(buffered, passphrase, header, "")
+		case 2:
+			if id != exportEncryptor {
+				return nil, nil, fmt.Errorf("unsupported export encryptor %q", id)
+			}
+			if compression != exportCompression {
+				return nil, nil, fmt.Errorf("unsupported export compression %q", compression)
+			}
+			return decodeChunks(buffered, passphrase, header, compression)
+		default:
+			return nil, nil, fmt.Errorf("unsupported export version %d", version)
+		}
+	case err == nil && bytes.Equal(magic, streamExportMagic):
+		if _, err := buffered.Discard(len(streamExportMagic)); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read export")
+		}
+		return decodeChunks(buffered, passphrase, nil, "")
+	default:
+		data, err := ioutil.ReadAll(buffered)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read export")
+		}
+		return decodeLegacy(data, passphrase)
+	}
+}
+
+// decodeEnclosedExport decodes a wallet from the decrypted contents of an export encrypted to recipients, which is
+// either the unencrypted export or an export protected by a passphrase.
+func decodeEnclosedExport(reader io.Reader, passphrase []byte) (*wallet, func() (*account, error), error) {
+	buffered := bufio.NewReader(reader)
+	start, err := buffered.Peek(1)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read export")
+	}
+	if start[0] == '{' {
+		return decodeJSON(buffered)
+	}
+	return decodeExport(buffered, passphrase, &options{})
+}
+
+// readExportHeader reads the header of a versioned export, returning the version, encryptor identifier, compression
+// identifier and raw header.
+func readExportHeader(reader io.Reader) (byte, string, string, []byte, error) {
+	header := make([]byte, len(exportMagic)+2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return 0, "", "", nil, errors.New("export truncated")
+	}
+	version := header[len(exportMagic)]
+	id := make([]byte, header[len(exportMagic)+1])
+	if _, err := io.ReadFull(reader, id); err != nil {
+		return 0, "", "", nil, errors.New("export truncated")
+	}
+	header = append(header, id...)
+	if version != 2 {
+		return version, string(id), "", header, nil
+	}
+
+	compressionLen := make([]byte, 1)
+	if _, err := io.ReadFull(reader, compressionLen); err != nil {
+		return 0, "", "", nil, errors.New("export truncated")
+	}
+	compression := make([]byte, compressionLen[0])
+	if _, err := io.ReadFull(reader, compression); err != nil {
+		return 0, "", "", nil, errors.New("export truncated")
+	}
+	header = append(append(header, compressionLen...), compression...)
+	return version, string(id), string(compression), header, nil
+}
+
+// decodeChunks decodes a wallet from the salt and encrypted chunks of an export, decompressing them if compression
+// is supplied.
+func decodeChunks(reader io.Reader, passphrase []byte, header []byte, compression string) (*wallet, func() (*account, error), error) {
+	salt := make([]byte, exportSaltLength)
+	if _, err := io.ReadFull(reader, salt); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read export")
+	}
+	aead, err := exportAEAD(passphrase, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks := newChunkReader(reader, aead, header)
+	if compression == "" {
+		return decodeJSON(chunks)
+	}
+	decompressor, err := gzip.NewReader(chunks)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to import wallet")
+	}
+	return decodeJSON(decompressor)
+}
+
+// decodeJSON decodes a wallet from the unencrypted export written by exportJSON.
+// The digest of the values is calculated as they are decoded, and the integrity record if present is set on the wallet.
+func decodeJSON(reader io.Reader) (*wallet, func() (*account, error), error) {
+	decoder := json.NewDecoder(reader)
+	digest := sha256.New()
+	// next decodes the next JSON value, adding it to the digest as it was encoded.
+	next := func() (json.RawMessage, error) {
+		var data json.RawMessage
+		if err := decoder.Decode(&data); err != nil {
+			return nil, err
+		}
+		digest.Write(data)
+		digest.Write([]byte("\n"))
+		return data, nil
+	}
+
+	data, err := next()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to import wallet")
+	}
+	w := newWallet()
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to import wallet")
+	}
+	// The wallet is followed by the number of accounts, except in exports made by earlier versions of this module, so
+	// the next value is held if it is not the count.
+	var pending json.RawMessage
+	var pendingSum []byte
+	if decoder.More() {
+		pendingSum = digest.Sum(nil)
+		if pending, err = next(); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to import wallet")
+		}
+		count := &countRecord{}
+		if err := json.Unmarshal(pending, count); err == nil && count.Accounts != nil {
+			w.exportAccountCount = *count.Accounts
+			pending = nil
+		}
+	}
+	return w, func() (*account, error) {
+		var sum []byte
+		var data json.RawMessage
+		if pending != nil {
+			sum, data = pendingSum, pending
+			pending = nil
+		} else {
+			if !decoder.More() {
+				// Ensure that the export is complete.
+				if _, err := decoder.Token(); err != io.EOF {
+					return nil, errors.Wrap(err, "failed to import accounts")
+				}
+				return nil, nil
+			}
+			sum = digest.Sum(nil)
+			var err error
+			if data, err = next(); err != nil {
+				return nil, errors.Wrap(err, "failed to import account")
+			}
+		}
+		record := &integrityRecord{}
+		if err := json.Unmarshal(data, record); err == nil && record.Integrity != "" {
+			tag, err := hex.DecodeString(record.Integrity)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to import integrity record")
+			}
+			w.exportIntegrity = &exportIntegrity{
+				digest: sum,
+				tag:    tag,
+			}
+			// The record is the final value of the export.
+			if _, err := decoder.Token(); err != io.EOF {
+				return nil, errors.New("failed to import account: data after integrity record")
+			}
+			return nil, nil
+		}
+		acc := newAccount()
+		acc.wallet = w
+		if err := json.Unmarshal(data, acc); err != nil {
+			return nil, errors.Wrap(err, "failed to import account")
+		}
+		return acc, nil
+	}, nil
+}
+
+// exportAEAD provides the cipher for a streamed export.
+func exportAEAD(passphrase []byte, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2.Key(passphrase, salt, exportKDFIterations, keyLength, sha256.New)
+	defer zeroBytes(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create export cipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce provides the nonce for a chunk.
+func chunkNonce(aead cipher.AEAD, sequence uint64, final bool) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce, sequence)
+	if final {
+		nonce[len(nonce)-1] = 0x01
+	}
+	return nonce
+}
+
+// chunkWriter encrypts data in chunks as it is written.  It must be closed to write the final chunk.
+type chunkWriter struct {
+	writer   io.Writer
+	aead     cipher.AEAD
+	aad      []byte
+	sequence uint64
+	buf      []byte
+}
+
+func newChunkWriter(writer io.Writer, aead cipher.AEAD, aad []byte) *chunkWriter {
+	return &chunkWriter{
+		writer: writer,
+		aead:   aead,
+		aad:    aad,
+		buf:    make([]byte, 0, exportChunkSize),
+	}
+}
+
+// Write buffers data, writing a chunk each time the buffer is full.  The last chunk is always held back, so that it
+// can be flagged as final when the writer is closed.
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > exportChunkSize-len(c.buf) {
+		space := exportChunkSize - len(c.buf)
+		c.buf = append(c.buf, p[:space]...)
+		p = p[space:]
+		if err := c.flush(false); err != nil {
+			return 0, err
+		}
+	}
+	c.buf = append(c.buf, p...)
+	return n, nil
+}
+
+// Close writes the final chunk.
+func (c *chunkWriter) Close() error {
+	return c.flush(true)
+}
+
+func (c *chunkWriter) flush(final bool) error {
+	sealed := c.aead.Seal(nil, chunkNonce(c.aead, c.sequence, final), c.buf, c.aad)
+	zeroBytes(c.buf)
+	c.buf = c.buf[:0]
+	c.sequence++
+
+	header := uint32(len(sealed))
+	if final {
+		header |= exportFinalChunk
+	}
+	if err := binary.Write(c.writer, binary.BigEndian, header); err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	if _, err := c.writer.Write(sealed); err != nil {
+		return errors.Wrap(err, "failed to write export")
+	}
+	return nil
+}
+
+// chunkReader decrypts data written by chunkWriter as it is read.
+type chunkReader struct {
+	reader   io.Reader
+	aead     cipher.AEAD
+	aad      []byte
+	sequence uint64
+	buf      []byte
+	final    bool
+}
+
+func newChunkReader(reader io.Reader, aead cipher.AEAD, aad []byte) *chunkReader {
+	return &chunkReader{
+		reader: reader,
+		aead:   aead,
+		aad:    aad,
+	}
+}
+
+// Read reads decrypted data.
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.final {
+			return 0, io.EOF
+		}
+		if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// next reads and decrypts the next chunk.
+func (c *chunkReader) next() error {
+	var header uint32
+	if err := binary.Read(c.reader, binary.BigEndian, &header); err != nil {
+		return errors.New("export truncated")
+	}
+	final := header&exportFinalChunk != 0
+	length := int(header &^ exportFinalChunk)
+	if length > exportChunkSize+c.aead.Overhead() {
+		return errors.New("export chunk too long")
+	}
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, sealed); err != nil {
+		return errors.New("export truncated")
+	}
+	data, err := c.aead.Open(sealed[:0], chunkNonce(c.aead, c.sequence, final), sealed, c.aad)
+	if err != nil {
+		if c.sequence == 0 {
+			return errors.New("invalid key")
+		}
+		return errors.New("export corrupt")
+	}
+	c.sequence++
+	c.buf = data
+	c.final = final
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestExportVersions(t *testing.T) {
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+	_, err = w.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, w.ExportTo(buf, []byte("export")))
+	data := buf.Bytes()
+	header := exportHeader(exportVersion, exportEncryptor, exportCompression)
+	require.True(t, bytes.HasPrefix(data, header))
+
+	// Version 1 exports are not compressed.
+	v1Header := exportHeader(1, exportEncryptor, "")
+	buf = bytes.NewBuffer(append([]byte{}, v1Header...))
+	require.NoError(t, w.exportChunks(buf, []byte("export"), v1Header, "", nil))
+	imported, err := ImportFrom(buf, []byte("export"), scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	_, err = imported.AccountByName("Account 1")
+	require.NoError(t, err)
+
+	// Streamed exports prior to versioning have no header beyond the magic value.
+	buf = bytes.NewBuffer(append([]byte{}, streamExportMagic...))
+	require.NoError(t, w.exportChunks(buf, []byte("export"), nil, "", nil))
+	imported, err = ImportFrom(buf, []byte("export"), scratch.New(), keystorev4.New())
+	require.NoError(t, err)
+	_, err = imported.AccountByName("Account 1")
+	require.NoError(t, err)
+
+	_, err = ImportFrom(bytes.NewReader(exportMagic), []byte("export"), scratch.New(), keystorev4.New())
+	assert.EqualError(t, err, "export truncated")
+
+	tests := []struct {
+		name   string
+		header []byte
+		err    string
+	}{
+		{
+			name:   "VersionUnknown",
+			header: exportHeader(3, exportEncryptor, exportCompression),
+			err:    "unsupported export version 3",
+		},
+		{
+			name:   "EncryptorUnknown",
+			header: exportHeader(exportVersion, "scrypt-aes-128-ctr", exportCompression),
+			err:    `unsupported export encryptor "scrypt-aes-128-ctr"`,
+		},
+		{
+			name:   "CompressionUnknown",
+			header: exportHeader(exportVersion, exportEncryptor, "zstd"),
+			err:    `unsupported export compression "zstd"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			export := append(append([]byte{}, test.header...), data[len(header):]...)
+			_, err := ImportFrom(bytes.NewReader(export), []byte("export"), scratch.New(), keystorev4.New())
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestExportAccountCount(t *testing.T) {
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+	_, err = w.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	_, err = w.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, w.exportJSON(buf))
+	lines := bytes.SplitAfter(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	require.Len(t, lines, 5)
+	assert.Equal(t, "{\"accounts\":2}\n", string(lines[1]))
+
+	tests := []struct {
+		name  string
+		data  []byte
+		count uint64
+	}{
+		{
+			name:  "Counted",
+			data:  buf.Bytes(),
+			count: 2,
+		},
+		{
+			// Exports made by earlier versions of this module do not have the count.
+			name: "Uncounted",
+			data: bytes.Join([][]byte{lines[0], lines[2], lines[3], lines[4]}, nil),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			imported, nextAccount, err := decodeJSON(bytes.NewReader(test.data))
+			require.NoError(t, err)
+			assert.Equal(t, test.count, imported.exportAccountCount)
+			names := make([]string, 0)
+			for {
+				acc, err := nextAccount()
+				require.NoError(t, err)
+				if acc == nil {
+					break
+				}
+				names = append(names, acc.name)
+			}
+			assert.Equal(t, []string{"Account 1", "Account 2"}, names)
+		})
+	}
+}
+
+func TestDeterministicExport(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	w, _, err := newWalletFromOptions("test wallet", store, encryptor, &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.storeWallet())
+	require.NoError(t, w.Unlock(nil))
+	for i := 0; i < 20; i++ {
+		_, err = w.CreateAccount(fmt.Sprintf("Account %d", i), nil)
+		require.NoError(t, err)
+	}
+
+	salt := bytes.Repeat([]byte{0x01}, exportSaltLength)
+	buf1 := new(bytes.Buffer)
+	require.NoError(t, w.exportTo(buf1, []byte("export"), salt))
+	// Exports from a reopened wallet, which retrieves its accounts afresh, are identical.
+	reopened, err := OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Unlock(nil))
+	buf2 := new(bytes.Buffer)
+	require.NoError(t, reopened.(*wallet).exportTo(buf2, []byte("export"), salt))
+	assert.Equal(t, buf1.Bytes(), buf2.Bytes())
+
+	// Exports with a random salt differ.
+	buf3 := new(bytes.Buffer)
+	require.NoError(t, w.ExportTo(buf3, []byte("export")))
+	assert.NotEqual(t, buf1.Bytes(), buf3.Bytes())
+	buf4 := new(bytes.Buffer)
+	require.NoError(t, w.ExportTo(buf4, []byte("export")))
+	assert.NotEqual(t, buf3.Bytes(), buf4.Bytes())
+
+	imported, err := ImportFrom(bytes.NewReader(buf1.Bytes()), []byte("export"), scratch.New(), encryptor, WithIntegrityCheck(nil))
+	require.NoError(t, err)
+	_, err = imported.AccountByName("Account 19")
+	require.NoError(t, err)
+}
@@ -0,0 +1,134 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wealdtech/go-ecodec"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestStreamedExport(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	// Create enough accounts for the compressed export to span multiple chunks.
+	names := make([]string, 750)
+	for i := range names {
+		names[i] = fmt.Sprintf("Account %d", i)
+	}
+	_, err = wallet.(hd.WalletAccountsCreator).CreateAccounts(names, nil)
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, wallet.(hd.WalletStreamExporter).ExportTo(buf, []byte("export")))
+	data := buf.Bytes()
+	require.Greater(t, len(data), 128*1024)
+
+	_, err = hd.ImportFrom(bytes.NewReader(data), []byte("wrong"), scratch.New(), encryptor)
+	assert.EqualError(t, err, "failed to import wallet: invalid key")
+
+	// Removing the final chunk is detected, and nothing is stored.
+	store2 := scratch.New()
+	_, err = hd.ImportFrom(bytes.NewReader(data[:len(data)-100]), []byte("export"), store2, encryptor)
+	assert.EqualError(t, err, "failed to import account: export truncated")
+	_, err = store2.RetrieveWallet("test wallet")
+	assert.Error(t, err)
+	_, err = hd.OpenWallet("test wallet", store2, encryptor)
+	assert.Error(t, err)
+
+	wallet2, err := hd.ImportFrom(bytes.NewReader(data), []byte("export"), store2, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID(), wallet2.ID())
+	accounts := 0
+	for range wallet2.Accounts() {
+		accounts++
+	}
+	assert.Equal(t, len(names), accounts)
+	require.NoError(t, wallet2.Unlock(nil))
+	account, err := wallet2.AccountByName("Account 249")
+	require.NoError(t, err)
+	require.NoError(t, account.Unlock(nil))
+}
+
+func TestImportLegacyExport(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", []byte("account passphrase"))
+	require.NoError(t, err)
+
+	// Exports prior to streaming are a single encrypted JSON object.
+	walletData, err := store.RetrieveWallet("test wallet")
+	require.NoError(t, err)
+	accounts := make([]json.RawMessage, 0)
+	for accountData := range store.RetrieveAccounts(wallet.ID()) {
+		accounts = append(accounts, accountData)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"wallet":   json.RawMessage(walletData),
+		"accounts": accounts,
+	})
+	require.NoError(t, err)
+	legacy, err := ecodec.Encrypt(data, []byte("export"))
+	require.NoError(t, err)
+
+	wallet2, err := hd.Import(legacy, []byte("export"), scratch.New(), encryptor)
+	require.NoError(t, err)
+	account, err := wallet2.AccountByName("Account 1")
+	require.NoError(t, err)
+	require.NoError(t, account.Unlock([]byte("account passphrase")))
+}
+
+func TestProgress(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	for i := 0; i < 10; i++ {
+		_, err = wallet.CreateAccount(fmt.Sprintf("Account %d", i), nil)
+		require.NoError(t, err)
+	}
+
+	exported := make([]uint64, 0)
+	opened, err := hd.OpenWallet("test wallet", store, encryptor, hd.WithProgress(func(processed uint64, total uint64) {
+		assert.Equal(t, uint64(10), total)
+		exported = append(exported, processed)
+	}))
+	require.NoError(t, err)
+	buf := new(bytes.Buffer)
+	require.NoError(t, opened.(hd.WalletStreamExporter).ExportTo(buf, []byte("export")))
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, exported)
+
+	imported := make([]uint64, 0)
+	_, err = hd.ImportFrom(buf, []byte("export"), scratch.New(), encryptor, hd.WithProgress(func(processed uint64, total uint64) {
+		assert.Equal(t, uint64(10), total)
+		imported = append(imported, processed)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, imported)
+}
@@ -0,0 +1,64 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// subWalletPath is the path from which sub-wallet seeds are derived.
+// It is in the wallet's internal branch, so no account can be created with a sub-wallet's seed as its key.
+const subWalletPath = internalPathRoot + "/1/%d"
+
+// DeriveSubWallet creates a new wallet in the same store whose seed is derived from this wallet's seed
+// at the given index.  The sub-wallet's seed is encrypted with the supplied passphrase.  Sub-wallets can be
+// re-created at any time from the parent wallet's seed and their index.  The wallet must be unlocked.
+func (w *wallet) DeriveSubWallet(name string, index uint64, passphrase []byte) (wtypes.Wallet, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to derive sub-wallets")
+	}
+	if index > math.MaxInt32 {
+		return nil, errors.New("sub-wallet index too large")
+	}
+
+	w.mutex.RLock()
+	var key e2types.PrivateKey
+	err := w.useSeed(func(seed []byte) error {
+		var err error
+		key, err = privateKeyFromSeedAndPath(w.backend, seed, fmt.Sprintf(subWalletPath, index))
+		return err
+	})
+	w.mutex.RUnlock()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to derive sub-wallet seed")
+	}
+
+	return CreateWallet(name, w.store, w.encryptor,
+		WithSeed(key.Marshal()),
+		WithPassphrase(passphrase),
+		WithBLSBackend(w.backend),
+		WithPassphrasePolicy(w.passphrasePolicy),
+		WithGuardedMemory(w.guardedMemory),
+		WithSealedSeed(w.sealSeed),
+		WithPassphraseProvider(w.passphraseProvider),
+	)
+}
@@ -0,0 +1,118 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestDeriveSubWallet(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("master wallet", store, encryptor, hd.WithSeed(seed))
+	require.NoError(t, err)
+	deriver, isDeriver := wallet.(hd.WalletSubWalletDeriver)
+	require.True(t, isDeriver)
+
+	_, err = deriver.DeriveSubWallet("sub wallet 1", 1, nil)
+	assert.EqualError(t, err, "wallet must be unlocked to derive sub-wallets")
+
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = deriver.DeriveSubWallet("sub wallet 1", math.MaxInt32+1, nil)
+	assert.EqualError(t, err, "sub-wallet index too large")
+	_, err = deriver.DeriveSubWallet("master wallet", 1, nil)
+	assert.EqualError(t, err, `wallet "master wallet" already exists`)
+
+	subWallet1, err := deriver.DeriveSubWallet("sub wallet 1", 1, []byte("sub wallet passphrase"))
+	require.NoError(t, err)
+	subWallet2, err := deriver.DeriveSubWallet("sub wallet 2", 2, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, subWallet1.Unlock([]byte("sub wallet passphrase")))
+	require.NoError(t, subWallet2.Unlock(nil))
+	masterAccount, err := wallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	subAccount1, err := subWallet1.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	subAccount2, err := subWallet2.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, masterAccount.PublicKey().Marshal(), subAccount1.PublicKey().Marshal())
+	assert.NotEqual(t, subAccount1.PublicKey().Marshal(), subAccount2.PublicKey().Marshal())
+
+	// Ensure the sub-wallet can be recovered from the master seed alone.
+	recoveredWallet, err := hd.CreateWallet("recovered master wallet", scratch.New(), encryptor, hd.WithSeed(seed))
+	require.NoError(t, err)
+	require.NoError(t, recoveredWallet.Unlock(nil))
+	recoveredSubWallet, err := recoveredWallet.(hd.WalletSubWalletDeriver).DeriveSubWallet("recovered sub wallet 1", 1, nil)
+	require.NoError(t, err)
+	require.NoError(t, recoveredSubWallet.Unlock(nil))
+	recoveredAccount, err := recoveredSubWallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+	assert.Equal(t, subAccount1.PublicKey().Marshal(), recoveredAccount.PublicKey().Marshal())
+}
+
+func TestSubWalletSeedNotAnAccount(t *testing.T) {
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("master wallet", store, encryptor, hd.WithSeed(seed))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+
+	subWallet, err := wallet.(hd.WalletSubWalletDeriver).DeriveSubWallet("sub wallet 1", 1, nil)
+	require.NoError(t, err)
+	require.NoError(t, subWallet.Unlock(nil))
+	subAccount, err := subWallet.CreateAccount("Account", nil)
+	require.NoError(t, err)
+
+	// Obtain the keys of the accounts closest to the sub-wallet's seed that can be created in the legacy path template.
+	_, err = wallet.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	extendedAccount, err := wallet.(hd.WalletExtendedAccountCreator).CreateExtendedAccount("Account 1", []uint64{1}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "m/12381/3600/1/0/1", extendedAccount.Path())
+	programmaticAccount, err := wallet.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/3600/1/0/1")
+	require.NoError(t, err)
+
+	require.NoError(t, extendedAccount.Unlock(nil))
+	for _, account := range []wtypes.Account{extendedAccount, programmaticAccount} {
+		privateKey, err := account.(wtypes.AccountPrivateKeyProvider).PrivateKey()
+		require.NoError(t, err)
+		// Use the account's key as a seed; if it were the sub-wallet's seed the accounts would match.
+		keyWallet, err := hd.CreateWallet("key wallet", scratch.New(), encryptor, hd.WithSeed(privateKey.Marshal()))
+		require.NoError(t, err)
+		require.NoError(t, keyWallet.Unlock(nil))
+		keyAccount, err := keyWallet.CreateAccount("Account", nil)
+		require.NoError(t, err)
+		assert.NotEqual(t, subAccount.PublicKey().Marshal(), keyAccount.PublicKey().Marshal())
+	}
+
+	// Paths in the internal branch cannot be reached by accounts.
+	_, err = wallet.(hd.WalletAccountByPathProvider).AccountByPath("m/12381/2147483647/1/1")
+	assert.Error(t, err)
+	customWallet, err := hd.CreateWallet("custom wallet", store, encryptor, hd.WithSeed(seed), hd.WithPathTemplate("m/12381/%a"))
+	require.NoError(t, err)
+	require.NoError(t, customWallet.Unlock(nil))
+	_, err = customWallet.(hd.WalletAccountAtIndexCreator).CreateAccountAtIndex("Account", 2147483647, nil)
+	assert.EqualError(t, err, `failed to create private key for account "Account": path "m/12381/2147483647" is reserved for use by the wallet`)
+}
@@ -0,0 +1,80 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+const (
+	// tekuKeysDir is the directory within a Teku archive that holds the keystores.
+	tekuKeysDir = "keys"
+	// tekuPasswordsDir is the directory within a Teku archive that holds the keystores' passwords.
+	tekuPasswordsDir = "passwords"
+	// tekuPasswordLength is the number of random bytes in each keystore's password.
+	tekuPasswordLength = 32
+)
+
+// ExportTekuKeystores exports accounts as a zip archive laid out for Teku's --validator-keys option: a keys directory
+// holding each account's EIP-2335 keystore and a passwords directory holding a file with the same base name containing
+// the keystore's password, so that the archive can be unpacked and passed to Teku as <dir>/keys:<dir>/passwords.  If
+// no accounts are supplied then all accounts in the wallet are exported.  The passphrase must unlock each account;
+// each keystore is encrypted with its own randomly generated password.
+func (w *wallet) ExportTekuKeystores(passphrase []byte, accounts ...wtypes.Account) ([]byte, error) {
+	exportAccounts, err := w.exportAccounts(accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	for _, a := range exportAccounts {
+		password := make([]byte, tekuPasswordLength)
+		if _, err := rand.Read(password); err != nil {
+			return nil, errors.Wrap(err, "failed to generate keystore password")
+		}
+		keystorePassword := []byte(hex.EncodeToString(password))
+		zeroBytes(password)
+		data, err := a.exportKeystore(passphrase, keystorePassword)
+		if err != nil {
+			zeroBytes(keystorePassword)
+			return nil, errors.Wrapf(err, "failed to export account %q", a.name)
+		}
+		filename := strings.TrimSuffix(keystoreFilename(a, now), ".json")
+		if err := addArchiveFile(archive, fmt.Sprintf("%s/%s.json", tekuKeysDir, filename), data, now); err != nil {
+			zeroBytes(keystorePassword)
+			return nil, err
+		}
+		err = addArchiveFile(archive, fmt.Sprintf("%s/%s.txt", tekuPasswordsDir, filename), keystorePassword, now)
+		zeroBytes(keystorePassword)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to create archive")
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ConvertToThreshold splits the wallet's seed in to shares, each encrypted with one of the supplied passphrases, so
+// that unlocking the wallet with UnlockWithShares requires the given threshold of the passphrases.  This provides
+// dual control of the seed.  The wallet's current passphrase is required, and the supplied passphrases must differ
+// from each other and from it.
+//
+// Once converted the wallet can no longer be unlocked by its previous passphrase, and any stored mnemonic and
+// additional passphrases are discarded as they would allow the seed to be recovered without the threshold.
+func (w *wallet) ConvertToThreshold(passphrase []byte, threshold int, passphrases [][]byte) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	if w.version < 2 {
+		return errors.New("wallet must be migrated to support threshold unlock")
+	}
+	if w.threshold > 0 {
+		return errors.New("wallet already requires threshold unlock")
+	}
+	// Passphrases are compared as normalised, as they are when they are used.  A share passphrase that is the same
+	// as another, or as the current passphrase, would allow fewer than the threshold of holders to unlock the wallet.
+	normalised := make(map[string]bool)
+	normalised[string(normalisePassphrase(passphrase))] = true
+	for i, sharePassphrase := range passphrases {
+		if err := w.checkPassphrase(sharePassphrase); err != nil {
+			return err
+		}
+		key := string(normalisePassphrase(sharePassphrase))
+		if normalised[key] {
+			return fmt.Errorf("passphrase %d is the same as the current passphrase or an earlier passphrase", i)
+		}
+		normalised[key] = true
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	seed, err := w.decryptSeed(passphrase)
+	if err != nil {
+		return errors.New("incorrect passphrase")
+	}
+	defer zeroBytes(seed)
+
+	shares, err := splitSecret(seed, threshold, len(passphrases))
+	if err != nil {
+		return err
+	}
+	shareCrypto := make([]map[string]interface{}, len(shares))
+	for i := range shares {
+		shareCrypto[i], err = encryptSecret(w.encryptor, shares[i], passphrases[i])
+		zeroBytes(shares[i])
+		if err != nil {
+			return errors.Wrapf(err, "failed to encrypt share %d", i)
+		}
+	}
+
+	w.threshold = threshold
+	w.shareCrypto = shareCrypto
+	w.crypto = nil
+	w.mnemonicCrypto = nil
+	w.additionalCrypto = nil
+	if err := w.storeWallet(); err != nil {
+		return errors.Wrap(err, "failed to store wallet")
+	}
+
+	return nil
+}
+
+// UnlockWithShares unlocks a wallet converted with ConvertToThreshold, given at least the threshold number of the
+// passphrases that protect its shares.  The passphrases can be supplied in any order.
+func (w *wallet) UnlockWithShares(passphrases [][]byte) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	if w.threshold == 0 {
+		return errors.New("wallet does not require threshold unlock")
+	}
+	if err := w.backoff.check(w.id); err != nil {
+		return err
+	}
+
+	if err := w.unlockWithShares(passphrases); err != nil {
+		w.backoff.failed(w.id)
+		return err
+	}
+	w.backoff.succeeded(w.id)
+	w.hooks.walletUnlocked(w)
+
+	return nil
+}
+
+// unlockWithShares unlocks the wallet with the shares protected by the passphrases without calling hooks.
+func (w *wallet) unlockWithShares(passphrases [][]byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	shares := make([][]byte, 0, w.threshold)
+	defer func() {
+		for _, share := range shares {
+			zeroBytes(share)
+		}
+	}()
+	used := make(map[int]bool)
+	for _, passphrase := range passphrases {
+		for i := range w.shareCrypto {
+			if used[i] {
+				continue
+			}
+			share, err := decryptSecret(w.encryptor, w.shareCrypto[i], passphrase)
+			if err != nil {
+				continue
+			}
+			used[i] = true
+			shares = append(shares, share)
+			break
+		}
+		if len(shares) == w.threshold {
+			break
+		}
+	}
+	if len(shares) < w.threshold {
+		return fmt.Errorf("%d of %d required passphrases supplied", len(shares), w.threshold)
+	}
+
+	seed, err := combineShares(shares)
+	if err != nil {
+		return errors.Wrap(err, "failed to combine shares")
+	}
+	return w.setSeed(seed)
+}
+
+// unmarshalShares unmarshals the shares of the crypto section of threshold wallets.
+func (w *wallet) unmarshalShares(crypto map[string]interface{}, val interface{}) error {
+	shares, ok := val.([]interface{})
+	if !ok {
+		return errors.New("wallet crypto shares invalid")
+	}
+	w.shareCrypto = make([]map[string]interface{}, len(shares))
+	for i := range shares {
+		share, ok := shares[i].(map[string]interface{})
+		if !ok {
+			return errors.New("wallet crypto shares invalid")
+		}
+		w.shareCrypto[i] = share
+	}
+	threshold, ok := crypto["threshold"].(float64)
+	if !ok {
+		return errors.New("wallet crypto threshold invalid")
+	}
+	if threshold < 2 || int(threshold) > len(w.shareCrypto) {
+		return errors.New("wallet crypto threshold invalid")
+	}
+	w.threshold = int(threshold)
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestThresholdUnlock(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	seed := _byteArray("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	passphrase := []byte("passphrase")
+	alice := []byte("alice")
+	bob := []byte("bob")
+	carol := []byte("carol")
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithSeed(seed), hd.WithPassphrase(passphrase))
+	require.NoError(t, err)
+	unlocker, isUnlocker := wallet.(hd.WalletThresholdUnlocker)
+	require.True(t, isUnlocker)
+
+	assert.EqualError(t, unlocker.UnlockWithShares([][]byte{alice, bob}), "wallet does not require threshold unlock")
+	assert.EqualError(t, unlocker.ConvertToThreshold([]byte("wrong"), 2, [][]byte{alice, bob, carol}), "incorrect passphrase")
+	assert.EqualError(t, unlocker.ConvertToThreshold(passphrase, 4, [][]byte{alice, bob, carol}), "number of shares must be at least the threshold")
+	// Passphrases must differ from each other and from the current passphrase once normalised.
+	assert.EqualError(t, unlocker.ConvertToThreshold(passphrase, 2, [][]byte{alice, bob, []byte("b\u0007ob")}), "passphrase 2 is the same as the current passphrase or an earlier passphrase")
+	assert.EqualError(t, unlocker.ConvertToThreshold(passphrase, 2, [][]byte{alice, []byte("pass\nphrase"), carol}), "passphrase 1 is the same as the current passphrase or an earlier passphrase")
+	require.NoError(t, unlocker.ConvertToThreshold(passphrase, 2, [][]byte{alice, bob, carol}))
+	assert.EqualError(t, unlocker.ConvertToThreshold(passphrase, 2, [][]byte{alice, bob, carol}), "wallet already requires threshold unlock")
+
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	unlocker = wallet.(hd.WalletThresholdUnlocker)
+	assert.EqualError(t, wallet.Unlock(passphrase), "wallet requires threshold unlock")
+	assert.EqualError(t, unlocker.UnlockWithShares([][]byte{alice}), "1 of 2 required passphrases supplied")
+	assert.EqualError(t, unlocker.UnlockWithShares([][]byte{alice, alice}), "1 of 2 required passphrases supplied")
+	assert.EqualError(t, unlocker.UnlockWithShares([][]byte{alice, []byte("wrong")}), "1 of 2 required passphrases supplied")
+	assert.False(t, wallet.IsUnlocked())
+
+	for _, passphrases := range [][][]byte{{alice, bob}, {carol, alice}, {bob, carol}, {alice, bob, carol}} {
+		require.NoError(t, unlocker.UnlockWithShares(passphrases))
+		key, err := wallet.(wtypes.WalletKeyProvider).Key()
+		require.NoError(t, err)
+		assert.Equal(t, seed, key)
+		wallet.Lock()
+	}
+}
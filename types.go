@@ -0,0 +1,432 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"context"
+	"io"
+	"regexp"
+
+	"github.com/google/uuid"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// PathProvider is the interface for providers of account derivation paths, for wallets whose paths cannot be expressed
+// with a path template.
+type PathProvider interface {
+	// PathFor provides the derivation path for the given wallet index and account number.
+	PathFor(walletIndex uint64, accountIndex uint64) string
+}
+
+// WalletMetadataProvider is the interface for wallets that provide metadata.
+type WalletMetadataProvider interface {
+	// Metadata provides a copy of the wallet's metadata.
+	Metadata() map[string]string
+
+	// SetMetadata sets a metadata value for the wallet.
+	// Setting an empty value removes the key from the metadata.
+	SetMetadata(key string, value string) error
+}
+
+// WalletIndexProvider is the interface for wallets that provide their wallet index.
+type WalletIndexProvider interface {
+	// WalletIndex provides the index of the wallet.
+	WalletIndex() uint64
+}
+
+// WalletPathTemplateProvider is the interface for wallets that provide their path template.
+type WalletPathTemplateProvider interface {
+	// PathTemplate provides the template from which the wallet generates account paths.
+	PathTemplate() string
+}
+
+// WalletNextAccountProvider is the interface for wallets that provide their next account number.
+type WalletNextAccountProvider interface {
+	// NextAccount provides the account number that will be used for the next account created.
+	NextAccount() uint64
+}
+
+// WalletNextAccountSetter is the interface for wallets that can fast-forward their next account number.
+type WalletNextAccountSetter interface {
+	// SetNextAccount sets the account number that will be used for the next account created.
+	SetNextAccount(nextAccount uint64) error
+}
+
+// WalletSubWalletDeriver is the interface for wallets that can derive sub-wallets from their seed.
+type WalletSubWalletDeriver interface {
+	// DeriveSubWallet creates a new wallet whose seed is derived from this wallet's seed at the given index.
+	DeriveSubWallet(name string, index uint64, passphrase []byte) (wtypes.Wallet, error)
+}
+
+// WalletStatsProvider is the interface for wallets that provide summary statistics.
+type WalletStatsProvider interface {
+	// Stats provides summary statistics for the wallet.
+	Stats() (*WalletStats, error)
+}
+
+// WalletAuditor is the interface for wallets that can audit their stored data.
+type WalletAuditor interface {
+	// Audit checks the consistency of the wallet's stored data.
+	Audit(passphrase []byte) (*AuditReport, error)
+}
+
+// WalletEIP2386Marshaler is the interface for wallets that can marshal themselves in EIP-2386 format.
+type WalletEIP2386Marshaler interface {
+	// MarshalEIP2386 marshals the wallet in the format defined by EIP-2386.
+	MarshalEIP2386() ([]byte, error)
+}
+
+// WalletMnemonicProvider is the interface for wallets that can provide their stored mnemonic.
+type WalletMnemonicProvider interface {
+	// Mnemonic provides the mnemonic from which the wallet's seed was generated.
+	Mnemonic(passphrase []byte) (string, error)
+}
+
+// WalletAccountsCreator is the interface for wallets that can create multiple accounts at once.
+type WalletAccountsCreator interface {
+	// CreateAccounts creates multiple accounts in the wallet.
+	CreateAccounts(names []string, passphrase []byte) ([]wtypes.Account, error)
+}
+
+// WalletExtendedAccountCreator is the interface for wallets that can create accounts with extended paths.
+type WalletExtendedAccountCreator interface {
+	// CreateExtendedAccount creates an account in the wallet with additional components appended to its path.
+	CreateExtendedAccount(name string, extension []uint64, passphrase []byte) (wtypes.Account, error)
+}
+
+// WalletAccountAtIndexCreator is the interface for wallets that can create accounts with explicit account numbers.
+type WalletAccountAtIndexCreator interface {
+	// CreateAccountAtIndex creates an account in the wallet with the given account number.
+	CreateAccountAtIndex(name string, index uint64, passphrase []byte) (wtypes.Account, error)
+}
+
+// WalletAccountDeleter is the interface for wallets that can delete accounts.
+type WalletAccountDeleter interface {
+	// DeleteAccount deletes an account, given its name or ID, from the wallet.
+	DeleteAccount(nameOrID string) error
+}
+
+// WalletDeletedAccountsProvider is the interface for wallets that provide their deleted accounts.
+type WalletDeletedAccountsProvider interface {
+	// DeletedAccounts provides the accounts that have been deleted but not yet purged.
+	DeletedAccounts() <-chan wtypes.Account
+}
+
+// WalletAccountRestorer is the interface for wallets that can restore deleted accounts.
+type WalletAccountRestorer interface {
+	// RestoreAccount restores an account that has been deleted but not yet purged.
+	RestoreAccount(id uuid.UUID) (wtypes.Account, error)
+}
+
+// WalletAccountPurger is the interface for wallets that can permanently delete deleted accounts.
+type WalletAccountPurger interface {
+	// PurgeAccount permanently deletes an account that has been deleted.
+	PurgeAccount(id uuid.UUID) error
+}
+
+// AccountDeleter is the interface for stores that can delete accounts.
+type AccountDeleter interface {
+	// DeleteAccount deletes account data.
+	DeleteAccount(walletID uuid.UUID, accountID uuid.UUID) error
+}
+
+// WalletAccountByPathProvider is the interface for wallets that can provide accounts given their derivation paths.
+type WalletAccountByPathProvider interface {
+	// AccountByPath provides an account calculated from the wallet's seed given its derivation path.
+	AccountByPath(path string) (wtypes.Account, error)
+}
+
+// WalletAccountsWithContextProvider is the interface for wallets that provide their accounts until a context is cancelled.
+type WalletAccountsWithContextProvider interface {
+	// AccountsWithContext provides all accounts in the wallet until the context is cancelled.
+	AccountsWithContext(ctx context.Context) <-chan wtypes.Account
+}
+
+// WalletAccountCounter is the interface for wallets that can count their accounts.
+type WalletAccountCounter interface {
+	// AccountCount provides the number of accounts in the wallet.
+	AccountCount() (int, error)
+}
+
+// WalletAccountsQuerier is the interface for wallets that can provide accounts matching their names.
+type WalletAccountsQuerier interface {
+	// AccountsByPrefix provides the accounts whose names start with the given prefix.
+	AccountsByPrefix(prefix string) ([]wtypes.Account, error)
+
+	// AccountsMatching provides the accounts whose names match the given regular expression.
+	AccountsMatching(re *regexp.Regexp) ([]wtypes.Account, error)
+}
+
+// WalletOrderedAccountsProvider is the interface for wallets that can provide their accounts in a given order.
+type WalletOrderedAccountsProvider interface {
+	// OrderedAccounts provides all accounts in the wallet in the given order.
+	OrderedAccounts(order AccountOrder) ([]wtypes.Account, error)
+}
+
+// WalletAccountsPager is the interface for wallets that can provide their accounts a page at a time.
+type WalletAccountsPager interface {
+	// AccountsPage provides up to limit accounts, ordered by name, starting at the given offset.
+	AccountsPage(offset int, limit int) ([]wtypes.Account, error)
+}
+
+// WalletNextAccountPublicKeyProvider is the interface for wallets that can provide the public key of their next account.
+type WalletNextAccountPublicKeyProvider interface {
+	// NextAccountPublicKey provides the public key of the account that will be created next.
+	NextAccountPublicKey() (e2types.PublicKey, error)
+}
+
+// WalletAccountsPreviewer is the interface for wallets that can preview the accounts they will create.
+type WalletAccountsPreviewer interface {
+	// PreviewAccounts provides the details of the next count accounts that will be created.
+	PreviewAccounts(count int) ([]*AccountPreview, error)
+}
+
+// WalletAccountRegenerator is the interface for wallets that can regenerate accounts from their seed.
+type WalletAccountRegenerator interface {
+	// RegenerateAccount rebuilds the account with the given account number and name from the wallet's seed.
+	RegenerateAccount(index uint64, name string, passphrase []byte) (wtypes.Account, error)
+}
+
+// WalletIndexGapsProvider is the interface for wallets that can report gaps in their account numbers.
+type WalletIndexGapsProvider interface {
+	// IndexGaps provides the ranges of account numbers below the next account number that are not in use by any
+	// account.
+	IndexGaps() ([]*IndexGap, error)
+}
+
+// WalletWithdrawalAccountCreator is the interface for wallets that can create withdrawal accounts.
+type WalletWithdrawalAccountCreator interface {
+	// CreateWithdrawalAccount creates a withdrawal account in the wallet using the next account number.
+	CreateWithdrawalAccount(name string, passphrase []byte) (wtypes.Account, error)
+}
+
+// WalletValidatorPairCreator is the interface for wallets that can create paired signing and withdrawal accounts.
+type WalletValidatorPairCreator interface {
+	// CreateValidatorPair creates a signing account and its matching withdrawal account.
+	CreateValidatorPair(name string, passphrase []byte) (*ValidatorPair, error)
+}
+
+// WalletExecutionKeyProvider is the interface for wallets that can provide execution-layer keys for their accounts.
+type WalletExecutionKeyProvider interface {
+	// ExecutionKey provides the execution-layer key associated with an account in the wallet.
+	ExecutionKey(account wtypes.Account) (*ExecutionKey, error)
+}
+
+// WalletHookRegistrar is the interface for wallets that call registered hooks on lifecycle events.
+type WalletHookRegistrar interface {
+	// OnAccountCreated registers a hook that is called after each account is created.
+	OnAccountCreated(hook AccountHook)
+
+	// OnAccountDeleted registers a hook that is called after an account is deleted.
+	OnAccountDeleted(hook AccountHook)
+
+	// OnWalletUnlocked registers a hook that is called after the wallet is unlocked.
+	OnWalletUnlocked(hook WalletHook)
+
+	// OnWalletLocked registers a hook that is called after the wallet is locked.
+	OnWalletLocked(hook WalletHook)
+
+	// OnAccountUnlocked registers a hook that is called after an account is unlocked.
+	OnAccountUnlocked(hook AccountHook)
+
+	// OnAccountLocked registers a hook that is called after an account is locked.
+	OnAccountLocked(hook AccountHook)
+}
+
+// WalletAccountsRenamer is the interface for wallets that can rename their accounts in bulk.
+type WalletAccountsRenamer interface {
+	// RenameAccounts renames the wallet's accounts using the supplied function.
+	RenameAccounts(renameFn RenameFunc) error
+}
+
+// WalletAccountImporter is the interface for wallets that can import EIP-2335 keystores as accounts.
+type WalletAccountImporter interface {
+	// ImportAccount imports the key held in an EIP-2335 keystore in to the wallet as a new account.
+	ImportAccount(keystoreJSON []byte, keystorePassphrase []byte, name string, passphrase []byte) (wtypes.Account, error)
+}
+
+// WalletRenamer is the interface for wallets that can be renamed.
+type WalletRenamer interface {
+	// Rename renames the wallet.
+	// This will error if a wallet with the new name already exists.
+	Rename(newName string) error
+}
+
+// WalletCopier is the interface for wallets that can copy themselves to another store.
+type WalletCopier interface {
+	// CopyTo copies the wallet, its accounts and its index to the given store.
+	// This will error if the wallet already exists in the store.
+	CopyTo(store wtypes.Store) (wtypes.Wallet, error)
+}
+
+// WalletReseeder is the interface for wallets that can replace their seed.
+type WalletReseeder interface {
+	// Reseed replaces the wallet's seed and re-derives its accounts, returning a map of
+	// hex-encoded old account public keys to new account public keys.
+	Reseed(newSeed []byte, passphrase []byte) (map[string]e2types.PublicKey, error)
+}
+
+// WalletReencrypter is the interface for wallets that can re-encrypt their secrets with a new encryptor.
+type WalletReencrypter interface {
+	// Reencrypt re-encrypts the wallet's seed and its accounts' keys with the new encryptor.
+	Reencrypt(passphrase []byte, newEncryptor wtypes.Encryptor) error
+}
+
+// WalletPassphraseManager is the interface for wallets that can be unlocked by multiple passphrases.
+type WalletPassphraseManager interface {
+	// AddPassphrase adds a passphrase with which the wallet can be unlocked.
+	AddPassphrase(passphrase []byte, newPassphrase []byte) error
+
+	// RemovePassphrase removes a passphrase added with AddPassphrase.
+	RemovePassphrase(passphrase []byte) error
+}
+
+// WalletThresholdUnlocker is the interface for wallets that can require multiple passphrases to unlock.
+type WalletThresholdUnlocker interface {
+	// ConvertToThreshold splits the wallet's seed so that unlocking requires the threshold of the passphrases.
+	ConvertToThreshold(passphrase []byte, threshold int, passphrases [][]byte) error
+
+	// UnlockWithShares unlocks the wallet given at least the threshold of its passphrases.
+	UnlockWithShares(passphrases [][]byte) error
+}
+
+// WalletProviderUnlocker is the interface for wallets that can be unlocked with a passphrase held by a passphrase
+// provider.
+type WalletProviderUnlocker interface {
+	// UnlockWithProvider unlocks the wallet with the passphrase held by the wallet's passphrase provider.
+	UnlockWithProvider() error
+
+	// RememberPassphrase stores the passphrase with the wallet's passphrase provider.
+	RememberPassphrase(passphrase []byte) error
+
+	// ForgetPassphrase removes the wallet's passphrase from the wallet's passphrase provider.
+	ForgetPassphrase() error
+}
+
+// WalletPassphraseChecker is the interface for wallets that can check a passphrase without unlocking.
+type WalletPassphraseChecker interface {
+	// CheckPassphrase returns nil if the passphrase unlocks the wallet.
+	CheckPassphrase(passphrase []byte) error
+}
+
+// WalletKeystoresExporter is the interface for wallets that can export their accounts as an archive of keystores.
+type WalletKeystoresExporter interface {
+	// ExportKeystores exports accounts as a zip archive of EIP-2335 keystores.
+	ExportKeystores(passphrase []byte, accounts ...wtypes.Account) ([]byte, error)
+}
+
+// WalletStreamExporter is the interface for wallets that can stream their export to a writer.
+type WalletStreamExporter interface {
+	// ExportTo writes the wallet and its accounts to the writer, encrypted in chunks with the passphrase.
+	ExportTo(writer io.Writer, passphrase []byte) error
+}
+
+// WalletRecoveryCodeProvider is the interface for wallets that can provide a recovery code for their seed.
+type WalletRecoveryCodeProvider interface {
+	// RecoveryCode provides the wallet's seed encrypted with the passphrase as a code for printing.
+	RecoveryCode(passphrase []byte) (string, error)
+}
+
+// WalletManifestExporter is the interface for wallets that can export a manifest of their accounts.
+type WalletManifestExporter interface {
+	// ExportManifest provides a signed manifest of the names, paths and public keys of the wallet's accounts.
+	ExportManifest() ([]byte, error)
+}
+
+// WalletShareExporter is the interface for wallets that can export to shares.
+type WalletShareExporter interface {
+	// ExportShares exports the wallet split in to shares, any threshold of which can re-create the export.
+	ExportShares(passphrase []byte, threshold int, shares int) ([][]byte, error)
+}
+
+// WalletRecipientsExporter is the interface for wallets that can export to age recipients.
+type WalletRecipientsExporter interface {
+	// ExportToRecipients writes the wallet and its accounts to the writer, encrypted to the age recipients.
+	ExportToRecipients(writer io.Writer, passphrase []byte, recipients ...string) error
+}
+
+// WalletOpenPGPExporter is the interface for wallets that can export to OpenPGP public keys.
+type WalletOpenPGPExporter interface {
+	// ExportToOpenPGP writes the wallet and its accounts to the writer, encrypted to the armored public keys.
+	ExportToOpenPGP(writer io.Writer, passphrase []byte, publicKeys ...[]byte) error
+}
+
+// WalletLighthouseImporter is the interface for wallets that can import validators from Lighthouse.
+type WalletLighthouseImporter interface {
+	// ImportLighthouseValidators imports the validators listed in a Lighthouse validator_definitions.yml file.
+	ImportLighthouseValidators(definitionsPath string, passphrase []byte) ([]wtypes.Account, error)
+}
+
+// WalletTekuExporter is the interface for wallets that can export their accounts for Teku.
+type WalletTekuExporter interface {
+	// ExportTekuKeystores exports accounts as a zip archive of keystores and password files laid out for Teku.
+	ExportTekuKeystores(passphrase []byte, accounts ...wtypes.Account) ([]byte, error)
+}
+
+// AccountRenamer is the interface for accounts that can be renamed.
+type AccountRenamer interface {
+	// Rename renames the account.
+	// This will error if an account with the new name already exists in the wallet.
+	Rename(newName string) error
+}
+
+// AccountDisabler is the interface for accounts that can be disabled.
+type AccountDisabler interface {
+	// Disabled returns true if the account is disabled.
+	Disabled() bool
+
+	// SetDisabled sets whether the account is disabled.
+	SetDisabled(disabled bool) error
+}
+
+// AccountDescriptionProvider is the interface for accounts that provide an EIP-2335 description.
+type AccountDescriptionProvider interface {
+	// Description provides the description of the account.
+	Description() string
+
+	// SetDescription sets the description of the account.
+	SetDescription(description string) error
+}
+
+// AccountDerivationPathProvider is the interface for accounts that provide their parsed derivation path.
+type AccountDerivationPathProvider interface {
+	// DerivationPath provides the parsed derivation path of the account.
+	DerivationPath() (*DerivationPath, error)
+}
+
+// AccountImportedProvider is the interface for accounts that can report if their key was imported.
+type AccountImportedProvider interface {
+	// Imported returns true if the account's key was imported rather than derived from the wallet's seed.
+	Imported() bool
+}
+
+// AccountKeystoreExporter is the interface for accounts that can export themselves as EIP-2335 keystores.
+type AccountKeystoreExporter interface {
+	// Export exports the account as an EIP-2335 keystore encrypted with the account's passphrase.
+	Export(passphrase []byte) ([]byte, error)
+}
+
+// AccountDepositDataProvider is the interface for accounts that can provide signed deposit data.
+type AccountDepositDataProvider interface {
+	// DepositData provides signed deposit data for the account as a validator.
+	DepositData(withdrawalCredentials []byte, amount uint64, forkVersion []byte) (*DepositData, error)
+}
+
+// AccountBLSToExecutionChanger is the interface for accounts that can sign changes to execution-layer withdrawal
+// credentials.
+type AccountBLSToExecutionChanger interface {
+	// BLSToExecutionChange provides a signed change of a validator's withdrawal credentials to an execution address.
+	BLSToExecutionChange(validatorIndex uint64, executionAddress []byte, genesisForkVersion []byte, genesisValidatorsRoot []byte) (*SignedBLSToExecutionChange, error)
+}
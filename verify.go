@@ -0,0 +1,229 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// CheckPassphrase returns nil if the passphrase unlocks the wallet, without unlocking it.  Where the seed is held in
+// an EIP-2335 keystore the passphrase is verified against the keystore's checksum, so the seed is not decrypted.
+func (w *wallet) CheckPassphrase(passphrase []byte) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	if w.threshold > 0 {
+		return errors.New("wallet requires threshold unlock")
+	}
+	if isWrapped(w.crypto) {
+		return errors.New("wallet is not protected by a passphrase")
+	}
+
+	if err := w.backoff.check(w.id); err != nil {
+		return err
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	for _, crypto := range append([]map[string]interface{}{w.crypto}, w.additionalCrypto...) {
+		if w.checkSecretPassphrase(crypto, passphrase) {
+			w.backoff.succeeded(w.id)
+			return nil
+		}
+	}
+	w.backoff.failed(w.id)
+	return errors.New("incorrect passphrase")
+}
+
+// checkSecretPassphrase returns true if the passphrase decrypts the secret encrypted with encryptSecret.
+func (w *wallet) checkSecretPassphrase(crypto map[string]interface{}, passphrase []byte) bool {
+	keystoreCrypto := crypto
+	if _, exists := crypto["data"]; exists {
+		// The passphrase protects the data key.
+		keyCrypto, ok := crypto["key"].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		keystoreCrypto = keyCrypto
+	}
+
+	normalised := normalisePassphrase(passphrase)
+	candidates := [][]byte{normalised}
+	if !bytes.Equal(normalised, passphrase) {
+		candidates = append(candidates, passphrase)
+	}
+	for _, candidate := range candidates {
+		valid, err := verifyKeystoreChecksum(keystoreCrypto, candidate)
+		if err != nil {
+			// The keystore is not one that can be checked directly, so fall back to decrypting it.
+			secret, err := decryptSecretWithPassphrase(w.encryptor, crypto, candidate)
+			if err != nil {
+				continue
+			}
+			zeroBytes(secret)
+			return true
+		}
+		if valid {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyKeystoreChecksum returns true if the passphrase matches the checksum of the EIP-2335 keystore crypto.
+// It returns an error if the crypto is not an EIP-2335 keystore crypto with a known key derivation function.
+func verifyKeystoreChecksum(crypto map[string]interface{}, passphrase []byte) (bool, error) {
+	key, _, match, err := keystoreKey(crypto, passphrase)
+	if err != nil {
+		return false, err
+	}
+	zeroBytes(key)
+	return match, nil
+}
+
+// decryptKeystoreSecret decrypts the secret of an EIP-2335 keystore crypto.  Unlike keystorev4 this is not limited to
+// 32-byte secrets.
+func decryptKeystoreSecret(crypto map[string]interface{}, passphrase []byte) ([]byte, error) {
+	key, cipherMessage, match, err := keystoreKey(crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(key)
+	if !match {
+		return nil, errors.New("incorrect passphrase")
+	}
+
+	cipherParams, ok := crypto["cipher"].(map[string]interface{})
+	if !ok || cipherParams["function"] != "aes-128-ctr" {
+		return nil, errors.New("cipher function unknown")
+	}
+	params, ok := cipherParams["params"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("cipher params invalid")
+	}
+	iv, err := hexField(params, "iv")
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, errors.New("iv invalid")
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, len(cipherMessage))
+	cipher.NewCTR(block, iv).XORKeyStream(secret, cipherMessage)
+	return secret, nil
+}
+
+// keystoreKey derives the decryption key of an EIP-2335 keystore crypto from the passphrase, returning the key, the
+// cipher message and true if the passphrase matches the checksum.
+func keystoreKey(crypto map[string]interface{}, passphrase []byte) ([]byte, []byte, bool, error) {
+	kdf, ok := crypto["kdf"].(map[string]interface{})
+	if !ok {
+		return nil, nil, false, errors.New("kdf invalid")
+	}
+	params, ok := kdf["params"].(map[string]interface{})
+	if !ok {
+		return nil, nil, false, errors.New("kdf params invalid")
+	}
+	checksum, ok := crypto["checksum"].(map[string]interface{})
+	if !ok || checksum["function"] != "sha256" {
+		return nil, nil, false, errors.New("checksum invalid")
+	}
+	cipherParams, ok := crypto["cipher"].(map[string]interface{})
+	if !ok {
+		return nil, nil, false, errors.New("cipher invalid")
+	}
+	salt, err := hexField(params, "salt")
+	if err != nil {
+		return nil, nil, false, err
+	}
+	checksumMessage, err := hexField(checksum, "message")
+	if err != nil {
+		return nil, nil, false, err
+	}
+	cipherMessage, err := hexField(cipherParams, "message")
+	if err != nil {
+		return nil, nil, false, err
+	}
+	dkLen := intField(params, "dklen")
+	if dkLen < 32 {
+		return nil, nil, false, errors.New("kdf dklen invalid")
+	}
+
+	var key []byte
+	switch kdf["function"] {
+	case "scrypt":
+		key, err = scrypt.Key(passphrase, salt, intField(params, "n"), intField(params, "r"), intField(params, "p"), dkLen)
+		if err != nil {
+			return nil, nil, false, err
+		}
+	case "pbkdf2":
+		if params["prf"] != "hmac-sha256" || intField(params, "c") < 1 {
+			return nil, nil, false, errors.New("kdf params invalid")
+		}
+		key = pbkdf2.Key(passphrase, salt, intField(params, "c"), dkLen, sha256.New)
+	default:
+		return nil, nil, false, errors.New("kdf function unknown")
+	}
+
+	h := sha256.New()
+	h.Write(key[16:32])
+	h.Write(cipherMessage)
+	return key, cipherMessage, subtle.ConstantTimeCompare(h.Sum(nil), checksumMessage) == 1, nil
+}
+
+// keystoreCipherMessage provides the cipher message of an EIP-2335 keystore crypto, which is the length of its secret.
+func keystoreCipherMessage(crypto map[string]interface{}) ([]byte, error) {
+	cipherParams, ok := crypto["cipher"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("cipher invalid")
+	}
+	return hexField(cipherParams, "message")
+}
+
+// hexField provides the hex-decoded string value of the field.
+func hexField(data map[string]interface{}, field string) ([]byte, error) {
+	str, ok := data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s invalid", field)
+	}
+	res, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s invalid", field)
+	}
+	return res, nil
+}
+
+// intField provides the integer value of the field, or 0 if it is not present.
+func intField(data map[string]interface{}, field string) int {
+	val, ok := data[field].(float64)
+	if !ok {
+		return 0
+	}
+	return int(val)
+}
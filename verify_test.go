@@ -0,0 +1,68 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestCheckPassphrase(t *testing.T) {
+	tests := []struct {
+		name      string
+		encryptor *keystorev4.Encryptor
+		opts      []hd.Option
+	}{
+		{
+			name:      "PBKDF2",
+			encryptor: keystorev4.New(),
+		},
+		{
+			name:      "Scrypt",
+			encryptor: keystorev4.New(),
+			opts:      []hd.Option{hd.WithKDFParams(hd.KDFParams{Function: "scrypt", N: 16})},
+		},
+		{
+			name:      "WithSeed",
+			encryptor: keystorev4.New(),
+			opts:      []hd.Option{hd.WithSeed(_byteArray("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c2d2e2f"))},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := scratch.New()
+			passphrase := []byte("passphrase")
+			wallet, err := hd.CreateWallet("test wallet", store, test.encryptor,
+				append([]hd.Option{hd.WithPassphrase(passphrase)}, test.opts...)...)
+			require.NoError(t, err)
+			checker, ok := wallet.(hd.WalletPassphraseChecker)
+			require.True(t, ok)
+
+			assert.EqualError(t, checker.CheckPassphrase([]byte("wrong")), "incorrect passphrase")
+			require.NoError(t, checker.CheckPassphrase(passphrase))
+			assert.False(t, wallet.IsUnlocked())
+
+			// Additional passphrases are also checked.
+			require.NoError(t, wallet.(hd.WalletPassphraseManager).AddPassphrase(passphrase, []byte("second passphrase")))
+			require.NoError(t, checker.CheckPassphrase([]byte("second passphrase")))
+			assert.False(t, wallet.IsUnlocked())
+		})
+	}
+}
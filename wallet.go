@@ -14,44 +14,126 @@
 package hd
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/awnumar/memguard"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	bip39 "github.com/tyler-smith/go-bip39"
 	"github.com/wealdtech/go-ecodec"
-	util "github.com/wealdtech/go-eth2-util"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
 	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
 	"github.com/wealdtech/go-indexer"
+	"golang.org/x/text/unicode/norm"
 )
 
 const (
 	walletType = "hierarchical deterministic"
-	version    = 1
+	version    = 2
+
+	// minSeedLength is the minimum seed length permitted by EIP-2333.
+	minSeedLength = 32
+	// maxSeedLength is the maximum seed length; BIP-39 seeds are 64 bytes.
+	maxSeedLength = 64
+
+	// legacyPathTemplate is the path template for wallets without a wallet index.
+	legacyPathTemplate = "m/12381/3600/%a/0"
+	// indexedPathTemplate is the default path template for wallets with a wallet index.
+	indexedPathTemplate = "m/12381/3600/%w/%a/0"
 )
 
+// ErrProgrammaticAccountsDisabled is returned when a programmatic account is requested from a wallet that has
+// programmatic accounts disabled.
+var ErrProgrammaticAccountsDisabled = errors.New("programmatic accounts are disabled")
+
+// accountIDNamespace is the UUID namespace from which deterministic account IDs are generated.
+var accountIDNamespace = uuid.MustParse("f833c88c-41a9-4a25-8be2-37b62f8de64a")
+
 // wallet contains the details of the wallet.
 type wallet struct {
-	id          uuid.UUID
-	name        string
-	version     uint
-	crypto      map[string]interface{}
-	seed        []byte
-	nextAccount uint64
-	store       wtypes.Store
-	encryptor   wtypes.Encryptor
-	mutex       *sync.RWMutex
-	index       *indexer.Index
+	id      uuid.UUID
+	name    string
+	version uint
+	crypto  map[string]interface{}
+	seed    []byte
+	// node is the cached derivation node from which accounts are derived, held while the wallet is unlocked.
+	node *derivationNode
+	// additionalCrypto holds the seed encrypted with additional passphrases, any of which unlocks the wallet.
+	additionalCrypto []map[string]interface{}
+	// threshold is the number of shares required to unlock the wallet, if its seed is split in to shareCrypto.
+	threshold   int
+	shareCrypto []map[string]interface{}
+	// mnemonicCrypto is the encrypted mnemonic from which the seed was generated, if stored.
+	mnemonicCrypto map[string]interface{}
+	walletIndex    uint64
+	pathTemplate   string
+	pathProvider   PathProvider
+	customPaths    bool
+	constrainPaths bool
+	noProgrammatic bool
+	accountCache   *accountCache
+	backend        BLSBackend
+	workers        int
+	encryptors     []wtypes.Encryptor
+	guardedMemory  bool
+	sealSeed       bool
+	sealed         *sealedSeed
+	keyWrapper     KeyWrapper
+	seedBuffer     *memguard.LockedBuffer
+	metadata       map[string]string
+	tombstones     map[string]string
+	watchOnly      bool
+	derivedIDs     bool
+	created        time.Time
+	modified       time.Time
+	nextAccount    uint64
+	store          wtypes.Store
+	encryptor      wtypes.Encryptor
+	// encryptorName and encryptorVersion are the details of the encryptor that encrypted the seed.
+	encryptorName    string
+	encryptorVersion uint
+	mutex            *sync.RWMutex
+	index            *indexer.Index
+	hooks            *hooks
+	backoff          *unlockBackoff
+	passphrasePolicy PassphrasePolicy
+	// passphraseProvider holds the wallet's passphrase for UnlockWithProvider.
+	passphraseProvider PassphraseProvider
+	// kdfParams are the KDF parameters with which the wallet encrypts secrets, if they are not the encryptor's.
+	kdfParams *KDFParams
+	// sharedPassphrase is true if accounts created without a passphrase are encrypted with the wallet passphrase,
+	// which is then held in passphrase while the wallet is unlocked.
+	sharedPassphrase bool
+	passphrase       []byte
+	// exportIntegrity is the integrity tag of the export from which the wallet is being imported, if any.
+	exportIntegrity *exportIntegrity
+	// exportAccountCount is the number of accounts in the export from which the wallet is imported, or 0 if unknown.
+	exportAccountCount uint64
+	// progress is called as accounts are exported.
+	progress ProgressFunc
 }
 
 // newWallet creates a new wallet
 func newWallet() *wallet {
 	return &wallet{
-		mutex: new(sync.RWMutex),
-		index: indexer.New(),
+		pathTemplate: legacyPathTemplate,
+		accountCache: newAccountCache(defaultProgrammaticCacheSize),
+		backend:      defaultBackend,
+		workers:      defaultDerivationWorkers,
+		mutex:        new(sync.RWMutex),
+		index:        indexer.New(),
+		hooks:        new(hooks),
+		backoff:      newUnlockBackoff(0, 0),
 	}
 }
 
@@ -62,8 +144,54 @@ func (w *wallet) MarshalJSON() ([]byte, error) {
 	data["name"] = w.name
 	data["version"] = w.version
 	data["type"] = walletType
-	data["crypto"] = w.crypto
+	if w.watchOnly {
+		data["watchonly"] = true
+	} else {
+		if w.version == 1 {
+			data["crypto"] = w.crypto
+		} else {
+			crypto := map[string]interface{}{
+				"encryptor": w.encryptorName,
+				"version":   w.encryptorVersion,
+			}
+			if w.threshold > 0 {
+				crypto["threshold"] = w.threshold
+				crypto["shares"] = w.shareCrypto
+			} else {
+				crypto["secret"] = w.crypto
+			}
+			if w.mnemonicCrypto != nil {
+				crypto["mnemonic"] = w.mnemonicCrypto
+			}
+			if len(w.additionalCrypto) > 0 {
+				crypto["additional"] = w.additionalCrypto
+			}
+			data["crypto"] = crypto
+		}
+	}
+	if w.derivedIDs {
+		data["deterministicids"] = true
+	}
+	if w.customPaths {
+		data["custompaths"] = true
+	}
 	data["nextaccount"] = w.nextAccount
+	data["walletindex"] = w.walletIndex
+	data["pathtemplate"] = w.pathTemplate
+	if len(w.metadata) > 0 {
+		data["metadata"] = w.metadata
+	}
+	if len(w.tombstones) > 0 {
+		data["tombstones"] = w.tombstones
+	}
+	if w.version > 1 {
+		if !w.created.IsZero() {
+			data["created"] = w.created.Format(time.RFC3339)
+		}
+		if !w.modified.IsZero() {
+			data["modified"] = w.modified.Format(time.RFC3339)
+		}
+	}
 	return json.Marshal(data)
 }
 
@@ -84,6 +212,18 @@ func (w *wallet) UnmarshalJSON(data []byte) error {
 	} else {
 		return errors.New("wallet type missing")
 	}
+	if val, exists := v["version"]; exists {
+		version, ok := val.(float64)
+		if !ok {
+			return errors.New("wallet version invalid")
+		}
+		w.version = uint(version)
+	} else {
+		return errors.New("wallet version missing")
+	}
+	if w.version < 1 || w.version > version {
+		return fmt.Errorf("wallet version %d unsupported", w.version)
+	}
 	if val, exists := v["uuid"]; exists {
 		idStr, ok := val.(string)
 		if !ok {
@@ -95,8 +235,8 @@ func (w *wallet) UnmarshalJSON(data []byte) error {
 		}
 		w.id = id
 	} else {
-		// Used to be ID; remove with V2.0
-		if val, exists := v["id"]; exists {
+		// Version 1 wallets may use the old "id" field.
+		if val, exists := v["id"]; exists && w.version == 1 {
 			idStr, ok := val.(string)
 			if !ok {
 				return errors.New("wallet ID invalid")
@@ -119,13 +259,40 @@ func (w *wallet) UnmarshalJSON(data []byte) error {
 	} else {
 		return errors.New("wallet name missing")
 	}
+	if val, exists := v["watchonly"]; exists {
+		watchOnly, ok := val.(bool)
+		if !ok {
+			return errors.New("wallet watch-only flag invalid")
+		}
+		w.watchOnly = watchOnly
+	}
+	if val, exists := v["deterministicids"]; exists {
+		derivedIDs, ok := val.(bool)
+		if !ok {
+			return errors.New("wallet deterministic IDs flag invalid")
+		}
+		w.derivedIDs = derivedIDs
+	}
+	if val, exists := v["custompaths"]; exists {
+		customPaths, ok := val.(bool)
+		if !ok {
+			return errors.New("wallet custom paths flag invalid")
+		}
+		w.customPaths = customPaths
+	}
 	if val, exists := v["crypto"]; exists {
 		crypto, ok := val.(map[string]interface{})
 		if !ok {
 			return errors.New("wallet crypto invalid")
 		}
-		w.crypto = crypto
-	} else {
+		if w.version == 1 {
+			w.crypto = crypto
+		} else {
+			if err := w.unmarshalStructuredCrypto(crypto); err != nil {
+				return err
+			}
+		}
+	} else if !w.watchOnly {
 		return errors.New("wallet crypto missing")
 	}
 	if val, exists := v["nextaccount"]; exists {
@@ -137,106 +304,408 @@ func (w *wallet) UnmarshalJSON(data []byte) error {
 	} else {
 		return errors.New("wallet next account missing")
 	}
-	if val, exists := v["version"]; exists {
-		version, ok := val.(float64)
+	if val, exists := v["walletindex"]; exists {
+		walletIndex, ok := val.(float64)
 		if !ok {
-			return errors.New("wallet version invalid")
+			return errors.New("wallet index invalid")
 		}
-		w.version = uint(version)
+		w.walletIndex = uint64(walletIndex)
+	} else if w.version > 1 {
+		return errors.New("wallet index missing")
+	}
+	if val, exists := v["pathtemplate"]; exists {
+		pathTemplate, ok := val.(string)
+		if !ok {
+			return errors.New("wallet path template invalid")
+		}
+		if err := validatePathTemplate(pathTemplate); err != nil {
+			return errors.Wrap(err, "wallet path template invalid")
+		}
+		w.pathTemplate = pathTemplate
 	} else {
-		return errors.New("wallet version missing")
+		if w.version > 1 {
+			return errors.New("wallet path template missing")
+		}
+		// Version 1 wallets that pre-date path templates use the template implied by the presence of their wallet index.
+		if _, exists := v["walletindex"]; exists {
+			w.pathTemplate = indexedPathTemplate
+		} else {
+			w.pathTemplate = legacyPathTemplate
+		}
+	}
+	if val, exists := v["metadata"]; exists {
+		metadata, ok := val.(map[string]interface{})
+		if !ok {
+			return errors.New("wallet metadata invalid")
+		}
+		w.metadata = make(map[string]string, len(metadata))
+		for key, value := range metadata {
+			valueStr, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("wallet metadata %q invalid", key)
+			}
+			w.metadata[key] = valueStr
+		}
+	}
+	if val, exists := v["tombstones"]; exists {
+		tombstones, ok := val.(map[string]interface{})
+		if !ok {
+			return errors.New("wallet tombstones invalid")
+		}
+		w.tombstones = make(map[string]string, len(tombstones))
+		for path, name := range tombstones {
+			nameStr, ok := name.(string)
+			if !ok {
+				return fmt.Errorf("wallet tombstone %q invalid", path)
+			}
+			w.tombstones[path] = nameStr
+		}
+	}
+	if w.version > 1 {
+		var err error
+		if w.created, err = unmarshalTimestamp(v, "created"); err != nil {
+			return err
+		}
+		if w.modified, err = unmarshalTimestamp(v, "modified"); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// CreateWalletFromSeed creates a wallet with the given name from a seed and stores it in the provided store.
-func CreateWalletFromSeed(name string, passphrase []byte, store wtypes.Store, encryptor wtypes.Encryptor, seed []byte) (wtypes.Wallet, error) {
+// unmarshalStructuredCrypto unmarshals the structured crypto section of version 2 wallets.
+func (w *wallet) unmarshalStructuredCrypto(crypto map[string]interface{}) error {
+	if val, exists := crypto["encryptor"]; exists {
+		encryptorName, ok := val.(string)
+		if !ok {
+			return errors.New("wallet crypto encryptor invalid")
+		}
+		w.encryptorName = encryptorName
+	} else {
+		return errors.New("wallet crypto encryptor missing")
+	}
+	if val, exists := crypto["version"]; exists {
+		encryptorVersion, ok := val.(float64)
+		if !ok {
+			return errors.New("wallet crypto version invalid")
+		}
+		w.encryptorVersion = uint(encryptorVersion)
+	} else {
+		return errors.New("wallet crypto version missing")
+	}
+	if val, exists := crypto["secret"]; exists {
+		secret, ok := val.(map[string]interface{})
+		if !ok {
+			return errors.New("wallet crypto secret invalid")
+		}
+		w.crypto = secret
+	} else if _, exists := crypto["shares"]; !exists {
+		return errors.New("wallet crypto secret missing")
+	}
+	if val, exists := crypto["shares"]; exists {
+		if err := w.unmarshalShares(crypto, val); err != nil {
+			return err
+		}
+	}
+	if val, exists := crypto["mnemonic"]; exists {
+		mnemonic, ok := val.(map[string]interface{})
+		if !ok {
+			return errors.New("wallet crypto mnemonic invalid")
+		}
+		w.mnemonicCrypto = mnemonic
+	}
+	if val, exists := crypto["additional"]; exists {
+		additional, ok := val.([]interface{})
+		if !ok {
+			return errors.New("wallet crypto additional invalid")
+		}
+		w.additionalCrypto = make([]map[string]interface{}, len(additional))
+		for i := range additional {
+			secret, ok := additional[i].(map[string]interface{})
+			if !ok {
+				return errors.New("wallet crypto additional invalid")
+			}
+			w.additionalCrypto[i] = secret
+		}
+	}
+	return nil
+}
+
+// unmarshalTimestamp unmarshals an optional RFC3339 timestamp.
+func unmarshalTimestamp(v map[string]interface{}, key string) (time.Time, error) {
+	val, exists := v[key]
+	if !exists {
+		return time.Time{}, nil
+	}
+	timestampStr, ok := val.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("wallet %s timestamp invalid", key)
+	}
+	timestamp, err := time.Parse(time.RFC3339, timestampStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("wallet %s timestamp invalid", key)
+	}
+	return timestamp, nil
+}
+
+// CreateWallet creates a new wallet with the given name and stores it in the provided store.
+// The wallet's seed is taken from WithSeed or WithMnemonic if supplied, otherwise a random seed is generated.
+// This will error if the wallet already exists.
+func CreateWallet(name string, store wtypes.Store, encryptor wtypes.Encryptor, opts ...Option) (wtypes.Wallet, error) {
+	options := options{
+		minSeedLength: minSeedLength,
+	}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
 	// First, try to open the wallet.
 	_, err := OpenWallet(name, store, encryptor)
 	if err == nil || !strings.Contains(err.Error(), "wallet not found") {
 		return nil, fmt.Errorf("wallet %q already exists", name)
 	}
 
-	id, err := uuid.NewRandom()
+	w, _, err := newWalletFromOptions(name, store, encryptor, &options)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(seed) != 32 {
-		return nil, errors.New("seed must be 32 bytes")
+	return w, w.storeWallet()
+}
+
+// newWalletFromOptions creates a new wallet given its creation options, returning the wallet and its seed.
+// The wallet is not stored.
+func newWalletFromOptions(name string, store wtypes.Store, encryptor wtypes.Encryptor, options *options) (*wallet, []byte, error) {
+	var walletIndex uint64
+	pathTemplate := legacyPathTemplate
+	if options.walletIndex != nil {
+		if *options.walletIndex > math.MaxInt32 {
+			return nil, nil, errors.New("wallet index too large")
+		}
+		walletIndex = *options.walletIndex
+		pathTemplate = indexedPathTemplate
+	}
+	if options.pathTemplate != "" {
+		if options.pathProvider != nil {
+			return nil, nil, errors.New("cannot supply both path template and path provider")
+		}
+		pathTemplate = options.pathTemplate
+	}
+	if err := validatePathTemplate(pathTemplate); err != nil {
+		return nil, nil, err
+	}
+	if options.pathProvider != nil {
+		if err := validateAccountPath(options.pathProvider.PathFor(walletIndex, 0)); err != nil {
+			return nil, nil, errors.Wrap(err, "path provider invalid")
+		}
+	}
+
+	if options.keyWrapper != nil && options.passphrase != nil {
+		return nil, nil, errors.New("cannot supply both passphrase and key wrapper")
+	}
+	if options.kdfParams != nil {
+		var err error
+		encryptor, err = tunedEncryptor(encryptor, *options.kdfParams)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if options.passphrasePolicy != nil && options.keyWrapper == nil {
+		if err := options.passphrasePolicy.Check(options.passphrase); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	seed, err := seedFromOptions(options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encryptWalletSecret := func(secret []byte) (map[string]interface{}, error) {
+		if options.keyWrapper != nil {
+			return wrapSecret(options.keyWrapper, secret)
+		}
+		return encryptSecret(encryptor, secret, options.passphrase)
 	}
-	crypto, err := encryptor.Encrypt(seed, passphrase)
+	crypto, err := encryptWalletSecret(seed)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to encrypt seed")
+		return nil, nil, errors.Wrap(err, "failed to encrypt seed")
+	}
+
+	var mnemonicCrypto map[string]interface{}
+	if options.storeMnemonic {
+		if options.mnemonic == "" {
+			return nil, nil, errors.New("cannot store mnemonic without mnemonic")
+		}
+		mnemonicCrypto, err = encryptWalletSecret([]byte(normaliseMnemonic(options.mnemonic)))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to encrypt mnemonic")
+		}
 	}
 
 	w := newWallet()
 	w.id = id
 	w.name = name
 	w.crypto = crypto
+	w.mnemonicCrypto = mnemonicCrypto
+	w.walletIndex = walletIndex
+	w.pathTemplate = pathTemplate
+	w.pathProvider = options.pathProvider
+	w.customPaths = options.pathProvider != nil
+	w.constrainPaths = options.constrainPaths
+	if w.constrainPaths && w.walletIndexComponent() < 0 {
+		return nil, nil, errors.New("constrained programmatic accounts require a path template containing the wallet index")
+	}
+	w.noProgrammatic = options.noProgrammatic
+	if options.cacheSize != nil {
+		w.accountCache = newAccountCache(*options.cacheSize)
+	}
+	if options.backend != nil {
+		w.backend = options.backend
+	}
+	if options.workers > 0 {
+		w.workers = options.workers
+	}
+	w.backoff = newUnlockBackoff(options.unlockBackoffBase, options.unlockBackoffMax)
+	w.passphrasePolicy = options.passphrasePolicy
+	w.guardedMemory = options.guardedMemory
+	w.sealSeed = options.sealSeed
+	w.keyWrapper = options.keyWrapper
+	w.passphraseProvider = options.passphraseProvider
+	w.kdfParams = options.kdfParams
+	w.sharedPassphrase = options.sharedPassphrase
+	w.progress = options.progress
+	w.derivedIDs = options.deterministicIDs
 	w.nextAccount = 0
 	w.version = version
+	w.created = time.Now()
 	w.store = store
 	w.encryptor = encryptor
+	w.encryptorName = encryptor.Name()
+	w.encryptorVersion = encryptor.Version()
 
-	return w, w.storeWallet()
+	return w, seed, nil
 }
 
-// CreateWallet creates a new wallet with the given name and stores it in the provided store.
-// This will error if the wallet already exists.
-func CreateWallet(name string, passphrase []byte, store wtypes.Store, encryptor wtypes.Encryptor) (wtypes.Wallet, error) {
-	// First, try to open the wallet.
-	_, err := OpenWallet(name, store, encryptor)
-	if err == nil || !strings.Contains(err.Error(), "wallet not found") {
-		return nil, fmt.Errorf("wallet %q already exists", name)
+// CreateWalletWithMnemonic creates a new wallet with the given name from a newly generated 24-word BIP-39 mnemonic,
+// and stores it in the provided store.
+// WithMnemonicPassphrase can be supplied to protect the mnemonic with a BIP-39 passphrase; if supplied it is required
+// alongside the mnemonic to recreate the wallet.
+// The mnemonic is returned to the caller and, unless WithStoreMnemonic is supplied, is not stored, so should be recorded
+// by the caller as a backup of the wallet.
+func CreateWalletWithMnemonic(name string, store wtypes.Store, encryptor wtypes.Encryptor, opts ...Option) (wtypes.Wallet, string, error) {
+	options := options{}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	if options.seed != nil || options.mnemonic != "" {
+		return nil, "", errors.New("cannot supply seed or mnemonic when generating a mnemonic")
 	}
 
-	id, err := uuid.NewRandom()
+	entropy, err := bip39.NewEntropy(256)
 	if err != nil {
-		return nil, err
+		return nil, "", errors.Wrap(err, "failed to generate mnemonic entropy")
 	}
-
-	// Random seed
-	seed := make([]byte, 32)
-	_, err = rand.Read(seed)
+	mnemonic, err := bip39.NewMnemonic(entropy)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to generate wallet seed")
+		return nil, "", errors.Wrap(err, "failed to generate mnemonic")
 	}
-	crypto, err := encryptor.Encrypt(seed, passphrase)
+
+	w, err := CreateWallet(name, store, encryptor, append(opts, WithMnemonic(mnemonic))...)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to encrypt seed")
+		return nil, "", err
 	}
 
-	w := newWallet()
-	w.id = id
-	w.name = name
-	w.crypto = crypto
-	w.nextAccount = 0
-	w.version = version
-	w.store = store
-	w.encryptor = encryptor
+	return w, mnemonic, nil
+}
 
-	return w, w.storeWallet()
+// seedFromOptions provides the seed for a new wallet given its creation options.
+func seedFromOptions(options *options) ([]byte, error) {
+	if options.minSeedLength < minSeedLength || options.minSeedLength > maxSeedLength {
+		return nil, fmt.Errorf("minimum seed length must be between %d and %d bytes", minSeedLength, maxSeedLength)
+	}
+	if options.seed != nil && options.mnemonic != "" {
+		return nil, errors.New("cannot supply both seed and mnemonic")
+	}
+	if options.mnemonicPassphrase != nil && options.mnemonic == "" {
+		return nil, errors.New("cannot supply mnemonic passphrase without mnemonic")
+	}
+
+	if options.mnemonic != "" {
+		mnemonic := normaliseMnemonic(options.mnemonic)
+		if !bip39.IsMnemonicValid(mnemonic) {
+			return nil, errors.New("mnemonic is invalid")
+		}
+		seed, err := bip39.NewSeedWithErrorChecking(mnemonic, norm.NFKD.String(string(options.mnemonicPassphrase)))
+		if err != nil {
+			return nil, errors.Wrap(err, "mnemonic is invalid")
+		}
+		return seed, nil
+	}
+
+	if options.seed != nil {
+		if len(options.seed) < options.minSeedLength || len(options.seed) > maxSeedLength {
+			return nil, fmt.Errorf("seed must be between %d and %d bytes", options.minSeedLength, maxSeedLength)
+		}
+		// Copy the seed, as the wallet overwrites its seed when it is locked.
+		seed := make([]byte, len(options.seed))
+		copy(seed, options.seed)
+		return seed, nil
+	}
+
+	// Random seed
+	seed := make([]byte, minSeedLength)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, errors.Wrap(err, "failed to generate wallet seed")
+	}
+	return seed, nil
+}
+
+// normaliseMnemonic normalises a mnemonic, as BIP-39 seed generation operates on the mnemonic string.
+func normaliseMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(norm.NFKD.String(mnemonic)), " ")
 }
 
 // OpenWallet opens an existing wallet with the given name.
-func OpenWallet(name string, store wtypes.Store, encryptor wtypes.Encryptor) (wtypes.Wallet, error) {
+// A wallet created with WithPathProvider must be opened with the same provider.
+func OpenWallet(name string, store wtypes.Store, encryptor wtypes.Encryptor, opts ...Option) (wtypes.Wallet, error) {
 	data, err := store.RetrieveWallet(name)
 	if err != nil {
 		return nil, errors.Wrapf(err, "wallet %q does not exist", name)
 	}
-	return DeserializeWallet(data, store, encryptor)
+	return DeserializeWallet(data, store, encryptor, opts...)
 }
 
 // DeserializeWallet deserializes a wallet from its byte-level representation
-func DeserializeWallet(data []byte, store wtypes.Store, encryptor wtypes.Encryptor) (wtypes.Wallet, error) {
+func DeserializeWallet(data []byte, store wtypes.Store, encryptor wtypes.Encryptor, opts ...Option) (wtypes.Wallet, error) {
 	wallet := newWallet()
 	if err := json.Unmarshal(data, wallet); err != nil {
 		return nil, errors.Wrap(err, "wallet corrupt")
 	}
 	wallet.store = store
 	wallet.encryptor = encryptor
+	if err := wallet.applyOpenOptions(opts); err != nil {
+		return nil, err
+	}
+	if wallet.encryptorName != "" {
+		// Version 2 wallets declare their encryptor, so select it from those available.
+		if encryptor := wallet.encryptorFor(wallet.encryptorName, wallet.encryptorVersion); encryptor != nil {
+			wallet.encryptor = encryptor
+		}
+	}
+	if wallet.kdfParams != nil {
+		encryptor, err := tunedEncryptor(wallet.encryptor, *wallet.kdfParams)
+		if err != nil {
+			return nil, err
+		}
+		wallet.encryptor = encryptor
+	}
 	if err := wallet.retrieveAccountsIndex(); err != nil {
 		return nil, errors.Wrap(err, "wallet index corrupt")
 	}
@@ -244,6 +713,64 @@ func DeserializeWallet(data []byte, store wtypes.Store, encryptor wtypes.Encrypt
 	return wallet, nil
 }
 
+// applyOpenOptions applies the options supplied when opening an existing wallet.
+func (w *wallet) applyOpenOptions(opts []Option) error {
+	options := options{}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+	if options.pathProvider != nil && !w.customPaths {
+		return errors.New("wallet does not use a path provider")
+	}
+	if options.pathProvider == nil && w.customPaths {
+		return errors.New("wallet requires a path provider")
+	}
+	w.pathProvider = options.pathProvider
+	w.constrainPaths = options.constrainPaths
+	if w.constrainPaths && w.walletIndexComponent() < 0 {
+		return errors.New("constrained programmatic accounts require a path template containing the wallet index")
+	}
+	w.noProgrammatic = options.noProgrammatic
+	w.accountCache = newAccountCache(defaultProgrammaticCacheSize)
+	if options.cacheSize != nil {
+		w.accountCache = newAccountCache(*options.cacheSize)
+	}
+	w.backend = defaultBackend
+	if options.backend != nil {
+		w.backend = options.backend
+	}
+	w.workers = defaultDerivationWorkers
+	if options.workers > 0 {
+		w.workers = options.workers
+	}
+	w.backoff = newUnlockBackoff(options.unlockBackoffBase, options.unlockBackoffMax)
+	w.passphrasePolicy = options.passphrasePolicy
+	w.guardedMemory = options.guardedMemory
+	w.sealSeed = options.sealSeed
+	w.keyWrapper = options.keyWrapper
+	w.passphraseProvider = options.passphraseProvider
+	w.kdfParams = options.kdfParams
+	w.sharedPassphrase = options.sharedPassphrase
+	w.encryptors = options.encryptors
+	w.progress = options.progress
+	return nil
+}
+
+// encryptorFor provides the wallet's encryptor with the given name and version, or nil if there is no such encryptor.
+// An empty name matches encryptors of any name.  The wallet's own encryptor is preferred over any additional
+// encryptors.
+func (w *wallet) encryptorFor(name string, version uint) wtypes.Encryptor {
+	for _, encryptor := range append([]wtypes.Encryptor{w.encryptor}, w.encryptors...) {
+		if encryptor == nil {
+			continue
+		}
+		if (name == "" || encryptor.Name() == name) && encryptor.Version() == version {
+			return encryptor
+		}
+	}
+	return nil
+}
+
 // ID provides the ID for the wallet.
 func (w *wallet) ID() uuid.UUID {
 	return w.id
@@ -259,67 +786,321 @@ func (w *wallet) Name() string {
 	return w.name
 }
 
-// Version provides the version of the wallet.
-func (w *wallet) Version() uint {
-	return w.version
-}
-
-// store stores the wallet in the store.
-func (w *wallet) storeWallet() error {
-	data, err := json.Marshal(w)
-	if err != nil {
-		return err
-	}
-
-	if err := w.storeAccountsIndex(); err != nil {
-		return err
+// Rename renames the wallet, and stores it under its new name.
+// Accounts are held against the wallet's ID, so are unaffected by the change of name.
+// This will error if a wallet with the new name already exists.
+func (w *wallet) Rename(newName string) error {
+	if newName == "" {
+		return errors.New("wallet name missing")
 	}
-
-	if err := w.store.StoreWallet(w.ID(), w.Name(), data); err != nil {
-		return err
+	if strings.HasPrefix(newName, "_") {
+		return fmt.Errorf("invalid wallet name %q", newName)
 	}
 
-	return nil
-}
-
-// Lock locks the wallet.  A locked wallet cannot create new accounts.
-func (w *wallet) Lock() {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 
-	w.seed = nil
-}
-
-// Unlock unlocks the wallet.  An unlocked wallet can create new accounts.
-func (w *wallet) Unlock(passphrase []byte) error {
-	w.mutex.Lock()
-	defer w.mutex.Unlock()
+	if newName == w.name {
+		return nil
+	}
+	if _, err := w.store.RetrieveWallet(newName); err == nil {
+		return fmt.Errorf("wallet %q already exists", newName)
+	}
 
-	seed, err := w.encryptor.Decrypt(w.crypto, passphrase)
-	if err != nil {
-		return errors.New("incorrect passphrase")
+	oldName := w.name
+	w.name = newName
+	if err := w.storeWallet(); err != nil {
+		w.name = oldName
+		return errors.Wrapf(err, "failed to rename wallet %q", oldName)
 	}
-	w.seed = seed
 
 	return nil
 }
 
-// IsUnlocked reports if the wallet is unlocked.
-func (w *wallet) IsUnlocked() bool {
-	return w.seed != nil
-}
+// CopyTo copies the wallet, its accounts and its index to the given store.
+// Data is copied as-is, so the copy is protected by the same passphrases as the original.
+// This will error if the wallet already exists in the store.
+func (w *wallet) CopyTo(store wtypes.Store) (wtypes.Wallet, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
 
-// CreateAccount creates a new account in the wallet.
-// The only rule for names is that they cannot start with an underscore (_) character.
-func (w *wallet) CreateAccount(name string, passphrase []byte) (wtypes.Account, error) {
-	if name == "" {
-		return nil, errors.New("account name missing")
+	if _, err := store.RetrieveWallet(w.name); err == nil {
+		return nil, fmt.Errorf("wallet %q already exists", w.name)
 	}
-	if strings.HasPrefix(name, "_") {
-		return nil, fmt.Errorf("invalid account name %q", name)
+	if _, err := store.RetrieveWalletByID(w.id); err == nil {
+		return nil, fmt.Errorf("wallet with ID %s already exists", w.id)
 	}
-	if !w.IsUnlocked() {
-		return nil, errors.New("wallet must be unlocked to create accounts")
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.StoreWallet(w.id, w.name, data); err != nil {
+		return nil, errors.Wrapf(err, "failed to copy wallet %q", w.name)
+	}
+
+	for accountData := range w.store.RetrieveAccounts(w.id) {
+		info := &struct {
+			ID uuid.UUID `json:"uuid"`
+		}{}
+		if err := json.Unmarshal(accountData, info); err != nil {
+			return nil, errors.Wrap(err, "account corrupt")
+		}
+		if err := store.StoreAccount(w.id, info.ID, accountData); err != nil {
+			return nil, errors.Wrapf(err, "failed to copy account %s", info.ID)
+		}
+	}
+
+	serializedIndex, err := w.index.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	if err := store.StoreAccountsIndex(w.id, serializedIndex); err != nil {
+		return nil, errors.Wrap(err, "failed to copy accounts index")
+	}
+
+	opts := []Option{
+		WithConstrainedProgrammaticAccounts(w.constrainPaths),
+		WithProgrammaticAccounts(!w.noProgrammatic),
+		WithProgrammaticAccountCacheSize(w.accountCache.size),
+		WithBLSBackend(w.backend),
+		WithDerivationWorkers(w.workers),
+		WithEncryptors(w.encryptors...),
+		WithUnlockBackoff(w.backoff.base, w.backoff.max),
+		WithPassphrasePolicy(w.passphrasePolicy),
+		WithGuardedMemory(w.guardedMemory),
+		WithSealedSeed(w.sealSeed),
+	}
+	if w.keyWrapper != nil {
+		opts = append(opts, WithKeyWrapper(w.keyWrapper))
+	}
+	if w.passphraseProvider != nil {
+		opts = append(opts, WithPassphraseProvider(w.passphraseProvider))
+	}
+	if w.kdfParams != nil {
+		opts = append(opts, WithKDFParams(*w.kdfParams))
+	}
+	if w.sharedPassphrase {
+		opts = append(opts, WithWalletPassphraseForAccounts(true))
+	}
+	if w.pathProvider != nil {
+		opts = append(opts, WithPathProvider(w.pathProvider))
+	}
+	return DeserializeWallet(data, store, w.encryptor, opts...)
+}
+
+// Version provides the version of the wallet.
+func (w *wallet) Version() uint {
+	return w.version
+}
+
+// WalletIndex provides the index of the wallet, used in account paths in place of "%w".
+func (w *wallet) WalletIndex() uint64 {
+	return w.walletIndex
+}
+
+// PathTemplate provides the template from which the wallet generates account paths.
+func (w *wallet) PathTemplate() string {
+	return w.pathTemplate
+}
+
+// NextAccount provides the account number that will be used for the next account created by the wallet.
+func (w *wallet) NextAccount() uint64 {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.nextAccount
+}
+
+// SetNextAccount sets the account number that will be used for the next account created by the wallet.
+// This allows account numbers that have already been used elsewhere to be skipped.  The next account number
+// cannot be lowered, as that would result in duplicate keys.  The wallet must be unlocked.
+func (w *wallet) SetNextAccount(nextAccount uint64) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return errors.New("wallet must be unlocked to set next account")
+	}
+	if nextAccount > math.MaxInt32 {
+		return errors.New("next account too large")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasSeed() {
+		return errors.New("wallet must be unlocked to set next account")
+	}
+
+	if nextAccount < w.nextAccount {
+		return fmt.Errorf("next account cannot be lowered from %d", w.nextAccount)
+	}
+	if nextAccount == w.nextAccount {
+		return nil
+	}
+	oldNextAccount := w.nextAccount
+	w.nextAccount = nextAccount
+	if err := w.storeWallet(); err != nil {
+		w.nextAccount = oldNextAccount
+		return err
+	}
+
+	return nil
+}
+
+// Metadata provides a copy of the wallet's metadata.
+func (w *wallet) Metadata() map[string]string {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	metadata := make(map[string]string, len(w.metadata))
+	for key, value := range w.metadata {
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// SetMetadata sets a metadata value for the wallet, and stores the wallet.
+// Setting an empty value removes the key from the metadata.
+func (w *wallet) SetMetadata(key string, value string) error {
+	if key == "" {
+		return errors.New("metadata key missing")
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if value == "" {
+		delete(w.metadata, key)
+	} else {
+		if w.metadata == nil {
+			w.metadata = make(map[string]string)
+		}
+		w.metadata[key] = value
+	}
+
+	return w.storeWallet()
+}
+
+// store stores the wallet in the store.
+func (w *wallet) storeWallet() error {
+	if w.version > 1 {
+		w.modified = time.Now()
+	}
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+
+	if err := w.storeAccountsIndex(); err != nil {
+		return err
+	}
+
+	if err := w.store.StoreWallet(w.ID(), w.Name(), data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Lock locks the wallet.  A locked wallet cannot create new accounts.
+func (w *wallet) Lock() {
+	w.mutex.Lock()
+	wasUnlocked := w.hasSeed()
+	w.clearSeed()
+	w.accountCache.clear()
+	w.mutex.Unlock()
+
+	if wasUnlocked {
+		w.hooks.walletLocked(w)
+	}
+}
+
+// Unlock unlocks the wallet.  An unlocked wallet can create new accounts.
+func (w *wallet) Unlock(passphrase []byte) error {
+	if err := w.unlock(passphrase); err != nil {
+		return err
+	}
+	w.hooks.walletUnlocked(w)
+
+	return nil
+}
+
+// unlock unlocks the wallet without calling hooks.
+func (w *wallet) unlock(passphrase []byte) error {
+	if w.watchOnly {
+		return ErrWatchOnly
+	}
+	if w.threshold > 0 {
+		return errors.New("wallet requires threshold unlock")
+	}
+
+	if err := w.backoff.check(w.id); err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	seed, err := w.decryptSeed(passphrase)
+	if err != nil {
+		w.backoff.failed(w.id)
+		if isWrapped(w.crypto) {
+			return errors.Wrap(err, "failed to unwrap seed")
+		}
+		return errors.New("incorrect passphrase")
+	}
+	w.backoff.succeeded(w.id)
+
+	if err := w.setSeed(seed); err != nil {
+		return err
+	}
+	if w.sharedPassphrase && !isWrapped(w.crypto) {
+		w.passphrase = append([]byte{}, passphrase...)
+	}
+	return nil
+}
+
+// IsUnlocked reports if the wallet is unlocked.
+func (w *wallet) IsUnlocked() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.hasSeed()
+}
+
+// CreateAccount creates a new account in the wallet.
+// The only rule for names is that they cannot start with an underscore (_) character.
+func (w *wallet) CreateAccount(name string, passphrase []byte) (wtypes.Account, error) {
+	a, err := w.createAccount(name, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	w.hooks.accountCreated(a)
+
+	return a, nil
+}
+
+// createAccount creates a new account in the wallet without calling hooks.
+func (w *wallet) createAccount(name string, passphrase []byte) (wtypes.Account, error) {
+	if name == "" {
+		return nil, errors.New("account name missing")
+	}
+	if strings.HasPrefix(name, "_") {
+		return nil, fmt.Errorf("invalid account name %q", name)
+	}
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+	passphrase, err := w.accountPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.checkPassphrase(passphrase); err != nil {
+		return nil, err
 	}
 
 	// Ensure that we don't already have an account with this name
@@ -330,26 +1111,371 @@ func (w *wallet) CreateAccount(name string, passphrase []byte) (wtypes.Account,
 	// Generate the private key from the seed and next account
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
+
+	// Check again under the lock, as the wallet may have been locked since the check above.
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+
 	accountNum := w.nextAccount
 	w.nextAccount++
 	if err := w.storeWallet(); err != nil {
 		return nil, errors.Wrapf(err, "failed to create account %q", name)
 	}
 
-	path := fmt.Sprintf("m/12381/3600/%d/0", accountNum)
-	privateKey, err := util.PrivateKeyFromSeedAndPath(w.seed, path)
+	a, err := w.deriveAccount(name, accountNum, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	w.index.Add(a.id, a.name)
+
+	if err := a.storeAccount(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// CreateAccounts creates multiple accounts in the wallet, all encrypted with the same passphrase.
+// This is considerably faster than creating the accounts individually, as the wallet and its index are
+// stored once rather than once per account.  The account numbers are reserved before any accounts are
+// stored, so a failure part-way through never results in an account number being reused.
+func (w *wallet) CreateAccounts(names []string, passphrase []byte) ([]wtypes.Account, error) {
+	accounts, err := w.createAccounts(names, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range accounts {
+		w.hooks.accountCreated(a)
+	}
+
+	return accounts, nil
+}
+
+// createAccounts creates multiple accounts in the wallet without calling hooks.
+func (w *wallet) createAccounts(names []string, passphrase []byte) ([]wtypes.Account, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+	passphrase, err := w.accountPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" {
+			return nil, errors.New("account name missing")
+		}
+		if strings.HasPrefix(name, "_") {
+			return nil, fmt.Errorf("invalid account name %q", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate account name %q", name)
+		}
+		seen[name] = true
+		if _, err := w.AccountByName(name); err == nil {
+			return nil, fmt.Errorf("account with name %q already exists", name)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+	// The next account number can be beyond the highest account number once that has been used.
+	if w.nextAccount > math.MaxInt32 || uint64(len(names)) > math.MaxInt32-w.nextAccount {
+		return nil, errors.New("too many accounts")
+	}
+
+	// Derive the accounts before reserving their account numbers, so that a derivation failure leaves the wallet
+	// untouched.
+	firstAccount := w.nextAccount
+	accounts := make([]*account, len(names))
+	if err := w.parallelise(len(names), func(i int) error {
+		a, err := w.deriveAccount(names[i], firstAccount+uint64(i), passphrase)
+		if err != nil {
+			return err
+		}
+		accounts[i] = a
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	w.nextAccount += uint64(len(names))
+	if err := w.storeWallet(); err != nil {
+		w.nextAccount = firstAccount
+		return nil, errors.Wrap(err, "failed to create accounts")
+	}
+
+	res := make([]wtypes.Account, len(accounts))
+	for i, a := range accounts {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.store.StoreAccount(w.id, a.id, data); err != nil {
+			return nil, errors.Wrapf(err, "failed to store account %q", a.name)
+		}
+		w.index.Add(a.id, a.name)
+		res[i] = a
+	}
+	if err := w.storeAccountsIndex(); err != nil {
+		return nil, errors.Wrap(err, "failed to store accounts index")
+	}
+
+	return res, nil
+}
+
+// CreateAccountAtIndex creates an account in the wallet with the given account number, rather than the next
+// account number.  This allows accounts that were skipped, or that were created elsewhere, to be re-created.
+// If the account number is at or beyond the next account number then the next account number is moved past it.
+// The account number of a deleted account can only be re-used by an account with the same name.
+func (w *wallet) CreateAccountAtIndex(name string, index uint64, passphrase []byte) (wtypes.Account, error) {
+	a, err := w.createAccountAtIndex(name, index, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	w.hooks.accountCreated(a)
+
+	return a, nil
+}
+
+// createAccountAtIndex creates an account with the given account number without calling hooks.
+func (w *wallet) createAccountAtIndex(name string, index uint64, passphrase []byte) (wtypes.Account, error) {
+	if name == "" {
+		return nil, errors.New("account name missing")
+	}
+	if strings.HasPrefix(name, "_") {
+		return nil, fmt.Errorf("invalid account name %q", name)
+	}
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+	passphrase, err := w.accountPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if index > math.MaxInt32 {
+		return nil, errors.New("account index too large")
+	}
+
+	// Ensure that we don't already have an account with this name or index
+	if _, err := w.AccountByName(name); err == nil {
+		return nil, fmt.Errorf("account with name %q already exists", name)
+	}
+	path := w.accountPath(index)
+	for account := range w.Accounts() {
+		if account.Path() == path {
+			return nil, fmt.Errorf("account at index %d already exists", index)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+
+	if tombstone, exists := w.tombstones[path]; exists && tombstone != name {
+		return nil, fmt.Errorf("account index %d was used by deleted account %q", index, tombstone)
+	}
+	if index >= w.nextAccount {
+		oldNextAccount := w.nextAccount
+		w.nextAccount = index + 1
+		if err := w.storeWallet(); err != nil {
+			w.nextAccount = oldNextAccount
+			return nil, errors.Wrapf(err, "failed to create account %q", name)
+		}
+	}
+
+	a, err := w.deriveAccount(name, index, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, exists := w.tombstones[path]; exists {
+		// The deleted account has been re-created.
+		delete(w.tombstones, path)
+		if err := w.storeWallet(); err != nil {
+			w.tombstones[path] = name
+			return nil, errors.Wrapf(err, "failed to create account %q", name)
+		}
+	}
+
+	w.index.Add(a.id, a.name)
+
+	if err := a.storeAccount(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// RegenerateAccount rebuilds the account with the given account number and name from the wallet's seed, for example
+// if the account's stored data has been lost or corrupted.  If the account is in the accounts index it retains its ID,
+// and if its stored data can still be read the regenerated public key is verified against it.  Otherwise the account is
+// re-created as per CreateAccountAtIndex.  The account number must already have been used by the wallet.
+func (w *wallet) RegenerateAccount(index uint64, name string, passphrase []byte) (wtypes.Account, error) {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to regenerate accounts")
+	}
+	passphrase, err := w.accountPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if index >= w.NextAccount() {
+		return nil, fmt.Errorf("account index %d has not been used", index)
+	}
+
+	w.mutex.RLock()
+	id, exists := w.index.ID(name)
+	w.mutex.RUnlock()
+	if !exists {
+		return w.CreateAccountAtIndex(name, index, passphrase)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to regenerate accounts")
+	}
+
+	a, err := w.deriveAccount(name, index, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	a.id = id
+	if data, err := w.store.RetrieveAccount(w.id, id); err == nil {
+		if existing, err := deserializeAccount(w, data); err == nil {
+			if !bytes.Equal(existing.PublicKey().Marshal(), a.publicKey.Marshal()) {
+				return nil, fmt.Errorf("regenerated public key does not match account %q", name)
+			}
+		}
+	}
+
+	if err := a.storeAccount(); err != nil {
+		return nil, errors.Wrapf(err, "failed to store account %q", name)
+	}
+
+	return a, nil
+}
+
+// DeleteAccount deletes an account, given its name or ID, from the wallet.
+// Deletion is in two phases.  The account is first soft-deleted: it is hidden from the wallet's accounts, but it
+// is retained in the store and is listed by DeletedAccounts() until it is either restored with RestoreAccount() or
+// permanently deleted with PurgeAccount().  The account's path is recorded so that it cannot later be re-used by an
+// account with a different name.
+func (w *wallet) DeleteAccount(nameOrID string) error {
+	a, err := w.deleteAccount(nameOrID)
+	if err != nil {
+		return err
+	}
+	w.hooks.accountDeleted(a)
+
+	return nil
+}
+
+// deleteAccount soft-deletes an account from the wallet without calling hooks, returning the deleted account.
+func (w *wallet) deleteAccount(nameOrID string) (*account, error) {
+	var walletAccount wtypes.Account
+	var err error
+	if id, parseErr := uuid.Parse(nameOrID); parseErr == nil {
+		walletAccount, err = w.AccountByID(id)
+	} else {
+		walletAccount, err = w.AccountByName(nameOrID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a, ok := walletAccount.(*account)
+	if !ok || !w.index.IDKnown(a.id) {
+		return nil, fmt.Errorf("account %q cannot be deleted", nameOrID)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// Flag the account as deleted before removing it from the index, so that a failure cannot leave the account
+	// visible but missing from the index.
+	a.setDeleted(true)
+	if err := w.storeAccountData(a); err != nil {
+		a.setDeleted(false)
+		return nil, errors.Wrapf(err, "failed to delete account %q", a.name)
+	}
+	if a.path != "" {
+		if w.tombstones == nil {
+			w.tombstones = make(map[string]string)
+		}
+		w.tombstones[a.path] = a.name
+	}
+	w.index.Remove(a.id, a.name)
+	if err := w.storeWallet(); err != nil {
+		return nil, w.undeleteAccount(a, errors.Wrapf(err, "failed to delete account %q", a.name))
+	}
+	if err := w.storeAccountsIndex(); err != nil {
+		return nil, w.undeleteAccount(a, errors.Wrapf(err, "failed to delete account %q", a.name))
+	}
+
+	return a, nil
+}
+
+// undeleteAccount reverts the soft deletion of an account after a failure, returning the error that caused
+// the failure.
+func (w *wallet) undeleteAccount(a *account, err error) error {
+	if w.tombstones[a.path] == a.name {
+		delete(w.tombstones, a.path)
+	}
+	w.index.Add(a.id, a.name)
+	a.setDeleted(false)
+	if restoreErr := w.storeAccountData(a); restoreErr != nil {
+		return errors.Wrapf(err, "failed to restore account %q: %v", a.name, restoreErr)
+	}
+	if restoreErr := w.storeWallet(); restoreErr != nil {
+		return errors.Wrapf(err, "failed to restore wallet: %v", restoreErr)
+	}
+	return err
+}
+
+// deriveAccount derives the account with the given account number from the wallet's seed.
+// The account is not stored.
+func (w *wallet) deriveAccount(name string, accountNum uint64, passphrase []byte) (*account, error) {
+	return w.deriveAccountAtPath(name, w.accountPath(accountNum), passphrase)
+}
+
+// deriveAccountAtPath derives the account with the given path from the wallet's seed.
+// The account is not stored.
+func (w *wallet) deriveAccountAtPath(name string, path string, passphrase []byte) (*account, error) {
+	if err := w.checkPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+	privateKey, err := w.derivePrivateKey(path)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create private key for account %q", name)
 	}
 	a := newAccount()
 	a.path = path
-	if a.id, err = uuid.NewRandom(); err != nil {
+	a.publicKey = privateKey.PublicKey()
+	if a.id, err = w.accountID(a.publicKey); err != nil {
 		return nil, err
 	}
 	a.name = name
-	a.publicKey = privateKey.PublicKey()
 	// Encrypt the private key
-	a.crypto, err = w.encryptor.Encrypt(privateKey.Marshal(), passphrase)
+	a.crypto, err = encryptSecret(w.encryptor, privateKey.Marshal(), passphrase)
 	if err != nil {
 		return nil, err
 	}
@@ -357,116 +1483,288 @@ func (w *wallet) CreateAccount(name string, passphrase []byte) (wtypes.Account,
 	a.version = w.encryptor.Version()
 	a.wallet = w
 
-	w.index.Add(a.id, a.name)
+	return a, nil
+}
 
-	if err := a.storeAccount(); err != nil {
-		return nil, err
+// accountID generates the ID for a new account with the given public key.
+// If the wallet uses deterministic IDs the ID is a version 5 UUID generated from the public key, so the same key
+// always has the same ID; otherwise it is random.
+func (w *wallet) accountID(publicKey e2types.PublicKey) (uuid.UUID, error) {
+	if w.derivedIDs {
+		return uuid.NewSHA1(accountIDNamespace, publicKey.Marshal()), nil
 	}
+	return uuid.NewRandom()
+}
 
-	return a, nil
+// Mnemonic provides the mnemonic from which the wallet's seed was generated, if it was stored when the
+// wallet was created.  The mnemonic is returned in its normalised form.
+func (w *wallet) Mnemonic(passphrase []byte) (string, error) {
+	if w.watchOnly {
+		return "", ErrWatchOnly
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if w.mnemonicCrypto == nil {
+		return "", errors.New("wallet has no stored mnemonic")
+	}
+	mnemonic, err := w.openSecret(w.mnemonicCrypto, passphrase)
+	if err != nil {
+		return "", errors.New("incorrect passphrase")
+	}
+	defer zeroBytes(mnemonic)
+
+	return string(mnemonic), nil
 }
 
-// Key returns the wallet's HD seed
+// Key returns a copy of the wallet's HD seed.
+// The copy is not overwritten when the wallet is locked, so the caller should do so when it has finished with it.
 func (w *wallet) Key() ([]byte, error) {
-	if !w.IsUnlocked() {
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	var seed []byte
+	if err := w.useSeed(func(walletSeed []byte) error {
+		seed = make([]byte, len(walletSeed))
+		copy(seed, walletSeed)
+		return nil
+	}); err != nil {
 		return nil, errors.New("wallet must be unlocked to provide seed")
 	}
-	return w.seed, nil
+	return seed, nil
 }
 
 // Accounts provides all accounts in the wallet.
 func (w *wallet) Accounts() <-chan wtypes.Account {
+	return w.AccountsWithContext(context.Background())
+}
+
+// AccountsWithContext provides all accounts in the wallet.
+// Accounts stop being provided, and the channel is closed, when the context is cancelled.
+func (w *wallet) AccountsWithContext(ctx context.Context) <-chan wtypes.Account {
 	ch := make(chan wtypes.Account, 1024)
 	go func() {
+		defer close(ch)
 		for data := range w.store.RetrieveAccounts(w.ID()) {
-			if a, err := deserializeAccount(w, data); err == nil {
-				ch <- a
+			if ctx.Err() != nil {
+				return
+			}
+			a, err := deserializeAccount(w, data)
+			if err != nil || a.(*account).deleted {
+				continue
+			}
+			select {
+			case ch <- a:
+			case <-ctx.Done():
+				return
 			}
 		}
-		close(ch)
 	}()
 	return ch
 }
 
 // Export exports the entire wallet, protected by an additional passphrase.
 func (w *wallet) Export(passphrase []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := w.ExportTo(buf, passphrase); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Import imports the entire wallet, protected by an additional passphrase.
+// A wallet exported from a wallet created with WithPathProvider must be imported with the same provider.
+func Import(encryptedData []byte, passphrase []byte, store wtypes.Store, encryptor wtypes.Encryptor, opts ...Option) (wtypes.Wallet, error) {
+	return ImportFrom(bytes.NewReader(encryptedData), passphrase, store, encryptor, opts...)
+}
+
+// decodeLegacy decodes a wallet exported as a single encrypted blob, prior to streaming exports.
+func decodeLegacy(encryptedData []byte, passphrase []byte) (*wallet, func() (*account, error), error) {
 	type walletExt struct {
 		Wallet   *wallet    `json:"wallet"`
 		Accounts []*account `json:"accounts"`
 	}
 
-	accounts := make([]*account, 0)
-	for acc := range w.Accounts() {
-		accounts = append(accounts, acc.(*account))
+	data, err := ecodec.Decrypt(encryptedData, passphrase)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	ext := &walletExt{
-		Wallet:   w,
-		Accounts: accounts,
+		Wallet: newWallet(),
 	}
-
-	data, err := json.Marshal(ext)
-	if err != nil {
-		return nil, err
+	if err := json.Unmarshal(data, ext); err != nil {
+		return nil, nil, err
 	}
 
-	return ecodec.Encrypt(data, passphrase)
+	ext.Wallet.exportAccountCount = uint64(len(ext.Accounts))
+	return ext.Wallet, func() (*account, error) {
+		if len(ext.Accounts) == 0 {
+			return nil, nil
+		}
+		acc := ext.Accounts[0]
+		ext.Accounts = ext.Accounts[1:]
+		return acc, nil
+	}, nil
 }
 
-// Import imports the entire wallet, protected by an additional passphrase.
-func Import(encryptedData []byte, passphrase []byte, store wtypes.Store, encryptor wtypes.Encryptor) (wtypes.Wallet, error) {
-	type walletExt struct {
-		Wallet   *wallet    `json:"wallet"`
-		Accounts []*account `json:"accounts"`
+// importWallet stores an imported wallet, followed by the accounts provided by nextAccount until it returns nil.
+// Accounts are stored as they are decoded, so that imports of wallets with many accounts are not held in memory,
+// unless the integrity tag of the export is to be verified, in which case the entire export is decoded and verified
+// before anything is written to the store.
+func importWallet(w *wallet, nextAccount func() (*account, error), store wtypes.Store, encryptor wtypes.Encryptor, opts []Option) (wtypes.Wallet, error) {
+	w.store = store
+	w.encryptor = encryptor
+	if err := w.applyOpenOptions(opts); err != nil {
+		return nil, err
 	}
 
-	data, err := ecodec.Decrypt(encryptedData, passphrase)
-	if err != nil {
-		return nil, err
+	options := options{}
+	for _, o := range opts {
+		o.apply(&options)
 	}
 
-	ext := &walletExt{}
-	if err := json.Unmarshal(data, ext); err != nil {
+	// Decode the entire export before writing anything, so that a truncated or corrupt export leaves the store untouched.
+	var key []byte
+	if options.verifyIntegrity {
+		var err error
+		key, err = importIntegrityKey(w, options.verifyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+	}
+	nextAccount, err := stagedAccounts(w, nextAccount, key)
+	zeroBytes(key)
+	if err != nil {
 		return nil, err
 	}
+	nextAccount = withImportProgress(w, nextAccount, options.progress)
 
-	ext.Wallet.mutex = new(sync.RWMutex)
-	ext.Wallet.index = indexer.New()
-	ext.Wallet.store = store
-	ext.Wallet.encryptor = encryptor
+	name := w.name
+	if options.newWalletName != "" {
+		if strings.HasPrefix(options.newWalletName, "_") {
+			return nil, fmt.Errorf("invalid wallet name %q", options.newWalletName)
+		}
+		w.name = options.newWalletName
+	}
 
-	// See if the wallet already exists
-	if _, err := OpenWallet(ext.Wallet.Name(), store, encryptor); err == nil {
-		return nil, fmt.Errorf("wallet %q already exists", ext.Wallet.Name())
+	// See if the wallet already exists.  This is checked in the store rather than by opening the wallet, as opening
+	// can fail for reasons other than the wallet not existing.
+	if _, err := store.RetrieveWallet(w.Name()); err == nil {
+		switch {
+		case options.mergeImport:
+			existing, err := OpenWallet(w.Name(), store, encryptor, opts...)
+			if err != nil {
+				return nil, err
+			}
+			return mergeWallet(existing.(*wallet), w, nextAccount, options.mergePassphrase)
+		case options.conflictSuffix != "":
+			w.name += options.conflictSuffix
+			if _, err := store.RetrieveWallet(w.name); err == nil {
+				return nil, fmt.Errorf("wallet %q already exists", w.name)
+			}
+		default:
+			return nil, fmt.Errorf("wallet %q already exists", w.Name())
+		}
+	}
+	if _, err := store.RetrieveWalletByID(w.id); err == nil {
+		if w.name == name {
+			return nil, fmt.Errorf("wallet with ID %s already exists", w.id)
+		}
+		// The renamed wallet is stored alongside the original, so cannot share its ID.
+		w.id = uuid.New()
 	}
 
 	// Create the wallet
-	if err := ext.Wallet.storeWallet(); err != nil {
-		return nil, fmt.Errorf("failed to store wallet %q", ext.Wallet.Name())
+	if err := w.storeWallet(); err != nil {
+		return nil, fmt.Errorf("failed to store wallet %q", w.Name())
 	}
 
 	// Create the accounts
-	for _, acc := range ext.Accounts {
-		acc.wallet = ext.Wallet
+	for {
+		acc, err := nextAccount()
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			break
+		}
+		acc.wallet = w
 		acc.encryptor = encryptor
 		acc.mutex = new(sync.RWMutex)
 		if err := acc.storeAccount(); err != nil {
 			return nil, fmt.Errorf("failed to store account %q", acc.Name())
 		}
-		ext.Wallet.index.Add(acc.id, acc.name)
+		w.index.Add(acc.id, acc.name)
+	}
+
+	return w, nil
+}
+
+// stagedAccounts decodes all of the accounts of an export, and verifies its integrity tag if an integrity key is
+// supplied, providing a function that returns the decoded accounts in turn.
+func stagedAccounts(w *wallet, nextAccount func() (*account, error), key []byte) (func() (*account, error), error) {
+	accounts := make([]*account, 0)
+	for {
+		acc, err := nextAccount()
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			break
+		}
+		accounts = append(accounts, acc)
 	}
+	if key != nil {
+		if err := verifyExportIntegrity(w, key); err != nil {
+			return nil, err
+		}
+	}
+	return func() (*account, error) {
+		if len(accounts) == 0 {
+			return nil, nil
+		}
+		acc := accounts[0]
+		accounts = accounts[1:]
+		return acc, nil
+	}, nil
+}
 
-	return ext.Wallet, nil
+// withImportProgress wraps a function that provides imported accounts, calling progress with the number of accounts
+// processed each time the next account is requested.
+func withImportProgress(w *wallet, nextAccount func() (*account, error), progress ProgressFunc) func() (*account, error) {
+	if progress == nil {
+		return nextAccount
+	}
+	processed := uint64(0)
+	return func() (*account, error) {
+		if processed > 0 {
+			progress(processed, w.exportAccountCount)
+		}
+		acc, err := nextAccount()
+		if acc != nil {
+			processed++
+		}
+		return acc, err
+	}
 }
 
 // AccountByName provides a single account from the wallet given its name.
+// A name starting "m/" is treated as a path, as per AccountByPath.
 // This will error if the account is not found.
 func (w *wallet) AccountByName(name string) (wtypes.Account, error) {
 	if strings.HasPrefix(name, "m/") {
-		// Programmatic name
-		return w.programmaticAccount(name)
+		// Programmatic name, retained for backwards compatibility.
+		return w.AccountByPath(name)
 	}
+	w.mutex.RLock()
 	id, exists := w.index.ID(name)
+	w.mutex.RUnlock()
 	if !exists {
 		return nil, fmt.Errorf("no account with name %q", name)
 	}
@@ -480,7 +1778,14 @@ func (w *wallet) AccountByID(id uuid.UUID) (wtypes.Account, error) {
 	if err != nil {
 		return nil, err
 	}
-	return deserializeAccount(w, data)
+	a, err := deserializeAccount(w, data)
+	if err != nil {
+		return nil, err
+	}
+	if a.(*account).deleted {
+		return nil, fmt.Errorf("account %s is deleted", id)
+	}
+	return a, nil
 }
 
 // Store returns the wallet's store.
@@ -488,31 +1793,83 @@ func (w *wallet) Store() wtypes.Store {
 	return w.store
 }
 
-// programmaticAccount calculates an account on the fly given its path.
-func (w *wallet) programmaticAccount(path string) (wtypes.Account, error) {
-	privateKey, err := util.PrivateKeyFromSeedAndPath(w.seed, path)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to create private key for path %q", path)
+// accountPath provides the derivation path for the given account number.
+func (w *wallet) accountPath(accountNum uint64) string {
+	if w.pathProvider != nil {
+		return w.pathProvider.PathFor(w.walletIndex, accountNum)
 	}
-	a := newAccount()
-	a.path = path
-	a.id, err = uuid.NewRandom()
-	if err != nil {
-		return nil, err
+	return expandPathTemplate(w.pathTemplate, w.walletIndex, accountNum)
+}
+
+// accountNumber provides the account number from which the wallet generated the path, including withdrawal paths.
+// It returns false if the path was not generated from one of the account numbers that the wallet has used.
+// Rather than generating the path of every account number used, which for wallets whose next account number has been
+// set high would be prohibitive, the candidate account number is obtained from the path and confirmed by generating
+// its path.  Paths from a path provider are assumed to contain the account number as one of their components.
+// The caller must hold the wallet's lock.
+func (w *wallet) accountNumber(path string) (uint64, bool) {
+	candidates := make([]uint64, 0, 1)
+	if w.pathProvider == nil {
+		derivationPath, err := w.parsePath(path)
+		if err != nil {
+			return 0, false
+		}
+		candidates = append(candidates, derivationPath.AccountIndex)
+	} else {
+		for _, component := range strings.Split(path, "/")[1:] {
+			if value, err := strconv.ParseUint(component, 10, 31); err == nil {
+				candidates = append(candidates, value)
+			}
+		}
 	}
-	a.name = path
-	a.publicKey = privateKey.PublicKey()
-	a.secretKey = privateKey
-	// Encrypt the private key with an empty passphrase
-	a.crypto, err = w.encryptor.Encrypt(privateKey.Marshal(), []byte{})
-	if err != nil {
-		return nil, err
+	for _, accountNum := range candidates {
+		if accountNum >= w.nextAccount {
+			continue
+		}
+		if path == w.accountPath(accountNum) || (w.supportsWithdrawalAccounts() && path == w.withdrawalPath(accountNum)) {
+			return accountNum, true
+		}
 	}
-	a.encryptor = w.encryptor
-	a.version = w.encryptor.Version()
-	a.wallet = w
+	return 0, false
+}
 
-	return a, nil
+// expandPathTemplate expands a path template given a wallet index and account number.
+func expandPathTemplate(pathTemplate string, walletIndex uint64, accountNum uint64) string {
+	return strings.NewReplacer(
+		"%w", strconv.FormatUint(walletIndex, 10),
+		"%a", strconv.FormatUint(accountNum, 10),
+	).Replace(pathTemplate)
+}
+
+// validatePathTemplate ensures that a path template generates valid account paths.
+func validatePathTemplate(pathTemplate string) error {
+	if !strings.Contains(pathTemplate, "%a") {
+		return errors.New(`path template must contain account number "%a"`)
+	}
+	components := strings.Split(expandPathTemplate(pathTemplate, 0, 0), "/")
+	if components[0] != "m" || len(components) < 2 {
+		return errors.New(`path template must start with "m/"`)
+	}
+	for _, component := range components[1:] {
+		if _, err := strconv.ParseUint(component, 10, 31); err != nil {
+			return fmt.Errorf("path template component %q invalid", component)
+		}
+	}
+	return nil
+}
+
+// validateAccountPath ensures that an account path is valid.
+func validateAccountPath(path string) error {
+	components := strings.Split(path, "/")
+	if components[0] != "m" || len(components) < 2 {
+		return errors.New(`path must start with "m/"`)
+	}
+	for _, component := range components[1:] {
+		if _, err := strconv.ParseUint(component, 10, 31); err != nil {
+			return fmt.Errorf("path component %q invalid", component)
+		}
+	}
+	return nil
 }
 
 // retrieveAccountsIndex retrieves the accounts index for a wallet.
@@ -537,6 +1894,37 @@ func (w *wallet) retrieveAccountsIndex() error {
 	return nil
 }
 
+// indexEntry is an entry in the accounts index.
+type indexEntry struct {
+	ID   uuid.UUID `json:"uuid"`
+	Name string    `json:"name"`
+}
+
+// indexEntries provides the entries in the accounts index, ordered by name.
+func (w *wallet) indexEntries() ([]*indexEntry, error) {
+	serializedIndex, err := w.index.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	var entries []*indexEntry
+	if err := json.Unmarshal(serializedIndex, &entries); err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i int, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// storeAccountData stores an account without storing the accounts index.
+func (w *wallet) storeAccountData(a *account) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return w.store.StoreAccount(w.id, a.id, data)
+}
+
 // storeAccountsIndex stores the accounts index for a wallet.
 func (w *wallet) storeAccountsIndex() error {
 	serializedIndex, err := w.index.Serialize()
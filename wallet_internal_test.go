@@ -21,16 +21,20 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
 )
 
 func TestUnmarshalWallet(t *testing.T) {
 	tests := []struct {
-		name       string
-		input      []byte
-		err        error
-		id         uuid.UUID
-		version    uint
-		walletType string
+		name         string
+		input        []byte
+		err          error
+		id           uuid.UUID
+		version      uint
+		walletType   string
+		walletIndex  uint64
+		pathTemplate string
 	}{
 		{
 			name: "Nil",
@@ -86,6 +90,24 @@ func TestUnmarshalWallet(t *testing.T) {
 			input: []byte(`{"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":1}`),
 			err:   errors.New("wallet crypto missing"),
 		},
+		{
+			name:  "BadWatchOnly",
+			input: []byte(`{"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","watchonly":1,"version":1}`),
+			err:   errors.New("wallet watch-only flag invalid"),
+		},
+		{
+			name:  "BadDeterministicIDs",
+			input: []byte(`{"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","watchonly":true,"deterministicids":"yes","version":1}`),
+			err:   errors.New("wallet deterministic IDs flag invalid"),
+		},
+		{
+			name:         "GoodWatchOnly",
+			input:        []byte(`{"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","watchonly":true,"version":1}`),
+			walletType:   "hierarchical deterministic",
+			id:           uuid.MustParse("7603a428-999c-49d0-8241-ddfd63ee143d"),
+			version:      1,
+			pathTemplate: "m/12381/3600/%a/0",
+		},
 		{
 			name:  "WrongCrypto",
 			input: []byte(`{"crypto":"foo","uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":1}`),
@@ -127,11 +149,119 @@ func TestUnmarshalWallet(t *testing.T) {
 			err:   errors.New("wallet version invalid"),
 		},
 		{
-			name:       "Good",
-			input:      []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":1}`),
-			walletType: "hierarchical deterministic",
-			id:         uuid.MustParse("7603a428-999c-49d0-8241-ddfd63ee143d"),
-			version:    1,
+			name:  "BadWalletIndex",
+			input: []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"walletindex":"bad","type":"hierarchical deterministic","version":1}`),
+			err:   errors.New("wallet index invalid"),
+		},
+		{
+			name:  "WrongPathTemplate",
+			input: []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"pathtemplate":1,"type":"hierarchical deterministic","version":1}`),
+			err:   errors.New("wallet path template invalid"),
+		},
+		{
+			name:  "BadPathTemplate",
+			input: []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"pathtemplate":"m/12381/3600/0","type":"hierarchical deterministic","version":1}`),
+			err:   errors.New(`wallet path template invalid: path template must contain account number "%a"`),
+		},
+		{
+			name:  "WrongMetadata",
+			input: []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"metadata":"bad","type":"hierarchical deterministic","version":1}`),
+			err:   errors.New("wallet metadata invalid"),
+		},
+		{
+			name:  "BadMetadata",
+			input: []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"metadata":{"owner":1},"type":"hierarchical deterministic","version":1}`),
+			err:   errors.New(`wallet metadata "owner" invalid`),
+		},
+		{
+			name:  "WrongTombstones",
+			input: []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"tombstones":"none","type":"hierarchical deterministic","version":1}`),
+			err:   errors.New("wallet tombstones invalid"),
+		},
+		{
+			name:  "BadTombstones",
+			input: []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"tombstones":{"m/12381/3600/0/0":1},"type":"hierarchical deterministic","version":1}`),
+			err:   errors.New(`wallet tombstone "m/12381/3600/0/0" invalid`),
+		},
+		{
+			name:         "GoodLegacy",
+			input:        []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":1}`),
+			walletType:   "hierarchical deterministic",
+			id:           uuid.MustParse("7603a428-999c-49d0-8241-ddfd63ee143d"),
+			version:      1,
+			pathTemplate: "m/12381/3600/%a/0",
+		},
+		{
+			name:         "GoodIndexed",
+			input:        []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"walletindex":3,"type":"hierarchical deterministic","version":1}`),
+			walletType:   "hierarchical deterministic",
+			id:           uuid.MustParse("7603a428-999c-49d0-8241-ddfd63ee143d"),
+			version:      1,
+			walletIndex:  3,
+			pathTemplate: "m/12381/3600/%w/%a/0",
+		},
+		{
+			name:         "GoodPathTemplate",
+			input:        []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","name":"hd wallet","nextaccount":2,"walletindex":3,"pathtemplate":"m/12381/3600/%a/0/0","type":"hierarchical deterministic","version":1}`),
+			walletType:   "hierarchical deterministic",
+			id:           uuid.MustParse("7603a428-999c-49d0-8241-ddfd63ee143d"),
+			version:      1,
+			walletIndex:  3,
+			pathTemplate: "m/12381/3600/%a/0/0",
+		},
+		{
+			name:  "UnsupportedVersion",
+			input: []byte(`{"crypto":{"encryptor":"keystore","version":4,"secret":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"pathtemplate":"m/12381/3600/%w/%a/0","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":3}`),
+			err:   errors.New("wallet version 3 unsupported"),
+		},
+		{
+			name:  "V2OldID",
+			input: []byte(`{"crypto":{"encryptor":"keystore","version":4,"secret":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}}},"id":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"pathtemplate":"m/12381/3600/%w/%a/0","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			err:   errors.New("wallet ID missing"),
+		},
+		{
+			name:  "V2UnstructuredCrypto",
+			input: []byte(`{"crypto":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"pathtemplate":"m/12381/3600/%w/%a/0","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			err:   errors.New("wallet crypto encryptor missing"),
+		},
+		{
+			name:  "V2WrongCryptoVersion",
+			input: []byte(`{"crypto":{"encryptor":"keystore","version":"4","secret":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"pathtemplate":"m/12381/3600/%w/%a/0","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			err:   errors.New("wallet crypto version invalid"),
+		},
+		{
+			name:  "V2MissingCryptoSecret",
+			input: []byte(`{"crypto":{"encryptor":"keystore","version":4},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"pathtemplate":"m/12381/3600/%w/%a/0","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			err:   errors.New("wallet crypto secret missing"),
+		},
+		{
+			name:  "V2BadMnemonic",
+			input: []byte(`{"crypto":{"encryptor":"keystore","version":4,"mnemonic":"words","secret":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"pathtemplate":"m/12381/3600/%w/%a/0","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			err:   errors.New("wallet crypto mnemonic invalid"),
+		},
+		{
+			name:  "V2MissingWalletIndex",
+			input: []byte(`{"crypto":{"encryptor":"keystore","version":4,"secret":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","pathtemplate":"m/12381/3600/%w/%a/0","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			err:   errors.New("wallet index missing"),
+		},
+		{
+			name:  "V2MissingPathTemplate",
+			input: []byte(`{"crypto":{"encryptor":"keystore","version":4,"secret":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			err:   errors.New("wallet path template missing"),
+		},
+		{
+			name:  "V2BadCreated",
+			input: []byte(`{"crypto":{"encryptor":"keystore","version":4,"secret":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"pathtemplate":"m/12381/3600/%w/%a/0","created":"yesterday","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			err:   errors.New("wallet created timestamp invalid"),
+		},
+		{
+			name:         "GoodV2",
+			input:        []byte(`{"crypto":{"encryptor":"keystore","version":4,"secret":{"checksum":{"function":"sha256","message":"d6f4c3898450a44666538785f419a78decde53da5f3ec17e611a961e204ed617","params":{}},"cipher":{"function":"aes-128-ctr","message":"0040872e1ba675bfe39053565f7ec02bc1560b2a95670b046f1a2e17facc1b57","params":{"iv":"7cbadf81a3895dbfee3863f0e5bd19f2"}},"kdf":{"function":"pbkdf2","message":"","params":{"c":16,"dklen":32,"prf":"hmac-sha256","salt":"fcb4992215d5f84444c6f49a69e2124a899740e76caea09a1d465a71f802023a"}}}},"uuid":"7603a428-999c-49d0-8241-ddfd63ee143d","walletindex":3,"pathtemplate":"m/12381/3600/%w/%a/0","created":"2020-06-01T12:00:00Z","modified":"2020-06-02T12:00:00Z","name":"hd wallet","nextaccount":2,"type":"hierarchical deterministic","version":2}`),
+			walletType:   "hierarchical deterministic",
+			id:           uuid.MustParse("7603a428-999c-49d0-8241-ddfd63ee143d"),
+			version:      2,
+			walletIndex:  3,
+			pathTemplate: "m/12381/3600/%w/%a/0",
 		},
 	}
 
@@ -147,7 +277,87 @@ func TestUnmarshalWallet(t *testing.T) {
 				assert.Equal(t, test.id, output.ID())
 				assert.Equal(t, test.version, output.Version())
 				assert.Equal(t, test.walletType, output.Type())
+				assert.Equal(t, test.walletIndex, output.walletIndex)
+				assert.Equal(t, test.pathTemplate, output.pathTemplate)
 			}
 		})
 	}
 }
+
+func TestLockZeroesSecrets(t *testing.T) {
+	seed := []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+		0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+	}
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		seed:          seed,
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+
+	// The seed supplied to the wallet is copied rather than retained.
+	require.NotNil(t, w.seed)
+	assert.False(t, &seed[0] == &w.seed[0])
+	// The seed provided by Key() is a copy.
+	key, err := w.Key()
+	require.NoError(t, err)
+	assert.Equal(t, seed, key)
+	assert.False(t, &key[0] == &w.seed[0])
+
+	heldSeed := w.seed
+	require.NotNil(t, w.node)
+	heldNodeKey := w.node.key
+	heldNodeWords := heldNodeKey.Bits()
+	w.Lock()
+	assert.Nil(t, w.seed)
+	assert.Nil(t, w.node)
+	assert.Equal(t, make([]byte, len(heldSeed)), heldSeed)
+	assert.Equal(t, int64(0), heldNodeKey.Int64())
+	for _, word := range heldNodeWords {
+		assert.Zero(t, word)
+	}
+	// The caller's copies are untouched.
+	assert.Equal(t, seed, key)
+}
+
+func TestLockZeroesKeys(t *testing.T) {
+	w, _, err := newWalletFromOptions("test wallet", scratch.New(), keystorev4.New(), &options{
+		minSeedLength: minSeedLength,
+	})
+	require.NoError(t, err)
+	require.NoError(t, w.Unlock(nil))
+
+	// The decrypted key of an account is overwritten when the account is locked.
+	created, err := w.CreateAccount("Account", []byte("secret"))
+	require.NoError(t, err)
+	a := created.(*account)
+	require.NoError(t, a.Unlock([]byte("secret")))
+	heldKey := a.secretKey
+	require.NotNil(t, heldKey)
+	assert.NotEqual(t, make([]byte, 32), heldKey.Marshal())
+	a.Lock()
+	assert.Nil(t, a.secretKey)
+	assert.Equal(t, make([]byte, 32), heldKey.Marshal())
+	require.NoError(t, a.Unlock([]byte("secret")))
+	_, err = a.Sign([]byte("data"))
+	require.NoError(t, err)
+
+	// The cached keys of programmatic accounts are overwritten when the wallet is locked, leaving those provided intact.
+	provided, err := w.AccountByPath("m/12381/3600/5/0")
+	require.NoError(t, err)
+	programmatic := provided.(*account)
+	element, exists := w.accountCache.entries["m/12381/3600/5/0"]
+	require.True(t, exists)
+	cachedKey := element.Value.(*accountCacheEntry).account.heldKey
+	require.NotNil(t, cachedKey)
+	assert.Equal(t, programmatic.heldKey.Marshal(), cachedKey.Marshal())
+	w.Lock()
+	assert.Equal(t, make([]byte, 32), cachedKey.Marshal())
+	assert.NotEqual(t, make([]byte, 32), programmatic.heldKey.Marshal())
+
+	// Locking a programmatic account keeps its held key so that it can be unlocked again.
+	programmatic.Lock()
+	assert.NotEqual(t, make([]byte, 32), programmatic.heldKey.Marshal())
+	require.NoError(t, programmatic.Unlock(nil))
+}
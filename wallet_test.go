@@ -14,8 +14,15 @@
 package hd_test
 
 import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
@@ -24,17 +31,22 @@ import (
 	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
 )
 
+func _byteArray(input string) []byte {
+	res, _ := hex.DecodeString(input)
+	return res
+}
+
 func TestCreateWallet(t *testing.T) {
 	store := scratch.New()
 	encryptor := keystorev4.New()
-	wallet, err := hd.CreateWallet("test wallet", []byte("wallet passphrase"), store, encryptor)
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")))
 	assert.Nil(t, err)
 
 	assert.Equal(t, "test wallet", wallet.Name())
-	assert.Equal(t, uint(1), wallet.Version())
+	assert.Equal(t, uint(2), wallet.Version())
 
 	// Try to create another wallet with the same name; should fail
-	_, err = hd.CreateWallet("test wallet", []byte("wallet passphrase"), store, encryptor)
+	_, err = hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")))
 	assert.NotNil(t, err)
 
 	// Try to obtain the key without unlocking the wallet; should fail
@@ -50,13 +62,15 @@ func TestCreateWallet(t *testing.T) {
 
 func TestCreateWalletFromSeed(t *testing.T) {
 	tests := []struct {
-		name string
-		seed []byte
-		err  string
+		name          string
+		seed          []byte
+		minSeedLength int
+		err           string
 	}{
 		{
-			name: "NoSeed",
-			err:  "seed must be 32 bytes",
+			name: "EmptySeed",
+			seed: []byte{},
+			err:  "seed must be between 32 and 64 bytes",
 		},
 		{
 			name: "ShortSeed",
@@ -64,16 +78,18 @@ func TestCreateWalletFromSeed(t *testing.T) {
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
 				0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e,
 			},
-			err: "seed must be 32 bytes",
+			err: "seed must be between 32 and 64 bytes",
 		},
 		{
 			name: "LongSeed",
 			seed: []byte{
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
 				0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
-				0x20,
+				0x20, 0x21, 0x22, 0x23, 0x24, 0x25, 0x26, 0x27, 0x28, 0x29, 0x2a, 0x2b, 0x2c, 0x2d, 0x2e, 0x2f,
+				0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39, 0x3a, 0x3b, 0x3c, 0x3d, 0x3e, 0x3f,
+				0x40,
 			},
-			err: "seed must be 32 bytes",
+			err: "seed must be between 32 and 64 bytes",
 		},
 		{
 			name: "Good",
@@ -82,6 +98,32 @@ func TestCreateWalletFromSeed(t *testing.T) {
 				0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
 			},
 		},
+		{
+			name: "GoodOdd",
+			seed: []byte{
+				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+				0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+				0x20,
+			},
+		},
+		{
+			name: "GoodBIP39",
+			seed: _byteArray("5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"),
+		},
+		{
+			name: "MinSeedLengthShort",
+			seed: []byte{
+				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+				0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f,
+			},
+			minSeedLength: 64,
+			err:           "seed must be between 64 and 64 bytes",
+		},
+		{
+			name:          "MinSeedLengthGood",
+			seed:          _byteArray("5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"),
+			minSeedLength: 64,
+		},
 		{
 			name: "Dup",
 			seed: []byte{
@@ -103,13 +145,550 @@ func TestCreateWalletFromSeed(t *testing.T) {
 	encryptor := keystorev4.New()
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := hd.CreateWalletFromSeed(test.name, []byte("wallet passphrase"), store, encryptor, test.seed)
+			opts := []hd.Option{
+				hd.WithPassphrase([]byte("wallet passphrase")),
+				hd.WithSeed(test.seed),
+			}
+			if test.minSeedLength != 0 {
+				opts = append(opts, hd.WithMinSeedLength(test.minSeedLength))
+			}
+			wallet, err := hd.CreateWallet(test.name, store, encryptor, opts...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+				seed, err := wallet.(wtypes.WalletKeyProvider).Key()
+				require.NoError(t, err)
+				assert.Equal(t, test.seed, seed)
+			}
+		})
+	}
+}
+
+func TestCreateWalletOptions(t *testing.T) {
+	seed := _byteArray("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	tests := []struct {
+		name string
+		opts []hd.Option
+		path string
+		err  string
+	}{
+		{
+			name: "Default",
+			path: "m/12381/3600/0/0",
+		},
+		{
+			name: "MinSeedLengthLow",
+			opts: []hd.Option{hd.WithMinSeedLength(31)},
+			err:  "minimum seed length must be between 32 and 64 bytes",
+		},
+		{
+			name: "MinSeedLengthHigh",
+			opts: []hd.Option{hd.WithMinSeedLength(65)},
+			err:  "minimum seed length must be between 32 and 64 bytes",
+		},
+		{
+			name: "SeedAndMnemonic",
+			opts: []hd.Option{hd.WithSeed(seed), hd.WithMnemonic(mnemonic)},
+			err:  "cannot supply both seed and mnemonic",
+		},
+		{
+			name: "MnemonicPassphraseWithoutMnemonic",
+			opts: []hd.Option{hd.WithSeed(seed), hd.WithMnemonicPassphrase([]byte("TREZOR"))},
+			err:  "cannot supply mnemonic passphrase without mnemonic",
+		},
+		{
+			name: "WalletIndex",
+			opts: []hd.Option{hd.WithSeed(seed), hd.WithWalletIndex(3)},
+			path: "m/12381/3600/3/0/0",
+		},
+		{
+			name: "PathTemplate",
+			opts: []hd.Option{hd.WithSeed(seed), hd.WithPathTemplate("m/12381/3600/%a/0/0")},
+			path: "m/12381/3600/0/0/0",
+		},
+		{
+			name: "PathTemplateWithWalletIndex",
+			opts: []hd.Option{hd.WithSeed(seed), hd.WithWalletIndex(2), hd.WithPathTemplate("m/12381/3600/%a/%w")},
+			path: "m/12381/3600/0/2",
+		},
+		{
+			name: "PathTemplateNoAccount",
+			opts: []hd.Option{hd.WithPathTemplate("m/12381/3600/%w/0")},
+			err:  `path template must contain account number "%a"`,
+		},
+		{
+			name: "PathTemplateNoMaster",
+			opts: []hd.Option{hd.WithPathTemplate("12381/3600/%a/0")},
+			err:  `path template must start with "m/"`,
+		},
+		{
+			name: "PathTemplateBadComponent",
+			opts: []hd.Option{hd.WithPathTemplate("m/12381/x/%a/0")},
+			err:  `path template component "x" invalid`,
+		},
+	}
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wallet, err := hd.CreateWallet(test.name, store, encryptor, test.opts...)
 			if test.err != "" {
 				require.EqualError(t, err, test.err)
 			} else {
 				require.NoError(t, err)
+				require.NoError(t, wallet.Unlock(nil))
+				account, err := wallet.CreateAccount("test", []byte("account passphrase"))
+				require.NoError(t, err)
+				assert.Equal(t, test.path, account.Path())
+
+				// Ensure that the path template survives reopening the wallet.
+				reopened, err := hd.OpenWallet(test.name, store, encryptor)
+				require.NoError(t, err)
+				assert.Equal(t, wallet.(hd.WalletPathTemplateProvider).PathTemplate(), reopened.(hd.WalletPathTemplateProvider).PathTemplate())
+				require.NoError(t, reopened.Unlock(nil))
+				account, err = reopened.CreateAccount("test 2", []byte("account passphrase"))
+				require.NoError(t, err)
+				assert.Equal(t, strings.Replace(test.path, "/0", "/1", 1), account.Path())
 			}
 		})
 	}
+}
+
+func TestDerivationState(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithWalletIndex(5))
+	require.NoError(t, err)
+	indexProvider, isIndexProvider := wallet.(hd.WalletIndexProvider)
+	require.True(t, isIndexProvider)
+	nextAccountProvider, isNextAccountProvider := wallet.(hd.WalletNextAccountProvider)
+	require.True(t, isNextAccountProvider)
+	assert.Equal(t, uint64(5), indexProvider.WalletIndex())
+	assert.Equal(t, uint64(0), nextAccountProvider.NextAccount())
+
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	_, err = wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), nextAccountProvider.NextAccount())
+
+	// Ensure the state survives reopening the wallet.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), wallet.(hd.WalletIndexProvider).WalletIndex())
+	assert.Equal(t, uint64(2), wallet.(hd.WalletNextAccountProvider).NextAccount())
+}
+
+func TestSetNextAccount(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	setter, isSetter := wallet.(hd.WalletNextAccountSetter)
+	require.True(t, isSetter)
+
+	assert.EqualError(t, setter.SetNextAccount(5), "wallet must be unlocked to set next account")
+
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	assert.EqualError(t, setter.SetNextAccount(0), "next account cannot be lowered from 1")
+	assert.EqualError(t, setter.SetNextAccount(math.MaxInt32+1), "next account too large")
+	require.NoError(t, setter.SetNextAccount(1))
+	require.NoError(t, setter.SetNextAccount(5))
+	account, err := wallet.CreateAccount("Account 2", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/5/0", account.Path())
+
+	// Ensure the change was persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), wallet.(hd.WalletNextAccountProvider).NextAccount())
+}
+
+func TestCreateWalletMnemonic(t *testing.T) {
+	tests := []struct {
+		name               string
+		walletIndex        uint64
+		mnemonic           string
+		mnemonicPassphrase []byte
+		seed               []byte
+		err                string
+	}{
+		{
+			name:     "BadWord",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon bad",
+			err:      "mnemonic is invalid",
+		},
+		{
+			name:     "BadChecksum",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+			err:      "mnemonic is invalid: Checksum incorrect",
+		},
+		{
+			name:        "BadWalletIndex",
+			walletIndex: 0x80000000,
+			mnemonic:    "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			err:         "wallet index too large",
+		},
+		{
+			name:     "Good",
+			mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			seed:     _byteArray("5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"),
+		},
+		{
+			name:               "GoodPassphrase",
+			mnemonic:           "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			mnemonicPassphrase: []byte("TREZOR"),
+			seed:               _byteArray("c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"),
+		},
+		{
+			// Passphrase provided in composed form is normalised to match its decomposed form.
+			name:               "GoodPassphraseNormalised",
+			mnemonic:           "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			mnemonicPassphrase: []byte("caf\u00e9"),
+			seed:               _byteArray("af8bbd2566df7b69d926f2b09dfdbd75db6c994a3399b2cc65f928d63e3fd4e61218ee0d15f8c810be4d45e66d47b43c15a5cc753976b1666912377ff7ae9818"),
+		},
+		{
+			name:        "GoodIndexed",
+			walletIndex: 5,
+			mnemonic:    "  legal winner thank year wave sausage worth useful legal winner thank yellow ",
+			seed:        _byteArray("878386efb78845b3355bd15ea4d39ef97d179cb712b77d5c12b6be415fffeffe5f377ba02bf3f8544ab800b955e51fbff09828f682052a20faa6addbbddfb096"),
+		},
+	}
+
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := []hd.Option{
+				hd.WithPassphrase([]byte("wallet passphrase")),
+				hd.WithWalletIndex(test.walletIndex),
+				hd.WithMnemonic(test.mnemonic),
+			}
+			if test.mnemonicPassphrase != nil {
+				opts = append(opts, hd.WithMnemonicPassphrase(test.mnemonicPassphrase))
+			}
+			wallet, err := hd.CreateWallet(test.name, store, encryptor, opts...)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+				seed, err := wallet.(wtypes.WalletKeyProvider).Key()
+				require.NoError(t, err)
+				assert.Equal(t, test.seed, seed)
+				account, err := wallet.CreateAccount("test", []byte("account passphrase"))
+				require.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf("m/12381/3600/%d/0/0", test.walletIndex), account.Path())
+			}
+		})
+	}
+}
+
+func TestCreateWalletWithMnemonic(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, mnemonic, err := hd.CreateWalletWithMnemonic("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")), hd.WithMnemonicPassphrase([]byte("mnemonic passphrase")))
+	require.NoError(t, err)
+	assert.Len(t, strings.Fields(mnemonic), 24)
+
+	// Try to create another wallet with the same name; should fail
+	_, _, err = hd.CreateWalletWithMnemonic("test wallet", store, encryptor)
+	assert.EqualError(t, err, `wallet "test wallet" already exists`)
+
+	// Try to create a wallet supplying a mnemonic; should fail
+	_, _, err = hd.CreateWalletWithMnemonic("bad wallet", store, encryptor, hd.WithMnemonic(mnemonic))
+	assert.EqualError(t, err, "cannot supply seed or mnemonic when generating a mnemonic")
+
+	// Recreate the wallet from the mnemonic without its passphrase and ensure the seeds differ
+	unprotectedWallet, err := hd.CreateWallet("unprotected wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")), hd.WithMnemonic(mnemonic))
+	require.NoError(t, err)
+	require.NoError(t, unprotectedWallet.Unlock([]byte("wallet passphrase")))
+	unprotectedSeed, err := unprotectedWallet.(wtypes.WalletKeyProvider).Key()
+	require.NoError(t, err)
+
+	// Recreate the wallet from the mnemonic and its passphrase and ensure the seeds match
+	recreatedWallet, err := hd.CreateWallet("recreated wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")), hd.WithMnemonic(mnemonic), hd.WithMnemonicPassphrase([]byte("mnemonic passphrase")))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+	seed, err := wallet.(wtypes.WalletKeyProvider).Key()
+	require.NoError(t, err)
+	assert.Len(t, seed, 64)
+	require.NoError(t, recreatedWallet.Unlock([]byte("wallet passphrase")))
+	recreatedSeed, err := recreatedWallet.(wtypes.WalletKeyProvider).Key()
+	require.NoError(t, err)
+	assert.Equal(t, seed, recreatedSeed)
+	assert.NotEqual(t, seed, unprotectedSeed)
+}
+
+func TestStoredMnemonic(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+
+	_, err := hd.CreateWallet("bad wallet", store, encryptor, hd.WithStoreMnemonic(true))
+	assert.EqualError(t, err, "cannot store mnemonic without mnemonic")
+
+	// Wallets do not store their mnemonic by default.
+	wallet, _, err := hd.CreateWalletWithMnemonic("unstored wallet", store, encryptor)
+	require.NoError(t, err)
+	_, err = wallet.(hd.WalletMnemonicProvider).Mnemonic(nil)
+	assert.EqualError(t, err, "wallet has no stored mnemonic")
+
+	wallet, mnemonic, err := hd.CreateWalletWithMnemonic("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")), hd.WithStoreMnemonic(true))
+	require.NoError(t, err)
+	mnemonicProvider, isMnemonicProvider := wallet.(hd.WalletMnemonicProvider)
+	require.True(t, isMnemonicProvider)
+	_, err = mnemonicProvider.Mnemonic([]byte("wrong passphrase"))
+	assert.EqualError(t, err, "incorrect passphrase")
+	storedMnemonic, err := mnemonicProvider.Mnemonic([]byte("wallet passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, mnemonic, storedMnemonic)
+
+	// Ensure the mnemonic is persisted, and is stored in normalised form.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	storedMnemonic, err = wallet.(hd.WalletMnemonicProvider).Mnemonic([]byte("wallet passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, mnemonic, storedMnemonic)
+	wallet, err = hd.CreateWallet("spaced wallet", store, encryptor, hd.WithMnemonic("  "+strings.Replace(mnemonic, " ", "   ", -1)), hd.WithStoreMnemonic(true))
+	require.NoError(t, err)
+	storedMnemonic, err = wallet.(hd.WalletMnemonicProvider).Mnemonic(nil)
+	require.NoError(t, err)
+	assert.Equal(t, mnemonic, storedMnemonic)
+}
+
+func TestDeterministicAccountIDs(t *testing.T) {
+	seed := []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+		0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+	}
+	encryptor := keystorev4.New()
+
+	// Accounts have random IDs by default.
+	store1 := scratch.New()
+	wallet1, err := hd.CreateWallet("test wallet", store1, encryptor, hd.WithSeed(seed))
+	require.NoError(t, err)
+	require.NoError(t, wallet1.Unlock(nil))
+	randomAccount, err := wallet1.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+
+	store2 := scratch.New()
+	wallet2, err := hd.CreateWallet("test wallet", store2, encryptor, hd.WithSeed(seed), hd.WithDeterministicAccountIDs(true))
+	require.NoError(t, err)
+	require.NoError(t, wallet2.Unlock(nil))
+	account, err := wallet2.CreateAccount("Account 0", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, randomAccount.ID(), account.ID())
+	assert.Equal(t, uuid.Version(5), account.ID().Version())
+
+	// The same key in a different store has the same ID.
+	store3 := scratch.New()
+	wallet3, err := hd.CreateWallet("other wallet", store3, encryptor, hd.WithSeed(seed), hd.WithDeterministicAccountIDs(true))
+	require.NoError(t, err)
+	require.NoError(t, wallet3.Unlock(nil))
+	otherAccount, err := wallet3.CreateAccount("Other account", nil)
+	require.NoError(t, err)
+	assert.Equal(t, account.ID(), otherAccount.ID())
+
+	// Ensure the option is persisted.
+	wallet3, err = hd.OpenWallet("other wallet", store3, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet3.Unlock(nil))
+	otherAccount, err = wallet3.CreateAccount("Other account 1", nil)
+	require.NoError(t, err)
+	account, err = wallet2.CreateAccount("Account 1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, account.ID(), otherAccount.ID())
+}
+
+func TestMetadata(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	provider, isProvider := wallet.(hd.WalletMetadataProvider)
+	require.True(t, isProvider)
+	assert.Empty(t, provider.Metadata())
+
+	require.EqualError(t, provider.SetMetadata("", "value"), "metadata key missing")
+	require.NoError(t, provider.SetMetadata("environment", "production"))
+	require.NoError(t, provider.SetMetadata("owner", "ops"))
+
+	// Changes to the returned metadata should not affect the wallet.
+	provider.Metadata()["owner"] = "someone else"
+	assert.Equal(t, map[string]string{"environment": "production", "owner": "ops"}, provider.Metadata())
+
+	// Ensure the metadata is persisted.
+	reopened, err := hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	reopenedProvider := reopened.(hd.WalletMetadataProvider)
+	assert.Equal(t, map[string]string{"environment": "production", "owner": "ops"}, reopenedProvider.Metadata())
+
+	// Remove a value.
+	require.NoError(t, reopenedProvider.SetMetadata("owner", ""))
+	reopened, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"environment": "production"}, reopened.(hd.WalletMetadataProvider).Metadata())
+}
+
+func TestRename(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")))
+	require.NoError(t, err)
+	_, err = hd.CreateWallet("other wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+	account, err := wallet.CreateAccount("test account", []byte("account passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	renamer, isRenamer := wallet.(hd.WalletRenamer)
+	require.True(t, isRenamer)
+	require.EqualError(t, renamer.Rename(""), "wallet name missing")
+	require.EqualError(t, renamer.Rename("_bad"), `invalid wallet name "_bad"`)
+	require.EqualError(t, renamer.Rename("other wallet"), `wallet "other wallet" already exists`)
+	assert.Equal(t, "test wallet", wallet.Name())
+
+	require.NoError(t, renamer.Rename("renamed wallet"))
+	assert.Equal(t, "renamed wallet", wallet.Name())
+
+	// Old name should no longer be present.
+	_, err = hd.OpenWallet("test wallet", store, encryptor)
+	assert.NotNil(t, err)
+
+	// Accounts should be accessible through the renamed wallet.
+	reopened, err := hd.OpenWallet("renamed wallet", store, encryptor)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID(), reopened.ID())
+	reopenedAccount, err := reopened.AccountByName("test account")
+	require.NoError(t, err)
+	assert.Equal(t, account.ID(), reopenedAccount.ID())
+}
+
+func TestCopyTo(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithPassphrase([]byte("wallet passphrase")))
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock([]byte("wallet passphrase")))
+	account1, err := wallet.CreateAccount("Account 1", []byte("account 1 passphrase"))
+	require.NoError(t, err)
+	account2, err := wallet.CreateAccount("Account 2", []byte("account 2 passphrase"))
+	require.NoError(t, err)
+	wallet.Lock()
+
+	destStore := scratch.New()
+	copied, err := wallet.(hd.WalletCopier).CopyTo(destStore)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID(), copied.ID())
+	assert.Equal(t, wallet.Name(), copied.Name())
+	assert.Equal(t, destStore, copied.(wtypes.StoreProvider).Store())
+
+	// Accounts should be available by name without passphrase round-trips.
+	reopened, err := hd.OpenWallet("test wallet", destStore, encryptor)
+	require.NoError(t, err)
+	copiedAccount1, err := reopened.AccountByName("Account 1")
+	require.NoError(t, err)
+	assert.Equal(t, account1.PublicKey().Marshal(), copiedAccount1.PublicKey().Marshal())
+	require.NoError(t, copiedAccount1.Unlock([]byte("account 1 passphrase")))
+	copiedAccount2, err := reopened.AccountByName("Account 2")
+	require.NoError(t, err)
+	assert.Equal(t, account2.ID(), copiedAccount2.ID())
+
+	// The wallet should continue to create accounts at the correct index.
+	require.NoError(t, reopened.Unlock([]byte("wallet passphrase")))
+	account3, err := reopened.CreateAccount("Account 3", []byte("account 3 passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, "m/12381/3600/2/0", account3.Path())
+
+	// Copying again should fail.
+	_, err = wallet.(hd.WalletCopier).CopyTo(destStore)
+	assert.EqualError(t, err, `wallet "test wallet" already exists`)
+}
+
+func TestAccountsWithContext(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	for i := 0; i < 5; i++ {
+		_, err := wallet.CreateAccount(fmt.Sprintf("Account %d", i), nil)
+		require.NoError(t, err)
+	}
+	provider, isProvider := wallet.(hd.WalletAccountsWithContextProvider)
+	require.True(t, isProvider)
+
+	accounts := 0
+	for range provider.AccountsWithContext(context.Background()) {
+		accounts++
+	}
+	assert.Equal(t, 5, accounts)
+
+	// Ensure a cancelled context closes the channel without providing accounts.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	accounts = 0
+	for range provider.AccountsWithContext(ctx) {
+		accounts++
+	}
+	assert.Equal(t, 0, accounts)
+}
+
+func TestConcurrentLockAndCreateAccount(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+
+	// Repeatedly lock and unlock the wallet while accounts are created.
+	done := make(chan struct{})
+	var lockerWG sync.WaitGroup
+	lockerWG.Add(1)
+	go func() {
+		defer lockerWG.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				wallet.Lock()
+				assert.NoError(t, wallet.Unlock(nil))
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	created := 0
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 8; j++ {
+				_, err := wallet.CreateAccount(fmt.Sprintf("Account %d-%d", i, j), nil)
+				if err != nil {
+					assert.EqualError(t, err, "wallet must be unlocked to create accounts")
+					continue
+				}
+				mutex.Lock()
+				created++
+				mutex.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(done)
+	lockerWG.Wait()
 
+	// Account numbers are only used by accounts that were created.
+	assert.Equal(t, uint64(created), wallet.(hd.WalletNextAccountProvider).NextAccount())
 }
@@ -0,0 +1,109 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// ErrWatchOnly is returned when an operation that requires secret information is attempted on a watch-only
+// wallet or account.
+var ErrWatchOnly = errors.New("wallet is watch-only")
+
+// WatchOnlyAccount contains the information required to create an account in a watch-only wallet.
+type WatchOnlyAccount struct {
+	// Name is the name of the account.
+	Name string
+	// Path is the path from which the account's key was derived.
+	Path string
+	// PublicKey is the public key of the account.
+	PublicKey e2types.PublicKey
+}
+
+// CreateWatchOnlyWallet creates a watch-only wallet with the given name and accounts, and stores it in the provided
+// store.  A watch-only wallet holds no seed and its accounts hold no private keys, so it can enumerate accounts and
+// their public keys but cannot create accounts or sign.
+// This will error if the wallet already exists.
+func CreateWatchOnlyWallet(name string, store wtypes.Store, encryptor wtypes.Encryptor, accounts []*WatchOnlyAccount) (wtypes.Wallet, error) {
+	// First, try to open the wallet.
+	_, err := OpenWallet(name, store, encryptor)
+	if err == nil || !strings.Contains(err.Error(), "wallet not found") {
+		return nil, fmt.Errorf("wallet %q already exists", name)
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	w := newWallet()
+	w.id = id
+	w.name = name
+	w.watchOnly = true
+	w.nextAccount = uint64(len(accounts))
+	w.version = version
+	w.created = time.Now()
+	w.store = store
+	w.encryptor = encryptor
+	w.encryptorName = encryptor.Name()
+	w.encryptorVersion = encryptor.Version()
+
+	newAccounts := make([]*account, 0, len(accounts))
+	for _, watchOnlyAccount := range accounts {
+		if watchOnlyAccount.Name == "" {
+			return nil, errors.New("account name missing")
+		}
+		if strings.HasPrefix(watchOnlyAccount.Name, "_") {
+			return nil, fmt.Errorf("invalid account name %q", watchOnlyAccount.Name)
+		}
+		if watchOnlyAccount.PublicKey == nil {
+			return nil, fmt.Errorf("public key missing for account %q", watchOnlyAccount.Name)
+		}
+		if _, exists := w.index.ID(watchOnlyAccount.Name); exists {
+			return nil, fmt.Errorf("account with name %q already exists", watchOnlyAccount.Name)
+		}
+
+		a := newAccount()
+		if a.id, err = uuid.NewRandom(); err != nil {
+			return nil, err
+		}
+		a.name = watchOnlyAccount.Name
+		a.path = watchOnlyAccount.Path
+		a.publicKey = watchOnlyAccount.PublicKey
+		a.watchOnly = true
+		a.encryptor = encryptor
+		a.version = encryptor.Version()
+		a.wallet = w
+		w.index.Add(a.id, a.name)
+		newAccounts = append(newAccounts, a)
+	}
+
+	if err := w.storeWallet(); err != nil {
+		return nil, err
+	}
+	for _, a := range newAccounts {
+		if err := a.storeAccount(); err != nil {
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+func TestWatchOnlyWallet(t *testing.T) {
+	store := scratch.New()
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	require.NoError(t, wallet.Unlock(nil))
+	account1, err := wallet.CreateAccount("Account 1", []byte("account 1 passphrase"))
+	require.NoError(t, err)
+	account2, err := wallet.CreateAccount("Account 2", []byte("account 2 passphrase"))
+	require.NoError(t, err)
+
+	accounts := []*hd.WatchOnlyAccount{
+		{Name: account1.Name(), Path: account1.Path(), PublicKey: account1.PublicKey()},
+		{Name: account2.Name(), Path: account2.Path(), PublicKey: account2.PublicKey()},
+	}
+
+	_, err = hd.CreateWatchOnlyWallet("bad wallet", store, encryptor, []*hd.WatchOnlyAccount{{Name: "Account 1"}})
+	require.EqualError(t, err, `public key missing for account "Account 1"`)
+	_, err = hd.CreateWatchOnlyWallet("bad wallet", store, encryptor, append(accounts, accounts[0]))
+	require.EqualError(t, err, `account with name "Account 1" already exists`)
+	_, err = hd.CreateWatchOnlyWallet("test wallet", store, encryptor, accounts)
+	require.EqualError(t, err, `wallet "test wallet" already exists`)
+
+	_, err = hd.CreateWatchOnlyWallet("watch-only wallet", store, encryptor, accounts)
+	require.NoError(t, err)
+
+	// Reopen to ensure that the wallet is stored correctly.
+	watchOnly, err := hd.OpenWallet("watch-only wallet", store, encryptor)
+	require.NoError(t, err)
+
+	assert.Equal(t, hd.ErrWatchOnly, watchOnly.Unlock(nil))
+	assert.False(t, watchOnly.IsUnlocked())
+	_, err = watchOnly.CreateAccount("Account 3", nil)
+	assert.Equal(t, hd.ErrWatchOnly, err)
+	_, err = watchOnly.(wtypes.WalletKeyProvider).Key()
+	assert.Equal(t, hd.ErrWatchOnly, err)
+	_, err = watchOnly.AccountByName("m/12381/3600/0/0")
+	assert.Equal(t, hd.ErrWatchOnly, err)
+
+	found := 0
+	for account := range watchOnly.Accounts() {
+		found++
+		assert.Equal(t, hd.ErrWatchOnly, account.Unlock([]byte("account 1 passphrase")))
+		_, err := account.(wtypes.AccountPrivateKeyProvider).PrivateKey()
+		assert.Equal(t, hd.ErrWatchOnly, err)
+	}
+	assert.Equal(t, 2, found)
+
+	account, err := watchOnly.AccountByName("Account 2")
+	require.NoError(t, err)
+	assert.Equal(t, account2.Path(), account.Path())
+	assert.Equal(t, account2.PublicKey().Marshal(), account.PublicKey().Marshal())
+}
@@ -0,0 +1,231 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	util "github.com/wealdtech/go-eth2-util"
+	wtypes "github.com/wealdtech/go-eth2-wallet-types/v2"
+)
+
+// CreateWithdrawalAccount creates a withdrawal account in the wallet using the next account number.
+// Withdrawal accounts use the account path without its final "/0" component, as per EIP-2334, so for the
+// default path template the path is m/12381/3600/walletIndex/n.  The wallet's path template must end in "/0".
+func (w *wallet) CreateWithdrawalAccount(name string, passphrase []byte) (wtypes.Account, error) {
+	a, err := w.createWithdrawalAccount(name, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	w.hooks.accountCreated(a)
+
+	return a, nil
+}
+
+// createWithdrawalAccount creates a withdrawal account in the wallet without calling hooks.
+func (w *wallet) createWithdrawalAccount(name string, passphrase []byte) (wtypes.Account, error) {
+	if name == "" {
+		return nil, errors.New("account name missing")
+	}
+	if strings.HasPrefix(name, "_") {
+		return nil, fmt.Errorf("invalid account name %q", name)
+	}
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+	passphrase, err := w.accountPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !w.supportsWithdrawalAccounts() {
+		return nil, errors.New("path template does not support withdrawal accounts")
+	}
+	if err := w.checkPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+
+	// Ensure that we don't already have an account with this name
+	if _, err := w.AccountByName(name); err == nil {
+		return nil, fmt.Errorf("account with name %q already exists", name)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+
+	accountNum := w.nextAccount
+	w.nextAccount++
+	if err := w.storeWallet(); err != nil {
+		return nil, errors.Wrapf(err, "failed to create account %q", name)
+	}
+
+	a, err := w.deriveAccountAtPath(name, w.withdrawalPath(accountNum), passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	w.index.Add(a.id, a.name)
+
+	if err := a.storeAccount(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// supportsWithdrawalAccounts returns true if the wallet's path template ends in the signing key component, which can
+// be dropped to obtain the withdrawal path.  Wallets with a path provider do not support withdrawal accounts.
+func (w *wallet) supportsWithdrawalAccounts() bool {
+	return w.pathProvider == nil && strings.HasSuffix(w.pathTemplate, "/0")
+}
+
+// isWithdrawalAccount returns true if the account's path is a withdrawal path generated from its wallet's path
+// template.
+func (a *account) isWithdrawalAccount() bool {
+	w, ok := a.wallet.(*wallet)
+	if !ok || !w.supportsWithdrawalAccounts() {
+		return false
+	}
+	derivationPath, err := w.parsePath(a.path)
+	if err != nil {
+		return false
+	}
+	return derivationPath.Use == nil && len(derivationPath.Extension) == 0
+}
+
+// withdrawalPath provides the withdrawal path for the given account number.
+func (w *wallet) withdrawalPath(accountNum uint64) string {
+	return strings.TrimSuffix(w.accountPath(accountNum), "/0")
+}
+
+// blsWithdrawalPrefix is the prefix for BLS withdrawal credentials.
+const blsWithdrawalPrefix = 0x00
+
+// ValidatorPair contains a validator's signing and withdrawal accounts.
+type ValidatorPair struct {
+	// Signing is the validator's signing account.
+	Signing wtypes.Account
+	// Withdrawal is the validator's withdrawal account.
+	Withdrawal wtypes.Account
+	// WithdrawalCredentials are the BLS withdrawal credentials for the withdrawal account.
+	WithdrawalCredentials []byte
+}
+
+// CreateValidatorPair creates a signing account and its matching withdrawal account with the next account number.
+// The signing account is given the supplied name, and the withdrawal account the supplied name followed by
+// " withdrawal".  Both accounts are derived before either is stored, and if the second account cannot be stored
+// the first is removed again where the store supports account deletion.  The wallet's path template must end in "/0".
+func (w *wallet) CreateValidatorPair(name string, passphrase []byte) (*ValidatorPair, error) {
+	pair, err := w.createValidatorPair(name, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	w.hooks.accountCreated(pair.Withdrawal)
+	w.hooks.accountCreated(pair.Signing)
+
+	return pair, nil
+}
+
+// createValidatorPair creates a signing account and its matching withdrawal account without calling hooks.
+func (w *wallet) createValidatorPair(name string, passphrase []byte) (*ValidatorPair, error) {
+	if name == "" {
+		return nil, errors.New("account name missing")
+	}
+	if strings.HasPrefix(name, "_") {
+		return nil, fmt.Errorf("invalid account name %q", name)
+	}
+	if w.watchOnly {
+		return nil, ErrWatchOnly
+	}
+	if !w.IsUnlocked() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+	passphrase, err := w.accountPassphrase(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if !w.supportsWithdrawalAccounts() {
+		return nil, errors.New("path template does not support withdrawal accounts")
+	}
+	withdrawalName := fmt.Sprintf("%s withdrawal", name)
+	for _, accountName := range []string{name, withdrawalName} {
+		if _, err := w.AccountByName(accountName); err == nil {
+			return nil, fmt.Errorf("account with name %q already exists", accountName)
+		}
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.hasSeed() {
+		return nil, errors.New("wallet must be unlocked to create accounts")
+	}
+
+	accountNum := w.nextAccount
+	signing, err := w.deriveAccount(name, accountNum, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	withdrawal, err := w.deriveAccountAtPath(withdrawalName, w.withdrawalPath(accountNum), passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	w.nextAccount++
+	if err := w.storeWallet(); err != nil {
+		w.nextAccount = accountNum
+		return nil, errors.Wrapf(err, "failed to create account %q", name)
+	}
+
+	w.index.Add(withdrawal.id, withdrawal.name)
+	if err := withdrawal.storeAccount(); err != nil {
+		w.index.Remove(withdrawal.id, withdrawal.name)
+		return nil, err
+	}
+	w.index.Add(signing.id, signing.name)
+	if err := signing.storeAccount(); err != nil {
+		w.index.Remove(signing.id, signing.name)
+		w.index.Remove(withdrawal.id, withdrawal.name)
+		if deleter, isDeleter := w.store.(AccountDeleter); isDeleter {
+			if deleteErr := deleter.DeleteAccount(w.id, withdrawal.id); deleteErr != nil {
+				return nil, errors.Wrapf(err, "failed to store account %q, and failed to remove account %q: %v", signing.name, withdrawal.name, deleteErr)
+			}
+		}
+		if indexErr := w.storeAccountsIndex(); indexErr != nil {
+			return nil, errors.Wrapf(err, "failed to store account %q, and failed to restore accounts index: %v", signing.name, indexErr)
+		}
+		return nil, errors.Wrapf(err, "failed to store account %q", signing.name)
+	}
+
+	return &ValidatorPair{
+		Signing:               signing,
+		Withdrawal:            withdrawal,
+		WithdrawalCredentials: withdrawalCredentials(withdrawal.publicKey.Marshal()),
+	}, nil
+}
+
+// withdrawalCredentials provides the BLS withdrawal credentials for a withdrawal public key.
+func withdrawalCredentials(publicKey []byte) []byte {
+	credentials := util.SHA256(publicKey)
+	credentials[0] = blsWithdrawalPrefix
+	return credentials
+}
@@ -0,0 +1,143 @@
+// Copyright 2019, 2020 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hd_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	keystorev4 "github.com/wealdtech/go-eth2-wallet-encryptor-keystorev4"
+	hd "github.com/wealdtech/go-eth2-wallet-hd/v2"
+	scratch "github.com/wealdtech/go-eth2-wallet-store-scratch"
+)
+
+func TestCreateWithdrawalAccount(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []hd.Option
+		err  string
+		path string
+	}{
+		{
+			name: "Legacy",
+			path: "m/12381/3600/1",
+		},
+		{
+			name: "Indexed",
+			opts: []hd.Option{hd.WithWalletIndex(3)},
+			path: "m/12381/3600/3/1",
+		},
+		{
+			name: "EIP2334",
+			opts: []hd.Option{hd.WithPathTemplate("m/12381/3600/%a/0/0")},
+			path: "m/12381/3600/1/0",
+		},
+		{
+			name: "Unsupported",
+			opts: []hd.Option{hd.WithPathTemplate("m/12381/3600/0/%a")},
+			err:  "path template does not support withdrawal accounts",
+		},
+	}
+
+	encryptor := keystorev4.New()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := scratch.New()
+			wallet, err := hd.CreateWallet("test wallet", store, encryptor, test.opts...)
+			require.NoError(t, err)
+			require.NoError(t, wallet.Unlock(nil))
+			_, err = wallet.CreateAccount("Signing 0", nil)
+			require.NoError(t, err)
+			creator, isCreator := wallet.(hd.WalletWithdrawalAccountCreator)
+			require.True(t, isCreator)
+
+			account, err := creator.CreateWithdrawalAccount("Withdrawal 1", []byte("withdrawal passphrase"))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.path, account.Path())
+			_, err = creator.CreateWithdrawalAccount("Withdrawal 1", nil)
+			assert.EqualError(t, err, `account with name "Withdrawal 1" already exists`)
+
+			// Ensure the key matches that derived directly from the path, and the account number is consumed.
+			direct, err := wallet.AccountByName(test.path)
+			require.NoError(t, err)
+			assert.Equal(t, direct.PublicKey().Marshal(), account.PublicKey().Marshal())
+			assert.Equal(t, uint64(2), wallet.(hd.WalletNextAccountProvider).NextAccount())
+			gaps, err := wallet.(hd.WalletIndexGapsProvider).IndexGaps()
+			require.NoError(t, err)
+			assert.Empty(t, gaps)
+
+			wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+			require.NoError(t, err)
+			account, err = wallet.AccountByName("Withdrawal 1")
+			require.NoError(t, err)
+			assert.Equal(t, test.path, account.Path())
+			require.NoError(t, account.Unlock([]byte("withdrawal passphrase")))
+		})
+	}
+}
+
+func TestCreateValidatorPair(t *testing.T) {
+	store := &failingAccountStore{Store: scratch.New()}
+	encryptor := keystorev4.New()
+	wallet, err := hd.CreateWallet("test wallet", store, encryptor, hd.WithWalletIndex(1))
+	require.NoError(t, err)
+	creator, isCreator := wallet.(hd.WalletValidatorPairCreator)
+	require.True(t, isCreator)
+
+	_, err = creator.CreateValidatorPair("Validator 0", nil)
+	assert.EqualError(t, err, "wallet must be unlocked to create accounts")
+
+	require.NoError(t, wallet.Unlock(nil))
+	_, err = wallet.CreateAccount("Validator 1 withdrawal", nil)
+	require.NoError(t, err)
+	_, err = creator.CreateValidatorPair("Validator 1", nil)
+	assert.EqualError(t, err, `account with name "Validator 1 withdrawal" already exists`)
+
+	pair, err := creator.CreateValidatorPair("Validator 0", []byte("validator passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, "Validator 0", pair.Signing.Name())
+	assert.Equal(t, "m/12381/3600/1/1/0", pair.Signing.Path())
+	assert.Equal(t, "Validator 0 withdrawal", pair.Withdrawal.Name())
+	assert.Equal(t, "m/12381/3600/1/1", pair.Withdrawal.Path())
+	credentials := sha256.Sum256(pair.Withdrawal.PublicKey().Marshal())
+	credentials[0] = 0x00
+	assert.Equal(t, credentials[:], pair.WithdrawalCredentials)
+
+	// Ensure a failure to store the accounts leaves neither in the wallet.
+	store.fail = true
+	_, err = creator.CreateValidatorPair("Validator 2", nil)
+	assert.EqualError(t, err, "store unavailable")
+	store.fail = false
+	_, err = wallet.AccountByName("Validator 2")
+	assert.NotNil(t, err)
+	_, err = wallet.AccountByName("Validator 2 withdrawal")
+	assert.NotNil(t, err)
+
+	// Ensure the pair was persisted.
+	wallet, err = hd.OpenWallet("test wallet", store, encryptor)
+	require.NoError(t, err)
+	signing, err := wallet.AccountByName("Validator 0")
+	require.NoError(t, err)
+	assert.Equal(t, pair.Signing.PublicKey().Marshal(), signing.PublicKey().Marshal())
+	withdrawal, err := wallet.AccountByName("Validator 0 withdrawal")
+	require.NoError(t, err)
+	assert.Equal(t, pair.Withdrawal.PublicKey().Marshal(), withdrawal.PublicKey().Marshal())
+	require.NoError(t, withdrawal.Unlock([]byte("validator passphrase")))
+}